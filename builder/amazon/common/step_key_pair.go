@@ -67,6 +67,8 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 	// Set some data for use in future steps
 	s.Comm.SSHKeyPairName = s.Comm.SSHTemporaryKeyPairName
 	s.Comm.SSHPrivateKey = []byte(*keyResp.KeyMaterial)
+	state.Put("keyPair", s.Comm.SSHTemporaryKeyPairName)
+	state.Put("privateKey", *keyResp.KeyMaterial)
 
 	// If we're in debug mode, output the private key to the working
 	// directory.
@@ -97,6 +99,32 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys lets a resumed build use the temporary key pair of the
+// failed build to connect to its instance. The private key is saved to the
+// checkpoint, which only the user can read.
+func (s *StepKeyPair) ResumeStateKeys() []string {
+	return []string{"keyPair", "privateKey"}
+}
+
+func (s *StepKeyPair) Resume(state multistep.StateBag) bool {
+	keyPair, ok := state.Get("keyPair").(string)
+	privateKey, pkOk := state.Get("privateKey").(string)
+	if !ok || !pkOk {
+		// No temporary key pair was created, so running the step again
+		// only reads the key that is configured.
+		if s.Comm.SSHPrivateKeyFile == "" && s.Comm.SSHTemporaryKeyPairName != "" && !s.Comm.SSHAgentAuth {
+			return false
+		}
+		return s.Run(context.Background(), state) == multistep.ActionContinue
+	}
+
+	s.doCleanup = true
+	s.Comm.SSHTemporaryKeyPairName = keyPair
+	s.Comm.SSHKeyPairName = keyPair
+	s.Comm.SSHPrivateKey = []byte(privateKey)
+	return true
+}
+
 func (s *StepKeyPair) Cleanup(state multistep.StateBag) {
 	if !s.doCleanup {
 		return
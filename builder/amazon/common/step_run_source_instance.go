@@ -223,6 +223,7 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 	}
 
 	state.Put("instance", instance)
+	state.Put("instance_id", instanceId)
 
 	// If we're in a region that doesn't support tagging on instance creation,
 	// do that now.
@@ -291,6 +292,40 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys lets a resumed build provision the instance of the failed
+// build, rather than launch and set up a new one.
+func (s *StepRunSourceInstance) ResumeStateKeys() []string {
+	return []string{"instance_id"}
+}
+
+func (s *StepRunSourceInstance) Resume(state multistep.StateBag) bool {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	instanceId, ok := state.Get("instance_id").(string)
+	if !ok {
+		return false
+	}
+
+	r, err := ec2conn.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(instanceId)},
+	})
+	if err != nil || len(r.Reservations) == 0 || len(r.Reservations[0].Instances) == 0 {
+		log.Printf("Error finding the instance of the failed build: %v", err)
+		return false
+	}
+	instance := r.Reservations[0].Instances[0]
+	if *instance.State.Name != ec2.InstanceStateNameRunning {
+		log.Printf("The instance of the failed build is %s", *instance.State.Name)
+		return false
+	}
+
+	ui.Say(fmt.Sprintf("Using the instance of the failed build: %s", instanceId))
+	s.instanceId = instanceId
+	state.Put("instance", instance)
+	return true
+}
+
 func (s *StepRunSourceInstance) Cleanup(state multistep.StateBag) {
 
 	ec2conn := state.Get("ec2").(*ec2.EC2)
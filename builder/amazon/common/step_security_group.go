@@ -153,6 +153,31 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys lets a resumed build keep the security group of the failed
+// build, which its instance is in.
+func (s *StepSecurityGroup) ResumeStateKeys() []string {
+	return []string{"securityGroupIds"}
+}
+
+func (s *StepSecurityGroup) Resume(state multistep.StateBag) bool {
+	raw, ok := state.Get("securityGroupIds").([]interface{})
+	if !ok {
+		return false
+	}
+
+	securityGroupIds := make([]string, len(raw))
+	for i, id := range raw {
+		securityGroupIds[i] = id.(string)
+	}
+	state.Put("securityGroupIds", securityGroupIds)
+
+	// Without groups to use, the failed build created one
+	if len(s.SecurityGroupIds) == 0 && s.SecurityGroupFilter.Empty() && len(securityGroupIds) == 1 {
+		s.createdGroupId = securityGroupIds[0]
+	}
+	return true
+}
+
 func (s *StepSecurityGroup) Cleanup(state multistep.StateBag) {
 	if s.createdGroupId == "" {
 		return
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStepSecurityGroup_Resume(t *testing.T) {
+	state := testState()
+	step := new(StepSecurityGroup)
+
+	if step.Resume(state) {
+		t.Fatal("should not resume without security groups")
+	}
+
+	// The checkpoint turns the slice of strings into a slice of interfaces
+	state.Put("securityGroupIds", []interface{}{"sg-1"})
+	if !step.Resume(state) {
+		t.Fatal("should resume")
+	}
+	if ids := state.Get("securityGroupIds"); !reflect.DeepEqual(ids, []string{"sg-1"}) {
+		t.Fatalf("bad: %#v", ids)
+	}
+	if step.createdGroupId != "sg-1" {
+		t.Fatalf("the temporary security group should be cleaned up: %#v", step.createdGroupId)
+	}
+
+	step = &StepSecurityGroup{SecurityGroupIds: []string{"sg-1"}}
+	state.Put("securityGroupIds", []interface{}{"sg-1"})
+	if !step.Resume(state) {
+		t.Fatal("should resume")
+	}
+	if step.createdGroupId != "" {
+		t.Fatal("the security group that is configured should be kept")
+	}
+}
@@ -6,6 +6,7 @@
 package ebs
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -86,6 +87,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	session, err := b.config.Session()
 	if err != nil {
@@ -245,7 +251,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
 		return nil, rawErr.(error)
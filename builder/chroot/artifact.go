@@ -0,0 +1,37 @@
+package chroot
+
+import (
+	"fmt"
+	"os"
+)
+
+// Artifact is the disk image the builder customised.
+type Artifact struct {
+	dir   string
+	f     []string
+	state map[string]interface{}
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.f
+}
+
+func (a *Artifact) Id() string {
+	return a.state["diskName"].(string)
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("Disk image: %s", a.f[0])
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return a.state[name]
+}
+
+func (a *Artifact) Destroy() error {
+	return os.RemoveAll(a.dir)
+}
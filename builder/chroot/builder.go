@@ -0,0 +1,121 @@
+// The chroot package customises local disk images without booting them. It
+// attaches a raw or qcow2 image to a loop or NBD device, mounts its
+// partitions and runs the provisioners in a chroot of its root filesystem.
+package chroot
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"runtime"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The unique ID for this builder
+const BuilderId = "packer.chroot"
+
+type wrappedCommandTemplate struct {
+	Command string
+}
+
+type Builder struct {
+	config *Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	c, warnings, errs := NewConfig(raws...)
+	if errs != nil {
+		return warnings, errs
+	}
+	b.config = c
+
+	return warnings, nil
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("The chroot builder only works on Linux environments.")
+	}
+
+	wrappedCommand := func(command string) (string, error) {
+		ctx := b.config.ctx
+		ctx.Data = &wrappedCommandTemplate{Command: command}
+		return interpolate.Render(b.config.CommandWrapper, &ctx)
+	}
+
+	steps := []multistep.Step{
+		&common.StepOutputDir{
+			Force: b.config.PackerForce,
+			Path:  b.config.OutputDir,
+		},
+		&StepCopyImage{},
+		&StepAttachImage{},
+		&chroot.StepPreMountCommands{
+			Commands: b.config.PreMountCommands,
+			Ctx:      b.config.ctx,
+		},
+		&StepMountImage{
+			MountOptions:    b.config.MountOptions,
+			MountPartition:  b.config.MountPartition,
+			PartitionMounts: b.config.PartitionMounts,
+		},
+		&chroot.StepPostMountCommands{
+			Commands: b.config.PostMountCommands,
+			Ctx:      b.config.ctx,
+		},
+		&chroot.StepMountExtra{
+			ChrootMounts: b.config.ChrootMounts,
+		},
+		&chroot.StepCopyFiles{
+			Files: b.config.CopyFiles,
+		},
+		&chroot.StepChrootProvision{},
+		&chroot.StepEarlyCleanup{},
+	}
+
+	// Setup the state bag and initial state for the steps
+	state := new(multistep.BasicStateBag)
+	state.Put("config", b.config)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+	state.Put("wrappedCommand", chroot.CommandWrapper(wrappedCommand))
+
+	// Run!
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If it was cancelled, then just return
+	if _, ok := state.GetOk(multistep.StateCancelled); ok {
+		return nil, nil
+	}
+
+	imagePath := state.Get("image_path").(string)
+	artifact := &Artifact{
+		dir: b.config.OutputDir,
+		f:   []string{imagePath},
+		state: map[string]interface{}{
+			"diskName": filepath.Base(imagePath),
+			"diskType": b.config.SourceFormat,
+		},
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
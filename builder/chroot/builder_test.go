@@ -0,0 +1,37 @@
+package chroot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func testState(t *testing.T, config *Config) multistep.StateBag {
+	state := new(multistep.BasicStateBag)
+	state.Put("config", config)
+	state.Put("hook", &packer.MockHook{})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	state.Put("wrappedCommand", chroot.CommandWrapper(func(command string) (string, error) {
+		return command, nil
+	}))
+	return state
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var _ packer.Builder = new(Builder)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestSteps_ImplementCleanupFunc(t *testing.T) {
+	var _ chroot.Cleanup = new(StepAttachImage)
+	var _ chroot.Cleanup = new(StepMountImage)
+}
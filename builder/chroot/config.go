@@ -0,0 +1,194 @@
+package chroot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The formats of the images the builder can customise.
+const (
+	FormatRaw   = "raw"
+	FormatQcow2 = "qcow2"
+)
+
+// The ways of attaching an image to a block device.
+const (
+	AttachLoop = "loop"
+	AttachNBD  = "nbd"
+)
+
+// qcow2Magic is what qcow2 images start with.
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+// Config is the configuration that is chained through the steps and
+// settable from the template.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	AttachMethod      string     `mapstructure:"attach_method"`
+	ChrootMounts      [][]string `mapstructure:"chroot_mounts"`
+	CommandWrapper    string     `mapstructure:"command_wrapper"`
+	CopyFiles         []string   `mapstructure:"copy_files"`
+	ImageName         string     `mapstructure:"image_name"`
+	MountOptions      []string   `mapstructure:"mount_options"`
+	MountPartition    string     `mapstructure:"mount_partition"`
+	MountPath         string     `mapstructure:"mount_path"`
+	NBDDevice         string     `mapstructure:"nbd_device"`
+	OutputDir         string     `mapstructure:"output_directory"`
+	PartitionMounts   [][]string `mapstructure:"partition_mounts"`
+	PostMountCommands []string   `mapstructure:"post_mount_commands"`
+	PreMountCommands  []string   `mapstructure:"pre_mount_commands"`
+	SourceFormat      string     `mapstructure:"source_format"`
+	SourcePath        string     `mapstructure:"source_path"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"command_wrapper",
+				"post_mount_commands",
+				"pre_mount_commands",
+				"mount_path",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.ChrootMounts == nil {
+		c.ChrootMounts = [][]string{
+			{"proc", "proc", "/proc"},
+			{"sysfs", "sysfs", "/sys"},
+			{"bind", "/dev", "/dev"},
+			{"devpts", "devpts", "/dev/pts"},
+		}
+	}
+
+	if c.CopyFiles == nil {
+		c.CopyFiles = []string{"/etc/resolv.conf"}
+	}
+
+	if c.CommandWrapper == "" {
+		c.CommandWrapper = "{{.Command}}"
+	}
+
+	if c.MountPath == "" {
+		c.MountPath = "/mnt/packer-chroot-images/{{.Device}}"
+	}
+
+	if c.MountPartition == "" {
+		c.MountPartition = "1"
+	}
+
+	if c.OutputDir == "" {
+		c.OutputDir = fmt.Sprintf("output-%s", c.PackerBuildName)
+	}
+
+	if c.ImageName == "" && c.SourcePath != "" {
+		c.ImageName = filepath.Base(c.SourcePath)
+	}
+
+	var errs *packer.MultiError
+	if c.SourcePath == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("source_path is required"))
+	} else if c.SourceFormat == "" {
+		format, err := detectFormat(c.SourcePath)
+		if err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is invalid: %s", err))
+		}
+		c.SourceFormat = format
+	} else if _, err := os.Stat(c.SourcePath); err != nil {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is invalid: %s", err))
+	}
+
+	switch c.SourceFormat {
+	case "", FormatRaw, FormatQcow2:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"source_format must be %q or %q", FormatRaw, FormatQcow2))
+	}
+
+	if c.AttachMethod == "" {
+		c.AttachMethod = AttachLoop
+		if c.SourceFormat == FormatQcow2 {
+			c.AttachMethod = AttachNBD
+		}
+	}
+	switch c.AttachMethod {
+	case AttachLoop:
+		if c.SourceFormat == FormatQcow2 {
+			errs = packer.MultiErrorAppend(errs, errors.New(
+				"qcow2 images can only be attached with the nbd attach_method"))
+		}
+	case AttachNBD:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"attach_method must be %q or %q", AttachLoop, AttachNBD))
+	}
+
+	if c.NBDDevice != "" && c.AttachMethod != AttachNBD {
+		errs = packer.MultiErrorAppend(errs, errors.New(
+			"nbd_device can only be set with the nbd attach_method"))
+	}
+
+	for _, mounts := range c.ChrootMounts {
+		if len(mounts) != 3 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("Each chroot_mounts entry should be three elements."))
+			break
+		}
+	}
+
+	for _, mount := range c.PartitionMounts {
+		if len(mount) != 2 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("Each partition_mounts entry should be two elements."))
+			break
+		}
+		if !filepath.IsAbs(mount[1]) {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("partition_mounts path must be absolute: %s", mount[1]))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	return c, nil, nil
+}
+
+// detectFormat returns the format of the image at path.
+func detectFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(qcow2Magic))
+	if _, err := io.ReadFull(f, magic); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if bytes.Equal(magic, qcow2Magic) {
+		return FormatQcow2, nil
+	}
+	return FormatRaw, nil
+}
@@ -0,0 +1,168 @@
+package chroot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testImage(t *testing.T, data []byte) string {
+	f, err := ioutil.TempFile("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return f.Name()
+}
+
+func testConfig(t *testing.T) map[string]interface{} {
+	return map[string]interface{}{
+		"source_path":       testImage(t, make([]byte, 1024)),
+		"packer_build_name": "test",
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	raw := testConfig(t)
+	defer os.Remove(raw["source_path"].(string))
+
+	c, warns, err := NewConfig(raw)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.OutputDir != "output-test" {
+		t.Fatalf("bad: %s", c.OutputDir)
+	}
+	if c.SourceFormat != FormatRaw || c.AttachMethod != AttachLoop {
+		t.Fatalf("bad: %s %s", c.SourceFormat, c.AttachMethod)
+	}
+	if c.MountPartition != "1" {
+		t.Fatalf("bad: %s", c.MountPartition)
+	}
+	if c.ImageName != filepath.Base(raw["source_path"].(string)) {
+		t.Fatalf("bad: %s", c.ImageName)
+	}
+	if len(c.ChrootMounts) != 4 || len(c.CopyFiles) != 1 {
+		t.Fatalf("bad: %#v %#v", c.ChrootMounts, c.CopyFiles)
+	}
+}
+
+func TestNewConfig_sourcePath(t *testing.T) {
+	raw := testConfig(t)
+	os.Remove(raw["source_path"].(string))
+
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error for a missing source_path")
+	}
+
+	raw["source_format"] = FormatRaw
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error for a missing source_path")
+	}
+
+	delete(raw, "source_path")
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error without source_path")
+	}
+}
+
+func TestNewConfig_qcow2(t *testing.T) {
+	raw := testConfig(t)
+	source := testImage(t, append(qcow2Magic, 0, 0, 0, 3))
+	defer os.Remove(source)
+	defer os.Remove(raw["source_path"].(string))
+	raw["source_path"] = source
+
+	c, _, err := NewConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.SourceFormat != FormatQcow2 || c.AttachMethod != AttachNBD {
+		t.Fatalf("bad: %s %s", c.SourceFormat, c.AttachMethod)
+	}
+
+	raw["attach_method"] = AttachLoop
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error attaching qcow2 to a loop device")
+	}
+}
+
+func TestNewConfig_sourceFormat(t *testing.T) {
+	raw := testConfig(t)
+	defer os.Remove(raw["source_path"].(string))
+
+	raw["source_format"] = "vmdk"
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error")
+	}
+
+	// The format isn't detected when it is set
+	raw["source_format"] = FormatQcow2
+	c, _, err := NewConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.AttachMethod != AttachNBD {
+		t.Fatalf("bad: %s", c.AttachMethod)
+	}
+}
+
+func TestNewConfig_attachMethod(t *testing.T) {
+	raw := testConfig(t)
+	defer os.Remove(raw["source_path"].(string))
+
+	raw["attach_method"] = "iscsi"
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error")
+	}
+
+	raw["attach_method"] = AttachNBD
+	raw["nbd_device"] = "/dev/nbd3"
+	if _, _, err := NewConfig(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw["attach_method"] = AttachLoop
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error setting nbd_device for a loop device")
+	}
+}
+
+func TestNewConfig_partitionMounts(t *testing.T) {
+	raw := testConfig(t)
+	defer os.Remove(raw["source_path"].(string))
+
+	raw["partition_mounts"] = [][]string{{"2", "/boot"}, {"3", "/boot/efi"}}
+	if _, _, err := NewConfig(raw); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw["partition_mounts"] = [][]string{{"2"}}
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error")
+	}
+
+	raw["partition_mounts"] = [][]string{{"2", "boot"}}
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error for a relative path")
+	}
+}
+
+func TestNewConfig_chrootMounts(t *testing.T) {
+	raw := testConfig(t)
+	defer os.Remove(raw["source_path"].(string))
+
+	raw["chroot_mounts"] = [][]string{{"proc", "proc"}}
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,70 @@
+package chroot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sysBlock is where the kernel lists block devices.
+var sysBlock = "/sys/block"
+
+// freeNBDDevices returns the NBD devices that no client is connected to, in
+// order.
+func freeNBDDevices() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(sysBlock, "nbd*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers []int
+	for _, match := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(match), "nbd"))
+		if err != nil {
+			continue
+		}
+		// A connected device has the pid of its client
+		if _, err := os.Stat(filepath.Join(match, "pid")); err == nil {
+			continue
+		}
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	devices := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		devices = append(devices, fmt.Sprintf("/dev/nbd%d", n))
+	}
+	return devices, nil
+}
+
+// partitionDevice returns the device of a partition of device. Partition
+// "0" is the whole device.
+func partitionDevice(device, partition string) string {
+	if partition == "0" {
+		return device
+	}
+	if last := device[len(device)-1]; last >= '0' && last <= '9' {
+		return device + "p" + partition
+	}
+	return device + partition
+}
+
+// waitForDevice waits until the device node at path exists, since the
+// nodes of partitions are created asynchronously after attaching an image.
+func waitForDevice(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("device %s didn't appear after %s", path, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
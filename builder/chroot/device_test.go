@@ -0,0 +1,73 @@
+package chroot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFreeNBDDevices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := sysBlock
+	sysBlock = dir
+	defer func() { sysBlock = old }()
+
+	for _, name := range []string{"nbd0", "nbd1", "nbd10", "nbd2", "loop0"} {
+		os.MkdirAll(filepath.Join(dir, name), 0755)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "nbd1", "pid"), []byte("42\n"), 0644)
+
+	devices, err := freeNBDDevices()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"/dev/nbd0", "/dev/nbd2", "/dev/nbd10"}
+	if !reflect.DeepEqual(devices, expected) {
+		t.Fatalf("bad: %#v", devices)
+	}
+}
+
+func TestPartitionDevice(t *testing.T) {
+	cases := []struct {
+		device, partition, expected string
+	}{
+		{"/dev/loop0", "1", "/dev/loop0p1"},
+		{"/dev/nbd12", "2", "/dev/nbd12p2"},
+		{"/dev/sdb", "1", "/dev/sdb1"},
+		{"/dev/loop0", "0", "/dev/loop0"},
+	}
+	for _, tc := range cases {
+		if actual := partitionDevice(tc.device, tc.partition); actual != tc.expected {
+			t.Fatalf("%s %s: %s", tc.device, tc.partition, actual)
+		}
+	}
+}
+
+func TestWaitForDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	device := filepath.Join(dir, "loop0p1")
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		ioutil.WriteFile(device, nil, 0644)
+	}()
+	if err := waitForDevice(device, 5*time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := waitForDevice(filepath.Join(dir, "loop0p2"), 200*time.Millisecond); err == nil {
+		t.Fatal("should time out")
+	}
+}
@@ -0,0 +1,145 @@
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepAttachImage attaches the image to a loop or NBD block device.
+//
+// Produces:
+//   device string - The block device the image is attached to
+//   attach_cleanup CleanupFunc - To perform early cleanup
+type StepAttachImage struct {
+	device string
+}
+
+func (s *StepAttachImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	imagePath := state.Get("image_path").(string)
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	var device string
+	var err error
+	switch config.AttachMethod {
+	case AttachNBD:
+		ui.Say("Attaching the image to an NBD device...")
+		device, err = attachNBD(wrappedCommand, imagePath, config.SourceFormat, config.NBDDevice)
+	default:
+		ui.Say("Attaching the image to a loop device...")
+		device, err = attachLoop(wrappedCommand, imagePath)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error attaching image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Message(fmt.Sprintf("Attached to: %s", device))
+	s.device = device
+	state.Put("device", device)
+	state.Put("attach_cleanup", s)
+	return multistep.ActionContinue
+}
+
+func (s *StepAttachImage) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+func (s *StepAttachImage) CleanupFunc(state multistep.StateBag) error {
+	if s.device == "" {
+		return nil
+	}
+
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	ui.Say("Detaching the image...")
+	detach := fmt.Sprintf("losetup --detach %s", s.device)
+	if config.AttachMethod == AttachNBD {
+		detach = fmt.Sprintf("qemu-nbd --disconnect %s", s.device)
+	}
+	if _, err := runCommand(wrappedCommand, detach); err != nil {
+		return fmt.Errorf("Error detaching image: %s", err)
+	}
+
+	s.device = ""
+	return nil
+}
+
+// attachLoop attaches the image at path to a free loop device, scanning its
+// partition table.
+func attachLoop(wrappedCommand chroot.CommandWrapper, path string) (string, error) {
+	stdout, err := runCommand(wrappedCommand,
+		fmt.Sprintf("losetup --find --show --partscan '%s'", path))
+	if err != nil {
+		return "", err
+	}
+
+	device := strings.TrimSpace(stdout)
+	if device == "" {
+		return "", errors.New("losetup didn't return a device")
+	}
+	return device, nil
+}
+
+// attachNBD connects the image at path to the NBD device, or to the first
+// free one when device is empty. The nbd kernel module must be loaded.
+func attachNBD(wrappedCommand chroot.CommandWrapper, path, format, device string) (string, error) {
+	candidates := []string{device}
+	if device == "" {
+		var err error
+		if candidates, err = freeNBDDevices(); err != nil {
+			return "", err
+		}
+		if len(candidates) == 0 {
+			return "", errors.New("no free NBD device, is the nbd kernel module loaded?")
+		}
+	}
+
+	// Another process can take a device after it is found free, in which
+	// case the next one is tried
+	var err error
+	for _, candidate := range candidates {
+		_, err = runCommand(wrappedCommand, fmt.Sprintf(
+			"qemu-nbd --connect=%s --format=%s '%s'", candidate, format, path))
+		if err == nil {
+			return candidate, nil
+		}
+		log.Printf("Error connecting %s: %s", candidate, err)
+	}
+	return "", err
+}
+
+// runCommand runs a command on the host through the command wrapper and
+// returns what it wrote to stdout.
+func runCommand(wrappedCommand chroot.CommandWrapper, command string) (string, error) {
+	cmdText, err := wrappedCommand(command)
+	if err != nil {
+		return "", fmt.Errorf("Error building command: %s", err)
+	}
+
+	log.Printf("Executing: %s", cmdText)
+	var stdout, stderr bytes.Buffer
+	cmd := chroot.ShellCommand(cmdText)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s\nStderr: %s", err, stderr.String())
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,105 @@
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// copyChunkSize is the size of the chunks the image is copied in. Chunks of
+// zeros are skipped, which keeps the copy of a sparse image sparse.
+const copyChunkSize = 1 << 20
+
+// StepCopyImage copies the source image into the output directory, so that
+// the source is left untouched.
+//
+// Produces:
+//   image_path string - The path to the image that is customised
+type StepCopyImage struct{}
+
+func (s *StepCopyImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	dst := filepath.Join(config.OutputDir, config.ImageName)
+	ui.Say(fmt.Sprintf("Copying source image to %s...", dst))
+
+	if info, err := os.Stat(config.SourcePath); err == nil {
+		ui.Message(fmt.Sprintf("Image size: %s", packer.FormatSize(info.Size())))
+	}
+
+	if err := copySparse(ctx, config.SourcePath, dst); err != nil {
+		os.Remove(dst)
+		err := fmt.Errorf("Error copying source image: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("Image to customise: %s", dst)
+	state.Put("image_path", dst)
+	return multistep.ActionContinue
+}
+
+func (s *StepCopyImage) Cleanup(state multistep.StateBag) {}
+
+// copySparse copies the file src to dst, seeking over the chunks that only
+// contain zeros instead of writing them.
+func copySparse(ctx context.Context, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	zeros := make([]byte, copyChunkSize)
+	buf := make([]byte, copyChunkSize)
+	var size int64
+	for {
+		if err := ctx.Err(); err != nil {
+			out.Close()
+			return err
+		}
+
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			if bytes.Equal(buf[:n], zeros[:n]) {
+				_, err = out.Seek(int64(n), io.SeekCurrent)
+			} else {
+				_, err = out.Write(buf[:n])
+			}
+			if err != nil {
+				out.Close()
+				return err
+			}
+			size += int64(n)
+			continue
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+
+	// Extend the file over the zeros it ends with, if any
+	if err := out.Truncate(size); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,71 @@
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestCopySparse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Data, a hole and data, then zeros up to the end
+	data := make([]byte, 3*copyChunkSize+100)
+	copy(data, "boot sector")
+	copy(data[2*copyChunkSize:], "root filesystem")
+	src := filepath.Join(dir, "source.img")
+	if err := ioutil.WriteFile(src, data, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dst := filepath.Join(dir, "output.img")
+	if err := copySparse(context.Background(), src, dst); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	actual, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(actual, data) {
+		t.Fatalf("the copy differs from the source: %d bytes", len(actual))
+	}
+}
+
+func TestStepCopyImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "source.img")
+	ioutil.WriteFile(src, []byte("image"), 0644)
+
+	state := testState(t, &Config{
+		SourcePath: src,
+		OutputDir:  dir,
+		ImageName:  "output.img",
+	})
+	step := new(StepCopyImage)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v %s", action, state.Get("error"))
+	}
+
+	path := state.Get("image_path").(string)
+	if path != filepath.Join(dir, "output.img") {
+		t.Fatalf("bad: %s", path)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "image" {
+		t.Fatalf("bad: %q %s", data, err)
+	}
+}
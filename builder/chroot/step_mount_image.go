@@ -0,0 +1,125 @@
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// partitionTimeout is how long to wait for the device of a partition to
+// appear after the image is attached.
+const partitionTimeout = 10 * time.Second
+
+type mountPathData struct {
+	Device string
+}
+
+// StepMountImage mounts the root partition of the attached image, and then
+// the partitions to mount within it.
+//
+// Produces:
+//   mount_path string - The location where the root partition was mounted.
+//   mount_device_cleanup CleanupFunc - To perform early cleanup
+type StepMountImage struct {
+	MountOptions    []string
+	MountPartition  string
+	PartitionMounts [][]string
+
+	mounts []string
+}
+
+func (s *StepMountImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	device := state.Get("device").(string)
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	halt := func(err error) multistep.StepAction {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ctx := config.ctx
+	ctx.Data = &mountPathData{Device: filepath.Base(device)}
+	mountPath, err := interpolate.Render(config.MountPath, &ctx)
+	if err != nil {
+		return halt(fmt.Errorf("Error preparing mount directory: %s", err))
+	}
+	mountPath, err = filepath.Abs(mountPath)
+	if err != nil {
+		return halt(fmt.Errorf("Error preparing mount directory: %s", err))
+	}
+	log.Printf("Mount path: %s", mountPath)
+
+	// build mount options from mount_options config, useful for nouuid options
+	// or other specific device type settings for mount
+	opts := ""
+	if len(s.MountOptions) > 0 {
+		opts = "-o " + strings.Join(s.MountOptions, " -o ")
+	}
+
+	mounts := append([][]string{{s.MountPartition, "/"}}, s.PartitionMounts...)
+	s.mounts = make([]string, 0, len(mounts))
+
+	ui.Say("Mounting the partitions of the image...")
+	for _, mount := range mounts {
+		partition := partitionDevice(device, mount[0])
+		target := filepath.Join(mountPath, mount[1])
+
+		ui.Message(fmt.Sprintf("Mounting %s on %s", partition, mount[1]))
+		if err := waitForDevice(partition, partitionTimeout); err != nil {
+			return halt(fmt.Errorf("Error mounting partition: %s", err))
+		}
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return halt(fmt.Errorf("Error creating mount directory: %s", err))
+		}
+
+		if _, err := runCommand(wrappedCommand,
+			fmt.Sprintf("mount %s %s %s", opts, partition, target)); err != nil {
+			return halt(fmt.Errorf("Error mounting partition: %s", err))
+		}
+		s.mounts = append(s.mounts, target)
+	}
+
+	state.Put("mount_path", mountPath)
+	state.Put("mount_device_cleanup", s)
+	return multistep.ActionContinue
+}
+
+func (s *StepMountImage) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+func (s *StepMountImage) CleanupFunc(state multistep.StateBag) error {
+	if len(s.mounts) == 0 {
+		return nil
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+
+	ui.Say("Unmounting the partitions of the image...")
+	for len(s.mounts) > 0 {
+		last := len(s.mounts) - 1
+		if _, err := runCommand(wrappedCommand, fmt.Sprintf("umount %s", s.mounts[last])); err != nil {
+			return fmt.Errorf("Error unmounting partition: %s", err)
+		}
+		s.mounts = s.mounts[:last]
+	}
+
+	s.mounts = nil
+	return nil
+}
@@ -9,6 +9,10 @@ import (
 // exported from docker into a single flat file.
 type ExportArtifact struct {
 	path string
+
+	// StateData is returned by State, such as the name of the driver
+	// under "driver".
+	StateData map[string]interface{}
 }
 
 func (*ExportArtifact) BuilderId() string {
@@ -28,7 +32,7 @@ func (a *ExportArtifact) String() string {
 }
 
 func (a *ExportArtifact) State(name string) interface{} {
-	return nil
+	return a.StateData[name]
 }
 
 func (a *ExportArtifact) Destroy() error {
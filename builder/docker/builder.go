@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"log"
 
 	"github.com/hashicorp/packer/common"
@@ -30,7 +31,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
-	driver := &DockerDriver{Ctx: &b.config.ctx, Ui: ui}
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	driver := NewDriver(b.config.Driver, &b.config.ctx, ui)
 	if err := driver.Verify(); err != nil {
 		return nil, err
 	}
@@ -39,7 +45,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("[DEBUG] Docker version: %s", version.String())
+	log.Printf("[DEBUG] %s version: %s", b.config.Driver, version.String())
 
 	steps := []multistep.Step{
 		&StepTempDir{},
@@ -82,7 +88,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -96,14 +102,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// No errors, must've worked
 	var artifact packer.Artifact
+	stateData := map[string]interface{}{"driver": b.config.Driver}
 	if b.config.Commit {
 		artifact = &ImportArtifact{
 			IdValue:        state.Get("image_id").(string),
 			BuilderIdValue: BuilderIdImport,
 			Driver:         driver,
+			StateData:      stateData,
 		}
 	} else {
-		artifact = &ExportArtifact{path: b.config.ExportPath}
+		artifact = &ExportArtifact{path: b.config.ExportPath, StateData: stateData}
 	}
 
 	return artifact, nil
@@ -24,7 +24,20 @@ type Communicator struct {
 	Version       *version.Version
 	Config        *Config
 	ContainerUser string
-	lock          sync.Mutex
+
+	// Executable is the Docker client to run, "docker" by default.
+	Executable string
+
+	lock sync.Mutex
+}
+
+// command returns a command running the Docker client.
+func (c *Communicator) command(args ...string) *exec.Cmd {
+	executable := c.Executable
+	if executable == "" {
+		executable = "docker"
+	}
+	return exec.Command(executable, args...)
 }
 
 func (c *Communicator) Start(remote *packer.RemoteCmd) error {
@@ -46,7 +59,7 @@ func (c *Communicator) Start(remote *packer.RemoteCmd) error {
 			append([]string{"-u", c.Config.ExecUser}, dockerArgs[2:]...)...)
 	}
 
-	cmd := exec.Command("docker", dockerArgs...)
+	cmd := c.command(dockerArgs...)
 
 	var (
 		stdin_w io.WriteCloser
@@ -108,7 +121,7 @@ func (c *Communicator) uploadFile(dst string, src io.Reader, fi *os.FileInfo) er
 	// command format: docker cp /path/to/infile containerid:/path/to/outfile
 	log.Printf("Copying to %s on container %s.", dst, c.ContainerID)
 
-	localCmd := exec.Command("docker", "cp", "-",
+	localCmd := c.command("cp", "-",
 		fmt.Sprintf("%s:%s", c.ContainerID, filepath.Dir(dst)))
 
 	stderrP, err := localCmd.StderrPipe()
@@ -183,6 +196,12 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 
 	*/
 
+	src, cleanup, err := packer.StageDir(src, exclude)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	var dockerSource string
 
 	if src[len(src)-1] == '/' {
@@ -192,7 +211,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 	}
 
 	// Make the directory, then copy into it
-	localCmd := exec.Command("docker", "cp", dockerSource, fmt.Sprintf("%s:%s", c.ContainerID, dst))
+	localCmd := c.command("cp", dockerSource, fmt.Sprintf("%s:%s", c.ContainerID, dst))
 
 	stderrP, err := localCmd.StderrPipe()
 	if err != nil {
@@ -223,7 +242,7 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 // cp to write to stdout, and then copy the stream to our destination io.Writer.
 func (c *Communicator) Download(src string, dst io.Writer) error {
 	log.Printf("Downloading file from container: %s:%s", c.ContainerID, src)
-	localCmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", c.ContainerID, src), "-")
+	localCmd := c.command("cp", fmt.Sprintf("%s:%s", c.ContainerID, src), "-")
 
 	pipe, err := localCmd.StdoutPipe()
 	if err != nil {
@@ -331,10 +350,10 @@ func (c *Communicator) fixDestinationOwner(destination string) error {
 	}
 
 	chownArgs := []string{
-		"docker", "exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
+		"exec", "--user", "root", c.ContainerID, "/bin/sh", "-c",
 		fmt.Sprintf("chown -R %s %s", owner, destination),
 	}
-	if output, err := exec.Command(chownArgs[0], chownArgs[1:]...).CombinedOutput(); err != nil {
+	if output, err := c.command(chownArgs...).CombinedOutput(); err != nil {
 		return fmt.Errorf("Failed to set owner of the uploaded file: %s, %s", err, output)
 	}
 
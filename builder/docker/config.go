@@ -28,6 +28,7 @@ type Config struct {
 	Commit         bool
 	ContainerDir   string `mapstructure:"container_dir"`
 	Discard        bool
+	Driver         string `mapstructure:"driver"`
 	ExecUser       string `mapstructure:"exec_user"`
 	ExportPath     string `mapstructure:"export_path"`
 	Image          string
@@ -116,6 +117,14 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		}
 	}
 
+	if c.Driver == "" {
+		c.Driver = DriverDocker
+	}
+	if c.Driver != DriverDocker && c.Driver != DriverPodman {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"driver must be %q or %q, not %q", DriverDocker, DriverPodman, c.Driver))
+	}
+
 	if c.ContainerDir == "" {
 		c.ContainerDir = "/packer-files"
 	}
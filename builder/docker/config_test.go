@@ -141,3 +141,25 @@ func TestConfigPrepare_pull(t *testing.T) {
 		t.Fatal("should not pull")
 	}
 }
+
+func TestConfigPrepare_driver(t *testing.T) {
+	raw := testConfig()
+
+	// Default
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.Driver != DriverDocker {
+		t.Fatalf("bad: %s", c.Driver)
+	}
+
+	raw["driver"] = "podman"
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.Driver != DriverPodman {
+		t.Fatalf("bad: %s", c.Driver)
+	}
+
+	raw["driver"] = "rkt"
+	_, warns, errs = NewConfig(raw)
+	testConfigErr(t, warns, errs)
+}
@@ -4,6 +4,14 @@ import (
 	"io"
 
 	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The names of the drivers that can be set with the driver option.
+const (
+	DriverDocker = "docker"
+	DriverPodman = "podman"
 )
 
 // Driver is the interface that has to be implemented to communicate with
@@ -59,6 +67,24 @@ type Driver interface {
 	Version() (*version.Version, error)
 }
 
+// NewDriver returns the driver with the given name, which is either
+// DriverDocker or DriverPodman. Docker is used when name is empty.
+func NewDriver(name string, ctx *interpolate.Context, ui packer.Ui) Driver {
+	if name == DriverPodman {
+		return NewPodmanDriver(ctx, ui)
+	}
+	return &DockerDriver{Ctx: ctx, Ui: ui}
+}
+
+// ArtifactDriverName returns the name of the driver that built an artifact,
+// so that post-processors work on the image with the same tool.
+func ArtifactDriverName(a packer.Artifact) string {
+	if name, ok := a.State("driver").(string); ok && name != "" {
+		return name
+	}
+	return DriverDocker
+}
+
 // ContainerConfig is the configuration used to start a container.
 type ContainerConfig struct {
 	Image      string
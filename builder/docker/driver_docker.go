@@ -21,12 +21,27 @@ type DockerDriver struct {
 	Ui  packer.Ui
 	Ctx *interpolate.Context
 
+	// Executable is the Docker client to run, "docker" by default.
+	Executable string
+
 	l sync.Mutex
 }
 
+func (d *DockerDriver) executable() string {
+	if d.Executable == "" {
+		return "docker"
+	}
+	return d.Executable
+}
+
+// command returns a command running the Docker client.
+func (d *DockerDriver) command(args ...string) *exec.Cmd {
+	return exec.Command(d.executable(), args...)
+}
+
 func (d *DockerDriver) DeleteImage(id string) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", "rmi", id)
+	cmd := d.command("rmi", id)
 	cmd.Stderr = &stderr
 
 	log.Printf("Deleting image: %s", id)
@@ -60,7 +75,7 @@ func (d *DockerDriver) Commit(id string, author string, changes []string, messag
 	args = append(args, id)
 
 	log.Printf("Committing container with args: %v", args)
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -74,12 +89,12 @@ func (d *DockerDriver) Commit(id string, author string, changes []string, messag
 		return "", err
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return lastLine(stdout.String()), nil
 }
 
 func (d *DockerDriver) Export(id string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", "export", id)
+	cmd := d.command("export", id)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -109,7 +124,7 @@ func (d *DockerDriver) Import(path string, changes []string, repo string) (strin
 	args = append(args, "-")
 	args = append(args, repo)
 
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	stdin, err := cmd.StdinPipe()
@@ -140,13 +155,12 @@ func (d *DockerDriver) Import(path string, changes []string, repo string) (strin
 		return "", fmt.Errorf("Error importing container: %s\n\nStderr: %s", err, stderr.String())
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return lastLine(stdout.String()), nil
 }
 
 func (d *DockerDriver) IPAddress(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Command(
-		"docker",
+	cmd := d.command(
 		"inspect",
 		"--format",
 		"{{ .NetworkSettings.IPAddress }}",
@@ -179,20 +193,29 @@ func (d *DockerDriver) Login(repo, user, pass string) error {
 		return err
 	}
 
-	cmd := exec.Command("docker")
-	cmd.Args = append(cmd.Args, "login")
+	if err := d.login(repo, user, pass, constraint.Check(version_running)); err != nil {
+		d.l.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// login runs the login command, passing the password on the standard input
+// when passwordStdin is set.
+func (d *DockerDriver) login(repo, user, pass string, passwordStdin bool) error {
+	cmd := d.command("login")
 
 	if user != "" {
 		cmd.Args = append(cmd.Args, "-u", user)
 	}
 
 	if pass != "" {
-		if constraint.Check(version_running) {
+		if passwordStdin {
 			cmd.Args = append(cmd.Args, "--password-stdin")
 
 			stdin, err := cmd.StdinPipe()
 			if err != nil {
-				d.l.Unlock()
 				return err
 			}
 			io.WriteString(stdin, pass)
@@ -206,13 +229,7 @@ func (d *DockerDriver) Login(repo, user, pass string) error {
 		cmd.Args = append(cmd.Args, repo)
 	}
 
-	err = runAndStream(cmd, d.Ui)
-	if err != nil {
-		d.l.Unlock()
-		return err
-	}
-
-	return nil
+	return runAndStream(cmd, d.Ui)
 }
 
 func (d *DockerDriver) Logout(repo string) error {
@@ -221,25 +238,25 @@ func (d *DockerDriver) Logout(repo string) error {
 		args = append(args, repo)
 	}
 
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	err := runAndStream(cmd, d.Ui)
 	d.l.Unlock()
 	return err
 }
 
 func (d *DockerDriver) Pull(image string) error {
-	cmd := exec.Command("docker", "pull", image)
+	cmd := d.command("pull", image)
 	return runAndStream(cmd, d.Ui)
 }
 
 func (d *DockerDriver) Push(name string) error {
-	cmd := exec.Command("docker", "push", name)
+	cmd := d.command("push", name)
 	return runAndStream(cmd, d.Ui)
 }
 
 func (d *DockerDriver) SaveImage(id string, dst io.Writer) error {
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", "save", id)
+	cmd := d.command("save", id)
 	cmd.Stdout = dst
 	cmd.Stderr = &stderr
 
@@ -286,11 +303,11 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 		args = append(args, v)
 	}
 	d.Ui.Message(fmt.Sprintf(
-		"Run command: docker %s", strings.Join(args, " ")))
+		"Run command: %s %s", d.executable(), strings.Join(args, " ")))
 
 	// Start the container
 	var stdout, stderr bytes.Buffer
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -314,11 +331,11 @@ func (d *DockerDriver) StartContainer(config *ContainerConfig) (string, error) {
 }
 
 func (d *DockerDriver) StopContainer(id string) error {
-	if err := exec.Command("docker", "kill", id).Run(); err != nil {
+	if err := d.command("kill", id).Run(); err != nil {
 		return err
 	}
 
-	return exec.Command("docker", "rm", id).Run()
+	return d.command("rm", id).Run()
 }
 
 func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
@@ -352,10 +369,15 @@ func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
 			log.Printf("since it was removed after Docker 1.12.0 released")
 		}
 	}
+
+	return d.tagImage(args, id, repo)
+}
+
+func (d *DockerDriver) tagImage(args []string, id string, repo string) error {
 	args = append(args, id, repo)
 
 	var stderr bytes.Buffer
-	cmd := exec.Command("docker", args...)
+	cmd := d.command(args...)
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
@@ -372,7 +394,7 @@ func (d *DockerDriver) TagImage(id string, repo string, force bool) error {
 }
 
 func (d *DockerDriver) Verify() error {
-	if _, err := exec.LookPath("docker"); err != nil {
+	if _, err := exec.LookPath(d.executable()); err != nil {
 		return err
 	}
 
@@ -380,7 +402,7 @@ func (d *DockerDriver) Verify() error {
 }
 
 func (d *DockerDriver) Version() (*version.Version, error) {
-	output, err := exec.Command("docker", "-v").Output()
+	output, err := d.command("-v").Output()
 	if err != nil {
 		return nil, err
 	}
@@ -392,3 +414,10 @@ func (d *DockerDriver) Version() (*version.Version, error) {
 
 	return version.NewVersion(string(match[0]))
 }
+
+// lastLine returns the last line of the output of a command, which is where
+// IDs are printed after any progress.
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// PodmanDriver is a Driver that runs containers with Podman, which needs no
+// daemon. Its command line is compatible with the one of Docker, so only the
+// few differences are handled here.
+type PodmanDriver struct {
+	DockerDriver
+}
+
+func NewPodmanDriver(ctx *interpolate.Context, ui packer.Ui) *PodmanDriver {
+	return &PodmanDriver{
+		DockerDriver: DockerDriver{
+			Ui:         ui,
+			Ctx:        ctx,
+			Executable: "podman",
+		},
+	}
+}
+
+func (d *PodmanDriver) Login(repo, user, pass string) error {
+	d.l.Lock()
+
+	// Podman has always supported --password-stdin
+	if err := d.login(repo, user, pass, true); err != nil {
+		d.l.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (d *PodmanDriver) TagImage(id string, repo string, force bool) error {
+	// Podman moves an existing tag without being forced
+	return d.tagImage([]string{"tag"}, id, repo)
+}
@@ -0,0 +1,37 @@
+package docker
+
+import "testing"
+
+func TestPodmanDriver_impl(t *testing.T) {
+	var _ Driver = new(PodmanDriver)
+}
+
+func TestNewPodmanDriver(t *testing.T) {
+	d := NewPodmanDriver(nil, nil)
+
+	cmd := d.command("tag", "foo", "bar")
+	if cmd.Args[0] != "podman" {
+		t.Fatalf("bad: %#v", cmd.Args)
+	}
+}
+
+func TestNewDriver(t *testing.T) {
+	if _, ok := NewDriver(DriverPodman, nil, nil).(*PodmanDriver); !ok {
+		t.Fatal("should be a Podman driver")
+	}
+	if _, ok := NewDriver("", nil, nil).(*DockerDriver); !ok {
+		t.Fatal("should be a Docker driver")
+	}
+}
+
+func TestArtifactDriverName(t *testing.T) {
+	a := &ImportArtifact{}
+	if name := ArtifactDriverName(a); name != DriverDocker {
+		t.Fatalf("bad: %s", name)
+	}
+
+	a.StateData = map[string]interface{}{"driver": DriverPodman}
+	if name := ArtifactDriverName(a); name != DriverPodman {
+		t.Fatalf("bad: %s", name)
+	}
+}
@@ -24,7 +24,7 @@ func (s *StepConnectDocker) Run(_ context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
-	containerUser, err := getContainerUser(containerId)
+	containerUser, err := getContainerUser(config.Driver, containerId)
 	if err != nil {
 		state.Put("error", err)
 		return multistep.ActionHalt
@@ -37,6 +37,7 @@ func (s *StepConnectDocker) Run(_ context.Context, state multistep.StateBag) mul
 		HostDir:       tempDir,
 		ContainerDir:  config.ContainerDir,
 		Version:       version,
+		Executable:    config.Driver,
 		Config:        config,
 		ContainerUser: containerUser,
 	}
@@ -47,8 +48,8 @@ func (s *StepConnectDocker) Run(_ context.Context, state multistep.StateBag) mul
 
 func (s *StepConnectDocker) Cleanup(state multistep.StateBag) {}
 
-func getContainerUser(containerId string) (string, error) {
-	inspectArgs := []string{"docker", "inspect", "--format", "{{.Config.User}}", containerId}
+func getContainerUser(executable, containerId string) (string, error) {
+	inspectArgs := []string{executable, "inspect", "--format", "{{.Config.User}}", containerId}
 	stdout, err := exec.Command(inspectArgs[0], inspectArgs[1:]...).Output()
 	if err != nil {
 		errStr := fmt.Sprintf("Failed to inspect the container: %s", err)
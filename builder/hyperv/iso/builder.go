@@ -125,6 +125,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 			},
 		},
 	}, raws...)
@@ -380,13 +381,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			Path:  b.config.OutputDir,
 		},
 		&common.StepDownload{
-			Checksum:     b.config.ISOChecksum,
-			ChecksumType: b.config.ISOChecksumType,
-			Description:  "ISO",
-			ResultKey:    "iso_path",
-			Url:          b.config.ISOUrls,
-			Extension:    b.config.TargetExtension,
-			TargetPath:   b.config.TargetPath,
+			Checksum:          b.config.ISOChecksum,
+			ChecksumType:      b.config.ISOChecksumType,
+			ChecksumSignature: b.config.ChecksumSignature(),
+			Description:       "ISO",
+			ResultKey:         "iso_path",
+			Url:               b.config.ISOUrls,
+			Extension:         b.config.TargetExtension,
+			TemplatePath:      b.config.PackerTemplatePath,
+			Ranges:            b.config.ISODownloadRanges,
+			MinSpeed:          b.config.ISODownloadMinSpeed,
+			TargetPath:        b.config.TargetPath,
 		},
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
@@ -114,6 +114,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 			},
 		},
 	}, raws...)
@@ -400,13 +401,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	if b.config.RawSingleISOUrl != "" || len(b.config.ISOUrls) > 0 {
 		steps = append(steps,
 			&common.StepDownload{
-				Checksum:     b.config.ISOChecksum,
-				ChecksumType: b.config.ISOChecksumType,
-				Description:  "ISO",
-				ResultKey:    "iso_path",
-				Url:          b.config.ISOUrls,
-				Extension:    b.config.TargetExtension,
-				TargetPath:   b.config.TargetPath,
+				Checksum:          b.config.ISOChecksum,
+				ChecksumType:      b.config.ISOChecksumType,
+				ChecksumSignature: b.config.ChecksumSignature(),
+				Description:       "ISO",
+				ResultKey:         "iso_path",
+				Url:               b.config.ISOUrls,
+				Extension:         b.config.TargetExtension,
+				TemplatePath:      b.config.PackerTemplatePath,
+				Ranges:            b.config.ISODownloadRanges,
+				MinSpeed:          b.config.ISODownloadMinSpeed,
+				TargetPath:        b.config.TargetPath,
 			},
 		)
 	}
@@ -101,7 +101,12 @@ func (c *LxcAttachCommunicator) Upload(dst string, r io.Reader, fi *os.FileInfo)
 }
 
 func (c *LxcAttachCommunicator) UploadDir(dst string, src string, exclude []string) error {
-	// TODO: remove any file copied if it appears in `exclude`
+	src, cleanup, err := packer.StageDir(src, exclude)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	dest := filepath.Join(c.RootFs, dst)
 	log.Printf("Uploading directory '%s' to rootfs '%s'", src, dest)
 	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp -R %s/. %s", src, dest))
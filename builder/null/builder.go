@@ -1,6 +1,7 @@
 package null
 
 import (
+	"context"
 	"log"
 
 	"github.com/hashicorp/packer/common"
@@ -27,6 +28,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	steps := []multistep.Step{}
 
 	if b.config.CommConfig.Type != "none" {
@@ -51,7 +57,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// Run!
 	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
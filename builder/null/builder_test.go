@@ -8,4 +8,5 @@ import (
 
 func TestBuilder_implBuilder(t *testing.T) {
 	var _ packer.Builder = new(Builder)
+	var _ packer.ContextBuilder = new(Builder)
 }
@@ -0,0 +1,48 @@
+package oci
+
+import (
+	"fmt"
+	"os"
+)
+
+// Artifact is an OCI image layout directory that contains the image the
+// builder produced.
+type Artifact struct {
+	dir    string
+	files  []string
+	digest string
+	ref    string
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (a *Artifact) Files() []string {
+	return a.files
+}
+
+// Id is the digest of the manifest of the image.
+func (a *Artifact) Id() string {
+	return a.digest
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("OCI image %s (%s) in directory: %s", a.ref, a.digest, a.dir)
+}
+
+// State returns the directory of the layout under "layout" and the name of
+// the image in its index under "ref".
+func (a *Artifact) State(name string) interface{} {
+	switch name {
+	case "layout":
+		return a.dir
+	case "ref":
+		return a.ref
+	}
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	return os.RemoveAll(a.dir)
+}
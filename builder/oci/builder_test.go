@@ -0,0 +1,15 @@
+package oci
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var _ packer.Builder = new(Builder)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
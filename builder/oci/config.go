@@ -0,0 +1,93 @@
+package oci
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// Config is the configuration that is chained through the steps and
+// settable from the template.
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	Author         string     `mapstructure:"author"`
+	ChrootMounts   [][]string `mapstructure:"chroot_mounts"`
+	CommandWrapper string     `mapstructure:"command_wrapper"`
+	CopyFiles      []string   `mapstructure:"copy_files"`
+	ImageRef       string     `mapstructure:"image_ref"`
+	Message        string     `mapstructure:"message"`
+	OutputDir      string     `mapstructure:"output_directory"`
+	SourcePath     string     `mapstructure:"source_path"`
+	SourceRef      string     `mapstructure:"source_ref"`
+
+	ctx interpolate.Context
+}
+
+func NewConfig(raws ...interface{}) (*Config, []string, error) {
+	c := new(Config)
+	err := config.Decode(c, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &c.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"command_wrapper",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Defaults
+	if c.ChrootMounts == nil {
+		c.ChrootMounts = [][]string{
+			{"proc", "proc", "/proc"},
+			{"sysfs", "sysfs", "/sys"},
+			{"bind", "/dev", "/dev"},
+			{"devpts", "devpts", "/dev/pts"},
+		}
+	}
+
+	if c.CopyFiles == nil {
+		c.CopyFiles = []string{"/etc/resolv.conf"}
+	}
+
+	if c.CommandWrapper == "" {
+		c.CommandWrapper = "{{.Command}}"
+	}
+
+	if c.ImageRef == "" {
+		c.ImageRef = "latest"
+	}
+
+	if c.OutputDir == "" {
+		c.OutputDir = fmt.Sprintf("output-%s", c.PackerBuildName)
+	}
+
+	var errs *packer.MultiError
+	if c.SourcePath == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("source_path is required"))
+	} else if _, err := os.Stat(c.SourcePath); err != nil {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is invalid: %s", err))
+	}
+
+	for _, mounts := range c.ChrootMounts {
+		if len(mounts) != 3 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("Each chroot_mounts entry should be three elements."))
+			break
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	return c, nil, nil
+}
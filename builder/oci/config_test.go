@@ -0,0 +1,76 @@
+package oci
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func testConfig(t *testing.T) map[string]interface{} {
+	dir, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return map[string]interface{}{
+		"source_path":       dir,
+		"packer_build_name": "test",
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	raw := testConfig(t)
+	defer os.RemoveAll(raw["source_path"].(string))
+
+	c, warns, err := NewConfig(raw)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.OutputDir != "output-test" {
+		t.Fatalf("bad: %s", c.OutputDir)
+	}
+	if c.ImageRef != "latest" {
+		t.Fatalf("bad: %s", c.ImageRef)
+	}
+	if len(c.ChrootMounts) != 4 || len(c.CopyFiles) != 1 {
+		t.Fatalf("bad: %#v %#v", c.ChrootMounts, c.CopyFiles)
+	}
+}
+
+func TestNewConfig_sourcePath(t *testing.T) {
+	raw := testConfig(t)
+	os.RemoveAll(raw["source_path"].(string))
+
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error for a missing source_path")
+	}
+
+	delete(raw, "source_path")
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error without source_path")
+	}
+}
+
+func TestNewConfig_chrootMounts(t *testing.T) {
+	raw := testConfig(t)
+	defer os.RemoveAll(raw["source_path"].(string))
+
+	raw["chroot_mounts"] = [][]string{{"bind", "/dev"}}
+	if _, _, err := NewConfig(raw); err == nil {
+		t.Fatal("should error for a mount of two elements")
+	}
+
+	raw["chroot_mounts"] = [][]string{}
+	raw["copy_files"] = []string{}
+	c, _, err := NewConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(c.ChrootMounts) != 0 || len(c.CopyFiles) != 0 {
+		t.Fatalf("defaults should be overridable: %#v %#v", c.ChrootMounts, c.CopyFiles)
+	}
+}
@@ -0,0 +1,168 @@
+package oci
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// fileState is what a change to a file of the root filesystem is detected
+// by.
+type fileState struct {
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	uid     int
+	gid     int
+	link    string
+}
+
+func (s fileState) equal(o fileState) bool {
+	return s.mode == o.mode && s.size == o.size && s.modTime.Equal(o.modTime) &&
+		s.uid == o.uid && s.gid == o.gid && s.link == o.link
+}
+
+func statFile(p string, info os.FileInfo) (fileState, error) {
+	uid, gid, _ := fileOwner(info)
+	s := fileState{
+		mode:    info.Mode(),
+		modTime: info.ModTime(),
+		uid:     uid,
+		gid:     gid,
+	}
+	if info.Mode().IsRegular() {
+		s.size = info.Size()
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(p)
+		if err != nil {
+			return s, err
+		}
+		s.link = link
+	}
+	return s, nil
+}
+
+// snapshot records the state of every file of the root filesystem root,
+// keyed by their path relative to it.
+func snapshot(root string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		s, err := statFile(p, info)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = s
+		return nil
+	})
+	return files, err
+}
+
+// writeLayer writes a tar stream to w of the changes made to the root
+// filesystem root since the snapshot before was taken: the files that were
+// added or changed, and whiteouts for those that were removed. It returns
+// the digest of the stream and how many changes it contains.
+func writeLayer(root string, before map[string]fileState, w io.Writer) (string, int, error) {
+	h := sha256.New()
+	tw := tar.NewWriter(io.MultiWriter(w, h))
+	changes := 0
+
+	after := make(map[string]bool)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		after[name] = true
+
+		s, err := statFile(p, info)
+		if err != nil {
+			return err
+		}
+		if old, ok := before[name]; ok && old.equal(s) {
+			return nil
+		}
+
+		changes++
+		return addFile(tw, p, name, info, s)
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Whiteout the topmost of the removed files, which hides the rest
+	var removed []string
+	for name := range before {
+		if !after[name] && (path.Dir(name) == "." || after[path.Dir(name)]) {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Join(path.Dir(name), whiteoutPrefix+path.Base(name)),
+			Mode:     0644,
+			ModTime:  time.Unix(0, 0),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return "", 0, err
+		}
+		changes++
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", 0, err
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), changes, nil
+}
+
+func addFile(tw *tar.Writer, p, name string, info os.FileInfo, s fileState) error {
+	hdr, err := tar.FileInfoHeader(info, s.link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	hdr.Uid, hdr.Gid = s.uid, s.gid
+	hdr.Uname, hdr.Gname = "", ""
+	if info.Mode()&(os.ModeDevice|os.ModeCharDevice) != 0 {
+		_, _, rdev := fileOwner(info)
+		hdr.Devmajor, hdr.Devminor = devNumbers(rdev)
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(tw, f, hdr.Size)
+	return err
+}
@@ -0,0 +1,87 @@
+package oci
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteLayer(t *testing.T) {
+	base := map[string]string{
+		"etc/":         "",
+		"etc/hostname": "base",
+		"etc/motd":     "hello",
+		"var/":         "",
+		"var/cache/":   "",
+		"var/cache/a":  "a",
+		"bin/":         "",
+		"bin/sh":       "shell",
+	}
+
+	root, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(root)
+	if err := applyLayer(bytes.NewReader(testLayer(t, base)), root); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	before, err := snapshot(root)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Nothing changed
+	var buf bytes.Buffer
+	if _, changes, err := writeLayer(root, before, &buf); err != nil || changes != 0 {
+		t.Fatalf("bad: %d %s", changes, err)
+	}
+
+	// Make sure changes are noticed on file systems with coarse timestamps
+	future := time.Now().Add(time.Hour)
+	ioutil.WriteFile(filepath.Join(root, "etc", "hostname"), []byte("changed"), 0644)
+	os.Chtimes(filepath.Join(root, "etc", "hostname"), future, future)
+	os.Remove(filepath.Join(root, "etc", "motd"))
+	os.RemoveAll(filepath.Join(root, "var", "cache"))
+	os.MkdirAll(filepath.Join(root, "opt", "app"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "opt", "app", "run"), []byte("run"), 0755)
+	os.Symlink("/opt/app/run", filepath.Join(root, "bin", "run"))
+
+	buf.Reset()
+	diffID, changes, err := writeLayer(root, before, &buf)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if changes == 0 {
+		t.Fatal("should have changes")
+	}
+	if diffID != digestOf(buf.Bytes()) {
+		t.Fatalf("bad diff ID: %s", diffID)
+	}
+
+	// Applying the layer to the base gives the changed tree
+	check, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(check)
+	if err := applyLayer(bytes.NewReader(testLayer(t, base)), check); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := applyLayer(bytes.NewReader(buf.Bytes()), check); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := listTree(t, root)
+	if actual := listTree(t, check); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v\n\nexpected: %#v", actual, expected)
+	}
+	if _, ok := expected["var/cache/"]; ok {
+		t.Fatal("var/cache should be gone")
+	}
+}
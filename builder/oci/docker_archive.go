@@ -0,0 +1,127 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// dockerManifest is an image of the manifest.json file of the archives that
+// "docker save" writes. The paths are relative to the archive.
+type dockerManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// isDockerArchive reports whether dir is an extracted "docker save" archive
+// rather than an OCI image layout.
+func isDockerArchive(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "index.json")); err == nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, "manifest.json"))
+	return err == nil
+}
+
+// importDockerArchive adds the images of the extracted "docker save" archive
+// in dir to the layout l, which is then completed with an index. Each tag of
+// an image names it in the index, so that source_ref can pick it by tag.
+func importDockerArchive(dir string, l *layout) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	var images []dockerManifest
+	if err := json.Unmarshal(data, &images); err != nil {
+		return fmt.Errorf("Error reading manifest.json: %s", err)
+	}
+
+	idx := &index{SchemaVersion: 2, Manifests: []descriptor{}}
+	for _, image := range images {
+		m := manifest{SchemaVersion: 2, MediaType: mediaTypeManifest}
+		if m.Config, err = l.importBlob(dir, image.Config, mediaTypeConfig); err != nil {
+			return err
+		}
+		for _, layer := range image.Layers {
+			d, err := l.importBlob(dir, layer, mediaTypeLayer)
+			if err != nil {
+				return err
+			}
+			m.Layers = append(m.Layers, d)
+		}
+
+		d, err := l.writeJSON(mediaTypeManifest, m)
+		if err != nil {
+			return err
+		}
+		if len(image.RepoTags) == 0 {
+			idx.Manifests = append(idx.Manifests, d)
+		}
+		for _, tag := range image.RepoTags {
+			tagged := d
+			tagged.Annotations = map[string]string{annotationRefName: tag}
+			idx.Manifests = append(idx.Manifests, tagged)
+		}
+	}
+
+	return l.writeIndex(idx)
+}
+
+// importBlob adds the file at name within dir as a blob. Layers that are
+// compressed get the media type of gzipped layers.
+func (l *layout) importBlob(dir, name, mediaType string) (descriptor, error) {
+	p, err := securePath(dir, name)
+	if err != nil {
+		return descriptor{}, err
+	}
+	in, err := os.Open(p)
+	if err != nil {
+		return descriptor{}, err
+	}
+	defer in.Close()
+
+	if mediaType == mediaTypeLayer {
+		magic := make([]byte, 2)
+		if n, _ := io.ReadFull(in, magic); isGzip(magic[:n]) {
+			mediaType = mediaTypeLayerGzip
+		}
+		if _, err := in.Seek(0, io.SeekStart); err != nil {
+			return descriptor{}, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(l.dir, "blobs", "sha256"), 0755); err != nil {
+		return descriptor{}, err
+	}
+	out, err := ioutil.TempFile(filepath.Join(l.dir, "blobs", "sha256"), "import")
+	if err != nil {
+		return descriptor{}, err
+	}
+	defer os.Remove(out.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, h), in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return descriptor{}, err
+	}
+
+	d := descriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:      size,
+	}
+	blob, err := l.blobPath(d.Digest)
+	if err != nil {
+		return d, err
+	}
+	return d, os.Rename(out.Name(), blob)
+}
@@ -0,0 +1,323 @@
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Media types of the OCI image specification, and the Docker ones that OCI
+// layouts written by some tools contain.
+const (
+	mediaTypeIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaTypeManifest   = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeConfig     = "application/vnd.oci.image.config.v1+json"
+	mediaTypeLayer      = "application/vnd.oci.image.layer.v1.tar"
+	mediaTypeLayerGzip  = "application/vnd.oci.image.layer.v1.tar+gzip"
+	mediaTypeDockerList = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// annotationRefName names a manifest of the index of a layout, such as
+// "latest".
+const annotationRefName = "org.opencontainers.image.ref.name"
+
+// layoutVersion is the content of the oci-layout file.
+const layoutVersion = `{"imageLayoutVersion":"1.0.0"}`
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *platform         `json:"platform,omitempty"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type index struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Manifests     []descriptor      `json:"manifests"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// layout is an OCI image layout directory.
+type layout struct {
+	dir string
+}
+
+func (l *layout) blobPath(digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] != "sha256" || len(parts[1]) != sha256.Size*2 {
+		return "", fmt.Errorf("unsupported digest: %q", digest)
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", fmt.Errorf("unsupported digest: %q", digest)
+	}
+	return filepath.Join(l.dir, "blobs", parts[0], parts[1]), nil
+}
+
+// openBlob opens the blob with the given digest. The digest isn't verified.
+func (l *layout) openBlob(digest string) (*os.File, error) {
+	p, err := l.blobPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// readJSON reads the blob of a descriptor, verifying its digest, and
+// decodes it into v.
+func (l *layout) readJSON(d descriptor, v interface{}) error {
+	f, err := l.openBlob(d.Digest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if actual := digestOf(data); actual != d.Digest {
+		return fmt.Errorf("blob %s has digest %s", d.Digest, actual)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeBlob adds data as a blob and returns its descriptor.
+func (l *layout) writeBlob(mediaType string, data []byte) (descriptor, error) {
+	d := descriptor{
+		MediaType: mediaType,
+		Digest:    digestOf(data),
+		Size:      int64(len(data)),
+	}
+	p, err := l.blobPath(d.Digest)
+	if err != nil {
+		return d, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return d, err
+	}
+	return d, ioutil.WriteFile(p, data, 0644)
+}
+
+// writeJSON adds v encoded as JSON as a blob.
+func (l *layout) writeJSON(mediaType string, v interface{}) (descriptor, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return descriptor{}, err
+	}
+	return l.writeBlob(mediaType, data)
+}
+
+// copyBlob copies the blob of a descriptor from another layout.
+func (l *layout) copyBlob(src *layout, d descriptor) error {
+	dst, err := l.blobPath(d.Digest)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	in, err := src.openBlob(d.Digest)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (l *layout) readIndex() (*index, error) {
+	data, err := ioutil.ReadFile(filepath.Join(l.dir, "index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an OCI image layout: %s", l.dir, err)
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("Error reading the index of %s: %s", l.dir, err)
+	}
+	return &idx, nil
+}
+
+// writeIndex writes the index and the oci-layout file, which completes the
+// layout.
+func (l *layout) writeIndex(idx *index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(l.dir, "index.json"), data, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(l.dir, "oci-layout"), []byte(layoutVersion), 0644)
+}
+
+// resolveManifest returns the image manifest named ref in the index of the
+// layout. ref can be left empty when the layout contains a single image.
+// Multi-platform images resolve to the manifest for the platform Packer
+// runs on.
+func (l *layout) resolveManifest(ref string) (*manifest, error) {
+	idx, err := l.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	// An image can be in the index several times, once for each name
+	var candidates []descriptor
+	seen := make(map[string]bool)
+	for _, d := range idx.Manifests {
+		if seen[d.Digest] {
+			continue
+		}
+		if ref == "" || d.Annotations[annotationRefName] == ref {
+			candidates = append(candidates, d)
+			seen[d.Digest] = true
+		}
+	}
+	switch {
+	case len(candidates) == 0 && ref != "":
+		return nil, fmt.Errorf("%s has no image named %q", l.dir, ref)
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("%s contains no image", l.dir)
+	case len(candidates) > 1 && ref == "":
+		return nil, fmt.Errorf("%s contains %d images, set source_ref to pick one", l.dir, len(candidates))
+	}
+
+	d := candidates[0]
+	for d.MediaType == mediaTypeIndex || d.MediaType == mediaTypeDockerList {
+		var nested index
+		if err := l.readJSON(d, &nested); err != nil {
+			return nil, err
+		}
+		if d, err = selectPlatform(nested.Manifests); err != nil {
+			return nil, err
+		}
+	}
+
+	var m manifest
+	if err := l.readJSON(d, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// selectPlatform returns the manifest for Linux on the architecture Packer
+// runs on.
+func selectPlatform(manifests []descriptor) (descriptor, error) {
+	for _, d := range manifests {
+		if d.Platform == nil {
+			continue
+		}
+		if d.Platform.OS == "linux" && d.Platform.Architecture == runtime.GOARCH {
+			return d, nil
+		}
+	}
+	return descriptor{}, fmt.Errorf("the image has no manifest for linux/%s", runtime.GOARCH)
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// imageConfig is the configuration blob of an image. Only the fields that
+// the builder changes are decoded, the others are kept as they are.
+type imageConfig map[string]json.RawMessage
+
+type rootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type history struct {
+	Created    string `json:"created,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
+}
+
+// addHistory records a build step in the history of the image. When diffID
+// isn't empty, the layer the step created is appended to the root
+// filesystem of the image, otherwise the step is recorded as an empty layer.
+func (c imageConfig) addHistory(h history, diffID string) error {
+	fields := map[string]interface{}{"created": h.Created}
+
+	if diffID != "" {
+		var rootfs rootFS
+		if raw, ok := c["rootfs"]; ok {
+			if err := json.Unmarshal(raw, &rootfs); err != nil {
+				return fmt.Errorf("Error reading the rootfs of the image: %s", err)
+			}
+		}
+		rootfs.Type = "layers"
+		rootfs.DiffIDs = append(rootfs.DiffIDs, diffID)
+		fields["rootfs"] = rootfs
+	} else {
+		h.EmptyLayer = true
+	}
+
+	var hist []json.RawMessage
+	if raw, ok := c["history"]; ok {
+		if err := json.Unmarshal(raw, &hist); err != nil {
+			return fmt.Errorf("Error reading the history of the image: %s", err)
+		}
+	}
+	entry, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	fields["history"] = append(hist, entry)
+
+	return c.set(fields)
+}
+
+func (c imageConfig) set(fields map[string]interface{}) error {
+	for k, v := range fields {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		c[k] = raw
+	}
+	return nil
+}
+
+// isGzip reports whether data starts like a gzip stream.
+func isGzip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1f, 0x8b})
+}
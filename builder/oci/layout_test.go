@@ -0,0 +1,226 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testLayer returns a gzipped layer of the given files. Names ending with a
+// slash are directories, and contents starting with "->" symlinks.
+func testLayer(t *testing.T, files map[string]string) []byte {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, name := range names {
+		content := files[name]
+		hdr := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(content))}
+		switch {
+		case strings.HasSuffix(name, "/"):
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		case strings.HasPrefix(content, "->"):
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, content[2:], 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte(content))
+		}
+	}
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+// testLayout writes an OCI image layout with a single image made of the
+// given layers, named ref.
+func testLayout(t *testing.T, ref string, layers ...map[string]string) string {
+	dir, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	l := &layout{dir: dir}
+	m := manifest{SchemaVersion: 2, MediaType: mediaTypeManifest}
+	for _, files := range layers {
+		d, err := l.writeBlob(mediaTypeLayerGzip, testLayer(t, files))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		m.Layers = append(m.Layers, d)
+	}
+
+	config := imageConfig{}
+	config.set(map[string]interface{}{
+		"architecture": runtime.GOARCH,
+		"os":           "linux",
+		"config":       map[string]interface{}{"Cmd": []string{"/bin/sh"}},
+	})
+	if m.Config, err = l.writeJSON(mediaTypeConfig, config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	d, err := l.writeJSON(mediaTypeManifest, m)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	d.Annotations = map[string]string{annotationRefName: ref}
+	if err := l.writeIndex(&index{SchemaVersion: 2, Manifests: []descriptor{d}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir
+}
+
+// listTree returns the files under dir, with the contents of regular files
+// and the targets of symlinks.
+func listTree(t *testing.T, dir string) map[string]string {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.IsDir():
+			files[rel+"/"] = ""
+		case info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(p)
+			files[rel] = "->" + target
+		default:
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			files[rel] = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return files
+}
+
+func TestLayoutResolveManifest(t *testing.T) {
+	dir := testLayout(t, "1.0", map[string]string{"etc/": "", "etc/hostname": "base"})
+	defer os.RemoveAll(dir)
+	l := &layout{dir: dir}
+
+	m, err := l.resolveManifest("")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(m.Layers) != 1 {
+		t.Fatalf("bad: %#v", m)
+	}
+
+	if _, err := l.resolveManifest("1.0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := l.resolveManifest("2.0"); err == nil {
+		t.Fatal("should error for a missing ref")
+	}
+
+	// Tampered blobs are detected
+	p, _ := l.blobPath(m.Config.Digest)
+	if err := ioutil.WriteFile(p, []byte("{}"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var config imageConfig
+	if err := l.readJSON(m.Config, &config); err == nil {
+		t.Fatal("should error for a digest mismatch")
+	}
+}
+
+func TestLayoutResolveManifest_platforms(t *testing.T) {
+	dir := testLayout(t, "latest", map[string]string{"a": "a"})
+	defer os.RemoveAll(dir)
+	l := &layout{dir: dir}
+
+	idx, err := l.readIndex()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	image := idx.Manifests[0]
+	image.Annotations = nil
+	image.Platform = &platform{OS: "linux", Architecture: runtime.GOARCH}
+	other := image
+	other.Digest = "sha256:" + strings.Repeat("0", 64)
+	other.Platform = &platform{OS: "linux", Architecture: "s390x-not-" + runtime.GOARCH}
+
+	list, err := l.writeJSON(mediaTypeIndex, &index{
+		SchemaVersion: 2,
+		Manifests:     []descriptor{other, image},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	list.Annotations = map[string]string{annotationRefName: "multi"}
+	idx.Manifests = append(idx.Manifests, list)
+	if err := l.writeIndex(idx); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := l.resolveManifest(""); err == nil {
+		t.Fatal("should error with several images and no ref")
+	}
+	m, err := l.resolveManifest("multi")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(m.Layers) != 1 {
+		t.Fatalf("bad: %#v", m)
+	}
+}
+
+func TestImageConfigAddHistory(t *testing.T) {
+	config := imageConfig{}
+	config.set(map[string]interface{}{
+		"os":     "linux",
+		"rootfs": rootFS{Type: "layers", DiffIDs: []string{"sha256:a"}},
+	})
+
+	if err := config.addHistory(history{CreatedBy: "packer"}, "sha256:b"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := config.addHistory(history{CreatedBy: "packer"}, ""); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var rootfs rootFS
+	var hist []history
+	decodeJSON(t, config["rootfs"], &rootfs)
+	decodeJSON(t, config["history"], &hist)
+	if expected := []string{"sha256:a", "sha256:b"}; !reflect.DeepEqual(rootfs.DiffIDs, expected) {
+		t.Fatalf("bad: %#v", rootfs)
+	}
+	if len(hist) != 2 || hist[0].EmptyLayer || !hist[1].EmptyLayer {
+		t.Fatalf("bad: %#v", hist)
+	}
+	if string(config["os"]) != `"linux"` {
+		t.Fatalf("other fields should be kept: %s", config["os"])
+	}
+}
+
+func decodeJSON(t *testing.T, data []byte, v interface{}) {
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,102 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/klauspost/pgzip"
+)
+
+// StepCommitLayer writes the changes provisioning made to the root
+// filesystem as a new layer of the output layout.
+//
+// Produces:
+//   layer *descriptor - The new layer, or nil when nothing changed
+//   layer_diff_id string - The digest of the uncompressed layer
+type StepCommitLayer struct{}
+
+func (s *StepCommitLayer) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	mountPath := state.Get("mount_path").(string)
+	before := state.Get("rootfs_snapshot").(map[string]fileState)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Committing the changes to a new layer...")
+	out := &layout{dir: config.OutputDir}
+	layer, diffID, changes, err := commitLayer(out, mountPath, before)
+	if err != nil {
+		return halt(state, fmt.Errorf("Error committing layer: %s", err))
+	}
+
+	if layer == nil {
+		ui.Message("Provisioning changed no files, the image gets no new layer")
+		state.Put("layer", (*descriptor)(nil))
+		return multistep.ActionContinue
+	}
+
+	ui.Message(fmt.Sprintf("Layer %s: %d changes, %s",
+		layer.Digest, changes, packer.FormatSize(layer.Size)))
+	state.Put("layer", layer)
+	state.Put("layer_diff_id", diffID)
+	return multistep.ActionContinue
+}
+
+func (s *StepCommitLayer) Cleanup(state multistep.StateBag) {}
+
+// commitLayer writes the changes made to rootfs since the snapshot before
+// was taken as a gzipped layer blob of out. The descriptor of the layer is
+// nil when nothing changed.
+func commitLayer(out *layout, rootfs string, before map[string]fileState) (*descriptor, string, int, error) {
+	blobDir := filepath.Join(out.dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobDir, 0755); err != nil {
+		return nil, "", 0, err
+	}
+	f, err := os.Create(filepath.Join(blobDir, "packer-layer.tmp"))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	zw := pgzip.NewWriter(io.MultiWriter(f, h))
+	diffID, changes, err := writeLayer(rootfs, before, zw)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", 0, err
+	}
+	if changes == 0 {
+		return nil, "", 0, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, "", 0, err
+	}
+
+	layer := &descriptor{
+		MediaType: mediaTypeLayerGzip,
+		Digest:    "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:      info.Size(),
+	}
+	p, err := out.blobPath(layer.Digest)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		return nil, "", 0, err
+	}
+	return layer, diffID, changes, nil
+}
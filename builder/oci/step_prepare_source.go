@@ -0,0 +1,119 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// StepPrepareSource finds the manifest of the source image, extracting the
+// layout first when the source is an archive. The images of "docker save"
+// archives are imported into a layout.
+//
+// Produces:
+//   work_dir string - A temporary directory for the build
+//   source_layout *layout - The layout the source image is in
+//   source_manifest *manifest - The manifest of the source image
+//   image_config imageConfig - The configuration of the source image
+type StepPrepareSource struct {
+	workDir string
+}
+
+func (s *StepPrepareSource) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	workDir, err := tmp.Dir("packer-oci")
+	if err != nil {
+		return halt(state, fmt.Errorf("Error creating work directory: %s", err))
+	}
+	s.workDir = workDir
+	state.Put("work_dir", workDir)
+
+	src := &layout{dir: config.SourcePath}
+	if info, err := os.Stat(config.SourcePath); err == nil && !info.IsDir() {
+		ui.Say("Extracting source image archive...")
+		src.dir = filepath.Join(workDir, "source")
+		if err := extractSourceArchive(config.SourcePath, src.dir); err != nil {
+			return halt(state, fmt.Errorf("Error extracting %s: %s", config.SourcePath, err))
+		}
+	}
+
+	if isDockerArchive(src.dir) {
+		ui.Say("Importing the images of the docker archive...")
+		docker := src.dir
+		src = &layout{dir: filepath.Join(workDir, "layout")}
+		if err := importDockerArchive(docker, src); err != nil {
+			return halt(state, fmt.Errorf("Error importing %s: %s", config.SourcePath, err))
+		}
+	}
+
+	m, err := src.resolveManifest(config.SourceRef)
+	if err != nil {
+		return halt(state, err)
+	}
+
+	imgConfig := make(imageConfig)
+	if err := src.readJSON(m.Config, &imgConfig); err != nil {
+		return halt(state, fmt.Errorf("Error reading the configuration of the image: %s", err))
+	}
+	log.Printf("Source image has %d layers, config %s", len(m.Layers), m.Config.Digest)
+
+	state.Put("source_layout", src)
+	state.Put("source_manifest", m)
+	state.Put("image_config", imgConfig)
+	return multistep.ActionContinue
+}
+
+func (s *StepPrepareSource) Cleanup(state multistep.StateBag) {
+	if s.workDir == "" {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	if err := removeAll(state, s.workDir); err != nil {
+		ui.Error(fmt.Sprintf("Error removing work directory: %s", err))
+	}
+	s.workDir = ""
+}
+
+func extractSourceArchive(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return extractArchive(f, dir)
+}
+
+// removeAll removes a directory that provisioners may have filled with
+// files Packer can only remove through the command wrapper.
+func removeAll(state multistep.StateBag, dir string) error {
+	if err := os.RemoveAll(dir); err == nil {
+		return nil
+	}
+
+	wrappedCommand := state.Get("wrappedCommand").(chroot.CommandWrapper)
+	rmCmd, err := wrappedCommand(fmt.Sprintf("rm -rf '%s'", dir))
+	if err != nil {
+		return err
+	}
+	if output, err := chroot.ShellCommand(rmCmd).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %s", err, output)
+	}
+	return nil
+}
+
+func halt(state multistep.StateBag, err error) multistep.StepAction {
+	state.Put("error", err)
+	state.Get("ui").(packer.Ui).Error(err.Error())
+	return multistep.ActionHalt
+}
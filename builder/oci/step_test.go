@@ -0,0 +1,228 @@
+package oci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/common/chroot"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func testState(t *testing.T, config *Config) multistep.StateBag {
+	state := new(multistep.BasicStateBag)
+	state.Put("config", config)
+	state.Put("hook", &packer.MockHook{})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	state.Put("wrappedCommand", chroot.CommandWrapper(func(command string) (string, error) {
+		return command, nil
+	}))
+	return state
+}
+
+func runSteps(t *testing.T, state multistep.StateBag, steps ...multistep.Step) {
+	for _, step := range steps {
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("%T: %s", step, state.Get("error"))
+		}
+	}
+}
+
+// TestSteps builds an image from an archive of a layout, without the steps
+// that need root.
+func TestSteps(t *testing.T) {
+	base := map[string]string{
+		"etc/":         "",
+		"etc/hostname": "base",
+		"etc/motd":     "hello",
+	}
+	src := testLayout(t, "latest", base)
+	defer os.RemoveAll(src)
+
+	dir, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "source.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := WriteArchive(src, f); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	config := &Config{
+		SourcePath: archive,
+		OutputDir:  filepath.Join(dir, "output"),
+		ImageRef:   "1.0",
+		Author:     "packer@example.com",
+	}
+	state := testState(t, config)
+	prepare := &StepPrepareSource{}
+	defer prepare.Cleanup(state)
+	runSteps(t, state, prepare, &StepUnpackRootfs{})
+
+	// Provision
+	rootfs := state.Get("mount_path").(string)
+	os.Remove(filepath.Join(rootfs, "etc", "motd"))
+	ioutil.WriteFile(filepath.Join(rootfs, "etc", "app.conf"), []byte("conf"), 0644)
+
+	runSteps(t, state, &StepCommitLayer{}, &StepWriteLayout{})
+
+	out := &layout{dir: config.OutputDir}
+	m, err := out.resolveManifest("1.0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(m.Layers) != 2 {
+		t.Fatalf("bad: %#v", m.Layers)
+	}
+	if d := state.Get("manifest_digest").(string); d == "" {
+		t.Fatal("should put the manifest digest")
+	}
+
+	var config2 imageConfig
+	if err := out.readJSON(m.Config, &config2); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var rootfsConfig rootFS
+	decodeJSON(t, config2["rootfs"], &rootfsConfig)
+	if len(rootfsConfig.DiffIDs) != 1 || rootfsConfig.DiffIDs[0] != state.Get("layer_diff_id") {
+		t.Fatalf("bad: %#v", rootfsConfig)
+	}
+	if _, ok := config2["config"]; !ok {
+		t.Fatal("the configuration of the source image should be kept")
+	}
+
+	// The new image unpacks to the provisioned tree
+	check := filepath.Join(dir, "check")
+	for _, l := range m.Layers {
+		if err := applyLayerBlob(out, l, check); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	expected := map[string]string{
+		"etc/":         "",
+		"etc/hostname": "base",
+		"etc/app.conf": "conf",
+	}
+	if actual := listTree(t, check); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The work directory is removed
+	prepare.Cleanup(state)
+	if _, err := os.Stat(rootfs); !os.IsNotExist(err) {
+		t.Fatal("work directory should be removed")
+	}
+}
+
+func TestStepCopyFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The host file is copied over the file of the image at the same path
+	host := filepath.Join(dir, "resolv.conf")
+	ioutil.WriteFile(host, []byte("host"), 0644)
+	rootfs := filepath.Join(dir, "rootfs")
+	os.MkdirAll(filepath.Join(rootfs, dir), 0755)
+	ioutil.WriteFile(filepath.Join(rootfs, host), []byte("image"), 0644)
+
+	before, err := snapshot(rootfs)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := testState(t, &Config{})
+	state.Put("mount_path", rootfs)
+
+	step := &chroot.StepCopyFiles{Files: []string{host}}
+	runSteps(t, state, step)
+	data, err := ioutil.ReadFile(filepath.Join(rootfs, host))
+	if err != nil || string(data) != "host" {
+		t.Fatalf("bad: %q %s", data, err)
+	}
+
+	if err := step.CleanupFunc(state); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var buf bytes.Buffer
+	if _, changes, err := writeLayer(rootfs, before, &buf); err != nil || changes != 0 {
+		t.Fatalf("the copied files should leave no changes: %d %s", changes, err)
+	}
+}
+
+// TestStepPrepareSource_dockerArchive unpacks an image of an archive that
+// "docker save" wrote.
+func TestStepPrepareSource_dockerArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The layers of docker archives aren't compressed
+	saved := filepath.Join(dir, "saved")
+	os.MkdirAll(filepath.Join(saved, "0123"), 0755)
+	zr, err := gzip.NewReader(bytes.NewReader(testLayer(t, map[string]string{
+		"etc/":         "",
+		"etc/hostname": "base",
+	})))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	layer, _ := ioutil.ReadAll(zr)
+	ioutil.WriteFile(filepath.Join(saved, "0123", "layer.tar"), layer, 0644)
+	ioutil.WriteFile(filepath.Join(saved, "config.json"), []byte(`{"os":"linux"}`), 0644)
+	ioutil.WriteFile(filepath.Join(saved, "manifest.json"), []byte(`[{
+		"Config": "config.json",
+		"RepoTags": ["app:1.0", "app:latest"],
+		"Layers": ["0123/layer.tar"]
+	}]`), 0644)
+
+	archive := filepath.Join(dir, "app.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := WriteArchive(saved, f); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	for _, ref := range []string{"", "app:latest"} {
+		state := testState(t, &Config{SourcePath: archive, SourceRef: ref})
+		prepare := &StepPrepareSource{}
+		runSteps(t, state, prepare, &StepUnpackRootfs{})
+
+		m := state.Get("source_manifest").(*manifest)
+		if len(m.Layers) != 1 || m.Layers[0].MediaType != mediaTypeLayer {
+			t.Fatalf("bad: %#v", m.Layers)
+		}
+		rootfs := state.Get("mount_path").(string)
+		expected := map[string]string{
+			"etc/":         "",
+			"etc/hostname": "base",
+		}
+		if actual := listTree(t, rootfs); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %#v", actual)
+		}
+		prepare.Cleanup(state)
+	}
+}
@@ -0,0 +1,66 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepUnpackRootfs applies the layers of the source image to an empty
+// directory, and records the state of its files to find what provisioning
+// changes.
+//
+// Produces:
+//   mount_path string - The root filesystem of the image
+//   rootfs_snapshot map[string]fileState - The files before provisioning
+type StepUnpackRootfs struct{}
+
+func (s *StepUnpackRootfs) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+	src := state.Get("source_layout").(*layout)
+	m := state.Get("source_manifest").(*manifest)
+	workDir := state.Get("work_dir").(string)
+
+	if os.Geteuid() != 0 {
+		ui.Message("Packer isn't running as root, so the owners of the files " +
+			"of the image won't be kept")
+	}
+
+	rootfs := filepath.Join(workDir, "rootfs")
+	if err := os.MkdirAll(rootfs, 0755); err != nil {
+		return halt(state, fmt.Errorf("Error creating root filesystem: %s", err))
+	}
+
+	ui.Say("Unpacking the root filesystem of the image...")
+	for i, layer := range m.Layers {
+		ui.Message(fmt.Sprintf("Layer %d/%d: %s", i+1, len(m.Layers), layer.Digest))
+		if err := applyLayerBlob(src, layer, rootfs); err != nil {
+			return halt(state, fmt.Errorf("Error applying layer %s: %s", layer.Digest, err))
+		}
+	}
+
+	files, err := snapshot(rootfs)
+	if err != nil {
+		return halt(state, fmt.Errorf("Error reading the root filesystem: %s", err))
+	}
+
+	state.Put("mount_path", rootfs)
+	state.Put("rootfs_snapshot", files)
+	return multistep.ActionContinue
+}
+
+func (s *StepUnpackRootfs) Cleanup(state multistep.StateBag) {}
+
+func applyLayerBlob(src *layout, layer descriptor, rootfs string) error {
+	f, err := src.openBlob(layer.Digest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return applyLayer(f, rootfs)
+}
@@ -0,0 +1,84 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepWriteLayout completes the output layout with the layers of the source
+// image, and the configuration, manifest and index of the new image.
+//
+// Produces:
+//   manifest_digest string - The digest of the manifest of the new image
+type StepWriteLayout struct{}
+
+func (s *StepWriteLayout) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	src := state.Get("source_layout").(*layout)
+	m := state.Get("source_manifest").(*manifest)
+	imgConfig := state.Get("image_config").(imageConfig)
+	layer := state.Get("layer").(*descriptor)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Writing the image layout...")
+	out := &layout{dir: config.OutputDir}
+	for _, l := range m.Layers {
+		if err := out.copyBlob(src, l); err != nil {
+			return halt(state, fmt.Errorf("Error copying layer %s: %s", l.Digest, err))
+		}
+	}
+
+	h := history{
+		Created:   time.Now().UTC().Format(time.RFC3339),
+		CreatedBy: "packer build",
+		Author:    config.Author,
+		Comment:   config.Message,
+	}
+	if config.PackerBuildName != "" {
+		h.CreatedBy = fmt.Sprintf("packer build (%s)", config.PackerBuildName)
+	}
+	layers := append([]descriptor{}, m.Layers...)
+	diffID := ""
+	if layer != nil {
+		layers = append(layers, *layer)
+		diffID = state.Get("layer_diff_id").(string)
+	}
+	if err := imgConfig.addHistory(h, diffID); err != nil {
+		return halt(state, err)
+	}
+
+	configDesc, err := out.writeJSON(mediaTypeConfig, imgConfig)
+	if err != nil {
+		return halt(state, fmt.Errorf("Error writing the image configuration: %s", err))
+	}
+
+	manifestDesc, err := out.writeJSON(mediaTypeManifest, &manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeManifest,
+		Config:        configDesc,
+		Layers:        layers,
+	})
+	if err != nil {
+		return halt(state, fmt.Errorf("Error writing the image manifest: %s", err))
+	}
+	manifestDesc.Annotations = map[string]string{annotationRefName: config.ImageRef}
+
+	err = out.writeIndex(&index{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeIndex,
+		Manifests:     []descriptor{manifestDesc},
+	})
+	if err != nil {
+		return halt(state, fmt.Errorf("Error writing the image index: %s", err))
+	}
+
+	ui.Message(fmt.Sprintf("Image %s: %s", config.ImageRef, manifestDesc.Digest))
+	state.Put("manifest_digest", manifestDesc.Digest)
+	return multistep.ActionContinue
+}
+
+func (s *StepWriteLayout) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,293 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/pgzip"
+)
+
+const (
+	// whiteoutPrefix marks a file deleted by a layer.
+	whiteoutPrefix = ".wh."
+
+	// whiteoutOpaque marks a directory whose contents in lower layers are
+	// hidden.
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// decompress returns a reader of the tar stream of a layer or archive,
+// which may be compressed with gzip.
+func decompress(r io.Reader) (io.Reader, func() error, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if !isGzip(magic) {
+		return br, func() error { return nil }, nil
+	}
+
+	zr, err := pgzip.NewReader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	return zr, zr.Close, nil
+}
+
+// extractArchive extracts a tar archive, such as an OCI layout saved with
+// "skopeo copy oci-archive:", into dir.
+func extractArchive(r io.Reader, dir string) error {
+	tr, closeFn, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	return applyTar(tar.NewReader(tr), dir, false)
+}
+
+// applyLayer applies a layer of an image to the root filesystem root,
+// honouring its whiteouts.
+func applyLayer(r io.Reader, root string) error {
+	tr, closeFn, err := decompress(r)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	return applyTar(tar.NewReader(tr), root, true)
+}
+
+func applyTar(tr *tar.Reader, root string, layer bool) error {
+	// The paths this layer adds, which opaque whiteouts must keep
+	added := make(map[string]bool)
+	var opaque []string
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if name == "" {
+			continue
+		}
+		dir, base := path.Split(name)
+
+		if layer && base == whiteoutOpaque {
+			opaque = append(opaque, dir)
+			continue
+		}
+		if layer && strings.HasPrefix(base, whiteoutPrefix) {
+			target, err := securePath(root, dir+strings.TrimPrefix(base, whiteoutPrefix))
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			continue
+		}
+
+		target, err := securePath(root, name)
+		if err != nil {
+			return err
+		}
+		if err := extractEntry(tr, hdr, root, target); err != nil {
+			return fmt.Errorf("Error extracting %s: %s", name, err)
+		}
+		added[name] = true
+	}
+
+	for _, dir := range opaque {
+		if err := clearDir(root, strings.TrimSuffix(dir, "/"), added); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearDir removes the contents of dir that weren't added by the current
+// layer.
+func clearDir(root, dir string, keep map[string]bool) error {
+	target, err := securePath(root, dir)
+	if err != nil {
+		return err
+	}
+	entries, err := readDirNames(target)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry)
+		if keep[name] {
+			info, err := os.Lstat(filepath.Join(target, entry))
+			if err == nil && info.IsDir() {
+				if err := clearDir(root, name, keep); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(target, entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+func extractEntry(r io.Reader, hdr *tar.Header, root, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	// Replace whatever lower layers left there, unless both are directories
+	if info, err := os.Lstat(target); err == nil {
+		if !(info.IsDir() && hdr.Typeflag == tar.TypeDir) {
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		}
+	}
+
+	mode := os.FileMode(hdr.Mode).Perm()
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, mode); err != nil {
+			return err
+		}
+	case tar.TypeReg, tar.TypeRegA:
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		return setOwner(target, hdr, os.Symlink(hdr.Linkname, target))
+	case tar.TypeLink:
+		source, err := securePath(root, strings.TrimPrefix(path.Clean("/"+hdr.Linkname), "/"))
+		if err != nil {
+			return err
+		}
+		return os.Link(source, target)
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		if err := mknod(target, hdr); err != nil {
+			log.Printf("[WARN] Skipping special file %s: %s", hdr.Name, err)
+			return nil
+		}
+	default:
+		log.Printf("[WARN] Skipping %s of unsupported type %c", hdr.Name, hdr.Typeflag)
+		return nil
+	}
+
+	if err := setOwner(target, hdr, nil); err != nil {
+		return err
+	}
+	// Chmod after chown, which clears the setuid and setgid bits
+	if err := os.Chmod(target, fileMode(hdr)); err != nil {
+		return err
+	}
+	return os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+}
+
+// setOwner gives target the owner of hdr when Packer runs as root, since
+// nobody else can, after the error of creating it, if any.
+func setOwner(target string, hdr *tar.Header, err error) error {
+	if err != nil || os.Geteuid() != 0 {
+		return err
+	}
+	return os.Lchown(target, hdr.Uid, hdr.Gid)
+}
+
+// fileMode returns the permissions and special bits of a tar entry.
+func fileMode(hdr *tar.Header) os.FileMode {
+	mode := os.FileMode(hdr.Mode).Perm()
+	if hdr.Mode&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if hdr.Mode&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if hdr.Mode&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// securePath returns the path of name within root, resolving symlinks as if
+// root were the root directory, so that no entry of an archive can write
+// outside of it. The last element of name isn't resolved.
+func securePath(root, name string) (string, error) {
+	current := ""
+	remaining := strings.Split(name, "/")
+	for links := 0; len(remaining) > 0; {
+		part := remaining[0]
+		remaining = remaining[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = path.Dir("/" + current)[1:]
+			continue
+		}
+
+		next := path.Join(current, part)
+		if len(remaining) == 0 {
+			current = next
+			break
+		}
+
+		info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > 255 {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", name)
+		}
+		target, err := os.Readlink(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil {
+			return "", err
+		}
+		if !path.IsAbs(target) {
+			target = path.Join(current, target)
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+		current = ""
+	}
+
+	return filepath.Join(root, filepath.FromSlash(current)), nil
+}
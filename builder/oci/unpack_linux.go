@@ -0,0 +1,35 @@
+package oci
+
+import (
+	"archive/tar"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func mknod(target string, hdr *tar.Header) error {
+	mode := uint32(hdr.Mode & 07777)
+	switch hdr.Typeflag {
+	case tar.TypeChar:
+		mode |= unix.S_IFCHR
+	case tar.TypeBlock:
+		mode |= unix.S_IFBLK
+	case tar.TypeFifo:
+		mode |= unix.S_IFIFO
+	}
+	return unix.Mknod(target, mode, int(unix.Mkdev(uint32(hdr.Devmajor), uint32(hdr.Devminor))))
+}
+
+// fileOwner returns the owner of a file and the device it is, if any.
+func fileOwner(info os.FileInfo) (uid, gid int, rdev uint64) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid), uint64(st.Rdev)
+	}
+	return 0, 0, 0
+}
+
+// devNumbers splits a device number into its major and minor numbers.
+func devNumbers(rdev uint64) (int64, int64) {
+	return int64(unix.Major(rdev)), int64(unix.Minor(rdev))
+}
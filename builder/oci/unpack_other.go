@@ -0,0 +1,21 @@
+// +build !linux
+
+package oci
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+)
+
+func mknod(target string, hdr *tar.Header) error {
+	return errors.New("only supported on Linux")
+}
+
+func fileOwner(info os.FileInfo) (uid, gid int, rdev uint64) {
+	return 0, 0, 0
+}
+
+func devNumbers(rdev uint64) (int64, int64) {
+	return 0, 0
+}
@@ -0,0 +1,126 @@
+package oci
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyLayer(t *testing.T) {
+	root, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	layers := []map[string]string{
+		{
+			"etc/":          "",
+			"etc/hostname":  "base",
+			"etc/motd":      "hello",
+			"var/":          "",
+			"var/cache/":    "",
+			"var/cache/a":   "a",
+			"var/cache/b/":  "",
+			"var/cache/b/c": "c",
+			"bin/":          "",
+			"bin/sh":        "shell",
+		},
+		{
+			"etc/.wh.motd":           "",
+			"etc/hostname":           "changed",
+			"var/cache/.wh..wh..opq": "",
+			"var/cache/d":            "d",
+			"usr/":                   "",
+			"usr/bin":                "->/bin",
+		},
+	}
+	for _, files := range layers {
+		if err := applyLayer(bytes.NewReader(testLayer(t, files)), root); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	expected := map[string]string{
+		"bin/":         "",
+		"bin/sh":       "shell",
+		"etc/":         "",
+		"etc/hostname": "changed",
+		"usr/":         "",
+		"usr/bin":      "->/bin",
+		"var/":         "",
+		"var/cache/":   "",
+		"var/cache/d":  "d",
+	}
+	if actual := listTree(t, root); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestApplyLayer_symlinkEscape(t *testing.T) {
+	root, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(root)
+	outside, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(outside)
+
+	layers := []map[string]string{
+		{"escape": "->" + outside, "up": "->../../../../../../" + outside},
+		{"escape/file": "bad", "up/file": "bad", "../file": "bad"},
+	}
+	for _, files := range layers {
+		if err := applyLayer(bytes.NewReader(testLayer(t, files)), root); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if files := listTree(t, outside); len(files) != 0 {
+		t.Fatalf("wrote outside of the root: %#v", files)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(root, outside, "file"))
+	if err != nil || string(data) != "bad" {
+		t.Fatalf("should be written within the root: %s", err)
+	}
+}
+
+func TestSecurePath(t *testing.T) {
+	root, err := ioutil.TempDir("", "packer-oci")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(filepath.Join(root, "usr", "lib"), 0755)
+	os.Symlink("usr/lib", filepath.Join(root, "lib"))
+	os.Symlink("/usr", filepath.Join(root, "abs"))
+	os.Symlink("loop", filepath.Join(root, "loop"))
+
+	cases := map[string]string{
+		"lib/foo":     "usr/lib/foo",
+		"abs/lib/foo": "usr/lib/foo",
+		"../../etc":   "etc",
+		"lib/../foo":  "usr/foo",
+		"lib":         "lib",
+	}
+	for name, expected := range cases {
+		actual, err := securePath(root, name)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if actual != filepath.Join(root, expected) {
+			t.Fatalf("%s: %s", name, actual)
+		}
+	}
+
+	if _, err := securePath(root, "loop/foo"); err == nil {
+		t.Fatal("should error for a symlink loop")
+	}
+}
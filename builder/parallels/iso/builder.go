@@ -55,6 +55,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 				"prlctl",
 				"prlctl_post",
 				"parallels_tools_guest_path",
@@ -154,13 +155,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			ParallelsToolsMode:   b.config.ParallelsToolsMode,
 		},
 		&common.StepDownload{
-			Checksum:     b.config.ISOChecksum,
-			ChecksumType: b.config.ISOChecksumType,
-			Description:  "ISO",
-			Extension:    b.config.TargetExtension,
-			ResultKey:    "iso_path",
-			TargetPath:   b.config.TargetPath,
-			Url:          b.config.ISOUrls,
+			Checksum:          b.config.ISOChecksum,
+			ChecksumType:      b.config.ISOChecksumType,
+			ChecksumSignature: b.config.ChecksumSignature(),
+			Description:       "ISO",
+			Extension:         b.config.TargetExtension,
+			TemplatePath:      b.config.PackerTemplatePath,
+			Ranges:            b.config.ISODownloadRanges,
+			MinSpeed:          b.config.ISODownloadMinSpeed,
+			ResultKey:         "iso_path",
+			TargetPath:        b.config.TargetPath,
+			Url:               b.config.ISOUrls,
 		},
 		&parallelscommon.StepOutputDir{
 			Force: b.config.PackerForce,
@@ -41,6 +41,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 				"prlctl",
 				"prlctl_post",
 				"parallels_tools_guest_path",
@@ -1,6 +1,7 @@
 package qemu
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/common"
@@ -94,35 +96,40 @@ type Config struct {
 	bootcommand.VNCConfig `mapstructure:",squash"`
 	Comm                  communicator.Config `mapstructure:",squash"`
 	common.FloppyConfig   `mapstructure:",squash"`
-
-	ISOSkipCache      bool       `mapstructure:"iso_skip_cache"`
-	Accelerator       string     `mapstructure:"accelerator"`
-	CpuCount          int        `mapstructure:"cpus"`
-	DiskInterface     string     `mapstructure:"disk_interface"`
-	DiskSize          uint       `mapstructure:"disk_size"`
-	DiskCache         string     `mapstructure:"disk_cache"`
-	DiskDiscard       string     `mapstructure:"disk_discard"`
-	DetectZeroes      string     `mapstructure:"disk_detect_zeroes"`
-	SkipCompaction    bool       `mapstructure:"skip_compaction"`
-	DiskCompression   bool       `mapstructure:"disk_compression"`
-	Format            string     `mapstructure:"format"`
-	Headless          bool       `mapstructure:"headless"`
-	DiskImage         bool       `mapstructure:"disk_image"`
-	UseBackingFile    bool       `mapstructure:"use_backing_file"`
-	MachineType       string     `mapstructure:"machine_type"`
-	MemorySize        int        `mapstructure:"memory"`
-	NetDevice         string     `mapstructure:"net_device"`
-	OutputDir         string     `mapstructure:"output_directory"`
-	QemuArgs          [][]string `mapstructure:"qemuargs"`
-	QemuBinary        string     `mapstructure:"qemu_binary"`
-	ShutdownCommand   string     `mapstructure:"shutdown_command"`
-	SSHHostPortMin    int        `mapstructure:"ssh_host_port_min"`
-	SSHHostPortMax    int        `mapstructure:"ssh_host_port_max"`
-	UseDefaultDisplay bool       `mapstructure:"use_default_display"`
-	VNCBindAddress    string     `mapstructure:"vnc_bind_address"`
-	VNCPortMin        int        `mapstructure:"vnc_port_min"`
-	VNCPortMax        int        `mapstructure:"vnc_port_max"`
-	VMName            string     `mapstructure:"vm_name"`
+	common.CDConfig       `mapstructure:",squash"`
+
+	ISOSkipCache      bool             `mapstructure:"iso_skip_cache"`
+	Accelerator       string           `mapstructure:"accelerator"`
+	CloudInit         *CloudInitConfig `mapstructure:"cloud_init"`
+	CpuCount          int              `mapstructure:"cpus"`
+	DiskInterface     string           `mapstructure:"disk_interface"`
+	DiskSize          uint             `mapstructure:"disk_size"`
+	DiskCache         string           `mapstructure:"disk_cache"`
+	DiskDiscard       string           `mapstructure:"disk_discard"`
+	DetectZeroes      string           `mapstructure:"disk_detect_zeroes"`
+	SkipCompaction    bool             `mapstructure:"skip_compaction"`
+	DiskCompression   bool             `mapstructure:"disk_compression"`
+	Format            string           `mapstructure:"format"`
+	Headless          bool             `mapstructure:"headless"`
+	DiskImage         bool             `mapstructure:"disk_image"`
+	DisableQMP        bool             `mapstructure:"disable_qmp"`
+	UseBackingFile    bool             `mapstructure:"use_backing_file"`
+	MachineType       string           `mapstructure:"machine_type"`
+	MemorySize        int              `mapstructure:"memory"`
+	NetDevice         string           `mapstructure:"net_device"`
+	OutputDir         string           `mapstructure:"output_directory"`
+	QemuArgs          [][]string       `mapstructure:"qemuargs"`
+	QemuBinary        string           `mapstructure:"qemu_binary"`
+	QMPSocketPath     string           `mapstructure:"qmp_socket_path"`
+	RevertOnRetry     bool             `mapstructure:"revert_on_retry"`
+	ShutdownCommand   string           `mapstructure:"shutdown_command"`
+	SSHHostPortMin    int              `mapstructure:"ssh_host_port_min"`
+	SSHHostPortMax    int              `mapstructure:"ssh_host_port_max"`
+	UseDefaultDisplay bool             `mapstructure:"use_default_display"`
+	VNCBindAddress    string           `mapstructure:"vnc_bind_address"`
+	VNCPortMin        int              `mapstructure:"vnc_port_min"`
+	VNCPortMax        int              `mapstructure:"vnc_port_max"`
+	VMName            string           `mapstructure:"vm_name"`
 
 	// These are deprecated, but we keep them around for BC
 	// TODO(@mitchellh): remove
@@ -131,10 +138,12 @@ type Config struct {
 	// TODO(mitchellh): deprecate
 	RunOnce bool `mapstructure:"run_once"`
 
-	RawShutdownTimeout string `mapstructure:"shutdown_timeout"`
+	RawShutdownTimeout    string `mapstructure:"shutdown_timeout"`
+	RawScreendumpInterval string `mapstructure:"screendump_interval"`
 
-	shutdownTimeout time.Duration ``
-	ctx             interpolate.Context
+	shutdownTimeout    time.Duration ``
+	screendumpInterval time.Duration
+	ctx                interpolate.Context
 }
 
 func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
@@ -144,6 +153,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 				"qemuargs",
 			},
 		},
@@ -242,6 +252,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CDConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VNCConfig.Prepare(&b.config.ctx)...)
 
 	if b.config.NetDevice == "" {
@@ -333,6 +344,40 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("Failed parsing shutdown_timeout: %s", err))
 	}
 
+	if b.config.RawScreendumpInterval != "" {
+		b.config.screendumpInterval, err = time.ParseDuration(b.config.RawScreendumpInterval)
+		if err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Failed parsing screendump_interval: %s", err))
+		}
+	}
+
+	if b.config.DisableQMP {
+		if b.config.QMPSocketPath != "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("qmp_socket_path can't be set when disable_qmp is true"))
+		}
+		if b.config.RawScreendumpInterval != "" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("screendump_interval needs QMP, which disable_qmp disables"))
+		}
+		if b.config.RevertOnRetry {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("revert_on_retry needs QMP, which disable_qmp disables"))
+		}
+	}
+
+	if b.config.RevertOnRetry {
+		if b.config.Format != "qcow2" {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("revert_on_retry needs the qcow2 format, raw images can't hold snapshots"))
+		}
+		if len(b.config.FloppyFiles) > 0 || len(b.config.FloppyDirectories) > 0 {
+			errs = packer.MultiErrorAppend(
+				errs, errors.New("revert_on_retry can't be used with a floppy, which can't hold snapshots"))
+		}
+	}
+
 	if b.config.SSHHostPortMin > b.config.SSHHostPortMax {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("ssh_host_port_min must be less than ssh_host_port_max"))
@@ -347,6 +392,17 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("vnc_port_min must be less than vnc_port_max"))
 	}
 
+	if b.config.CloudInit != nil {
+		errs = packer.MultiErrorAppend(errs, b.config.CloudInit.Prepare()...)
+
+		if b.config.CDLabel == "" {
+			b.config.CDLabel = cloudInitLabel
+		} else if !strings.EqualFold(b.config.CDLabel, cloudInitLabel) {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("cd_label must be %q for cloud-init to find its seed", cloudInitLabel))
+		}
+	}
+
 	if b.config.QemuArgs == nil {
 		b.config.QemuArgs = make([][]string, 0)
 	}
@@ -359,6 +415,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	// Create the driver that we'll use to communicate with Qemu
 	driver, err := b.newDriver(b.config.QemuBinary)
 	if err != nil {
@@ -377,13 +438,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	steps := []multistep.Step{}
 	if !b.config.ISOSkipCache {
 		steps = append(steps, &common.StepDownload{
-			Checksum:     b.config.ISOChecksum,
-			ChecksumType: b.config.ISOChecksumType,
-			Description:  "ISO",
-			Extension:    b.config.TargetExtension,
-			ResultKey:    "iso_path",
-			TargetPath:   b.config.TargetPath,
-			Url:          b.config.ISOUrls,
+			Checksum:          b.config.ISOChecksum,
+			ChecksumType:      b.config.ISOChecksumType,
+			ChecksumSignature: b.config.ChecksumSignature(),
+			Description:       "ISO",
+			Extension:         b.config.TargetExtension,
+			TemplatePath:      b.config.PackerTemplatePath,
+			Ranges:            b.config.ISODownloadRanges,
+			MinSpeed:          b.config.ISODownloadMinSpeed,
+			ResultKey:         "iso_path",
+			TargetPath:        b.config.TargetPath,
+			Url:               b.config.ISOUrls,
 		},
 		)
 	} else {
@@ -399,6 +464,24 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
 		},
+	)
+
+	if b.config.CloudInit != nil && b.config.Comm.Type == "ssh" {
+		steps = append(steps,
+			&communicator.StepSSHKeyGen{
+				Debug:        b.config.PackerDebug,
+				DebugKeyPath: fmt.Sprintf("%s.pem", b.config.PackerBuildName),
+				Comm:         &b.config.Comm,
+			},
+		)
+	}
+
+	steps = append(steps,
+		new(stepCreateCloudInit),
+		&common.StepCreateCD{
+			Files: b.config.CDConfig.CDFiles,
+			Label: b.config.CDConfig.CDLabel,
+		},
 		new(stepCreateDisk),
 		new(stepCopyDisk),
 		new(stepResizeDisk),
@@ -409,7 +492,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 		},
 	)
 
-	if b.config.Comm.Type != "none" {
+	if forwardsCommPort(&b.config) {
 		steps = append(steps,
 			new(stepForwardSSH),
 		)
@@ -417,24 +500,35 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	steps = append(steps,
 		new(stepConfigureVNC),
+		new(stepConfigureQMP),
+		new(stepConfigureQGA),
 		steprun,
+		new(stepScreendump),
 		&stepTypeBootCommand{},
 	)
 
+	var connect multistep.Step
 	if b.config.Comm.Type != "none" {
-		steps = append(steps,
-			&communicator.StepConnect{
-				Config:    &b.config.Comm,
-				Host:      commHost,
-				SSHConfig: b.config.Comm.SSHConfigFunc(),
-				SSHPort:   commPort,
-				WinRMPort: commPort,
-			},
-		)
+		connect = &communicator.StepConnect{
+			Config:    &b.config.Comm,
+			Host:      commHost,
+			SSHConfig: b.config.Comm.SSHConfigFunc(),
+			SSHPort:   commPort,
+			WinRMPort: commPort,
+
+			QGASocketPath: qgaSocketPath,
+		}
+		steps = append(steps, connect)
 	}
 
+	// With -on-error=ask, revert to a snapshot of the VM when provisioning
+	// is retried, and connect to it again.
+	var provision multistep.Step = new(common.StepProvision)
+	if b.config.RevertOnRetry && b.config.PackerOnError == "ask" {
+		provision = &stepSnapshot{Step: provision, Name: "packer-provision", Connect: connect}
+	}
 	steps = append(steps,
-		new(common.StepProvision),
+		provision,
 	)
 
 	steps = append(steps,
@@ -460,7 +554,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// Run
 	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
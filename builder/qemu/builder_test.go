@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/packer"
 )
@@ -56,6 +57,9 @@ func TestBuilder_ImplementsBuilder(t *testing.T) {
 	if _, ok := raw.(packer.Builder); !ok {
 		t.Error("Builder must implement builder.")
 	}
+	if _, ok := raw.(packer.ContextBuilder); !ok {
+		t.Error("Builder must implement context builder.")
+	}
 }
 
 func TestBuilderPrepare_Defaults(t *testing.T) {
@@ -316,6 +320,175 @@ func TestBuilderPrepare_InvalidFloppies(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_CDFiles(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	floppies_path := "../../common/test-fixtures/floppies"
+	config["cd_files"] = []string{fmt.Sprintf("%s/*", floppies_path)}
+	config["cd_label"] = "config"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CDLabel != "config" {
+		t.Fatalf("bad: %s", b.config.CDLabel)
+	}
+
+	config["cd_files"] = []string{"nonexistent.cfg"}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_CloudInit(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["cloud_init"] = map[string]interface{}{
+		"user_data": "#cloud-config\n",
+	}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CDLabel != "cidata" {
+		t.Fatalf("bad: %s", b.config.CDLabel)
+	}
+
+	config["cd_label"] = "CIDATA"
+	b = Builder{}
+	if _, err = b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["cd_label"] = "config"
+	b = Builder{}
+	if _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "cd_label")
+	config["cloud_init"] = map[string]interface{}{
+		"user_data":      "#cloud-config\n",
+		"user_data_file": "nonexistent.yml",
+	}
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if len(err.(*packer.MultiError).Errors) != 2 {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestBuilderPrepare_QMP(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["qmp_socket_path"] = "/tmp/qmp.sock"
+	config["screendump_interval"] = "5s"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.screendumpInterval != 5*time.Second {
+		t.Fatalf("bad: %s", b.config.screendumpInterval)
+	}
+
+	config["screendump_interval"] = "bad"
+	b = Builder{}
+	if _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["screendump_interval"] = "5s"
+	config["disable_qmp"] = true
+	b = Builder{}
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	if len(err.(*packer.MultiError).Errors) != 2 {
+		t.Fatalf("bad: %s", err)
+	}
+
+	delete(config, "qmp_socket_path")
+	delete(config, "screendump_interval")
+	b = Builder{}
+	if _, err = b.Prepare(config); err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_RevertOnRetry(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["revert_on_retry"] = true
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	config["format"] = "raw"
+	b = Builder{}
+	if _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	config["format"] = "qcow2"
+	config["disable_qmp"] = true
+	b = Builder{}
+	if _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+
+	delete(config, "disable_qmp")
+	config["floppy_dirs"] = []string{"."}
+	b = Builder{}
+	if _, err = b.Prepare(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_QemuGuestAgent(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	config["communicator"] = "qemu-guest-agent"
+	config[packer.BuilderTypeConfigKey] = "qemu"
+	delete(config, "ssh_username")
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if forwardsCommPort(&b.config) {
+		t.Fatal("should not forward a port to the guest agent")
+	}
+	if b.config.Comm.QGATimeout != 5*time.Minute {
+		t.Fatalf("bad: %s", b.config.Comm.QGATimeout)
+	}
+}
+
 func TestBuilderPrepare_InvalidKey(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -0,0 +1,107 @@
+package qemu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// cloudInitLabel is the volume label of a cloud-init NoCloud seed.
+const cloudInitLabel = "cidata"
+
+// CloudInitConfig is the configuration of the cloud-init NoCloud seed that
+// the builder attaches to the VM, so that cloud images boot to SSH without
+// a boot command.
+type CloudInitConfig struct {
+	MetaData          map[string]string `mapstructure:"meta_data"`
+	NetworkConfig     string            `mapstructure:"network_config"`
+	NetworkConfigFile string            `mapstructure:"network_config_file"`
+	UserData          string            `mapstructure:"user_data"`
+	UserDataFile      string            `mapstructure:"user_data_file"`
+}
+
+func (c *CloudInitConfig) Prepare() []error {
+	var errs []error
+
+	if c.UserData != "" && c.UserDataFile != "" {
+		errs = append(errs, errors.New("only one of user_data or user_data_file can be specified"))
+	}
+	if c.NetworkConfig != "" && c.NetworkConfigFile != "" {
+		errs = append(errs, errors.New("only one of network_config or network_config_file can be specified"))
+	}
+
+	for _, path := range []string{c.UserDataFile, c.NetworkConfigFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("Bad cloud-init file '%s': %s", path, err))
+		}
+	}
+
+	return errs
+}
+
+// Seed returns the files of the NoCloud seed: meta-data, user-data and
+// network-config when one is given. The public key, if any, is put in the
+// meta-data, which cloud-init authorizes for the default user of the image.
+// When no user data is given, the password, if any, is set for the default
+// user, and password logins over SSH are allowed.
+func (c *CloudInitConfig) Seed(instanceID, hostname string, publicKey []byte, password string) (map[string]string, error) {
+	metaData := map[string]interface{}{
+		"instance-id":    instanceID,
+		"local-hostname": hostname,
+	}
+	for k, v := range c.MetaData {
+		metaData[k] = v
+	}
+	if key := strings.TrimSpace(string(publicKey)); key != "" {
+		metaData["public-keys"] = []string{key}
+	}
+	// JSON is YAML, which cloud-init reads the meta-data as
+	rawMetaData, err := json.MarshalIndent(metaData, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	seed := map[string]string{
+		"meta-data": string(rawMetaData) + "\n",
+	}
+
+	switch {
+	case c.UserDataFile != "":
+		data, err := ioutil.ReadFile(c.UserDataFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading user_data_file: %s", err)
+		}
+		seed["user-data"] = string(data)
+	case c.UserData != "":
+		seed["user-data"] = c.UserData
+	default:
+		userData := "#cloud-config\n"
+		if password != "" {
+			quoted, err := json.Marshal(password)
+			if err != nil {
+				return nil, err
+			}
+			userData += fmt.Sprintf("password: %s\nchpasswd: {expire: false}\nssh_pwauth: true\n", quoted)
+		}
+		seed["user-data"] = userData
+	}
+
+	switch {
+	case c.NetworkConfigFile != "":
+		data, err := ioutil.ReadFile(c.NetworkConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading network_config_file: %s", err)
+		}
+		seed["network-config"] = string(data)
+	case c.NetworkConfig != "":
+		seed["network-config"] = c.NetworkConfig
+	}
+
+	return seed, nil
+}
@@ -0,0 +1,114 @@
+package qemu
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCloudInitConfigPrepare(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	cases := []struct {
+		Config CloudInitConfig
+		Errs   int
+	}{
+		{CloudInitConfig{}, 0},
+		{CloudInitConfig{UserData: "#cloud-config\n"}, 0},
+		{CloudInitConfig{UserDataFile: tf.Name(), NetworkConfigFile: tf.Name()}, 0},
+		{CloudInitConfig{UserData: "#cloud-config\n", UserDataFile: tf.Name()}, 1},
+		{CloudInitConfig{NetworkConfig: "version: 2\n", NetworkConfigFile: tf.Name()}, 1},
+		{CloudInitConfig{UserDataFile: "i/dont/exist"}, 1},
+		{CloudInitConfig{NetworkConfigFile: "i/dont/exist"}, 1},
+	}
+
+	for i, tc := range cases {
+		if errs := tc.Config.Prepare(); len(errs) != tc.Errs {
+			t.Fatalf("%d: bad: %#v", i, errs)
+		}
+	}
+}
+
+func TestCloudInitConfigSeed(t *testing.T) {
+	c := &CloudInitConfig{
+		MetaData: map[string]string{"foo": "bar"},
+	}
+	seed, err := c.Seed("packer-1", "vm", []byte("ssh-rsa AAAA packer\n"), "")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var metaData map[string]interface{}
+	if err := json.Unmarshal([]byte(seed["meta-data"]), &metaData); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]interface{}{
+		"instance-id":    "packer-1",
+		"local-hostname": "vm",
+		"foo":            "bar",
+		"public-keys":    []interface{}{"ssh-rsa AAAA packer"},
+	}
+	if !reflect.DeepEqual(metaData, expected) {
+		t.Fatalf("bad: %#v", metaData)
+	}
+
+	if seed["user-data"] != "#cloud-config\n" {
+		t.Fatalf("bad: %q", seed["user-data"])
+	}
+	if _, ok := seed["network-config"]; ok {
+		t.Fatal("should not have network-config")
+	}
+}
+
+func TestCloudInitConfigSeed_password(t *testing.T) {
+	c := new(CloudInitConfig)
+	seed, err := c.Seed("packer-1", "vm", nil, `pa"ss`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var metaData map[string]interface{}
+	if err := json.Unmarshal([]byte(seed["meta-data"]), &metaData); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := metaData["public-keys"]; ok {
+		t.Fatalf("should not have public-keys: %#v", metaData)
+	}
+
+	expected := "#cloud-config\npassword: \"pa\\\"ss\"\nchpasswd: {expire: false}\nssh_pwauth: true\n"
+	if seed["user-data"] != expected {
+		t.Fatalf("bad: %q", seed["user-data"])
+	}
+}
+
+func TestCloudInitConfigSeed_files(t *testing.T) {
+	userData, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	userData.WriteString("#cloud-config\npackages: [nginx]\n")
+	userData.Close()
+	defer os.Remove(userData.Name())
+
+	c := &CloudInitConfig{
+		UserDataFile:  userData.Name(),
+		NetworkConfig: "version: 2\n",
+	}
+	seed, err := c.Seed("packer-1", "vm", nil, "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if seed["user-data"] != "#cloud-config\npackages: [nginx]\n" {
+		t.Fatalf("bad: %q", seed["user-data"])
+	}
+	if seed["network-config"] != "version: 2\n" {
+		t.Fatalf("bad: %q", seed["network-config"])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -31,6 +32,14 @@ type Driver interface {
 	// wait on shutdown of the VM with option to cancel
 	WaitForShutdown(<-chan struct{}) bool
 
+	// Pid returns the process ID of the running machine, or 0 if there
+	// is none.
+	Pid() int
+
+	// Attach makes the driver manage the machine with the given process
+	// ID, that an earlier run started and left running.
+	Attach(pid int) error
+
 	// Qemu executes the given command via qemu-img
 	QemuImg(...string) error
 
@@ -47,17 +56,17 @@ type QemuDriver struct {
 	QemuPath    string
 	QemuImgPath string
 
-	vmCmd   *exec.Cmd
-	vmEndCh <-chan int
-	lock    sync.Mutex
+	vmProcess *os.Process
+	vmEndCh   <-chan int
+	lock      sync.Mutex
 }
 
 func (d *QemuDriver) Stop() error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	if d.vmCmd != nil {
-		if err := d.vmCmd.Process.Kill(); err != nil {
+	if d.vmProcess != nil {
+		if err := d.vmProcess.Kill(); err != nil {
 			return err
 		}
 	}
@@ -69,7 +78,7 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	if d.vmCmd != nil {
+	if d.vmProcess != nil {
 		panic("Existing VM state found")
 	}
 
@@ -114,7 +123,7 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 
 		d.lock.Lock()
 		defer d.lock.Unlock()
-		d.vmCmd = nil
+		d.vmProcess = nil
 		d.vmEndCh = nil
 	}()
 
@@ -129,7 +138,57 @@ func (d *QemuDriver) Qemu(qemuArgs ...string) error {
 	}
 
 	// Setup our state so we know we are running
-	d.vmCmd = cmd
+	d.vmProcess = cmd.Process
+	d.vmEndCh = endCh
+
+	return nil
+}
+
+func (d *QemuDriver) Pid() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.vmProcess == nil {
+		return 0
+	}
+
+	return d.vmProcess.Pid
+}
+
+func (d *QemuDriver) Attach(pid int) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.vmProcess != nil {
+		panic("Existing VM state found")
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("Error finding VM process %d: %s", pid, err)
+	}
+
+	log.Printf("Attached to Qemu. Pid: %d", pid)
+
+	// The process isn't a child of this one, so on most platforms it
+	// can't be waited for and is polled instead.
+	endCh := make(chan int, 1)
+	go func() {
+		if _, err := process.Wait(); err != nil {
+			for process.Signal(syscall.Signal(0)) == nil {
+				time.Sleep(time.Second)
+			}
+		}
+
+		endCh <- 0
+
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		d.vmProcess = nil
+		d.vmEndCh = nil
+	}()
+
+	d.vmProcess = process
 	d.vmEndCh = endCh
 
 	return nil
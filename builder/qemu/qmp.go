@@ -0,0 +1,255 @@
+package qemu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// qmpClient is a client of the QEMU Machine Protocol, the JSON protocol of
+// the QEMU monitor. Commands are run one at a time, and asynchronous events
+// are logged.
+type qmpClient struct {
+	conn net.Conn
+	enc  *json.Encoder
+
+	// lock serializes commands, as responses have no ID to match them by
+	lock      sync.Mutex
+	responses chan qmpMessage
+}
+
+const (
+	// qmpDialTimeout is how long to wait for QEMU to create the QMP socket.
+	qmpDialTimeout = 10 * time.Second
+
+	// qmpQuitTimeout is how long to wait for QEMU to exit after quit.
+	qmpQuitTimeout = 10 * time.Second
+
+	// qmpStatusInterval is how often to check the status of the VM while
+	// waiting for it.
+	qmpStatusInterval = 5 * time.Second
+)
+
+// errQMPClosed is returned by the commands run after QEMU closed the
+// connection.
+var errQMPClosed = errors.New("QMP connection closed")
+
+// qmpMessage is a message from QEMU: a greeting, the response to a command
+// or an event.
+type qmpMessage struct {
+	Greeting *json.RawMessage `json:"QMP"`
+	Return   *json.RawMessage `json:"return"`
+	Error    *qmpError        `json:"error"`
+	Event    string           `json:"event"`
+	Data     json.RawMessage  `json:"data"`
+}
+
+type qmpError struct {
+	Class string `json:"class"`
+	Desc  string `json:"desc"`
+}
+
+func (e *qmpError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Class, e.Desc)
+}
+
+// qmpStatus is the status of the VM, as returned by query-status.
+type qmpStatus struct {
+	Running bool   `json:"running"`
+	Status  string `json:"status"`
+}
+
+// qmpFailedStatuses are the statuses of a VM that stopped running because
+// of an error, and won't resume by itself.
+var qmpFailedStatuses = map[string]bool{
+	"guest-panicked": true,
+	"internal-error": true,
+	"io-error":       true,
+}
+
+// Err returns an error if the VM stopped running because of an error, such
+// as a guest kernel panic or the host running out of disk space.
+func (s *qmpStatus) Err() error {
+	if qmpFailedStatuses[s.Status] {
+		return fmt.Errorf("VM stopped with status %s", s.Status)
+	}
+	return nil
+}
+
+// dialQMP connects to the QMP socket at path, retrying until the timeout
+// while QEMU creates it, and negotiates the capabilities of the protocol.
+func dialQMP(path string, timeout time.Duration) (*qmpClient, error) {
+	var conn net.Conn
+	var err error
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err = net.Dial("unix", path)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newQMPClient(conn)
+}
+
+func newQMPClient(conn net.Conn) (*qmpClient, error) {
+	c := &qmpClient{
+		conn:      conn,
+		enc:       json.NewEncoder(conn),
+		responses: make(chan qmpMessage),
+	}
+
+	// QEMU sends its greeting first, and then waits for qmp_capabilities
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	dec := json.NewDecoder(conn)
+	var greeting qmpMessage
+	if err := dec.Decode(&greeting); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Error reading QMP greeting: %s", err)
+	}
+	if greeting.Greeting == nil {
+		conn.Close()
+		return nil, errors.New("Error reading QMP greeting: not a QMP server")
+	}
+	log.Printf("QMP greeting: %s", *greeting.Greeting)
+	conn.SetReadDeadline(time.Time{})
+
+	go c.read(dec)
+
+	if _, err := c.execute("qmp_capabilities", nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// read reads the messages from QEMU until the connection is closed, passing
+// the responses on to execute.
+func (c *qmpClient) read(dec *json.Decoder) {
+	defer close(c.responses)
+
+	for {
+		var msg qmpMessage
+		if err := dec.Decode(&msg); err != nil {
+			log.Printf("QMP connection closed: %s", err)
+			return
+		}
+		if msg.Event != "" {
+			log.Printf("QMP event: %s %s", msg.Event, msg.Data)
+			continue
+		}
+		c.responses <- msg
+	}
+}
+
+// execute runs a QMP command with the given arguments, if any, and returns
+// its raw return value.
+func (c *qmpClient) execute(command string, args interface{}) (json.RawMessage, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	req := map[string]interface{}{"execute": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	log.Printf("Executing QMP command: %s", command)
+	if err := c.enc.Encode(req); err != nil {
+		return nil, fmt.Errorf("Error sending QMP command %s: %s", command, err)
+	}
+
+	msg, ok := <-c.responses
+	if !ok {
+		return nil, errQMPClosed
+	}
+	if msg.Error != nil {
+		return nil, fmt.Errorf("Error running QMP command %s: %s", command, msg.Error)
+	}
+	if msg.Return == nil {
+		return nil, nil
+	}
+	return *msg.Return, nil
+}
+
+// humanMonitorCommand runs a command of the human monitor, for the features
+// QMP lacks, such as the internal snapshots of savevm and loadvm. The human
+// monitor reports errors as output rather than failing.
+func (c *qmpClient) humanMonitorCommand(command string) error {
+	raw, err := c.execute("human-monitor-command", map[string]string{
+		"command-line": command,
+	})
+	if err != nil {
+		return err
+	}
+
+	var output string
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return err
+	}
+	if output != "" {
+		return fmt.Errorf("Error running %q: %s", command, output)
+	}
+	return nil
+}
+
+// Status returns the status of the VM.
+func (c *qmpClient) Status() (*qmpStatus, error) {
+	raw, err := c.execute("query-status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := new(qmpStatus)
+	if err := json.Unmarshal(raw, status); err != nil {
+		return nil, fmt.Errorf("Error reading VM status: %s", err)
+	}
+	return status, nil
+}
+
+// Powerdown presses the ACPI power button of the VM, which most guests
+// respond to by shutting down.
+func (c *qmpClient) Powerdown() error {
+	_, err := c.execute("system_powerdown", nil)
+	return err
+}
+
+// Quit makes QEMU exit straight away, after flushing the disks.
+func (c *qmpClient) Quit() error {
+	// QEMU may exit before it responds
+	if _, err := c.execute("quit", nil); err != nil && err != errQMPClosed {
+		return err
+	}
+	return nil
+}
+
+// Screendump saves the screen of the VM to a PPM image.
+func (c *qmpClient) Screendump(path string) error {
+	_, err := c.execute("screendump", map[string]string{"filename": path})
+	return err
+}
+
+// SaveSnapshot takes an internal snapshot of the VM and its disks.
+func (c *qmpClient) SaveSnapshot(name string) error {
+	return c.humanMonitorCommand(fmt.Sprintf("savevm %s", name))
+}
+
+// LoadSnapshot reverts the VM and its disks to an internal snapshot.
+func (c *qmpClient) LoadSnapshot(name string) error {
+	return c.humanMonitorCommand(fmt.Sprintf("loadvm %s", name))
+}
+
+// DeleteSnapshot deletes an internal snapshot.
+func (c *qmpClient) DeleteSnapshot(name string) error {
+	return c.humanMonitorCommand(fmt.Sprintf("delvm %s", name))
+}
+
+func (c *qmpClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,157 @@
+package qemu
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+// testQMPServer is a fake QMP server that responds to the commands it is
+// sent with the given responses, by name, and records them.
+type testQMPServer struct {
+	conn      net.Conn
+	responses map[string][]string
+	commands  chan map[string]interface{}
+}
+
+func newTestQMP(t *testing.T, responses map[string][]string) (*qmpClient, *testQMPServer) {
+	client, server := net.Pipe()
+	s := &testQMPServer{
+		conn:      server,
+		responses: responses,
+		commands:  make(chan map[string]interface{}, 10),
+	}
+	go s.serve()
+
+	c, err := newQMPClient(client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cmd := <-s.commands; cmd["execute"] != "qmp_capabilities" {
+		t.Fatalf("bad: %#v", cmd)
+	}
+	return c, s
+}
+
+func (s *testQMPServer) serve() {
+	defer s.conn.Close()
+
+	s.conn.Write([]byte(`{"QMP": {"version": {}, "capabilities": []}}` + "\n"))
+	r := bufio.NewReader(s.conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var cmd map[string]interface{}
+		if err := json.Unmarshal(line, &cmd); err != nil {
+			return
+		}
+		s.commands <- cmd
+
+		name := cmd["execute"].(string)
+		lines, ok := s.responses[name]
+		if !ok {
+			lines = []string{`{"return": {}}`}
+		}
+		for _, line := range lines {
+			if line == "close" {
+				return
+			}
+			s.conn.Write([]byte(line + "\n"))
+		}
+	}
+}
+
+func TestQMPClient_Status(t *testing.T) {
+	c, _ := newTestQMP(t, map[string][]string{
+		"query-status": {
+			`{"event": "RESUME", "data": {}, "timestamp": {}}`,
+			`{"return": {"running": false, "status": "io-error"}}`,
+		},
+	})
+	defer c.Close()
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if status.Running || status.Status != "io-error" {
+		t.Fatalf("bad: %#v", status)
+	}
+	if err := status.Err(); err == nil {
+		t.Fatal("should have error")
+	}
+
+	status = &qmpStatus{Running: true, Status: "running"}
+	if err := status.Err(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestQMPClient_Screendump(t *testing.T) {
+	c, s := newTestQMP(t, map[string][]string{
+		"screendump": {`{"error": {"class": "GenericError", "desc": "bad path"}}`},
+	})
+	defer c.Close()
+
+	err := c.Screendump("/foo.ppm")
+	if err == nil || !strings.Contains(err.Error(), "bad path") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	cmd := <-s.commands
+	args := cmd["arguments"].(map[string]interface{})
+	if args["filename"] != "/foo.ppm" {
+		t.Fatalf("bad: %#v", cmd)
+	}
+}
+
+func TestQMPClient_snapshots(t *testing.T) {
+	c, s := newTestQMP(t, map[string][]string{
+		"human-monitor-command": {`{"return": ""}`},
+	})
+	defer c.Close()
+
+	if err := c.SaveSnapshot("packer"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd := <-s.commands
+	args := cmd["arguments"].(map[string]interface{})
+	if args["command-line"] != "savevm packer" {
+		t.Fatalf("bad: %#v", cmd)
+	}
+
+	s.responses["human-monitor-command"] = []string{`{"return": "Error: No such snapshot\r\n"}`}
+	if err := c.LoadSnapshot("packer"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestQMPClient_Quit(t *testing.T) {
+	c, _ := newTestQMP(t, map[string][]string{
+		"quit": {"close"},
+	})
+	defer c.Close()
+
+	if err := c.Quit(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := c.Status(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestNewQMPClient_notQMP(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		server.Write([]byte(`{"foo": "bar"}` + "\n"))
+		server.Close()
+	}()
+
+	if _, err := newQMPClient(client); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -12,3 +12,13 @@ func commPort(state multistep.StateBag) (int, error) {
 	sshHostPort := state.Get("sshHostPort").(int)
 	return int(sshHostPort), nil
 }
+
+func qgaSocketPath(state multistep.StateBag) (string, error) {
+	return state.Get("qga_socket_path").(string), nil
+}
+
+// forwardsCommPort tells whether the communicator connects to a port of the
+// guest forwarded from the host, rather than to the guest agent socket.
+func forwardsCommPort(config *Config) bool {
+	return config.Comm.Type != "none" && config.Comm.Type != "qemu-guest-agent"
+}
@@ -0,0 +1,75 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// This step chooses the path of the socket of the virtio-serial port the
+// QEMU guest agent listens on, when it is the communicator.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   qga_socket_path string - The path of the guest agent socket.
+type stepConfigureQGA struct {
+	dir string
+}
+
+func (s *stepConfigureQGA) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.Comm.Type != "qemu-guest-agent" {
+		return multistep.ActionContinue
+	}
+
+	var err error
+	s.dir, err = tmp.Dir("packer-qga")
+	if err != nil {
+		err := fmt.Errorf("Error creating guest agent socket directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	socketPath := filepath.Join(s.dir, "qga.sock")
+
+	log.Printf("Guest agent socket path: %s", socketPath)
+	state.Put("qga_socket_path", socketPath)
+	return multistep.ActionContinue
+}
+
+// ResumeStateKeys lets a resumed build connect to the guest agent of the VM
+// that the failed build left running.
+func (s *stepConfigureQGA) ResumeStateKeys() []string {
+	return []string{"qga_socket_path"}
+}
+
+// Resume takes over the socket directory of the failed build, so that it is
+// removed when the VM is done with it.
+func (s *stepConfigureQGA) Resume(state multistep.StateBag) bool {
+	if socketPath, ok := state.GetOk("qga_socket_path"); ok {
+		s.dir = filepath.Dir(socketPath.(string))
+	}
+	return true
+}
+
+func (s *stepConfigureQGA) Cleanup(multistep.StateBag) {
+	if s.dir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Printf("Error removing guest agent socket directory: %s", err)
+	}
+	s.dir = ""
+}
@@ -0,0 +1,80 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// This step chooses the path of the QMP socket the VM listens on.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   qmp_socket_path string - The path of the QMP socket.
+type stepConfigureQMP struct {
+	dir string
+}
+
+func (s *stepConfigureQMP) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.DisableQMP {
+		log.Println("QMP is disabled, not configuring the QMP socket.")
+		return multistep.ActionContinue
+	}
+
+	socketPath := config.QMPSocketPath
+	if socketPath == "" {
+		var err error
+		s.dir, err = tmp.Dir("packer-qmp")
+		if err != nil {
+			err := fmt.Errorf("Error creating QMP socket directory: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		socketPath = filepath.Join(s.dir, "qmp.sock")
+	}
+
+	log.Printf("QMP socket path: %s", socketPath)
+	state.Put("qmp_socket_path", socketPath)
+	return multistep.ActionContinue
+}
+
+// ResumeStateKeys lets a resumed build connect to the QMP socket of the VM
+// that the failed build left running.
+func (s *stepConfigureQMP) ResumeStateKeys() []string {
+	return []string{"qmp_socket_path"}
+}
+
+// Resume takes over the socket directory of the failed build, so that it is
+// removed when the VM is done with it.
+func (s *stepConfigureQMP) Resume(state multistep.StateBag) bool {
+	config := state.Get("config").(*Config)
+
+	if socketPath, ok := state.GetOk("qmp_socket_path"); ok && config.QMPSocketPath == "" {
+		s.dir = filepath.Dir(socketPath.(string))
+	}
+	return true
+}
+
+func (s *stepConfigureQMP) Cleanup(multistep.StateBag) {
+	if s.dir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Printf("Error removing QMP socket directory: %s", err)
+	}
+	s.dir = ""
+}
@@ -56,6 +56,12 @@ func (s *stepConfigureVNC) Run(ctx context.Context, state multistep.StateBag) mu
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys lets a resumed build connect to the VNC server of the VM
+// that the failed build left running.
+func (s *stepConfigureVNC) ResumeStateKeys() []string {
+	return []string{"vnc_port", "vnc_ip"}
+}
+
 func (s *stepConfigureVNC) Cleanup(multistep.StateBag) {
 	if s.l != nil {
 		err := s.l.Close()
@@ -46,3 +46,7 @@ func (s *stepCopyDisk) Run(_ context.Context, state multistep.StateBag) multiste
 }
 
 func (s *stepCopyDisk) Cleanup(state multistep.StateBag) {}
+
+func (s *stepCopyDisk) ResumeStateKeys() []string {
+	return []string{"disk_filename"}
+}
@@ -0,0 +1,44 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/common/uuid"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepCreateCloudInit generates the cloud-init NoCloud seed, which the CD
+// step writes to the CD.
+//
+// Produces:
+//   cd_content map[string]string - The files of the seed
+type stepCreateCloudInit struct{}
+
+func (s *stepCreateCloudInit) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.CloudInit == nil {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Generating cloud-init seed...")
+	seed, err := config.CloudInit.Seed(
+		fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID()),
+		config.VMName,
+		config.Comm.SSHPublicKey,
+		config.Comm.SSHPassword)
+	if err != nil {
+		err := fmt.Errorf("Error generating cloud-init seed: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("cd_content", seed)
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateCloudInit) Cleanup(state multistep.StateBag) {}
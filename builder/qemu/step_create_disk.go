@@ -53,3 +53,7 @@ func (s *stepCreateDisk) Run(_ context.Context, state multistep.StateBag) multis
 }
 
 func (s *stepCreateDisk) Cleanup(state multistep.StateBag) {}
+
+func (s *stepCreateDisk) ResumeStateKeys() []string {
+	return []string{"disk_filename"}
+}
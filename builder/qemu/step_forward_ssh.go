@@ -48,6 +48,12 @@ func (s *stepForwardSSH) Run(ctx context.Context, state multistep.StateBag) mult
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys lets a resumed build connect to the communicator port
+// forwarded to the VM that the failed build left running.
+func (s *stepForwardSSH) ResumeStateKeys() []string {
+	return []string{"sshHostPort"}
+}
+
 func (s *stepForwardSSH) Cleanup(state multistep.StateBag) {
 	if s.l != nil {
 		err := s.l.Close()
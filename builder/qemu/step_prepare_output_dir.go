@@ -29,6 +29,10 @@ func (stepPrepareOutputDir) Run(_ context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
+func (stepPrepareOutputDir) ResumeStateKeys() []string {
+	return nil
+}
+
 func (stepPrepareOutputDir) Cleanup(state multistep.StateBag) {
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
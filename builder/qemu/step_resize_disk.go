@@ -42,3 +42,7 @@ func (s *stepResizeDisk) Run(_ context.Context, state multistep.StateBag) multis
 }
 
 func (s *stepResizeDisk) Cleanup(state multistep.StateBag) {}
+
+func (s *stepResizeDisk) ResumeStateKeys() []string {
+	return nil
+}
@@ -7,13 +7,20 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
-// stepRun runs the virtual machine
+// stepRun runs the virtual machine. When the build is resumable, a failed
+// build leaves the VM running, and the resumed build attaches to it over
+// QMP instead of starting it again, so that nothing is installed twice.
+//
+// Produces:
+//   qemu_pid int        - The process ID of the VM.
+//   qmp      *qmpClient - The QMP connection, when QMP is enabled.
 type stepRun struct {
 	BootDrive string
 	Message   string
@@ -46,14 +53,94 @@ func (s *stepRun) Run(_ context.Context, state multistep.StateBag) multistep.Ste
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	state.Put("qemu_pid", driver.Pid())
+
+	if qmpPathRaw, ok := state.GetOk("qmp_socket_path"); ok {
+		qmp, err := dialQMP(qmpPathRaw.(string), qmpDialTimeout)
+		if err != nil {
+			err := fmt.Errorf("Error connecting to QMP: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		state.Put("qmp", qmp)
+
+		status, err := qmp.Status()
+		if err == nil {
+			err = status.Err()
+		}
+		if err != nil {
+			err := fmt.Errorf("Error launching VM: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		log.Printf("VM status: %s", status.Status)
+	}
 
 	return multistep.ActionContinue
 }
 
+func (s *stepRun) ResumeStateKeys() []string {
+	return []string{"qemu_pid"}
+}
+
+// Resume attaches to the VM that the failed build left running. This takes
+// QMP, to make sure that the process is still the VM and that it still runs.
+func (s *stepRun) Resume(state multistep.StateBag) bool {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	pid, ok := state.Get("qemu_pid").(int)
+	qmpPath, qmpOk := state.Get("qmp_socket_path").(string)
+	if !ok || !qmpOk {
+		log.Println("Can't attach to the VM without QMP, starting it again.")
+		return false
+	}
+
+	qmp, err := dialQMP(qmpPath, 0)
+	if err == nil {
+		var status *qmpStatus
+		status, err = qmp.Status()
+		if err == nil {
+			err = status.Err()
+		}
+		if err != nil {
+			qmp.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("Error connecting to the VM of the failed build: %s", err)
+		return false
+	}
+
+	if err := driver.Attach(pid); err != nil {
+		log.Printf("Error attaching to the VM of the failed build: %s", err)
+		qmp.Close()
+		return false
+	}
+
+	ui.Say(fmt.Sprintf("Attached to the VM of the failed build (pid %d)", pid))
+	state.Put("qmp", qmp)
+	return true
+}
+
 func (s *stepRun) Cleanup(state multistep.StateBag) {
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
+	if qmp, ok := state.Get("qmp").(*qmpClient); ok {
+		// Let QEMU flush the disks and exit, rather than kill it
+		if err := qmp.Quit(); err != nil {
+			log.Printf("Error quitting QEMU: %s", err)
+		} else {
+			cancelCh := make(chan struct{})
+			time.AfterFunc(qmpQuitTimeout, func() { close(cancelCh) })
+			driver.WaitForShutdown(cancelCh)
+		}
+		qmp.Close()
+	}
+
 	if err := driver.Stop(); err != nil {
 		ui.Error(fmt.Sprintf("Error shutting down VM: %s", err))
 	}
@@ -78,7 +165,7 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 
 	defaultArgs["-name"] = vmName
 	defaultArgs["-machine"] = fmt.Sprintf("type=%s", config.MachineType)
-	if config.Comm.Type != "none" {
+	if forwardsCommPort(config) {
 		sshHostPort = state.Get("sshHostPort").(int)
 		defaultArgs["-netdev"] = fmt.Sprintf("user,id=user.0,hostfwd=tcp::%v-:%d", sshHostPort, config.Comm.Port())
 	} else {
@@ -177,7 +264,7 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 
 		httpPort := state.Get("http_port").(int)
 		ctx := config.ctx
-		if config.Comm.Type != "none" {
+		if forwardsCommPort(config) {
 			ctx.Data = qemuArgsTemplateData{
 				"10.0.2.2",
 				httpPort,
@@ -236,6 +323,22 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 		}
 	}
 
+	// Attach the CD, if any, besides the drives of QemuArgs too
+	if cdPathRaw, ok := state.GetOk("cd_path"); ok {
+		inArgs["-drive"] = append(inArgs["-drive"], fmt.Sprintf("file=%s,media=cdrom,format=raw", cdPathRaw.(string)))
+	}
+
+	// Open the QMP socket, besides the monitors of QemuArgs too
+	if qmpPathRaw, ok := state.GetOk("qmp_socket_path"); ok {
+		inArgs["-qmp"] = append(inArgs["-qmp"], fmt.Sprintf("unix:%s,server,nowait", qmpPathRaw.(string)))
+	}
+
+	// Open the virtio-serial port of the QEMU guest agent
+	if qgaPathRaw, ok := state.GetOk("qga_socket_path"); ok {
+		inArgs["-chardev"] = append(inArgs["-chardev"], fmt.Sprintf("socket,path=%s,server,nowait,id=qga0", qgaPathRaw.(string)))
+		inArgs["-device"] = append(inArgs["-device"], "virtio-serial", "virtserialport,chardev=qga0,name=org.qemu.guest_agent.0")
+	}
+
 	// Flatten to array of strings
 	outArgs := make([]string, 0)
 	for key, values := range inArgs {
@@ -0,0 +1,95 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step saves screendumps of the VM to the output directory
+// periodically while it boots, until the communicator connects to it.
+//
+// Uses:
+//   communicator packer.Communicator
+//   config *config
+//   qmp    *qmpClient
+//   ui     packer.Ui
+//
+// Produces:
+//   <nothing>
+type stepScreendump struct {
+	cancelCh chan struct{}
+	doneCh   chan struct{}
+}
+
+func (s *stepScreendump) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	qmp, ok := state.Get("qmp").(*qmpClient)
+	if !ok || config.screendumpInterval == 0 {
+		return multistep.ActionContinue
+	}
+
+	dir, err := filepath.Abs(filepath.Join(config.OutputDir, "screendumps"))
+	if err == nil {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err != nil {
+		err := fmt.Errorf("Error creating screendump directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say(fmt.Sprintf("Saving screendumps every %s to %s", config.screendumpInterval, dir))
+	s.cancelCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	go s.screendump(qmp, state, dir, config.screendumpInterval)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepScreendump) screendump(qmp *qmpClient, state multistep.StateBag, dir string, interval time.Duration) {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 1; ; i++ {
+		select {
+		case <-ticker.C:
+		case <-s.cancelCh:
+			return
+		}
+
+		if _, ok := state.GetOk("communicator"); ok {
+			log.Println("Communicator connected, no more screendumps.")
+			return
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("screendump-%04d.ppm", i))
+		if err := qmp.Screendump(path); err != nil {
+			log.Printf("Error saving screendump: %s", err)
+			if err == errQMPClosed {
+				return
+			}
+		}
+	}
+}
+
+func (s *stepScreendump) Cleanup(multistep.StateBag) {
+	if s.cancelCh == nil {
+		return
+	}
+
+	close(s.cancelCh)
+	<-s.doneCh
+	s.cancelCh = nil
+}
@@ -12,12 +12,14 @@ import (
 )
 
 // This step shuts down the machine. It first attempts to do so gracefully,
-// but ultimately forcefully shuts it down if that fails.
+// with the shutdown command, or the ACPI power button over QMP when there is
+// none, but ultimately forcefully shuts it down if that fails.
 //
 // Uses:
 //   communicator packer.Communicator
 //   config *config
 //   driver Driver
+//   qmp    *qmpClient
 //   ui     packer.Ui
 //
 // Produces:
@@ -27,20 +29,16 @@ type stepShutdown struct{}
 func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	driver := state.Get("driver").(Driver)
+	qmp, _ := state.Get("qmp").(*qmpClient)
 	ui := state.Get("ui").(packer.Ui)
 
 	if state.Get("communicator") == nil {
-		cancelCh := make(chan struct{}, 1)
-		go func() {
-			defer close(cancelCh)
-			<-time.After(config.shutdownTimeout)
-		}()
 		ui.Say("Waiting for shutdown...")
-		if ok := driver.WaitForShutdown(cancelCh); ok {
+		if err := waitForShutdown(driver, qmp, config.shutdownTimeout); err == nil {
 			log.Println("VM shut down.")
 			return multistep.ActionContinue
 		} else {
-			err := fmt.Errorf("Failed to shutdown")
+			err := fmt.Errorf("Failed to shutdown: %s", err)
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
@@ -59,21 +57,27 @@ func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multiste
 			return multistep.ActionHalt
 		}
 
-		// Start the goroutine that will time out our graceful attempt
-		cancelCh := make(chan struct{}, 1)
-		go func() {
-			defer close(cancelCh)
-			<-time.After(config.shutdownTimeout)
-		}()
-
 		log.Printf("Waiting max %s for shutdown to complete", config.shutdownTimeout)
-		if ok := driver.WaitForShutdown(cancelCh); !ok {
-			err := errors.New("Timeout while waiting for machine to shut down.")
+		if err := waitForShutdown(driver, qmp, config.shutdownTimeout); err != nil {
 			state.Put("error", err)
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
 	} else {
+		if qmp != nil {
+			ui.Say("Gracefully halting virtual machine with the ACPI power button...")
+			err := qmp.Powerdown()
+			if err == nil {
+				log.Printf("Waiting max %s for shutdown to complete", config.shutdownTimeout)
+				err = waitForShutdown(driver, qmp, config.shutdownTimeout)
+			}
+			if err == nil {
+				log.Println("VM shut down.")
+				return multistep.ActionContinue
+			}
+			ui.Error(fmt.Sprintf("Failed to shut down gracefully: %s", err))
+		}
+
 		ui.Say("Halting the virtual machine...")
 		if err := driver.Stop(); err != nil {
 			err := fmt.Errorf("Error stopping VM: %s", err)
@@ -88,3 +92,47 @@ func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multiste
 }
 
 func (s *stepShutdown) Cleanup(state multistep.StateBag) {}
+
+// waitForShutdown waits until the timeout for the VM to shut down. When QMP
+// is connected, the status of the VM is checked on the way, so as not to wait
+// for a VM that stopped because of an error.
+func waitForShutdown(driver Driver, qmp *qmpClient, timeout time.Duration) error {
+	cancelCh := make(chan struct{})
+	doneCh := make(chan bool, 1)
+	go func() {
+		doneCh <- driver.WaitForShutdown(cancelCh)
+	}()
+	cancel := func() {
+		close(cancelCh)
+		<-doneCh
+	}
+
+	var statusCh <-chan time.Time
+	if qmp != nil {
+		ticker := time.NewTicker(qmpStatusInterval)
+		defer ticker.Stop()
+		statusCh = ticker.C
+	}
+
+	timeoutCh := time.After(timeout)
+	for {
+		select {
+		case <-doneCh:
+			return nil
+		case <-timeoutCh:
+			cancel()
+			return errors.New("Timeout while waiting for machine to shut down.")
+		case <-statusCh:
+			status, err := qmp.Status()
+			if err != nil {
+				// QMP is closed as the VM shuts down
+				log.Printf("Error checking VM status: %s", err)
+				continue
+			}
+			if err := status.Err(); err != nil {
+				cancel()
+				return err
+			}
+		}
+	}
+}
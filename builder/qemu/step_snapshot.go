@@ -0,0 +1,95 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// stepSnapshot wraps a step that changes the VM, such as provisioning, to
+// take an internal snapshot of the VM before the step runs. When the step is
+// retried after failing, with -on-error=ask, the VM is reverted to the
+// snapshot first, so that the step runs on the VM it ran on the first time.
+// The snapshot is deleted once the step succeeds, so as not to end up in the
+// image.
+//
+// Reverting the VM breaks the connections the communicator had, so the
+// Connect step, when there is one, is run again after reverting.
+//
+// Uses:
+//   qmp *qmpClient
+//   ui  packer.Ui
+type stepSnapshot struct {
+	multistep.Step
+	Name    string
+	Connect multistep.Step
+
+	taken bool
+}
+
+func (s *stepSnapshot) InnerStepName() string {
+	return multistep.StepName(s.Step)
+}
+
+func (s *stepSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	qmp, ok := state.Get("qmp").(*qmpClient)
+	if !ok {
+		return s.Step.Run(ctx, state)
+	}
+
+	if s.taken {
+		ui.Say(fmt.Sprintf("Reverting the VM to snapshot %s...", s.Name))
+		if err := qmp.LoadSnapshot(s.Name); err != nil {
+			err := fmt.Errorf("Error reverting to snapshot: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+
+		if s.Connect != nil {
+			s.Connect.Cleanup(state)
+			if action := s.Connect.Run(ctx, state); action != multistep.ActionContinue {
+				return action
+			}
+		}
+	} else {
+		ui.Say(fmt.Sprintf("Taking snapshot %s of the VM...", s.Name))
+		if err := qmp.SaveSnapshot(s.Name); err != nil {
+			// Only retries depend on the snapshot, so carry on without
+			ui.Error(fmt.Sprintf("Error taking snapshot, retries won't be reverted: %s", err))
+		} else {
+			s.taken = true
+		}
+	}
+
+	action := s.Step.Run(ctx, state)
+	if action == multistep.ActionContinue {
+		s.deleteSnapshot(qmp)
+	}
+	return action
+}
+
+func (s *stepSnapshot) Cleanup(state multistep.StateBag) {
+	if qmp, ok := state.Get("qmp").(*qmpClient); ok {
+		s.deleteSnapshot(qmp)
+	}
+	s.Step.Cleanup(state)
+}
+
+func (s *stepSnapshot) deleteSnapshot(qmp *qmpClient) {
+	if !s.taken {
+		return
+	}
+
+	log.Printf("Deleting snapshot %s", s.Name)
+	if err := qmp.DeleteSnapshot(s.Name); err != nil {
+		log.Printf("Error deleting snapshot: %s", err)
+		return
+	}
+	s.taken = false
+}
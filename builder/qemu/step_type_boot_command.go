@@ -64,6 +64,31 @@ func (s *stepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 		pauseFn = state.Get("pauseFn").(multistep.DebugPauseFn)
 	}
 
+	hostIP := "10.0.2.2"
+	common.SetHTTPIP(hostIP)
+	configCtx := config.ctx
+	configCtx.Data = &bootCommandTemplateData{
+		hostIP,
+		httpPort,
+		config.VMName,
+	}
+
+	command, err := interpolate.Render(config.VNCConfig.FlatBootCommand(), &configCtx)
+	if err != nil {
+		err := fmt.Errorf("Error preparing boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	seq, err := bootcommand.GenerateExpressionSequence(command)
+	if err != nil {
+		err := fmt.Errorf("Error generating boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	// Connect to VNC
 	ui.Say(fmt.Sprintf("Connecting to VM via VNC (%s:%d)", vncIP, vncPort))
 
@@ -76,7 +101,12 @@ func (s *stepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 	}
 	defer nc.Close()
 
-	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: false})
+	// The screen driver reads the framebuffer updates for <waitScreen>
+	var updates chan vnc.ServerMessage
+	if seq.WaitsForScreen() {
+		updates = make(chan vnc.ServerMessage, bootcommand.VNCServerMessageBuffer)
+	}
+	c, err := vnc.Client(nc, &vnc.ClientConfig{Exclusive: false, ServerMessageCh: updates})
 	if err != nil {
 		err := fmt.Errorf("Error handshaking with VNC: %s", err)
 		state.Put("error", err)
@@ -87,35 +117,28 @@ func (s *stepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 
 	log.Printf("Connected to VNC desktop: %s", c.DesktopName)
 
-	hostIP := "10.0.2.2"
-	common.SetHTTPIP(hostIP)
-	configCtx := config.ctx
-	configCtx.Data = &bootCommandTemplateData{
-		hostIP,
-		httpPort,
-		config.VMName,
+	var d bootcommand.BCDriver = bootcommand.NewVNCDriver(c, config.VNCConfig.BootKeyInterval)
+	if updates != nil {
+		d, err = bootcommand.NewVNCScreenDriver(c, updates, config.VNCConfig.BootKeyInterval)
+		if err != nil {
+			err := fmt.Errorf("Error setting up VNC: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 	}
 
-	d := bootcommand.NewVNCDriver(c, config.VNCConfig.BootKeyInterval)
-
 	ui.Say("Typing the boot command over VNC...")
-	command, err := interpolate.Render(config.VNCConfig.FlatBootCommand(), &configCtx)
-	if err != nil {
-		err := fmt.Errorf("Error preparing boot command: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
-	}
-
-	seq, err := bootcommand.GenerateExpressionSequence(command)
+	rec, err := bootcommand.NewRecorder(config.VMName)
 	if err != nil {
-		err := fmt.Errorf("Error generating boot command: %s", err)
+		err := fmt.Errorf("Error recording boot command: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	defer rec.Close()
 
-	if err := seq.Do(ctx, d); err != nil {
+	if err := seq.Do(ctx, rec.Driver(d)); err != nil {
 		err := fmt.Errorf("Error running boot command: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -129,4 +152,10 @@ func (s *stepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys keeps a resumed build from typing the boot command again
+// into the VM that the failed build left running.
+func (*stepTypeBootCommand) ResumeStateKeys() []string {
+	return nil
+}
+
 func (*stepTypeBootCommand) Cleanup(multistep.StateBag) {}
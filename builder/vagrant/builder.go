@@ -87,6 +87,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 			},
 		},
 	}, raws...)
@@ -190,6 +191,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			ChecksumType: b.config.ChecksumType,
 			Description:  "Box",
 			Extension:    "box",
+			TemplatePath: b.config.PackerTemplatePath,
 			ResultKey:    "box_path",
 			Url:          []string{b.config.SourceBox},
 		})
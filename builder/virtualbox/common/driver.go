@@ -22,9 +22,15 @@ type Driver interface {
 	// Create a SCSI controller.
 	CreateSCSIController(vm string, controller string) error
 
+	// CreateSnapshot takes a snapshot of a VM with the given name.
+	CreateSnapshot(vm string, snapshot string) error
+
 	// Delete a VM by name
 	Delete(string) error
 
+	// HasSnapshot checks if the VM has a snapshot with the given name.
+	HasSnapshot(vm string, snapshot string) (bool, error)
+
 	// Import a VM
 	Import(string, string, []string) error
 
@@ -34,6 +40,17 @@ type Driver interface {
 	// Checks if the VM with the given name is running.
 	IsRunning(string) (bool, error)
 
+	// IsRegistered checks if a VM with the given name is registered with
+	// VirtualBox.
+	IsRegistered(string) (bool, error)
+
+	// LinkedClone creates and registers a VM with the given name, that is a
+	// linked clone of a snapshot of a VM, sharing its disks.
+	LinkedClone(vm string, snapshot string, name string) error
+
+	// RestoreSnapshot rolls a VM that isn't running back to a snapshot.
+	RestoreSnapshot(vm string, snapshot string) error
+
 	// Stop stops a running machine, forcefully.
 	Stop(string) error
 
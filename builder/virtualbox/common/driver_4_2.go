@@ -62,6 +62,10 @@ func (d *VBox42Driver) CreateSCSIController(vmName string, name string) error {
 	return d.VBoxManage(command...)
 }
 
+func (d *VBox42Driver) CreateSnapshot(vmName string, snapshot string) error {
+	return d.VBoxManage("snapshot", vmName, "take", snapshot)
+}
+
 func (d *VBox42Driver) Delete(name string) error {
 	return packer.Retry(1, 1, 5, func(i uint) (bool, error) {
 		if err := d.VBoxManage("unregistervm", name, "--delete"); err != nil {
@@ -74,6 +78,31 @@ func (d *VBox42Driver) Delete(name string) error {
 	})
 }
 
+func (d *VBox42Driver) HasSnapshot(vmName string, snapshot string) (bool, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath, "showvminfo", vmName, "--machinereadable")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return false, err
+	}
+
+	// The names of the snapshots are listed by their place in the tree,
+	// like SnapshotName-1-2="name".
+	snapshotRe := regexp.MustCompile(`^SnapshotName(-[0-9]+)*="(.*)"$`)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		// Need to trim off CR character when running in windows
+		line = strings.TrimRight(line, "\r")
+
+		matches := snapshotRe.FindStringSubmatch(line)
+		if matches != nil && matches[2] == snapshot {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func (d *VBox42Driver) Iso() (string, error) {
 	var stdout bytes.Buffer
 
@@ -115,6 +144,28 @@ func (d *VBox42Driver) Import(name string, path string, flags []string) error {
 	return d.VBoxManage(args...)
 }
 
+func (d *VBox42Driver) IsRegistered(name string) (bool, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath, "list", "vms")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return false, err
+	}
+
+	// Each VM is listed as "name" {uuid}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		// Need to trim off CR character when running in windows
+		line = strings.TrimRight(line, "\r")
+
+		if i := strings.LastIndex(line, `" {`); i > 0 && line[1:i] == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func (d *VBox42Driver) IsRunning(name string) (bool, error) {
 	var stdout bytes.Buffer
 
@@ -148,6 +199,19 @@ func (d *VBox42Driver) IsRunning(name string) (bool, error) {
 	return false, nil
 }
 
+func (d *VBox42Driver) LinkedClone(vmName string, snapshot string, name string) error {
+	return d.VBoxManage(
+		"clonevm", vmName,
+		"--snapshot", snapshot,
+		"--options", "link",
+		"--name", name,
+		"--register")
+}
+
+func (d *VBox42Driver) RestoreSnapshot(vmName string, snapshot string) error {
+	return d.VBoxManage("snapshot", vmName, "restore", snapshot)
+}
+
 func (d *VBox42Driver) Stop(name string) error {
 	if err := d.VBoxManage("controlvm", name, "poweroff"); err != nil {
 		return err
@@ -13,10 +13,19 @@ type DriverMock struct {
 	CreateSCSIControllerController string
 	CreateSCSIControllerErr        error
 
+	CreateSnapshotVM       string
+	CreateSnapshotSnapshot string
+	CreateSnapshotErr      error
+
 	DeleteCalled bool
 	DeleteName   string
 	DeleteErr    error
 
+	HasSnapshotVM       string
+	HasSnapshotSnapshot string
+	HasSnapshotReturn   bool
+	HasSnapshotErr      error
+
 	ImportCalled bool
 	ImportName   string
 	ImportPath   string
@@ -30,6 +39,19 @@ type DriverMock struct {
 	IsRunningReturn bool
 	IsRunningErr    error
 
+	IsRegisteredNames  []string
+	IsRegisteredReturn map[string]bool
+	IsRegisteredErr    error
+
+	LinkedCloneVM       string
+	LinkedCloneSnapshot string
+	LinkedCloneName     string
+	LinkedCloneErr      error
+
+	RestoreSnapshotVM       string
+	RestoreSnapshotSnapshot string
+	RestoreSnapshotErr      error
+
 	StopName string
 	StopErr  error
 
@@ -59,12 +81,24 @@ func (d *DriverMock) CreateSCSIController(vm string, controller string) error {
 	return d.CreateSCSIControllerErr
 }
 
+func (d *DriverMock) CreateSnapshot(vm string, snapshot string) error {
+	d.CreateSnapshotVM = vm
+	d.CreateSnapshotSnapshot = snapshot
+	return d.CreateSnapshotErr
+}
+
 func (d *DriverMock) Delete(name string) error {
 	d.DeleteCalled = true
 	d.DeleteName = name
 	return d.DeleteErr
 }
 
+func (d *DriverMock) HasSnapshot(vm string, snapshot string) (bool, error) {
+	d.HasSnapshotVM = vm
+	d.HasSnapshotSnapshot = snapshot
+	return d.HasSnapshotReturn, d.HasSnapshotErr
+}
+
 func (d *DriverMock) Import(name string, path string, flags []string) error {
 	d.ImportCalled = true
 	d.ImportName = name
@@ -86,6 +120,24 @@ func (d *DriverMock) IsRunning(name string) (bool, error) {
 	return d.IsRunningReturn, d.IsRunningErr
 }
 
+func (d *DriverMock) IsRegistered(name string) (bool, error) {
+	d.IsRegisteredNames = append(d.IsRegisteredNames, name)
+	return d.IsRegisteredReturn[name], d.IsRegisteredErr
+}
+
+func (d *DriverMock) LinkedClone(vm string, snapshot string, name string) error {
+	d.LinkedCloneVM = vm
+	d.LinkedCloneSnapshot = snapshot
+	d.LinkedCloneName = name
+	return d.LinkedCloneErr
+}
+
+func (d *DriverMock) RestoreSnapshot(vm string, snapshot string) error {
+	d.RestoreSnapshotVM = vm
+	d.RestoreSnapshotSnapshot = snapshot
+	return d.RestoreSnapshotErr
+}
+
 func (d *DriverMock) Stop(name string) error {
 	d.StopName = name
 	return d.StopErr
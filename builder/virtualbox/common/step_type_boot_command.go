@@ -97,7 +97,16 @@ func (s *StepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 		return multistep.ActionHalt
 	}
 
-	if err := seq.Do(ctx, d); err != nil {
+	rec, err := bootcommand.NewRecorder(vmName)
+	if err != nil {
+		err := fmt.Errorf("Error recording boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	defer rec.Close()
+
+	if err := seq.Do(ctx, rec.Driver(d)); err != nil {
 		err := fmt.Errorf("Error running boot command: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
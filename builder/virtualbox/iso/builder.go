@@ -1,6 +1,7 @@
 package iso
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -68,6 +69,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 				"guest_additions_path",
 				"guest_additions_url",
 				"vboxmanage",
@@ -194,6 +196,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	// Create the driver that we'll use to communicate with VirtualBox
 	driver, err := vboxcommon.NewDriver()
 	if err != nil {
@@ -208,13 +215,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			Ctx:                  b.config.ctx,
 		},
 		&common.StepDownload{
-			Checksum:     b.config.ISOChecksum,
-			ChecksumType: b.config.ISOChecksumType,
-			Description:  "ISO",
-			Extension:    b.config.TargetExtension,
-			ResultKey:    "iso_path",
-			TargetPath:   b.config.TargetPath,
-			Url:          b.config.ISOUrls,
+			Checksum:          b.config.ISOChecksum,
+			ChecksumType:      b.config.ISOChecksumType,
+			ChecksumSignature: b.config.ChecksumSignature(),
+			Description:       "ISO",
+			Extension:         b.config.TargetExtension,
+			TemplatePath:      b.config.PackerTemplatePath,
+			Ranges:            b.config.ISODownloadRanges,
+			MinSpeed:          b.config.ISODownloadMinSpeed,
+			ResultKey:         "iso_path",
+			TargetPath:        b.config.TargetPath,
+			Url:               b.config.ISOUrls,
 		},
 		&common.StepOutputDir{
 			Force: b.config.PackerForce,
@@ -229,7 +240,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
 		},
-		&vboxcommon.StepSshKeyPair{
+		&communicator.StepSSHKeyGen{
 			Debug:        b.config.PackerDebug,
 			DebugKeyPath: fmt.Sprintf("%s.pem", b.config.PackerBuildName),
 			Comm:         &b.config.Comm,
@@ -321,7 +332,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// Run
 	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -0,0 +1,39 @@
+package ovf
+
+import (
+	"fmt"
+
+	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
+)
+
+// linkedCloneArtifact is a VM registered with VirtualBox, that is a linked
+// clone of the snapshot of the base VM. Its disks are in the VirtualBox
+// machine folder, rather than in the output directory.
+type linkedCloneArtifact struct {
+	name   string
+	driver vboxcommon.Driver
+}
+
+func (*linkedCloneArtifact) BuilderId() string {
+	return vboxcommon.BuilderId
+}
+
+func (*linkedCloneArtifact) Files() []string {
+	return nil
+}
+
+func (a *linkedCloneArtifact) Id() string {
+	return a.name
+}
+
+func (a *linkedCloneArtifact) String() string {
+	return fmt.Sprintf("VM linked clone: %s", a.name)
+}
+
+func (*linkedCloneArtifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *linkedCloneArtifact) Destroy() error {
+	return a.driver.Delete(a.name)
+}
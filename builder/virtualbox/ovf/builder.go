@@ -1,6 +1,7 @@
 package ovf
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -33,6 +34,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 // Run executes a Packer build and returns a packer.Artifact representing
 // a VirtualBox appliance.
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	// Create the driver that we'll use to communicate with VirtualBox
 	driver, err := vboxcommon.NewDriver()
 	if err != nil {
@@ -47,6 +53,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
+	reused := false
+	if b.config.BaseVMName != "" {
+		reused, err = driver.IsRegistered(b.config.BaseVMName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed looking for base VM: %s", err)
+		}
+	}
+
 	// Build the steps.
 	steps := []multistep.Step{
 		&common.StepOutputDir{
@@ -63,7 +77,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			HTTPPortMin: b.config.HTTPPortMin,
 			HTTPPortMax: b.config.HTTPPortMax,
 		},
-		&vboxcommon.StepSshKeyPair{
+		&communicator.StepSSHKeyGen{
 			Debug:        b.config.PackerDebug,
 			DebugKeyPath: fmt.Sprintf("%s.pem", b.config.PackerBuildName),
 			Comm:         &b.config.Comm,
@@ -74,19 +88,39 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			GuestAdditionsSHA256: b.config.GuestAdditionsSHA256,
 			Ctx:                  b.config.ctx,
 		},
-		&common.StepDownload{
+	}
+
+	// A base VM that is registered already is reused, without the OVF
+	if !reused {
+		steps = append(steps, &common.StepDownload{
 			Checksum:     b.config.Checksum,
 			ChecksumType: b.config.ChecksumType,
 			Description:  "OVF/OVA",
 			Extension:    "ova",
+			TemplatePath: b.config.PackerTemplatePath,
 			ResultKey:    "vm_path",
 			TargetPath:   b.config.TargetPath,
 			Url:          []string{b.config.SourcePath},
-		},
-		&StepImport{
+		})
+	}
+
+	if b.config.BaseVMName != "" {
+		steps = append(steps, &StepReuseVM{
+			BaseName:    b.config.BaseVMName,
+			Snapshot:    b.config.BaseSnapshot,
 			Name:        b.config.VMName,
+			LinkedClone: b.config.LinkedClone,
 			ImportFlags: b.config.ImportFlags,
-		},
+			Force:       b.config.PackerForce,
+		})
+	} else {
+		steps = append(steps, &StepImport{
+			Name:        b.config.VMName,
+			ImportFlags: b.config.ImportFlags,
+		})
+	}
+
+	steps = append(steps,
 		&vboxcommon.StepAttachGuestAdditions{
 			GuestAdditionsMode:      b.config.GuestAdditionsMode,
 			GuestAdditionsInterface: b.config.GuestAdditionsInterface,
@@ -156,11 +190,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			SkipNatMapping: b.config.SSHSkipNatMapping,
 			SkipExport:     b.config.SkipExport,
 		},
-	}
+	)
 
 	// Run the steps.
 	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 
 	// Report any errors.
 	if rawErr, ok := state.GetOk("error"); ok {
@@ -176,6 +210,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 		return nil, errors.New("Build was halted.")
 	}
 
+	if b.config.LinkedClone {
+		return &linkedCloneArtifact{name: b.config.VMName, driver: driver}, nil
+	}
+
 	return vboxcommon.NewArtifact(b.config.OutputDir)
 }
 
@@ -30,6 +30,8 @@ type Config struct {
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 	vboxcommon.GuestAdditionsConfig `mapstructure:",squash"`
 
+	BaseSnapshot            string   `mapstructure:"base_snapshot"`
+	BaseVMName              string   `mapstructure:"base_vm_name"`
 	Checksum                string   `mapstructure:"checksum"`
 	ChecksumType            string   `mapstructure:"checksum_type"`
 	GuestAdditionsMode      string   `mapstructure:"guest_additions_mode"`
@@ -43,6 +45,7 @@ type Config struct {
 	TargetPath              string   `mapstructure:"target_path"`
 	VMName                  string   `mapstructure:"vm_name"`
 	KeepRegistered          bool     `mapstructure:"keep_registered"`
+	LinkedClone             bool     `mapstructure:"linked_clone"`
 	SkipExport              bool     `mapstructure:"skip_export"`
 
 	ctx interpolate.Context
@@ -56,6 +59,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"boot_command",
+				"boot_command_macros",
 				"guest_additions_path",
 				"guest_additions_url",
 				"vboxmanage",
@@ -79,13 +83,31 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.GuestAdditionsInterface = "ide"
 	}
 
+	if c.BaseSnapshot == "" {
+		c.BaseSnapshot = "packer-base"
+	}
+
+	// Without a linked clone, the base VM itself is provisioned
+	var errs *packer.MultiError
+	if c.BaseVMName != "" && !c.LinkedClone {
+		if c.VMName != "" && c.VMName != c.BaseVMName {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("vm_name must be base_vm_name unless linked_clone is true"))
+		}
+		c.VMName = c.BaseVMName
+	}
+
 	if c.VMName == "" {
 		c.VMName = fmt.Sprintf(
 			"packer-%s-%d", c.PackerBuildName, interpolate.InitTime.Unix())
 	}
 
+	// The linked clone is the artifact
+	if c.LinkedClone {
+		c.SkipExport = true
+	}
+
 	// Prepare the errors
-	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, c.ExportConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ExportOpts.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.FloppyConfig.Prepare(&c.ctx)...)
@@ -112,6 +134,14 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 			fmt.Errorf("Source file '%s' needs to exist at time of config validation! %v", c.SourcePath, err))
 	}
 
+	if c.LinkedClone {
+		if c.BaseVMName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("linked_clone requires base_vm_name"))
+		} else if c.VMName == c.BaseVMName {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("vm_name can't be base_vm_name when linked_clone is true"))
+		}
+	}
+
 	validMode := false
 	validModes := []string{
 		vboxcommon.GuestAdditionsModeDisable,
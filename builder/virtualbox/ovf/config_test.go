@@ -128,3 +128,40 @@ func TestNewConfig_shutdown_timeout(t *testing.T) {
 		t.Fatalf("bad: %s", err)
 	}
 }
+
+func TestNewConfig_baseVM(t *testing.T) {
+	c := testConfig(t)
+	c["base_vm_name"] = "base"
+	config, _, err := NewConfig(c)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if config.VMName != "base" || config.BaseSnapshot != "packer-base" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// Without a linked clone, the base VM is provisioned
+	c["vm_name"] = "other"
+	if _, _, err := NewConfig(c); err == nil {
+		t.Fatal("should error")
+	}
+
+	c["linked_clone"] = true
+	config, _, err = NewConfig(c)
+	if err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if config.VMName != "other" || !config.SkipExport {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	c["vm_name"] = "base"
+	if _, _, err := NewConfig(c); err == nil {
+		t.Fatal("should error")
+	}
+
+	delete(c, "base_vm_name")
+	if _, _, err := NewConfig(c); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,149 @@
+package ovf
+
+import (
+	"context"
+	"fmt"
+
+	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step imports an OVF VM into VirtualBox once, as a base VM that stays
+// registered, and takes a snapshot of it. Later builds reuse the base VM
+// rather than importing the OVF again.
+//
+// Without a linked clone, the base VM itself is provisioned. It is rolled
+// back to the snapshot when the build fails, and before the build starts
+// with -force. With a linked clone, a linked clone of the snapshot is
+// provisioned instead, which is deleted when the build fails.
+//
+// Uses:
+//   driver  vboxcommon.Driver
+//   ui      packer.Ui
+//   vm_path string - Only when the base VM isn't registered yet.
+//
+// Produces:
+//   vmName string - The name of the VM to provision.
+type StepReuseVM struct {
+	BaseName    string
+	Snapshot    string
+	Name        string
+	LinkedClone bool
+	ImportFlags []string
+	Force       bool
+
+	vmName string
+}
+
+func (s *StepReuseVM) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	registered, err := driver.IsRegistered(s.BaseName)
+	if err != nil {
+		return halt(state, fmt.Errorf("Error looking for base VM: %s", err))
+	}
+
+	if !registered {
+		vmPath := state.Get("vm_path").(string)
+		ui.Say(fmt.Sprintf("Importing base VM %s: %s", s.BaseName, vmPath))
+		if err := driver.Import(s.BaseName, vmPath, s.ImportFlags); err != nil {
+			return halt(state, fmt.Errorf("Error importing VM: %s", err))
+		}
+
+		ui.Say(fmt.Sprintf("Taking snapshot %s of base VM...", s.Snapshot))
+		if err := driver.CreateSnapshot(s.BaseName, s.Snapshot); err != nil {
+			// Without the snapshot, the base VM can't be reused
+			if err := driver.Delete(s.BaseName); err != nil {
+				ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
+			}
+			return halt(state, fmt.Errorf("Error taking snapshot: %s", err))
+		}
+	} else {
+		ok, err := driver.HasSnapshot(s.BaseName, s.Snapshot)
+		if err != nil {
+			return halt(state, fmt.Errorf("Error looking for snapshot: %s", err))
+		}
+		if !ok {
+			return halt(state, fmt.Errorf(
+				"Base VM %s has no snapshot %s. Take the snapshot, or unregister "+
+					"the VM to import it again.", s.BaseName, s.Snapshot))
+		}
+
+		if !s.LinkedClone {
+			if s.Force {
+				ui.Say(fmt.Sprintf("Rolling back base VM %s to snapshot %s (force)...", s.BaseName, s.Snapshot))
+				if err := driver.RestoreSnapshot(s.BaseName, s.Snapshot); err != nil {
+					return halt(state, fmt.Errorf("Error restoring snapshot: %s", err))
+				}
+			} else {
+				ui.Say(fmt.Sprintf("Reusing base VM %s as the last build left it. Run with "+
+					"-force to roll it back to snapshot %s first.", s.BaseName, s.Snapshot))
+			}
+		}
+	}
+
+	name := s.BaseName
+	if s.LinkedClone {
+		exists, err := driver.IsRegistered(s.Name)
+		if err != nil {
+			return halt(state, fmt.Errorf("Error looking for VM: %s", err))
+		}
+		if exists {
+			if !s.Force {
+				return halt(state, fmt.Errorf(
+					"VM %s already exists. Run with -force to delete it.", s.Name))
+			}
+			ui.Say(fmt.Sprintf("Deleting VM %s of a previous build (force)...", s.Name))
+			if err := driver.Delete(s.Name); err != nil {
+				return halt(state, fmt.Errorf("Error deleting VM: %s", err))
+			}
+		}
+
+		ui.Say(fmt.Sprintf("Creating linked clone %s of snapshot %s...", s.Name, s.Snapshot))
+		if err := driver.LinkedClone(s.BaseName, s.Snapshot, s.Name); err != nil {
+			return halt(state, fmt.Errorf("Error creating linked clone: %s", err))
+		}
+		name = s.Name
+	}
+
+	s.vmName = name
+	state.Put("vmName", name)
+	return multistep.ActionContinue
+}
+
+func (s *StepReuseVM) Cleanup(state multistep.StateBag) {
+	if s.vmName == "" {
+		return
+	}
+
+	// The VM of a successful build is kept
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	driver := state.Get("driver").(vboxcommon.Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if s.LinkedClone {
+		ui.Say("Deregistering and deleting linked clone...")
+		if err := driver.Delete(s.vmName); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting VM: %s", err))
+		}
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Rolling back base VM to snapshot %s...", s.Snapshot))
+	if err := driver.RestoreSnapshot(s.vmName, s.Snapshot); err != nil {
+		ui.Error(fmt.Sprintf("Error restoring snapshot: %s", err))
+	}
+}
+
+func halt(state multistep.StateBag, err error) multistep.StepAction {
+	state.Put("error", err)
+	state.Get("ui").(packer.Ui).Error(err.Error())
+	return multistep.ActionHalt
+}
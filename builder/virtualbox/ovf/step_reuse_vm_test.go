@@ -0,0 +1,133 @@
+package ovf
+
+import (
+	"context"
+	"testing"
+
+	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepReuseVM_impl(t *testing.T) {
+	var _ multistep.Step = new(StepReuseVM)
+}
+
+func TestStepReuseVM_import(t *testing.T) {
+	state := testState(t)
+	state.Put("vm_path", "foo")
+	step := &StepReuseVM{BaseName: "base", Snapshot: "packer-base"}
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.ImportName != "base" || driver.ImportPath != "foo" {
+		t.Fatalf("bad: %#v", driver)
+	}
+	if driver.CreateSnapshotVM != "base" || driver.CreateSnapshotSnapshot != "packer-base" {
+		t.Fatalf("bad: %#v", driver)
+	}
+	if name := state.Get("vmName"); name != "base" {
+		t.Fatalf("bad: %#v", name)
+	}
+
+	// The base VM of a successful build is kept
+	step.Cleanup(state)
+	if driver.DeleteCalled || driver.RestoreSnapshotVM != "" {
+		t.Fatal("should keep the VM")
+	}
+
+	// and rolled back when the build fails
+	state.Put(multistep.StateHalted, true)
+	step.Cleanup(state)
+	if driver.DeleteCalled {
+		t.Fatal("delete should not be called")
+	}
+	if driver.RestoreSnapshotVM != "base" || driver.RestoreSnapshotSnapshot != "packer-base" {
+		t.Fatalf("bad: %#v", driver)
+	}
+}
+
+func TestStepReuseVM_reuse(t *testing.T) {
+	state := testState(t)
+	step := &StepReuseVM{BaseName: "base", Snapshot: "packer-base"}
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+	driver.IsRegisteredReturn = map[string]bool{"base": true}
+	driver.HasSnapshotReturn = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.ImportCalled {
+		t.Fatal("import should not be called")
+	}
+	if driver.RestoreSnapshotVM != "" {
+		t.Fatal("should not roll back without force")
+	}
+
+	step = &StepReuseVM{BaseName: "base", Snapshot: "packer-base", Force: true}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.RestoreSnapshotVM != "base" || driver.RestoreSnapshotSnapshot != "packer-base" {
+		t.Fatalf("bad: %#v", driver)
+	}
+
+	// Without the snapshot, there is nothing to roll back to
+	driver.HasSnapshotReturn = false
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
+func TestStepReuseVM_linkedClone(t *testing.T) {
+	state := testState(t)
+	step := &StepReuseVM{
+		BaseName:    "base",
+		Snapshot:    "packer-base",
+		Name:        "clone",
+		LinkedClone: true,
+	}
+
+	driver := state.Get("driver").(*vboxcommon.DriverMock)
+	driver.IsRegisteredReturn = map[string]bool{"base": true, "clone": true}
+	driver.HasSnapshotReturn = true
+
+	// The clone of a previous build is only deleted with force
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.DeleteCalled || driver.LinkedCloneName != "" {
+		t.Fatal("should not replace the VM without force")
+	}
+
+	step.Force = true
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.DeleteName != "clone" {
+		t.Fatalf("bad: %#v", driver.DeleteName)
+	}
+	if driver.RestoreSnapshotVM != "" {
+		t.Fatal("should not roll back the base VM")
+	}
+	if driver.LinkedCloneVM != "base" || driver.LinkedCloneSnapshot != "packer-base" || driver.LinkedCloneName != "clone" {
+		t.Fatalf("bad: %#v", driver)
+	}
+	if name := state.Get("vmName"); name != "clone" {
+		t.Fatalf("bad: %#v", name)
+	}
+
+	// The clone of a failed build is deleted
+	driver.DeleteName = ""
+	state.Put(multistep.StateCancelled, true)
+	step.Cleanup(state)
+	if driver.DeleteName != "clone" {
+		t.Fatalf("bad: %#v", driver.DeleteName)
+	}
+	if driver.RestoreSnapshotVM != "" {
+		t.Fatal("should not roll back the base VM")
+	}
+}
@@ -68,6 +68,40 @@ func (s *StepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 		pauseFn = state.Get("pauseFn").(multistep.DebugPauseFn)
 	}
 
+	// Determine the host IP
+	hostIP, err := driver.HostIP(state)
+	if err != nil {
+		err := fmt.Errorf("Error detecting host IP: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	log.Printf("Host IP for the VMware machine: %s", hostIP)
+	common.SetHTTPIP(hostIP)
+
+	s.Ctx.Data = &bootCommandTemplateData{
+		hostIP,
+		httpPort,
+		s.VMName,
+	}
+
+	command, err := interpolate.Render(s.BootCommand, &s.Ctx)
+	if err != nil {
+		err := fmt.Errorf("Error preparing boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	seq, err := bootcommand.GenerateExpressionSequence(command)
+	if err != nil {
+		err := fmt.Errorf("Error generating boot command: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	// Connect to VNC
 	ui.Say(fmt.Sprintf("Connecting to VM via VNC (%s:%d)", vncIp, vncPort))
 
@@ -88,7 +122,12 @@ func (s *StepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 		auth = []vnc.ClientAuth{new(vnc.ClientAuthNone)}
 	}
 
-	c, err := vnc.Client(nc, &vnc.ClientConfig{Auth: auth, Exclusive: true})
+	// The screen driver reads the framebuffer updates for <waitScreen>
+	var updates chan vnc.ServerMessage
+	if seq.WaitsForScreen() {
+		updates = make(chan vnc.ServerMessage, bootcommand.VNCServerMessageBuffer)
+	}
+	c, err := vnc.Client(nc, &vnc.ClientConfig{Auth: auth, Exclusive: true, ServerMessageCh: updates})
 	if err != nil {
 		err := fmt.Errorf("Error handshaking with VNC: %s", err)
 		state.Put("error", err)
@@ -99,44 +138,28 @@ func (s *StepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag)
 
 	log.Printf("Connected to VNC desktop: %s", c.DesktopName)
 
-	// Determine the host IP
-	hostIP, err := driver.HostIP(state)
-	if err != nil {
-		err := fmt.Errorf("Error detecting host IP: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
-	}
-
-	log.Printf("Host IP for the VMware machine: %s", hostIP)
-	common.SetHTTPIP(hostIP)
-
-	s.Ctx.Data = &bootCommandTemplateData{
-		hostIP,
-		httpPort,
-		s.VMName,
+	var d bootcommand.BCDriver = bootcommand.NewVNCDriver(c, s.KeyInterval)
+	if updates != nil {
+		d, err = bootcommand.NewVNCScreenDriver(c, updates, s.KeyInterval)
+		if err != nil {
+			err := fmt.Errorf("Error setting up VNC: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
 	}
 
-	d := bootcommand.NewVNCDriver(c, s.KeyInterval)
-
 	ui.Say("Typing the boot command over VNC...")
-	command, err := interpolate.Render(s.BootCommand, &s.Ctx)
-	if err != nil {
-		err := fmt.Errorf("Error preparing boot command: %s", err)
-		state.Put("error", err)
-		ui.Error(err.Error())
-		return multistep.ActionHalt
-	}
-
-	seq, err := bootcommand.GenerateExpressionSequence(command)
+	rec, err := bootcommand.NewRecorder(s.VMName)
 	if err != nil {
-		err := fmt.Errorf("Error generating boot command: %s", err)
+		err := fmt.Errorf("Error recording boot command: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
+	defer rec.Close()
 
-	if err := seq.Do(ctx, d); err != nil {
+	if err := seq.Do(ctx, rec.Driver(d)); err != nil {
 		err := fmt.Errorf("Error running boot command: %s", err)
 		state.Put("error", err)
 		ui.Error(err.Error())
@@ -1,6 +1,7 @@
 package iso
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -31,6 +32,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+// RunContext runs the build, which is cancelled when ctx is done.
+func (b *Builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	driver, err := vmwcommon.NewDriver(&b.config.DriverConfig, &b.config.SSHConfig, b.config.VMName)
 	if err != nil {
 		return nil, fmt.Errorf("Failed creating VMware driver: %s", err)
@@ -72,13 +78,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 			ToolsUploadFlavor: b.config.ToolsUploadFlavor,
 		},
 		&common.StepDownload{
-			Checksum:     b.config.ISOChecksum,
-			ChecksumType: b.config.ISOChecksumType,
-			Description:  "ISO",
-			Extension:    b.config.TargetExtension,
-			ResultKey:    "iso_path",
-			TargetPath:   b.config.TargetPath,
-			Url:          b.config.ISOUrls,
+			Checksum:          b.config.ISOChecksum,
+			ChecksumType:      b.config.ISOChecksumType,
+			ChecksumSignature: b.config.ChecksumSignature(),
+			Description:       "ISO",
+			Extension:         b.config.TargetExtension,
+			TemplatePath:      b.config.PackerTemplatePath,
+			Ranges:            b.config.ISODownloadRanges,
+			MinSpeed:          b.config.ISODownloadMinSpeed,
+			ResultKey:         "iso_path",
+			TargetPath:        b.config.TargetPath,
+			Url:               b.config.ISOUrls,
 		},
 		&vmwcommon.StepOutputDir{
 			Force: b.config.PackerForce,
@@ -180,7 +190,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 
 	// Run!
 	b.runner = common.NewRunnerWithPauseFn(steps, b.config.PackerConfig, ui, state)
-	b.runner.Run(state)
+	multistep.RunContext(ctx, b.runner, state)
 
 	// If there was an error, return that
 	if rawErr, ok := state.GetOk("error"); ok {
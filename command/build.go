@@ -2,6 +2,7 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -23,21 +24,29 @@ type BuildCommand struct {
 }
 
 func (c *BuildCommand) Run(args []string) int {
-	var cfgColor, cfgDebug, cfgForce, cfgTimestamp, cfgParallel bool
+	var cfgColor, cfgDebug, cfgForce, cfgResume, cfgTimestamp, cfgParallel bool
+	var cfgParallelBuilds int
 	var cfgOnError string
 	flags := c.Meta.FlagSet("build", FlagSetBuildFilter|FlagSetVars)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
 	flags.BoolVar(&cfgColor, "color", true, "")
 	flags.BoolVar(&cfgDebug, "debug", false, "")
 	flags.BoolVar(&cfgForce, "force", false, "")
+	flags.BoolVar(&cfgResume, "resume", false, "")
 	flags.BoolVar(&cfgTimestamp, "timestamp-ui", false, "")
 	flagOnError := enumflag.New(&cfgOnError, "cleanup", "abort", "ask")
 	flags.Var(flagOnError, "on-error", "")
 	flags.BoolVar(&cfgParallel, "parallel", true, "")
+	flags.IntVar(&cfgParallelBuilds, "parallel-builds", 0, "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
 
+	if cfgParallelBuilds < 0 {
+		c.Ui.Error("-parallel-builds can't be negative")
+		return 1
+	}
+
 	args = flags.Args()
 	if len(args) != 1 {
 		flags.Usage()
@@ -47,7 +56,7 @@ func (c *BuildCommand) Run(args []string) int {
 	// Parse the template
 	var tpl *template.Template
 	var err error
-	tpl, err = template.ParseFile(args[0])
+	tpl, err = template.ParseFileWithVars(args[0], c.Meta.flagVars)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
 		return 1
@@ -60,8 +69,13 @@ func (c *BuildCommand) Run(args []string) int {
 		return 1
 	}
 
-	// Get the builds we care about
-	buildNames := c.Meta.BuildNames(core)
+	// Get the builds we care about, with every build after the builds it
+	// depends on
+	buildNames, err := sortBuildNames(tpl, c.Meta.BuildNames(core))
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 	builds := make([]packer.Build, 0, len(buildNames))
 	for _, n := range buildNames {
 		b, err := core.Build(n)
@@ -77,6 +91,9 @@ func (c *BuildCommand) Run(args []string) int {
 
 	if cfgDebug {
 		c.Ui.Say("Debug mode enabled. Builds will not be parallelized.")
+		if cfgResume {
+			c.Ui.Say("Resume is not supported in debug mode and will be ignored.")
+		}
 	}
 
 	// Compile all the UIs for the builds
@@ -88,15 +105,18 @@ func (c *BuildCommand) Run(args []string) int {
 		packer.UiColorBlue,
 	}
 	buildUis := make(map[string]packer.Ui)
+	_, events := c.Ui.(packer.EventUi)
+	_, machineReadable := c.Ui.(*packer.MachineReadableUi)
 	for i, b := range buildNames {
 		var ui packer.Ui
 		ui = c.Ui
-		if cfgColor {
+		// Events carry the build instead, so they aren't wrapped
+		if cfgColor && !events {
 			ui = &packer.ColoredUi{
 				Color: colors[i%len(colors)],
 				Ui:    ui,
 			}
-			if _, ok := c.Ui.(*packer.MachineReadableUi); !ok {
+			if !machineReadable {
 				ui.Say(fmt.Sprintf("%s output will be in this color.", b))
 				if i+1 == len(buildNames) {
 					// Add a newline between the color output and the actual output
@@ -117,18 +137,21 @@ func (c *BuildCommand) Run(args []string) int {
 	log.Printf("Build debug mode: %v", cfgDebug)
 	log.Printf("Force build: %v", cfgForce)
 	log.Printf("On error: %v", cfgOnError)
+	log.Printf("Resume: %v", cfgResume)
 
-	// Set the debug and force mode and prepare all the builds
-	for _, b := range builds {
+	// Set the debug and force mode and prepare a build, printing any
+	// warnings
+	prepare := func(b packer.Build) error {
 		log.Printf("Preparing build: %s", b.Name())
 		b.SetDebug(cfgDebug)
 		b.SetForce(cfgForce)
 		b.SetOnError(cfgOnError)
+		b.SetMachineReadable(events || machineReadable)
+		b.SetResume(cfgResume)
 
 		warnings, err := b.Prepare()
 		if err != nil {
-			c.Ui.Error(err.Error())
-			return 1
+			return err
 		}
 		if len(warnings) > 0 {
 			ui := buildUis[b.Name()]
@@ -138,73 +161,193 @@ func (c *BuildCommand) Run(args []string) int {
 			}
 			ui.Say("")
 		}
+		return nil
+	}
+
+	// Prepare all the builds that don't depend on other builds. The others
+	// are prepared once the artifacts they depend on are built.
+	for _, b := range builds {
+		if len(tpl.Builders[b.Name()].DependsOn) > 0 {
+			continue
+		}
+		if err := prepare(b); err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
 	}
 
-	// Run all the builds in parallel and wait for them to complete
+	// Run the builds on a pool of workers and wait for them to complete
 	var interruptWg, wg sync.WaitGroup
 	interrupted := false
 	var artifacts = struct {
 		sync.RWMutex
 		m map[string][]packer.Artifact
 	}{m: make(map[string][]packer.Artifact)}
-	errors := make(map[string]error)
-	// ctx := context.Background()
-	for _, b := range builds {
-		// Increment the waitgroup so we wait for this item to finish properly
-		wg.Add(1)
-		// buildCtx, cancelCtx := ctx.WithCancel()
-
-		// Handle interrupts for this build
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		defer signal.Stop(sigCh)
-		go func(b packer.Build) {
-			sig := <-sigCh
-			interruptWg.Add(1)
-			defer interruptWg.Done()
-			interrupted = true
-
-			log.Printf("Stopping build: %s after receiving %s", b.Name(), sig)
-			b.Cancel()
-			//cancelCtx()
-			log.Printf("Build cancelled: %s", b.Name())
-		}(b)
-
-		// Run the build in a goroutine
-		go func(b packer.Build) {
-			defer wg.Done()
+	var errors = struct {
+		sync.RWMutex
+		m map[string]error
+	}{m: make(map[string]error)}
+	buildFailed := func(name string, err error, message string) {
+		buildUis[name].Error(message)
+		errors.Lock()
+		errors.m[name] = err
+		errors.Unlock()
+		c.event(&packer.Event{
+			Type:     packer.EventBuildEnd,
+			Severity: packer.SeverityError,
+			Build:    name,
+			Message:  message,
+			Error:    err.Error(),
+		})
+	}
 
-			name := b.Name()
-			log.Printf("Starting build run: %s", name)
-			ui := buildUis[name]
-			runArtifacts, err := b.Run(ui)
-
-			if err != nil {
-				ui.Error(fmt.Sprintf("Build '%s' errored: %s", name, err))
-				errors[name] = err
-			} else {
-				ui.Say(fmt.Sprintf("Build '%s' finished.", name))
-				artifacts.Lock()
-				artifacts.m[name] = runArtifacts
-				artifacts.Unlock()
+	// Handle interrupts by cancelling all the builds
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		sig := <-sigCh
+		interruptWg.Add(1)
+		defer interruptWg.Done()
+		interrupted = true
+
+		log.Printf("Stopping builds after receiving %s", sig)
+		cancelCtx()
+		log.Printf("Builds cancelled")
+	}()
+
+	// runBuild runs a build whose dependencies have finished
+	runBuild := func(b packer.Build) {
+		name := b.Name()
+		ui := buildUis[name]
+
+		// Hand the artifacts of the builds this build depends on to it
+		if deps := tpl.Builders[name].DependsOn; len(deps) > 0 {
+			depArtifacts := make(map[string]packer.Artifact, len(deps))
+			for _, dep := range deps {
+				artifacts.RLock()
+				runArtifacts := artifacts.m[dep]
+				artifacts.RUnlock()
+				if len(runArtifacts) == 0 || runArtifacts[0] == nil {
+					err := fmt.Errorf("build '%s' that it depends on produced no artifact", dep)
+					buildFailed(name, err, fmt.Sprintf("Build '%s' skipped: %s", name, err))
+					return
+				}
+				depArtifacts[dep] = runArtifacts[0]
 			}
-		}(b)
 
-		if cfgDebug {
-			log.Printf("Debug enabled, so waiting for build to finish: %s", b.Name())
-			wg.Wait()
+			b.SetArtifacts(depArtifacts)
+			if err := prepare(b); err != nil {
+				buildFailed(name, err, fmt.Sprintf("Build '%s' errored: %s", name, err))
+				return
+			}
 		}
 
-		if !cfgParallel {
-			log.Printf("Parallelization disabled, waiting for build to finish: %s", b.Name())
-			wg.Wait()
+		log.Printf("Starting build run: %s", name)
+		runArtifacts, err := packer.RunBuild(ctx, b, ui)
+
+		if _, ok := err.(*packer.TimeoutError); ok {
+			buildFailed(name, err, fmt.Sprintf("Build '%s' timed out: %s", name, err))
+		} else if err != nil {
+			buildFailed(name, err, fmt.Sprintf("Build '%s' errored: %s", name, err))
+		} else {
+			message := fmt.Sprintf("Build '%s' finished.", name)
+			ui.Say(message)
+			artifacts.Lock()
+			artifacts.m[name] = runArtifacts
+			artifacts.Unlock()
+
+			for _, artifact := range runArtifacts {
+				if artifact != nil {
+					c.event(&packer.Event{
+						Type:     packer.EventArtifact,
+						Build:    name,
+						Artifact: packer.NewArtifactInfo(artifact),
+					})
+				}
+			}
+			c.event(&packer.Event{
+				Type:    packer.EventBuildEnd,
+				Build:   name,
+				Message: message,
+			})
 		}
+	}
 
-		if interrupted {
-			log.Println("Interrupted, not going to start any more builds.")
-			break
+	// The number of builds that run at the same time
+	workers := cfgParallelBuilds
+	if cfgDebug {
+		log.Printf("Debug enabled, so running one build at a time")
+		workers = 1
+	} else if !cfgParallel {
+		log.Printf("Parallelization disabled, running one build at a time")
+		workers = 1
+	}
+	if workers == 0 || workers > len(builds) {
+		workers = len(builds)
+	}
+
+	// Builds are queued once the builds they depend on have finished, and
+	// the queue has room for all of them so that queueing never blocks.
+	pool := &buildPool{
+		queue:      make(chan packer.Build, len(builds)),
+		builds:     make(map[string]packer.Build, len(builds)),
+		dependents: make(map[string][]string),
+		waiting:    make(map[string]int),
+		total:      len(builds),
+	}
+	for _, b := range builds {
+		pool.builds[b.Name()] = b
+	}
+	for _, b := range builds {
+		for _, dep := range tpl.Builders[b.Name()].DependsOn {
+			// Builds that failed to initialize have nothing to wait for
+			if _, ok := pool.builds[dep]; ok {
+				pool.dependents[dep] = append(pool.dependents[dep], b.Name())
+				pool.waiting[b.Name()]++
+			}
+		}
+	}
+	for _, b := range builds {
+		if pool.waiting[b.Name()] == 0 {
+			pool.enqueue(b)
 		}
 	}
+	if pool.total == 0 {
+		close(pool.queue)
+	}
+
+	// Report the progress of the builds whenever it changes. Only say it
+	// when builds have to wait for each other.
+	pool.status = func(queued, running, finished int) {
+		c.Ui.Machine("build-pool",
+			strconv.Itoa(queued), strconv.Itoa(running), strconv.Itoa(finished))
+		if workers < len(builds) {
+			c.Ui.Say(fmt.Sprintf("==> Builds: %d queued, %d running, %d finished",
+				queued, running, finished))
+		}
+	}
+
+	log.Printf("Running builds on %d workers", workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range pool.queue {
+				if ctx.Err() != nil {
+					log.Printf("Interrupted, not going to start build: %s", b.Name())
+					pool.finish(b, false)
+					continue
+				}
+
+				pool.start()
+				runBuild(b)
+				pool.finish(b, true)
+			}
+		}()
+	}
 
 	// Wait for both the builds to complete and the interrupt handler,
 	// if it is interrupted.
@@ -216,14 +359,17 @@ func (c *BuildCommand) Run(args []string) int {
 
 	if interrupted {
 		c.Ui.Say("Cleanly cancelled builds after being interrupted.")
+		if pool.skipped > 0 {
+			c.Ui.Say(fmt.Sprintf("%d queued builds were not started.", pool.skipped))
+		}
 		return 1
 	}
 
-	if len(errors) > 0 {
-		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors)), 10))
+	if len(errors.m) > 0 {
+		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors.m)), 10))
 
 		c.Ui.Error("\n==> Some builds didn't complete successfully and had errors:")
-		for name, err := range errors {
+		for name, err := range errors.m {
 			// Create a UI for the machine readable stuff to be targeted
 			ui := &packer.TargetedUI{
 				Target: name,
@@ -232,6 +378,12 @@ func (c *BuildCommand) Run(args []string) int {
 
 			ui.Machine("error", err.Error())
 
+			if terr, ok := err.(*packer.TimeoutError); ok {
+				ui.Machine("timeout", terr.Timeout.String(), terr.Error())
+				c.Ui.Error(fmt.Sprintf("--> %s: timed out: %s", name, err))
+				continue
+			}
+
 			c.Ui.Error(fmt.Sprintf("--> %s: %s", name, err))
 		}
 	}
@@ -284,7 +436,7 @@ func (c *BuildCommand) Run(args []string) int {
 		c.Ui.Say("\n==> Builds finished but no artifacts were created.")
 	}
 
-	if len(errors) > 0 {
+	if len(errors.m) > 0 {
 		// If any errors occurred, exit with a non-zero exit status
 		return 1
 	}
@@ -292,13 +444,132 @@ func (c *BuildCommand) Run(args []string) int {
 	return 0
 }
 
+// buildPool keeps track of the builds that run on the workers of
+// BuildCommand. Builds are queued once all the builds they depend on have
+// finished.
+type buildPool struct {
+	queue      chan packer.Build
+	builds     map[string]packer.Build
+	dependents map[string][]string
+	total      int
+
+	// status is called with the counts of builds whenever they change.
+	status func(queued, running, finished int)
+
+	l                         sync.Mutex
+	waiting                   map[string]int
+	queued, running, finished int
+	skipped                   int
+}
+
+// enqueue queues a build. The lock must be held unless no worker is
+// running yet.
+func (p *buildPool) enqueue(b packer.Build) {
+	p.queue <- b
+	p.queued++
+}
+
+// start records that a worker started running a queued build.
+func (p *buildPool) start() {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.queued--
+	p.running++
+	p.status(p.queued, p.running, p.finished)
+}
+
+// finish records that a build finished, or was skipped without running, and
+// queues the builds that no longer wait for any other build. The queue is
+// closed once every build has finished.
+func (p *buildPool) finish(b packer.Build, ran bool) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if ran {
+		p.running--
+	} else {
+		p.queued--
+		p.skipped++
+	}
+	p.finished++
+
+	for _, name := range p.dependents[b.Name()] {
+		p.waiting[name]--
+		if p.waiting[name] == 0 {
+			p.enqueue(p.builds[name])
+		}
+	}
+
+	if ran {
+		p.status(p.queued, p.running, p.finished)
+	}
+	if p.finished == p.total {
+		close(p.queue)
+	}
+}
+
+// event outputs an event when the UI is an EventUi.
+func (c *BuildCommand) event(e *packer.Event) {
+	if eu, ok := c.Ui.(packer.EventUi); ok {
+		eu.Event(e)
+	}
+}
+
+// sortBuildNames orders the names of the builds so that every build comes
+// after the builds it depends on, and otherwise keeps their order. The builds
+// that are depended on must be among the names.
+func sortBuildNames(tpl *template.Template, names []string) ([]string, error) {
+	selected := make(map[string]bool, len(names))
+	for _, n := range names {
+		selected[n] = true
+	}
+
+	result := make([]string, 0, len(names))
+	added := make(map[string]bool, len(names))
+	var add func(string) error
+	add = func(n string) error {
+		if added[n] {
+			return nil
+		}
+		added[n] = true
+
+		if b, ok := tpl.Builders[n]; ok {
+			for _, dep := range b.DependsOn {
+				if !selected[dep] {
+					return fmt.Errorf(
+						"Build '%s' depends on build '%s', which isn't selected to run", n, dep)
+				}
+				if err := add(dep); err != nil {
+					return err
+				}
+			}
+		}
+
+		result = append(result, n)
+		return nil
+	}
+
+	for _, n := range names {
+		if err := add(n); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
 func (*BuildCommand) Help() string {
 	helpText := `
 Usage: packer build [options] TEMPLATE
 
   Will execute multiple builds in parallel as defined in the template.
+  Builds that depend on other builds start once those have finished.
   The various artifacts created by the template will be outputted.
 
+  TEMPLATE is a JSON file, an HCL file ending in .pkr.hcl, or a
+  directory of .pkr.hcl files.
+
 Options:
 
   -color=false                  Disable color output. (Default: color)
@@ -307,8 +578,11 @@ Options:
   -only=foo,bar,baz             Build only the specified builds.
   -force                        Force a build to continue if artifacts exist, deletes existing artifacts.
   -machine-readable             Produce machine-readable output.
+  -output=json                  Output a JSON event per line.
   -on-error=[cleanup|abort|ask] If the build fails do: clean up (default), abort, or ask.
   -parallel=false               Disable parallelization. (Default: parallel)
+  -parallel-builds=N            Run at most N builds at the same time. (Default: no limit)
+  -resume                       Keep the progress of failed builds and resume them from the last completed step.
   -timestamp-ui                 Enable prefixing of each ui output with an RFC3339 timestamp.
   -var 'key=value'              Variable for templates, can be used multiple times.
   -var-file=path                JSON file containing user variables.
@@ -334,7 +608,10 @@ func (*BuildCommand) AutocompleteFlags() complete.Flags {
 		"-force":            complete.PredictNothing,
 		"-machine-readable": complete.PredictNothing,
 		"-on-error":         complete.PredictNothing,
+		"-output":           complete.PredictSet("json"),
 		"-parallel":         complete.PredictNothing,
+		"-parallel-builds":  complete.PredictNothing,
+		"-resume":           complete.PredictNothing,
 		"-timestamp-ui":     complete.PredictNothing,
 		"-var":              complete.PredictNothing,
 		"-var-file":         complete.PredictNothing,
@@ -2,13 +2,16 @@ package command
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/packer/builder/file"
 	"github.com/hashicorp/packer/packer"
 	shell_local "github.com/hashicorp/packer/post-processor/shell-local"
+	"github.com/hashicorp/packer/template"
 )
 
 func TestBuildOnlyFileCommaFlags(t *testing.T) {
@@ -127,6 +130,118 @@ func TestBuildEverything(t *testing.T) {
 	}
 }
 
+func TestBuildDependsOn(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-parallel=false",
+		filepath.Join(testFixture("build-depends-on"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	// vanilla is a copy of the file that chocolate built
+	contents, err := ioutil.ReadFile("vanilla.txt")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(contents) != "chocolate" {
+		t.Fatalf("bad: %q", contents)
+	}
+}
+
+func TestBuildParallelBuilds(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-parallel-builds=2",
+		filepath.Join(testFixture("build-depends-on"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	contents, err := ioutil.ReadFile("vanilla.txt")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(contents) != "chocolate" {
+		t.Fatalf("bad: %q", contents)
+	}
+}
+
+func TestBuildParallelBuilds_negative(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-parallel-builds=-1",
+		filepath.Join(testFixture("build-only"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+}
+
+func TestBuildDependsOn_notSelected(t *testing.T) {
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-only=vanilla",
+		filepath.Join(testFixture("build-depends-on"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if fileExists("vanilla.txt") {
+		t.Fatal("vanilla.txt should not exist")
+	}
+}
+
+func TestSortBuildNames(t *testing.T) {
+	tpl := &template.Template{
+		Builders: map[string]*template.Builder{
+			"app":  {Name: "app", DependsOn: []string{"base", "db"}},
+			"base": {Name: "base"},
+			"db":   {Name: "db", DependsOn: []string{"base"}},
+			"web":  {Name: "web"},
+		},
+	}
+
+	names, err := sortBuildNames(tpl, []string{"app", "web", "db", "base"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"base", "db", "app", "web"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %#v", names)
+	}
+
+	if _, err := sortBuildNames(tpl, []string{"db"}); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuildExceptFileCommaFlags(t *testing.T) {
 	c := &BuildCommand{
 		Meta: testMetaFile(t),
@@ -0,0 +1,263 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+
+	"github.com/posener/complete"
+)
+
+// CacheCommand lists, prunes and verifies the files that builds downloaded
+// into the cache directory.
+type CacheCommand struct {
+	Meta
+}
+
+func (c *CacheCommand) Run(args []string) int {
+	if len(args) == 0 {
+		c.Ui.Say(c.Help())
+		return 1
+	}
+
+	cache, err := packer.OpenCache()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error opening cache: %s", err))
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		return c.list(cache, args[1:])
+	case "prune":
+		return c.prune(cache, args[1:])
+	case "verify":
+		return c.verify(cache, args[1:])
+	default:
+		c.Ui.Error(fmt.Sprintf("Unknown cache subcommand: %s", args[0]))
+		c.Ui.Say(c.Help())
+		return 1
+	}
+}
+
+func (c *CacheCommand) list(cache *packer.Cache, args []string) int {
+	flags := c.Meta.FlagSet("cache list", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading cache: %s", err))
+		return 1
+	}
+
+	if len(entries) == 0 {
+		c.Ui.Say(fmt.Sprintf("The cache %s is empty.", cache.Dir))
+		return 0
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+		c.Ui.Machine("cache-entry", e.Name, e.Source,
+			strconv.FormatInt(e.Size, 10),
+			strconv.FormatInt(e.LastUsed.Unix(), 10),
+			strings.Join(e.Templates, ","))
+
+		c.Ui.Say(e.Name)
+		if e.Untracked {
+			c.Ui.Say("  Source:    <unknown, not in the cache index>")
+		} else {
+			c.Ui.Say(fmt.Sprintf("  Source:    %s", e.Source))
+		}
+		if e.Checksum != "" {
+			checksum := e.Checksum
+			if e.ChecksumType != "" {
+				checksum = e.ChecksumType + ":" + checksum
+			}
+			c.Ui.Say(fmt.Sprintf("  Checksum:  %s", checksum))
+		}
+		c.Ui.Say(fmt.Sprintf("  Size:      %s", packer.FormatSize(e.Size)))
+		c.Ui.Say(fmt.Sprintf("  Last used: %s", e.LastUsed.Local().Format(time.RFC1123)))
+		for _, t := range e.Templates {
+			c.Ui.Say(fmt.Sprintf("  Used by:   %s", t))
+		}
+	}
+
+	c.Ui.Say(fmt.Sprintf("\n%d files, %s in %s", len(entries), packer.FormatSize(total), cache.Dir))
+	return 0
+}
+
+func (c *CacheCommand) prune(cache *packer.Cache, args []string) int {
+	var cfgOlderThan, cfgMaxSize string
+	flags := c.Meta.FlagSet("cache prune", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&cfgOlderThan, "older-than", "", "")
+	flags.StringVar(&cfgMaxSize, "max-size", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if cfgOlderThan == "" && cfgMaxSize == "" {
+		c.Ui.Error("Either -older-than or -max-size must be given.")
+		return 1
+	}
+
+	var olderThan time.Duration
+	if cfgOlderThan != "" {
+		var err error
+		olderThan, err = parseAge(cfgOlderThan)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid -older-than: %s", err))
+			return 1
+		}
+	}
+
+	var maxSize int64
+	if cfgMaxSize != "" {
+		var err error
+		maxSize, err = packer.ParseSize(cfgMaxSize)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Invalid -max-size: %s", err))
+			return 1
+		}
+	}
+
+	removed, err := cache.Prune(olderThan, maxSize)
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+		c.Ui.Machine("cache-removed", e.Name, strconv.FormatInt(e.Size, 10))
+		c.Ui.Say(fmt.Sprintf("Removed %s (%s)", e.Name, packer.FormatSize(e.Size)))
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error pruning cache: %s", err))
+		return 1
+	}
+
+	c.Ui.Say(fmt.Sprintf("Removed %d files, freeing %s.", len(removed), packer.FormatSize(freed)))
+	return 0
+}
+
+func (c *CacheCommand) verify(cache *packer.Cache, args []string) int {
+	flags := c.Meta.FlagSet("cache verify", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading cache: %s", err))
+		return 1
+	}
+
+	// Only verify the files that were asked for, if any
+	if names := flags.Args(); len(names) > 0 {
+		byName := make(map[string]*packer.CacheEntry, len(entries))
+		for _, e := range entries {
+			byName[e.Name] = e
+		}
+
+		entries = entries[:0]
+		for _, name := range names {
+			e, ok := byName[name]
+			if !ok {
+				c.Ui.Error(fmt.Sprintf("%s is not in the cache", name))
+				return 1
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	failed := 0
+	for _, e := range entries {
+		checksummed, err := cache.Verify(e)
+		switch {
+		case err != nil:
+			failed++
+			c.Ui.Machine("cache-verify", e.Name, "failed", err.Error())
+			c.Ui.Error(fmt.Sprintf("%s: FAILED, %s", e.Name, err))
+		case !checksummed:
+			c.Ui.Machine("cache-verify", e.Name, "unchecked")
+			c.Ui.Say(fmt.Sprintf("%s: no checksum to verify against", e.Name))
+		default:
+			c.Ui.Machine("cache-verify", e.Name, "ok")
+			c.Ui.Say(fmt.Sprintf("%s: OK", e.Name))
+		}
+	}
+
+	if failed > 0 {
+		c.Ui.Error(fmt.Sprintf("%d of %d files failed to verify.", failed, len(entries)))
+		return 1
+	}
+	return 0
+}
+
+// parseAge parses a duration, which may also be given in days, such as "30d".
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("%q is not a number of days", s)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q is negative", s)
+	}
+	return d, nil
+}
+
+func (*CacheCommand) Help() string {
+	helpText := `
+Usage: packer cache <subcommand> [options]
+
+  Manages the files that builds downloaded into the cache directory, such as
+  ISOs. The cache directory is packer_cache, or PACKER_CACHE_DIR if it is set.
+
+Subcommands:
+
+  list                    Lists the files in the cache, with the URL they were
+                          downloaded from, their checksum, size, when they were
+                          last used and the templates that used them.
+  prune [options]         Removes files from the cache. Files that are being
+                          downloaded are kept.
+  verify [FILE...]        Checks the files in the cache against the checksum
+                          they were downloaded with.
+
+Options for prune:
+
+  -older-than=DURATION    Remove files that weren't used in DURATION, such as
+                          72h or 30d.
+  -max-size=SIZE          Remove the least recently used files until the cache
+                          is no larger than SIZE, such as 500MB or 20GB.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*CacheCommand) Synopsis() string {
+	return "manage the download cache"
+}
+
+func (*CacheCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("list", "prune", "verify")
+}
+
+func (*CacheCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-older-than": complete.PredictNothing,
+		"-max-size":   complete.PredictNothing,
+	}
+}
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output time.Duration
+		Err    bool
+	}{
+		{"72h", 72 * time.Hour, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"-1h", 0, true},
+		{"d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tc := range cases {
+		d, err := parseAge(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", tc.Input, err)
+		}
+		if d != tc.Output {
+			t.Fatalf("%s: bad: %s", tc.Input, d)
+		}
+	}
+}
@@ -20,8 +20,10 @@ type FixCommand struct {
 
 func (c *FixCommand) Run(args []string) int {
 	var flagValidate bool
+	var flagTo string
 	flags := c.Meta.FlagSet("fix", FlagSetNone)
 	flags.BoolVar(&flagValidate, "validate", true, "")
+	flags.StringVar(&flagTo, "to", "json", "")
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -33,6 +35,11 @@ func (c *FixCommand) Run(args []string) int {
 		return 1
 	}
 
+	if flagTo != "json" && flagTo != "hcl2" {
+		c.Ui.Error(fmt.Sprintf("-to must be json or hcl2, not %q", flagTo))
+		return 1
+	}
+
 	// Read the file for decoding
 	tplF, err := os.Open(args[0])
 	if err != nil {
@@ -84,11 +91,35 @@ func (c *FixCommand) Run(args []string) int {
 	result := indented.String()
 	result = strings.Replace(result, `\u003c`, "<", -1)
 	result = strings.Replace(result, `\u003e`, ">", -1)
+
+	if flagTo == "hcl2" {
+		// Convert the fixed template rather than the original one.
+		tpl, err := template.Parse(strings.NewReader(result))
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error parsing fixed template: %s", err))
+			return 1
+		}
+
+		out, err := fix.HCL2(tpl)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error converting to HCL: %s", err))
+			return 1
+		}
+		result = string(out)
+	}
 	c.Ui.Say(result)
 
-	if flagValidate {
+	// HCL output is always parsed, since the conversion can write HCL that
+	// doesn't parse, such as for builder types that aren't identifiers.
+	if flagValidate || flagTo == "hcl2" {
 		// Attempt to parse and validate the template
-		tpl, err := template.Parse(strings.NewReader(result))
+		var tpl *template.Template
+		var err error
+		if flagTo == "hcl2" {
+			tpl, err = template.ParseHCL(strings.NewReader(result), "<stdout>")
+		} else {
+			tpl, err = template.Parse(strings.NewReader(result))
+		}
 		if err != nil {
 			c.Ui.Error(fmt.Sprintf(
 				"Error! Fixed template fails to parse: %s\n\n"+
@@ -97,13 +128,15 @@ func (c *FixCommand) Run(args []string) int {
 				err))
 			return 1
 		}
-		if err := tpl.Validate(); err != nil {
-			c.Ui.Error(fmt.Sprintf(
-				"Error! Fixed template failed to validate: %s\n\n"+
-					"This is usually caused by an error in the input template.\n"+
-					"Please fix the error and try again.",
-				err))
-			return 1
+		if flagValidate {
+			if err := tpl.Validate(); err != nil {
+				c.Ui.Error(fmt.Sprintf(
+					"Error! Fixed template failed to validate: %s\n\n"+
+						"This is usually caused by an error in the input template.\n"+
+						"Please fix the error and try again.",
+					err))
+				return 1
+			}
 		}
 	}
 
@@ -162,6 +195,12 @@ Fixes that are run:
 
 Options:
 
+  -to=json            The format to output the fixed template in: json
+                      (default) or hcl2. With hcl2, the template is converted
+                      to an HCL template that can be saved as a .pkr.hcl
+                      file. Anything that can't be converted is left as a
+                      comment starting with "FIXME(packer fix):". The
+                      command fails if the HCL it writes doesn't parse.
   -validate=true      If true (default), validates the fixed template.
 `
 
@@ -178,6 +217,7 @@ func (c *FixCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *FixCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
+		"-to":       complete.PredictSet("json", "hcl2"),
 		"-validate": complete.PredictNothing,
 	}
 }
@@ -61,3 +61,20 @@ func TestFix_invalidTemplateDisableValidation(t *testing.T) {
 		fatalCommand(t, c.Meta)
 	}
 }
+
+func TestFix_hcl2InvalidOutput(t *testing.T) {
+	c := &FixCommand{
+		Meta: testMeta(t),
+	}
+
+	// The type of a builder can't be renamed, so one that isn't an
+	// identifier makes HCL that doesn't parse.
+	args := []string{
+		"-to=hcl2",
+		"-validate=false",
+		filepath.Join(testFixture("fix-hcl2-invalid"), "template.json"),
+	}
+	if code := c.Run(args); code != 1 {
+		fatalCommand(t, c.Meta)
+	}
+}
@@ -1,11 +1,17 @@
 package command
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
+	"github.com/mitchellh/mapstructure"
 
 	"github.com/posener/complete"
 )
@@ -15,8 +21,10 @@ type InspectCommand struct {
 }
 
 func (c *InspectCommand) Run(args []string) int {
-	flags := c.Meta.FlagSet("inspect", FlagSetNone)
+	var cfgBootCommand bool
+	flags := c.Meta.FlagSet("inspect", FlagSetVars)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.BoolVar(&cfgBootCommand, "boot-command", false, "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -28,12 +36,16 @@ func (c *InspectCommand) Run(args []string) int {
 	}
 
 	// Parse the template
-	tpl, err := template.ParseFile(args[0])
+	tpl, err := template.ParseFileWithVars(args[0], c.Meta.flagVars)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
 		return 1
 	}
 
+	if cfgBootCommand {
+		return c.inspectBootCommands(tpl)
+	}
+
 	// Convenience...
 	ui := c.Ui
 
@@ -61,8 +73,9 @@ func (c *InspectCommand) Run(args []string) int {
 					ui.Say("Required variables:\n")
 				}
 
-				ui.Machine("template-variable", k, v.Default, "1")
+				ui.Machine("template-variable", k, v.Default, "1", v.Description)
 				ui.Say("  " + k)
+				sayVariableDescription(ui, v)
 			}
 		}
 
@@ -96,8 +109,9 @@ func (c *InspectCommand) Run(args []string) int {
 			padding := strings.Repeat(" ", max-len(k))
 			output := fmt.Sprintf("  %s%s = %s", k, padding, v.Default)
 
-			ui.Machine("template-variable", k, v.Default, "0")
+			ui.Machine("template-variable", k, v.Default, "0", v.Description)
 			ui.Say(output)
+			sayVariableDescription(ui, v)
 		}
 	}
 
@@ -153,17 +167,155 @@ func (c *InspectCommand) Run(args []string) int {
 	return 0
 }
 
+// inspectBootCommands shows the timeline of the boot command of each builder
+// that has one, as the VM would get it.
+func (c *InspectCommand) inspectBootCommands(tpl *template.Template) int {
+	ui := c.Ui
+
+	// The user variables, as the builders get them
+	core, err := c.Meta.Core(tpl)
+	if err != nil {
+		ui.Error(err.Error())
+		return 1
+	}
+
+	ui.Say("Boot commands:\n")
+
+	keys := make([]string, 0, len(tpl.Builders))
+	for k, v := range tpl.Builders {
+		if _, ok := v.Config["boot_command"]; ok {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		ui.Say("  <No boot commands>")
+		return 0
+	}
+	sort.Strings(keys)
+
+	ret := 0
+	for _, k := range keys {
+		ui.Say(fmt.Sprintf("  %s:", k))
+		timeline, err := bootCommandTimeline(core.Context(), tpl.Builders[k])
+		if err != nil {
+			ui.Error(fmt.Sprintf("  Error: %s", err))
+			ret = 1
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(timeline, "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			ui.Machine("template-boot-command", k, line)
+			ui.Say("  " + line)
+		}
+		ui.Say("")
+	}
+
+	ui.Say("Note: The boot commands use placeholders for the HTTPIP, HTTPPort\n" +
+		"and SSHPublicKey of builds.")
+	return ret
+}
+
+// bootCommandTimeline does a dry run of the boot command of a builder, with
+// the kind of driver the builder types with, and returns its timeline. The
+// config of the builder is interpolated with ctx, as builds do.
+func bootCommandTimeline(coreCtx *interpolate.Context, b *template.Builder) (string, error) {
+	ctx := *coreCtx
+	ctx.BuildName = b.Name
+	ctx.BuildType = b.Type
+
+	raw, err := interpolate.RenderMap(b.Config, &ctx, &interpolate.RenderFilter{
+		Exclude: []string{"boot_command", "boot_command_macros"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var bc struct {
+		bootcommand.VNCConfig `mapstructure:",squash"`
+		VMName                string `mapstructure:"vm_name"`
+	}
+	if err := mapstructure.WeakDecode(raw, &bc); err != nil {
+		return "", err
+	}
+	if bc.VMName == "" {
+		bc.VMName = "packer-" + b.Name
+	}
+
+	// The drivers the builders' steps type with
+	var d bootcommand.BCDriver
+	var errs []error
+	switch b.Type {
+	case "qemu", "vmware-iso", "vmware-vmx":
+		errs = bc.VNCConfig.Prepare(&ctx)
+		d = bootcommand.NewVNCDriver(nil, bc.BootKeyInterval)
+	case "virtualbox-iso", "virtualbox-ovf":
+		errs = bc.BootConfig.Prepare(&ctx)
+		d = bootcommand.NewPCXTDriver(nil, 25, bc.BootGroupInterval)
+	case "hyperv-iso", "hyperv-vmcx", "parallels-iso", "parallels-pvm":
+		errs = bc.BootConfig.Prepare(&ctx)
+		d = bootcommand.NewPCXTDriver(nil, -1, bc.BootGroupInterval)
+	default:
+		return "", fmt.Errorf("the %s builder doesn't type boot commands", b.Type)
+	}
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+
+	ctx.Data = map[string]string{
+		"HTTPIP":       "10.0.2.2",
+		"HTTPPort":     "8000",
+		"Name":         bc.VMName,
+		"SSHPublicKey": "ssh-rsa AAAA... packer",
+	}
+	command, err := interpolate.Render(bc.FlatBootCommand(), &ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = bootcommand.DryRun(context.Background(), command, d, &buf)
+	return buf.String(), err
+}
+
+// sayVariableDescription shows the description and the rules of a typed
+// variable below its name.
+func sayVariableDescription(ui packer.Ui, v *template.Variable) {
+	if v.Description != "" {
+		ui.Say("      " + v.Description)
+	}
+
+	var rules []string
+	if v.Type != "" {
+		rules = append(rules, "type: "+v.Type)
+	}
+	if len(v.AllowedValues) > 0 {
+		rules = append(rules, "allowed values: "+strings.Join(v.AllowedValues, ", "))
+	}
+	if v.Pattern != "" {
+		rules = append(rules, "pattern: "+v.Pattern)
+	}
+	if len(rules) > 0 {
+		ui.Say("      (" + strings.Join(rules, "; ") + ")")
+	}
+}
+
 func (*InspectCommand) Help() string {
 	helpText := `
-Usage: packer inspect TEMPLATE
+Usage: packer inspect [options] TEMPLATE
 
   Inspects a template, parsing and outputting the components a template
   defines. This does not validate the contents of a template (other than
-  basic syntax by necessity).
+  basic syntax by necessity). TEMPLATE is a JSON file, an HCL file ending
+  in .pkr.hcl, or a directory of .pkr.hcl files.
 
 Options:
 
+  -boot-command      Show the timeline of the keys, the waits and the key
+                     codes the VMs get from the boot commands of the builders
   -machine-readable  Machine-readable output
+  -var 'key=value'   Variable for templates, can be used multiple times.
+  -var-file=path     JSON file containing user variables.
 `
 
 	return strings.TrimSpace(helpText)
@@ -179,6 +331,9 @@ func (c *InspectCommand) AutocompleteArgs() complete.Predictor {
 
 func (c *InspectCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
+		"-boot-command":     complete.PredictNothing,
 		"-machine-readable": complete.PredictNothing,
+		"-var":              complete.PredictNothing,
+		"-var-file":         complete.PredictNothing,
 	}
 }
@@ -34,7 +34,7 @@ func (c *ValidateCommand) Run(args []string) int {
 	}
 
 	// Parse the template
-	tpl, err := template.ParseFile(args[0])
+	tpl, err := template.ParseFileWithVars(args[0], c.Meta.flagVars)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
 		return 1
@@ -71,8 +71,17 @@ func (c *ValidateCommand) Run(args []string) int {
 		builds = append(builds, b)
 	}
 
-	// Check the configuration of all builds
+	// Check the configuration of all builds. The builds that depend on
+	// other builds get placeholder artifacts, since nothing is built here.
 	for _, b := range builds {
+		if deps := tpl.Builders[b.Name()].DependsOn; len(deps) > 0 {
+			depArtifacts := make(map[string]packer.Artifact, len(deps))
+			for _, dep := range deps {
+				depArtifacts[dep] = &placeholderArtifact{build: dep}
+			}
+			b.SetArtifacts(depArtifacts)
+		}
+
 		log.Printf("Preparing build: %s", b.Name())
 		warns, err := b.Prepare()
 		if len(warns) > 0 {
@@ -172,6 +181,8 @@ Usage: packer validate [options] TEMPLATE
 
   Checks the template is valid by parsing the template and also
   checking the configuration with the various builders, provisioners, etc.
+  TEMPLATE is a JSON file, an HCL file ending in .pkr.hcl, or a
+  directory of .pkr.hcl files.
 
   If it is not valid, the errors will be shown and the command will exit
   with a non-zero exit status. If it is valid, it will exit with a zero
@@ -206,3 +217,37 @@ func (*ValidateCommand) AutocompleteFlags() complete.Flags {
 		"-var-file":    complete.PredictNothing,
 	}
 }
+
+// placeholderArtifact stands in for the artifact of a build that another
+// build depends on, so that the dependent build can be validated.
+type placeholderArtifact struct {
+	build string
+}
+
+func (a *placeholderArtifact) placeholder(field string) string {
+	return fmt.Sprintf("%s-%s-placeholder", a.build, field)
+}
+
+func (a *placeholderArtifact) BuilderId() string {
+	return a.placeholder("builder_id")
+}
+
+func (a *placeholderArtifact) Files() []string {
+	return []string{a.placeholder("files")}
+}
+
+func (a *placeholderArtifact) Id() string {
+	return a.placeholder("id")
+}
+
+func (a *placeholderArtifact) String() string {
+	return a.placeholder("string")
+}
+
+func (a *placeholderArtifact) State(name string) interface{} {
+	return a.placeholder("state." + name)
+}
+
+func (a *placeholderArtifact) Destroy() error {
+	return nil
+}
@@ -42,3 +42,18 @@ func TestValidateCommandBadVersion(t *testing.T) {
 	}
 	t.Log(stdout)
 }
+
+func TestValidateCommandDependsOn(t *testing.T) {
+	c := &ValidateCommand{
+		Meta: testMetaFile(t),
+	}
+	args := []string{
+		filepath.Join(testFixture("build-depends-on"), "template.json"),
+	}
+
+	// The artifact of a build that another build depends on isn't built,
+	// so validating the dependent build must not need it
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+}
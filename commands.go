@@ -23,6 +23,12 @@ func init() {
 			}, nil
 		},
 
+		"cache": func() (cli.Command, error) {
+			return &command.CacheCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"fix": func() (cli.Command, error) {
 			return &command.FixCommand{
 				Meta: *CommandMeta,
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"io/ioutil"
 	"math"
@@ -61,18 +62,26 @@ var g = &grammar{
 							alternatives: []interface{}{
 								&ruleRefExpr{
 									pos:  position{line: 10, col: 13, offset: 87},
+									name: "WaitScreen",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 10, col: 26, offset: 100},
 									name: "Wait",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 10, col: 20, offset: 94},
+									pos:  position{line: 10, col: 33, offset: 107},
+									name: "Repeat",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 10, col: 42, offset: 116},
 									name: "CharToggle",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 10, col: 33, offset: 107},
+									pos:  position{line: 10, col: 55, offset: 129},
 									name: "Special",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 10, col: 43, offset: 117},
+									pos:  position{line: 10, col: 65, offset: 139},
 									name: "Literal",
 								},
 							},
@@ -83,36 +92,36 @@ var g = &grammar{
 		},
 		{
 			name: "Wait",
-			pos:  position{line: 14, col: 1, offset: 150},
+			pos:  position{line: 14, col: 1, offset: 172},
 			expr: &actionExpr{
-				pos: position{line: 14, col: 8, offset: 157},
+				pos: position{line: 14, col: 8, offset: 179},
 				run: (*parser).callonWait1,
 				expr: &seqExpr{
-					pos: position{line: 14, col: 8, offset: 157},
+					pos: position{line: 14, col: 8, offset: 179},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 14, col: 8, offset: 157},
+							pos:  position{line: 14, col: 8, offset: 179},
 							name: "ExprStart",
 						},
 						&litMatcher{
-							pos:        position{line: 14, col: 18, offset: 167},
+							pos:        position{line: 14, col: 18, offset: 189},
 							val:        "wait",
 							ignoreCase: false,
 						},
 						&labeledExpr{
-							pos:   position{line: 14, col: 25, offset: 174},
+							pos:   position{line: 14, col: 25, offset: 196},
 							label: "duration",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 14, col: 34, offset: 183},
+								pos: position{line: 14, col: 34, offset: 205},
 								expr: &choiceExpr{
-									pos: position{line: 14, col: 36, offset: 185},
+									pos: position{line: 14, col: 36, offset: 207},
 									alternatives: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 14, col: 36, offset: 185},
+											pos:  position{line: 14, col: 36, offset: 207},
 											name: "Duration",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 14, col: 47, offset: 196},
+											pos:  position{line: 14, col: 47, offset: 218},
 											name: "Integer",
 										},
 									},
@@ -120,7 +129,160 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 14, col: 58, offset: 207},
+							pos:  position{line: 14, col: 58, offset: 229},
+							name: "ExprEnd",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "WaitScreen",
+			pos:  position{line: 27, col: 1, offset: 475},
+			expr: &actionExpr{
+				pos: position{line: 27, col: 14, offset: 488},
+				run: (*parser).callonWaitScreen1,
+				expr: &seqExpr{
+					pos: position{line: 27, col: 14, offset: 488},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 27, col: 14, offset: 488},
+							name: "ExprStart",
+						},
+						&litMatcher{
+							pos:        position{line: 27, col: 24, offset: 498},
+							val:        "waitscreen",
+							ignoreCase: true,
+						},
+						&ruleRefExpr{
+							pos:  position{line: 27, col: 38, offset: 512},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 27, col: 40, offset: 514},
+							label: "path",
+							expr: &ruleRefExpr{
+								pos:  position{line: 27, col: 45, offset: 519},
+								name: "QuotedString",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 27, col: 58, offset: 532},
+							label: "timeout",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 27, col: 66, offset: 540},
+								expr: &seqExpr{
+									pos: position{line: 27, col: 68, offset: 542},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 27, col: 68, offset: 542},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 27, col: 70, offset: 544},
+											name: "Duration",
+										},
+									},
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 27, col: 82, offset: 556},
+							label: "region",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 27, col: 89, offset: 563},
+								expr: &seqExpr{
+									pos: position{line: 27, col: 91, offset: 565},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 27, col: 91, offset: 565},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 27, col: 93, offset: 567},
+											name: "Region",
+										},
+									},
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 27, col: 103, offset: 577},
+							label: "tolerance",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 27, col: 113, offset: 587},
+								expr: &seqExpr{
+									pos: position{line: 27, col: 115, offset: 589},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 27, col: 115, offset: 589},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 27, col: 117, offset: 591},
+											name: "Percentage",
+										},
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 27, col: 131, offset: 605},
+							name: "_",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 27, col: 133, offset: 607},
+							name: "ExprEnd",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Repeat",
+			pos:  position{line: 45, col: 1, offset: 1080},
+			expr: &actionExpr{
+				pos: position{line: 45, col: 10, offset: 1089},
+				run: (*parser).callonRepeat1,
+				expr: &seqExpr{
+					pos: position{line: 45, col: 10, offset: 1089},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 45, col: 10, offset: 1089},
+							name: "ExprStart",
+						},
+						&labeledExpr{
+							pos:   position{line: 45, col: 20, offset: 1099},
+							label: "key",
+							expr: &choiceExpr{
+								pos: position{line: 45, col: 26, offset: 1105},
+								alternatives: []interface{}{
+									&ruleRefExpr{
+										pos:  position{line: 45, col: 26, offset: 1105},
+										name: "SpecialKey",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 45, col: 39, offset: 1118},
+										name: "Literal",
+									},
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 45, col: 49, offset: 1128},
+							val:        "*",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 45, col: 53, offset: 1132},
+							label: "n",
+							expr: &ruleRefExpr{
+								pos:  position{line: 45, col: 55, offset: 1134},
+								name: "Natural",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 45, col: 63, offset: 1142},
 							name: "ExprEnd",
 						},
 					},
@@ -129,44 +291,44 @@ var g = &grammar{
 		},
 		{
 			name: "CharToggle",
-			pos:  position{line: 27, col: 1, offset: 453},
+			pos:  position{line: 56, col: 1, offset: 1376},
 			expr: &actionExpr{
-				pos: position{line: 27, col: 14, offset: 466},
+				pos: position{line: 56, col: 14, offset: 1389},
 				run: (*parser).callonCharToggle1,
 				expr: &seqExpr{
-					pos: position{line: 27, col: 14, offset: 466},
+					pos: position{line: 56, col: 14, offset: 1389},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 27, col: 14, offset: 466},
+							pos:  position{line: 56, col: 14, offset: 1389},
 							name: "ExprStart",
 						},
 						&labeledExpr{
-							pos:   position{line: 27, col: 24, offset: 476},
+							pos:   position{line: 56, col: 24, offset: 1399},
 							label: "lit",
 							expr: &ruleRefExpr{
-								pos:  position{line: 27, col: 29, offset: 481},
+								pos:  position{line: 56, col: 29, offset: 1404},
 								name: "Literal",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 27, col: 38, offset: 490},
+							pos:   position{line: 56, col: 38, offset: 1413},
 							label: "t",
 							expr: &choiceExpr{
-								pos: position{line: 27, col: 41, offset: 493},
+								pos: position{line: 56, col: 41, offset: 1416},
 								alternatives: []interface{}{
 									&ruleRefExpr{
-										pos:  position{line: 27, col: 41, offset: 493},
+										pos:  position{line: 56, col: 41, offset: 1416},
 										name: "On",
 									},
 									&ruleRefExpr{
-										pos:  position{line: 27, col: 46, offset: 498},
+										pos:  position{line: 56, col: 46, offset: 1421},
 										name: "Off",
 									},
 								},
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 27, col: 51, offset: 503},
+							pos:  position{line: 56, col: 51, offset: 1426},
 							name: "ExprEnd",
 						},
 					},
@@ -175,39 +337,39 @@ var g = &grammar{
 		},
 		{
 			name: "Special",
-			pos:  position{line: 31, col: 1, offset: 574},
+			pos:  position{line: 60, col: 1, offset: 1497},
 			expr: &actionExpr{
-				pos: position{line: 31, col: 11, offset: 584},
+				pos: position{line: 60, col: 11, offset: 1507},
 				run: (*parser).callonSpecial1,
 				expr: &seqExpr{
-					pos: position{line: 31, col: 11, offset: 584},
+					pos: position{line: 60, col: 11, offset: 1507},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 31, col: 11, offset: 584},
+							pos:  position{line: 60, col: 11, offset: 1507},
 							name: "ExprStart",
 						},
 						&labeledExpr{
-							pos:   position{line: 31, col: 21, offset: 594},
+							pos:   position{line: 60, col: 21, offset: 1517},
 							label: "s",
 							expr: &ruleRefExpr{
-								pos:  position{line: 31, col: 24, offset: 597},
+								pos:  position{line: 60, col: 24, offset: 1520},
 								name: "SpecialKey",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 31, col: 36, offset: 609},
+							pos:   position{line: 60, col: 36, offset: 1532},
 							label: "t",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 31, col: 38, offset: 611},
+								pos: position{line: 60, col: 38, offset: 1534},
 								expr: &choiceExpr{
-									pos: position{line: 31, col: 39, offset: 612},
+									pos: position{line: 60, col: 39, offset: 1535},
 									alternatives: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 39, offset: 612},
+											pos:  position{line: 60, col: 39, offset: 1535},
 											name: "On",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 44, offset: 617},
+											pos:  position{line: 60, col: 44, offset: 1540},
 											name: "Off",
 										},
 									},
@@ -215,7 +377,7 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 31, col: 50, offset: 623},
+							pos:  position{line: 60, col: 50, offset: 1546},
 							name: "ExprEnd",
 						},
 					},
@@ -224,39 +386,39 @@ var g = &grammar{
 		},
 		{
 			name: "Number",
-			pos:  position{line: 39, col: 1, offset: 810},
+			pos:  position{line: 68, col: 1, offset: 1733},
 			expr: &actionExpr{
-				pos: position{line: 39, col: 10, offset: 819},
+				pos: position{line: 68, col: 10, offset: 1742},
 				run: (*parser).callonNumber1,
 				expr: &seqExpr{
-					pos: position{line: 39, col: 10, offset: 819},
+					pos: position{line: 68, col: 10, offset: 1742},
 					exprs: []interface{}{
 						&zeroOrOneExpr{
-							pos: position{line: 39, col: 10, offset: 819},
+							pos: position{line: 68, col: 10, offset: 1742},
 							expr: &litMatcher{
-								pos:        position{line: 39, col: 10, offset: 819},
+								pos:        position{line: 68, col: 10, offset: 1742},
 								val:        "-",
 								ignoreCase: false,
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 39, col: 15, offset: 824},
+							pos:  position{line: 68, col: 15, offset: 1747},
 							name: "Integer",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 39, col: 23, offset: 832},
+							pos: position{line: 68, col: 23, offset: 1755},
 							expr: &seqExpr{
-								pos: position{line: 39, col: 25, offset: 834},
+								pos: position{line: 68, col: 25, offset: 1757},
 								exprs: []interface{}{
 									&litMatcher{
-										pos:        position{line: 39, col: 25, offset: 834},
+										pos:        position{line: 68, col: 25, offset: 1757},
 										val:        ".",
 										ignoreCase: false,
 									},
 									&oneOrMoreExpr{
-										pos: position{line: 39, col: 29, offset: 838},
+										pos: position{line: 68, col: 29, offset: 1761},
 										expr: &ruleRefExpr{
-											pos:  position{line: 39, col: 29, offset: 838},
+											pos:  position{line: 68, col: 29, offset: 1761},
 											name: "Digit",
 										},
 									},
@@ -269,29 +431,29 @@ var g = &grammar{
 		},
 		{
 			name: "Integer",
-			pos:  position{line: 43, col: 1, offset: 884},
+			pos:  position{line: 72, col: 1, offset: 1807},
 			expr: &choiceExpr{
-				pos: position{line: 43, col: 11, offset: 894},
+				pos: position{line: 72, col: 11, offset: 1817},
 				alternatives: []interface{}{
 					&litMatcher{
-						pos:        position{line: 43, col: 11, offset: 894},
+						pos:        position{line: 72, col: 11, offset: 1817},
 						val:        "0",
 						ignoreCase: false,
 					},
 					&actionExpr{
-						pos: position{line: 43, col: 17, offset: 900},
+						pos: position{line: 72, col: 17, offset: 1823},
 						run: (*parser).callonInteger3,
 						expr: &seqExpr{
-							pos: position{line: 43, col: 17, offset: 900},
+							pos: position{line: 72, col: 17, offset: 1823},
 							exprs: []interface{}{
 								&ruleRefExpr{
-									pos:  position{line: 43, col: 17, offset: 900},
+									pos:  position{line: 72, col: 17, offset: 1823},
 									name: "NonZeroDigit",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 43, col: 30, offset: 913},
+									pos: position{line: 72, col: 30, offset: 1836},
 									expr: &ruleRefExpr{
-										pos:  position{line: 43, col: 30, offset: 913},
+										pos:  position{line: 72, col: 30, offset: 1836},
 										name: "Digit",
 									},
 								},
@@ -303,21 +465,21 @@ var g = &grammar{
 		},
 		{
 			name: "Duration",
-			pos:  position{line: 47, col: 1, offset: 977},
+			pos:  position{line: 76, col: 1, offset: 1900},
 			expr: &actionExpr{
-				pos: position{line: 47, col: 12, offset: 988},
+				pos: position{line: 76, col: 12, offset: 1911},
 				run: (*parser).callonDuration1,
 				expr: &oneOrMoreExpr{
-					pos: position{line: 47, col: 12, offset: 988},
+					pos: position{line: 76, col: 12, offset: 1911},
 					expr: &seqExpr{
-						pos: position{line: 47, col: 14, offset: 990},
+						pos: position{line: 76, col: 14, offset: 1913},
 						exprs: []interface{}{
 							&ruleRefExpr{
-								pos:  position{line: 47, col: 14, offset: 990},
+								pos:  position{line: 76, col: 14, offset: 1913},
 								name: "Number",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 47, col: 21, offset: 997},
+								pos:  position{line: 76, col: 21, offset: 1920},
 								name: "TimeUnit",
 							},
 						},
@@ -325,14 +487,153 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "QuotedString",
+			pos:  position{line: 80, col: 1, offset: 1983},
+			expr: &actionExpr{
+				pos: position{line: 80, col: 16, offset: 1998},
+				run: (*parser).callonQuotedString1,
+				expr: &seqExpr{
+					pos: position{line: 80, col: 16, offset: 1998},
+					exprs: []interface{}{
+						&litMatcher{
+							pos:        position{line: 80, col: 16, offset: 1998},
+							val:        "\"",
+							ignoreCase: false,
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 80, col: 20, offset: 2002},
+							expr: &seqExpr{
+								pos: position{line: 80, col: 22, offset: 2004},
+								exprs: []interface{}{
+									&notExpr{
+										pos: position{line: 80, col: 22, offset: 2004},
+										expr: &litMatcher{
+											pos:        position{line: 80, col: 23, offset: 2005},
+											val:        "\"",
+											ignoreCase: false,
+										},
+									},
+									&anyMatcher{
+										line: 80, col: 27, offset: 2009,
+									},
+								},
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 80, col: 32, offset: 2014},
+							val:        "\"",
+							ignoreCase: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Region",
+			pos:  position{line: 84, col: 1, offset: 2073},
+			expr: &actionExpr{
+				pos: position{line: 84, col: 10, offset: 2082},
+				run: (*parser).callonRegion1,
+				expr: &seqExpr{
+					pos: position{line: 84, col: 10, offset: 2082},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 84, col: 10, offset: 2082},
+							label: "x",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 12, offset: 2084},
+								name: "Natural",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 84, col: 20, offset: 2092},
+							val:        ",",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 24, offset: 2096},
+							label: "y",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 26, offset: 2098},
+								name: "Natural",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 84, col: 34, offset: 2106},
+							val:        ",",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 38, offset: 2110},
+							label: "w",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 40, offset: 2112},
+								name: "Natural",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 84, col: 48, offset: 2120},
+							val:        ",",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 52, offset: 2124},
+							label: "h",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 54, offset: 2126},
+								name: "Natural",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Natural",
+			pos:  position{line: 89, col: 1, offset: 2229},
+			expr: &actionExpr{
+				pos: position{line: 89, col: 11, offset: 2239},
+				run: (*parser).callonNatural1,
+				expr: &oneOrMoreExpr{
+					pos: position{line: 89, col: 11, offset: 2239},
+					expr: &ruleRefExpr{
+						pos:  position{line: 89, col: 11, offset: 2239},
+						name: "Digit",
+					},
+				},
+			},
+		},
+		{
+			name: "Percentage",
+			pos:  position{line: 93, col: 1, offset: 2291},
+			expr: &actionExpr{
+				pos: position{line: 93, col: 14, offset: 2304},
+				run: (*parser).callonPercentage1,
+				expr: &seqExpr{
+					pos: position{line: 93, col: 14, offset: 2304},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 93, col: 14, offset: 2304},
+							name: "Number",
+						},
+						&litMatcher{
+							pos:        position{line: 93, col: 21, offset: 2311},
+							val:        "%",
+							ignoreCase: false,
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "On",
-			pos:  position{line: 51, col: 1, offset: 1060},
+			pos:  position{line: 98, col: 1, offset: 2413},
 			expr: &actionExpr{
-				pos: position{line: 51, col: 6, offset: 1065},
+				pos: position{line: 98, col: 6, offset: 2418},
 				run: (*parser).callonOn1,
 				expr: &litMatcher{
-					pos:        position{line: 51, col: 6, offset: 1065},
+					pos:        position{line: 98, col: 6, offset: 2418},
 					val:        "on",
 					ignoreCase: true,
 				},
@@ -340,12 +641,12 @@ var g = &grammar{
 		},
 		{
 			name: "Off",
-			pos:  position{line: 55, col: 1, offset: 1098},
+			pos:  position{line: 102, col: 1, offset: 2451},
 			expr: &actionExpr{
-				pos: position{line: 55, col: 7, offset: 1104},
+				pos: position{line: 102, col: 7, offset: 2457},
 				run: (*parser).callonOff1,
 				expr: &litMatcher{
-					pos:        position{line: 55, col: 7, offset: 1104},
+					pos:        position{line: 102, col: 7, offset: 2457},
 					val:        "off",
 					ignoreCase: true,
 				},
@@ -353,216 +654,690 @@ var g = &grammar{
 		},
 		{
 			name: "Literal",
-			pos:  position{line: 59, col: 1, offset: 1139},
+			pos:  position{line: 106, col: 1, offset: 2492},
 			expr: &actionExpr{
-				pos: position{line: 59, col: 11, offset: 1149},
+				pos: position{line: 106, col: 11, offset: 2502},
 				run: (*parser).callonLiteral1,
 				expr: &anyMatcher{
-					line: 59, col: 11, offset: 1149,
+					line: 106, col: 11, offset: 2502,
+				},
+			},
+		},
+		{
+			name: "Expansion",
+			pos:  position{line: 114, col: 1, offset: 2754},
+			expr: &actionExpr{
+				pos: position{line: 114, col: 14, offset: 2767},
+				run: (*parser).callonExpansion1,
+				expr: &seqExpr{
+					pos: position{line: 114, col: 14, offset: 2767},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 114, col: 14, offset: 2767},
+							label: "parts",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 114, col: 20, offset: 2773},
+								expr: &ruleRefExpr{
+									pos:  position{line: 114, col: 20, offset: 2773},
+									name: "ExpansionPart",
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 114, col: 35, offset: 2788},
+							name: "EOF",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ExpansionPart",
+			pos:  position{line: 118, col: 1, offset: 2819},
+			expr: &choiceExpr{
+				pos: position{line: 118, col: 17, offset: 2835},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 118, col: 17, offset: 2835},
+						name: "MacroCall",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 118, col: 29, offset: 2847},
+						name: "MacroArg",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 118, col: 40, offset: 2858},
+						name: "Include",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 118, col: 50, offset: 2868},
+						name: "If",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 118, col: 55, offset: 2873},
+						name: "Text",
+					},
+				},
+			},
+		},
+		{
+			name: "If",
+			pos:  position{line: 120, col: 1, offset: 2879},
+			expr: &actionExpr{
+				pos: position{line: 120, col: 6, offset: 2884},
+				run: (*parser).callonIf1,
+				expr: &seqExpr{
+					pos: position{line: 120, col: 6, offset: 2884},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 120, col: 6, offset: 2884},
+							name: "ExprStart",
+						},
+						&litMatcher{
+							pos:        position{line: 120, col: 16, offset: 2894},
+							val:        "if",
+							ignoreCase: true,
+						},
+						&ruleRefExpr{
+							pos:  position{line: 120, col: 22, offset: 2900},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 120, col: 24, offset: 2902},
+							val:        "$",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 120, col: 28, offset: 2906},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 120, col: 33, offset: 2911},
+								name: "Name",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 120, col: 38, offset: 2916},
+							label: "value",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 120, col: 44, offset: 2922},
+								expr: &seqExpr{
+									pos: position{line: 120, col: 46, offset: 2924},
+									exprs: []interface{}{
+										&litMatcher{
+											pos:        position{line: 120, col: 46, offset: 2924},
+											val:        "=",
+											ignoreCase: false,
+										},
+										&ruleRefExpr{
+											pos:  position{line: 120, col: 50, offset: 2928},
+											name: "QuotedString",
+										},
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 120, col: 66, offset: 2944},
+							name: "_",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 120, col: 68, offset: 2946},
+							name: "ExprEnd",
+						},
+						&labeledExpr{
+							pos:   position{line: 120, col: 76, offset: 2954},
+							label: "then",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 120, col: 81, offset: 2959},
+								expr: &ruleRefExpr{
+									pos:  position{line: 120, col: 81, offset: 2959},
+									name: "ExpansionPart",
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 120, col: 96, offset: 2974},
+							label: "els",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 120, col: 100, offset: 2978},
+								expr: &seqExpr{
+									pos: position{line: 120, col: 102, offset: 2980},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 120, col: 102, offset: 2980},
+											name: "ExprStart",
+										},
+										&litMatcher{
+											pos:        position{line: 120, col: 112, offset: 2990},
+											val:        "else",
+											ignoreCase: true,
+										},
+										&ruleRefExpr{
+											pos:  position{line: 120, col: 120, offset: 2998},
+											name: "ExprEnd",
+										},
+										&zeroOrMoreExpr{
+											pos: position{line: 120, col: 128, offset: 3006},
+											expr: &ruleRefExpr{
+												pos:  position{line: 120, col: 128, offset: 3006},
+												name: "ExpansionPart",
+											},
+										},
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 120, col: 146, offset: 3024},
+							name: "ExprStart",
+						},
+						&litMatcher{
+							pos:        position{line: 120, col: 156, offset: 3034},
+							val:        "/if",
+							ignoreCase: true,
+						},
+						&ruleRefExpr{
+							pos:  position{line: 120, col: 163, offset: 3041},
+							name: "ExprEnd",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "MacroCall",
+			pos:  position{line: 132, col: 1, offset: 3331},
+			expr: &actionExpr{
+				pos: position{line: 132, col: 13, offset: 3343},
+				run: (*parser).callonMacroCall1,
+				expr: &seqExpr{
+					pos: position{line: 132, col: 13, offset: 3343},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 132, col: 13, offset: 3343},
+							name: "ExprStart",
+						},
+						&litMatcher{
+							pos:        position{line: 132, col: 23, offset: 3353},
+							val:        "@",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 132, col: 27, offset: 3357},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 132, col: 32, offset: 3362},
+								name: "Name",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 132, col: 37, offset: 3367},
+							label: "args",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 132, col: 42, offset: 3372},
+								expr: &seqExpr{
+									pos: position{line: 132, col: 44, offset: 3374},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 132, col: 44, offset: 3374},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 132, col: 46, offset: 3376},
+											name: "Argument",
+										},
+									},
+								},
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 132, col: 58, offset: 3388},
+							name: "_",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 132, col: 60, offset: 3390},
+							name: "ExprEnd",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Argument",
+			pos:  position{line: 144, col: 1, offset: 3753},
+			expr: &actionExpr{
+				pos: position{line: 144, col: 12, offset: 3764},
+				run: (*parser).callonArgument1,
+				expr: &seqExpr{
+					pos: position{line: 144, col: 12, offset: 3764},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 144, col: 12, offset: 3764},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 144, col: 17, offset: 3769},
+								name: "Name",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 144, col: 22, offset: 3774},
+							val:        "=",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 144, col: 26, offset: 3778},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 144, col: 32, offset: 3784},
+								name: "QuotedString",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "MacroArg",
+			pos:  position{line: 148, col: 1, offset: 3859},
+			expr: &actionExpr{
+				pos: position{line: 148, col: 12, offset: 3870},
+				run: (*parser).callonMacroArg1,
+				expr: &seqExpr{
+					pos: position{line: 148, col: 12, offset: 3870},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 148, col: 12, offset: 3870},
+							name: "ExprStart",
+						},
+						&litMatcher{
+							pos:        position{line: 148, col: 22, offset: 3880},
+							val:        "$",
+							ignoreCase: false,
+						},
+						&labeledExpr{
+							pos:   position{line: 148, col: 26, offset: 3884},
+							label: "name",
+							expr: &ruleRefExpr{
+								pos:  position{line: 148, col: 31, offset: 3889},
+								name: "Name",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 148, col: 36, offset: 3894},
+							name: "ExprEnd",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Include",
+			pos:  position{line: 152, col: 1, offset: 3947},
+			expr: &actionExpr{
+				pos: position{line: 152, col: 11, offset: 3957},
+				run: (*parser).callonInclude1,
+				expr: &seqExpr{
+					pos: position{line: 152, col: 11, offset: 3957},
+					exprs: []interface{}{
+						&ruleRefExpr{
+							pos:  position{line: 152, col: 11, offset: 3957},
+							name: "ExprStart",
+						},
+						&litMatcher{
+							pos:        position{line: 152, col: 21, offset: 3967},
+							val:        "include",
+							ignoreCase: true,
+						},
+						&ruleRefExpr{
+							pos:  position{line: 152, col: 32, offset: 3978},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 152, col: 34, offset: 3980},
+							label: "path",
+							expr: &ruleRefExpr{
+								pos:  position{line: 152, col: 39, offset: 3985},
+								name: "QuotedString",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 152, col: 52, offset: 3998},
+							name: "_",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 152, col: 54, offset: 4000},
+							name: "ExprEnd",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Text",
+			pos:  position{line: 156, col: 1, offset: 4052},
+			expr: &actionExpr{
+				pos: position{line: 156, col: 8, offset: 4059},
+				run: (*parser).callonText1,
+				expr: &seqExpr{
+					pos: position{line: 156, col: 8, offset: 4059},
+					exprs: []interface{}{
+						&notExpr{
+							pos: position{line: 156, col: 8, offset: 4059},
+							expr: &seqExpr{
+								pos: position{line: 156, col: 11, offset: 4062},
+								exprs: []interface{}{
+									&ruleRefExpr{
+										pos:  position{line: 156, col: 11, offset: 4062},
+										name: "ExprStart",
+									},
+									&choiceExpr{
+										pos: position{line: 156, col: 23, offset: 4074},
+										alternatives: []interface{}{
+											&litMatcher{
+												pos:        position{line: 156, col: 23, offset: 4074},
+												val:        "@",
+												ignoreCase: false,
+											},
+											&litMatcher{
+												pos:        position{line: 156, col: 29, offset: 4080},
+												val:        "$",
+												ignoreCase: false,
+											},
+											&litMatcher{
+												pos:        position{line: 156, col: 35, offset: 4086},
+												val:        "include",
+												ignoreCase: true,
+											},
+											&seqExpr{
+												pos: position{line: 156, col: 48, offset: 4099},
+												exprs: []interface{}{
+													&litMatcher{
+														pos:        position{line: 156, col: 48, offset: 4099},
+														val:        "if",
+														ignoreCase: true,
+													},
+													&ruleRefExpr{
+														pos:  position{line: 156, col: 54, offset: 4105},
+														name: "_",
+													},
+													&litMatcher{
+														pos:        position{line: 156, col: 56, offset: 4107},
+														val:        "$",
+														ignoreCase: false,
+													},
+												},
+											},
+											&litMatcher{
+												pos:        position{line: 156, col: 62, offset: 4113},
+												val:        "else>",
+												ignoreCase: true,
+											},
+											&litMatcher{
+												pos:        position{line: 156, col: 73, offset: 4124},
+												val:        "/if>",
+												ignoreCase: true,
+											},
+										},
+									},
+								},
+							},
+						},
+						&choiceExpr{
+							pos: position{line: 156, col: 87, offset: 4138},
+							alternatives: []interface{}{
+								&oneOrMoreExpr{
+									pos: position{line: 156, col: 87, offset: 4138},
+									expr: &charClassMatcher{
+										pos:        position{line: 156, col: 87, offset: 4138},
+										val:        "[^<]",
+										chars:      []rune{'<'},
+										ignoreCase: false,
+										inverted:   true,
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 156, col: 95, offset: 4146},
+									name: "ExprStart",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Name",
+			pos:  position{line: 160, col: 1, offset: 4194},
+			expr: &actionExpr{
+				pos: position{line: 160, col: 8, offset: 4201},
+				run: (*parser).callonName1,
+				expr: &seqExpr{
+					pos: position{line: 160, col: 8, offset: 4201},
+					exprs: []interface{}{
+						&charClassMatcher{
+							pos:        position{line: 160, col: 8, offset: 4201},
+							val:        "[a-zA-Z_]",
+							chars:      []rune{'_'},
+							ranges:     []rune{'a', 'z', 'A', 'Z'},
+							ignoreCase: false,
+							inverted:   false,
+						},
+						&zeroOrMoreExpr{
+							pos: position{line: 160, col: 18, offset: 4211},
+							expr: &charClassMatcher{
+								pos:        position{line: 160, col: 18, offset: 4211},
+								val:        "[a-zA-Z0-9_-]",
+								chars:      []rune{'_', '-'},
+								ranges:     []rune{'a', 'z', 'A', 'Z', '0', '9'},
+								ignoreCase: false,
+								inverted:   false,
+							},
+						},
+					},
 				},
 			},
 		},
 		{
 			name: "ExprEnd",
-			pos:  position{line: 64, col: 1, offset: 1230},
+			pos:  position{line: 164, col: 1, offset: 4262},
 			expr: &litMatcher{
-				pos:        position{line: 64, col: 11, offset: 1240},
+				pos:        position{line: 164, col: 11, offset: 4272},
 				val:        ">",
 				ignoreCase: false,
 			},
 		},
 		{
 			name: "ExprStart",
-			pos:  position{line: 65, col: 1, offset: 1244},
+			pos:  position{line: 165, col: 1, offset: 4276},
 			expr: &litMatcher{
-				pos:        position{line: 65, col: 13, offset: 1256},
+				pos:        position{line: 165, col: 13, offset: 4288},
 				val:        "<",
 				ignoreCase: false,
 			},
 		},
 		{
 			name: "SpecialKey",
-			pos:  position{line: 66, col: 1, offset: 1260},
+			pos:  position{line: 166, col: 1, offset: 4292},
 			expr: &choiceExpr{
-				pos: position{line: 66, col: 14, offset: 1273},
+				pos: position{line: 166, col: 14, offset: 4305},
 				alternatives: []interface{}{
 					&litMatcher{
-						pos:        position{line: 66, col: 14, offset: 1273},
+						pos:        position{line: 166, col: 14, offset: 4305},
 						val:        "bs",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 66, col: 22, offset: 1281},
+						pos:        position{line: 166, col: 22, offset: 4313},
 						val:        "del",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 66, col: 31, offset: 1290},
+						pos:        position{line: 166, col: 31, offset: 4322},
 						val:        "enter",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 66, col: 42, offset: 1301},
+						pos:        position{line: 166, col: 42, offset: 4333},
 						val:        "esc",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 66, col: 51, offset: 1310},
+						pos:        position{line: 166, col: 51, offset: 4342},
 						val:        "f10",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 66, col: 60, offset: 1319},
+						pos:        position{line: 166, col: 60, offset: 4351},
 						val:        "f11",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 66, col: 69, offset: 1328},
+						pos:        position{line: 166, col: 69, offset: 4360},
 						val:        "f12",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 11, offset: 1345},
+						pos:        position{line: 167, col: 11, offset: 4377},
 						val:        "f1",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 19, offset: 1353},
+						pos:        position{line: 167, col: 19, offset: 4385},
 						val:        "f2",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 27, offset: 1361},
+						pos:        position{line: 167, col: 27, offset: 4393},
 						val:        "f3",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 35, offset: 1369},
+						pos:        position{line: 167, col: 35, offset: 4401},
 						val:        "f4",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 43, offset: 1377},
+						pos:        position{line: 167, col: 43, offset: 4409},
 						val:        "f5",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 51, offset: 1385},
+						pos:        position{line: 167, col: 51, offset: 4417},
 						val:        "f6",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 59, offset: 1393},
+						pos:        position{line: 167, col: 59, offset: 4425},
 						val:        "f7",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 67, offset: 1401},
+						pos:        position{line: 167, col: 67, offset: 4433},
 						val:        "f8",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 67, col: 75, offset: 1409},
+						pos:        position{line: 167, col: 75, offset: 4441},
 						val:        "f9",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 12, offset: 1426},
+						pos:        position{line: 168, col: 12, offset: 4458},
 						val:        "return",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 24, offset: 1438},
+						pos:        position{line: 168, col: 24, offset: 4470},
 						val:        "tab",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 33, offset: 1447},
+						pos:        position{line: 168, col: 33, offset: 4479},
 						val:        "up",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 41, offset: 1455},
+						pos:        position{line: 168, col: 41, offset: 4487},
 						val:        "down",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 51, offset: 1465},
+						pos:        position{line: 168, col: 51, offset: 4497},
 						val:        "spacebar",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 65, offset: 1479},
+						pos:        position{line: 168, col: 65, offset: 4511},
 						val:        "insert",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 68, col: 77, offset: 1491},
+						pos:        position{line: 168, col: 77, offset: 4523},
 						val:        "home",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 69, col: 11, offset: 1509},
+						pos:        position{line: 169, col: 11, offset: 4541},
 						val:        "end",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 69, col: 20, offset: 1518},
+						pos:        position{line: 169, col: 20, offset: 4550},
 						val:        "pageup",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 69, col: 32, offset: 1530},
+						pos:        position{line: 169, col: 32, offset: 4562},
 						val:        "pagedown",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 69, col: 46, offset: 1544},
+						pos:        position{line: 169, col: 46, offset: 4576},
 						val:        "leftalt",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 69, col: 59, offset: 1557},
+						pos:        position{line: 169, col: 59, offset: 4589},
 						val:        "leftctrl",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 69, col: 73, offset: 1571},
+						pos:        position{line: 169, col: 73, offset: 4603},
 						val:        "leftshift",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 70, col: 11, offset: 1594},
+						pos:        position{line: 170, col: 11, offset: 4626},
 						val:        "rightalt",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 70, col: 25, offset: 1608},
+						pos:        position{line: 170, col: 25, offset: 4640},
 						val:        "rightctrl",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 70, col: 40, offset: 1623},
+						pos:        position{line: 170, col: 40, offset: 4655},
 						val:        "rightshift",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 70, col: 56, offset: 1639},
+						pos:        position{line: 170, col: 56, offset: 4671},
 						val:        "leftsuper",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 70, col: 71, offset: 1654},
+						pos:        position{line: 170, col: 71, offset: 4686},
 						val:        "rightsuper",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 71, col: 11, offset: 1678},
+						pos:        position{line: 171, col: 11, offset: 4710},
 						val:        "left",
 						ignoreCase: true,
 					},
 					&litMatcher{
-						pos:        position{line: 71, col: 21, offset: 1688},
+						pos:        position{line: 171, col: 21, offset: 4720},
 						val:        "right",
 						ignoreCase: true,
 					},
@@ -571,9 +1346,9 @@ var g = &grammar{
 		},
 		{
 			name: "NonZeroDigit",
-			pos:  position{line: 73, col: 1, offset: 1698},
+			pos:  position{line: 173, col: 1, offset: 4730},
 			expr: &charClassMatcher{
-				pos:        position{line: 73, col: 16, offset: 1713},
+				pos:        position{line: 173, col: 16, offset: 4745},
 				val:        "[1-9]",
 				ranges:     []rune{'1', '9'},
 				ignoreCase: false,
@@ -582,9 +1357,9 @@ var g = &grammar{
 		},
 		{
 			name: "Digit",
-			pos:  position{line: 74, col: 1, offset: 1719},
+			pos:  position{line: 174, col: 1, offset: 4751},
 			expr: &charClassMatcher{
-				pos:        position{line: 74, col: 9, offset: 1727},
+				pos:        position{line: 174, col: 9, offset: 4759},
 				val:        "[0-9]",
 				ranges:     []rune{'0', '9'},
 				ignoreCase: false,
@@ -593,42 +1368,42 @@ var g = &grammar{
 		},
 		{
 			name: "TimeUnit",
-			pos:  position{line: 75, col: 1, offset: 1733},
+			pos:  position{line: 175, col: 1, offset: 4765},
 			expr: &choiceExpr{
-				pos: position{line: 75, col: 13, offset: 1745},
+				pos: position{line: 175, col: 13, offset: 4777},
 				alternatives: []interface{}{
 					&litMatcher{
-						pos:        position{line: 75, col: 13, offset: 1745},
+						pos:        position{line: 175, col: 13, offset: 4777},
 						val:        "ns",
 						ignoreCase: false,
 					},
 					&litMatcher{
-						pos:        position{line: 75, col: 20, offset: 1752},
+						pos:        position{line: 175, col: 20, offset: 4784},
 						val:        "us",
 						ignoreCase: false,
 					},
 					&litMatcher{
-						pos:        position{line: 75, col: 27, offset: 1759},
+						pos:        position{line: 175, col: 27, offset: 4791},
 						val:        "µs",
 						ignoreCase: false,
 					},
 					&litMatcher{
-						pos:        position{line: 75, col: 34, offset: 1767},
+						pos:        position{line: 175, col: 34, offset: 4799},
 						val:        "ms",
 						ignoreCase: false,
 					},
 					&litMatcher{
-						pos:        position{line: 75, col: 41, offset: 1774},
+						pos:        position{line: 175, col: 41, offset: 4806},
 						val:        "s",
 						ignoreCase: false,
 					},
 					&litMatcher{
-						pos:        position{line: 75, col: 47, offset: 1780},
+						pos:        position{line: 175, col: 47, offset: 4812},
 						val:        "m",
 						ignoreCase: false,
 					},
 					&litMatcher{
-						pos:        position{line: 75, col: 53, offset: 1786},
+						pos:        position{line: 175, col: 53, offset: 4818},
 						val:        "h",
 						ignoreCase: false,
 					},
@@ -638,11 +1413,11 @@ var g = &grammar{
 		{
 			name:        "_",
 			displayName: "\"whitespace\"",
-			pos:         position{line: 77, col: 1, offset: 1792},
+			pos:         position{line: 177, col: 1, offset: 4824},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 77, col: 19, offset: 1810},
+				pos: position{line: 177, col: 19, offset: 4842},
 				expr: &charClassMatcher{
-					pos:        position{line: 77, col: 19, offset: 1810},
+					pos:        position{line: 177, col: 19, offset: 4842},
 					val:        "[ \\n\\t\\r]",
 					chars:      []rune{' ', '\n', '\t', '\r'},
 					ignoreCase: false,
@@ -652,11 +1427,11 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 79, col: 1, offset: 1822},
+			pos:  position{line: 179, col: 1, offset: 4854},
 			expr: &notExpr{
-				pos: position{line: 79, col: 8, offset: 1829},
+				pos: position{line: 179, col: 8, offset: 4861},
 				expr: &anyMatcher{
-					line: 79, col: 9, offset: 1830,
+					line: 179, col: 9, offset: 4862,
 				},
 			},
 		},
@@ -702,6 +1477,47 @@ func (p *parser) callonWait1() (interface{}, error) {
 	return p.cur.onWait1(stack["duration"])
 }
 
+func (c *current) onWaitScreen1(path, timeout, region, tolerance interface{}) (interface{}, error) {
+	w := &waitScreenExpression{
+		path:      path.(string),
+		timeout:   defaultWaitScreenTimeout,
+		tolerance: defaultWaitScreenTolerance,
+	}
+	if timeout != nil {
+		w.timeout = timeout.([]interface{})[1].(time.Duration)
+	}
+	if region != nil {
+		w.region = region.([]interface{})[1].(image.Rectangle)
+	}
+	if tolerance != nil {
+		w.tolerance = tolerance.([]interface{})[1].(float64)
+	}
+	return w, nil
+}
+
+func (p *parser) callonWaitScreen1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onWaitScreen1(stack["path"], stack["timeout"], stack["region"], stack["tolerance"])
+}
+
+func (c *current) onRepeat1(key, n interface{}) (interface{}, error) {
+	var e expression
+	switch k := key.(type) {
+	case []byte:
+		e = &specialExpression{strings.ToLower(string(k)), KeyPress}
+	case *literal:
+		e = k
+	}
+	return &repeatExpression{e, n.(int)}, nil
+}
+
+func (p *parser) callonRepeat1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onRepeat1(stack["key"], stack["n"])
+}
+
 func (c *current) onCharToggle1(lit, t interface{}) (interface{}, error) {
 	return &literal{lit.(*literal).s, t.(KeyAction)}, nil
 }
@@ -756,6 +1572,48 @@ func (p *parser) callonDuration1() (interface{}, error) {
 	return p.cur.onDuration1()
 }
 
+func (c *current) onQuotedString1() (interface{}, error) {
+	return string(c.text[1 : len(c.text)-1]), nil
+}
+
+func (p *parser) callonQuotedString1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onQuotedString1()
+}
+
+func (c *current) onRegion1(x, y, w, h interface{}) (interface{}, error) {
+	x0, y0 := x.(int), y.(int)
+	return image.Rect(x0, y0, x0+w.(int), y0+h.(int)), nil
+}
+
+func (p *parser) callonRegion1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onRegion1(stack["x"], stack["y"], stack["w"], stack["h"])
+}
+
+func (c *current) onNatural1() (interface{}, error) {
+	return strconv.Atoi(string(c.text))
+}
+
+func (p *parser) callonNatural1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNatural1()
+}
+
+func (c *current) onPercentage1() (interface{}, error) {
+	p, err := strconv.ParseFloat(string(c.text[:len(c.text)-1]), 64)
+	return p / 100, err
+}
+
+func (p *parser) callonPercentage1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPercentage1()
+}
+
 func (c *current) onOn1() (interface{}, error) {
 	return KeyOn, nil
 }
@@ -787,6 +1645,102 @@ func (p *parser) callonLiteral1() (interface{}, error) {
 	return p.cur.onLiteral1()
 }
 
+func (c *current) onExpansion1(parts interface{}) (interface{}, error) {
+	return parts, nil
+}
+
+func (p *parser) callonExpansion1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpansion1(stack["parts"])
+}
+
+func (c *current) onIf1(name, value, then, els interface{}) (interface{}, error) {
+	cond := &conditional{arg: name.(string), then: then.([]interface{})}
+	if value != nil {
+		v := value.([]interface{})[1].(string)
+		cond.value = &v
+	}
+	if els != nil {
+		cond.els = els.([]interface{})[3].([]interface{})
+	}
+	return cond, nil
+}
+
+func (p *parser) callonIf1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIf1(stack["name"], stack["value"], stack["then"], stack["els"])
+}
+
+func (c *current) onMacroCall1(name, args interface{}) (interface{}, error) {
+	call := &macroCall{name: name.(string), args: map[string]string{}}
+	for _, a := range args.([]interface{}) {
+		arg := a.([]interface{})[1].([2]string)
+		if _, ok := call.args[arg[0]]; ok {
+			return nil, fmt.Errorf("argument %s given twice", arg[0])
+		}
+		call.args[arg[0]] = arg[1]
+	}
+	return call, nil
+}
+
+func (p *parser) callonMacroCall1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onMacroCall1(stack["name"], stack["args"])
+}
+
+func (c *current) onArgument1(name, value interface{}) (interface{}, error) {
+	return [2]string{name.(string), value.(string)}, nil
+}
+
+func (p *parser) callonArgument1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onArgument1(stack["name"], stack["value"])
+}
+
+func (c *current) onMacroArg1(name interface{}) (interface{}, error) {
+	return macroArg(name.(string)), nil
+}
+
+func (p *parser) callonMacroArg1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onMacroArg1(stack["name"])
+}
+
+func (c *current) onInclude1(path interface{}) (interface{}, error) {
+	return include(path.(string)), nil
+}
+
+func (p *parser) callonInclude1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onInclude1(stack["path"])
+}
+
+func (c *current) onText1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonText1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onText1()
+}
+
+func (c *current) onName1() (interface{}, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonName1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onName1()
+}
+
 var (
 	// errNoRule is returned when the grammar to parse has no rule.
 	errNoRule = errors.New("grammar has no rule")
@@ -3,7 +3,10 @@ package bootcommand
 import (
 	"context"
 	"fmt"
+	"image"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -72,6 +75,20 @@ func (s expressionSequence) Validate() (errs []error) {
 	return
 }
 
+// WaitsForScreen reports whether the sequence waits for the screen, and so
+// needs a ScreenDriver.
+func (s expressionSequence) WaitsForScreen() bool {
+	for _, exp := range s {
+		if r, ok := exp.(*repeatExpression); ok {
+			exp = r.e
+		}
+		if _, ok := exp.(*waitScreenExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // GenerateExpressionSequence generates a sequence of expressions from the
 // given command. This is the primary entry point to the boot command parser.
 func GenerateExpressionSequence(command string) (expressionSequence, error) {
@@ -97,9 +114,17 @@ type waitExpression struct {
 // through the context.
 func (w *waitExpression) Do(ctx context.Context, driver BCDriver) error {
 	driver.Flush()
-	log.Printf("[INFO] Waiting %s", w.d)
+	if waiter, ok := driver.(waiter); ok {
+		return waiter.wait(ctx, w.d)
+	}
+	return wait(ctx, w.d)
+}
+
+// wait waits for d, unless the context is cancelled first.
+func wait(ctx context.Context, d time.Duration) error {
+	log.Printf("[INFO] Waiting %s", d)
 	select {
-	case <-time.After(w.d):
+	case <-time.After(d):
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
@@ -155,3 +180,120 @@ func (l *literal) Validate() error {
 func (l *literal) String() string {
 	return fmt.Sprintf("LIT-%s(%s)", l.action, string(l.s))
 }
+
+// repeatExpression presses a key a number of times, like <tab*5>.
+type repeatExpression struct {
+	e expression
+	n int
+}
+
+// Do executes the repeated expression n times.
+func (r *repeatExpression) Do(ctx context.Context, driver BCDriver) error {
+	for i := 0; i < r.n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.e.Do(ctx, driver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repeatExpression) Validate() error {
+	if r.n <= 0 {
+		return fmt.Errorf("Expecting a positive repeat count. Got %d", r.n)
+	}
+	return r.e.Validate()
+}
+
+func (r *repeatExpression) String() string {
+	return fmt.Sprintf("Repeat<%d %s>", r.n, r.e)
+}
+
+type waitScreenExpression struct {
+	path      string
+	timeout   time.Duration
+	region    image.Rectangle
+	tolerance float64
+}
+
+// Do waits until the screen, or the region of the screen, matches the
+// reference image, polling the screen through the driver, which must be a
+// ScreenDriver. When the screen doesn't match until the timeout, it is saved
+// next to the reference image to help debugging.
+func (w *waitScreenExpression) Do(ctx context.Context, driver BCDriver) error {
+	driver.Flush()
+	if waiter, ok := driver.(waiter); ok {
+		return waiter.waitScreen(ctx, w)
+	}
+	return w.wait(ctx, driver)
+}
+
+func (w *waitScreenExpression) wait(ctx context.Context, driver BCDriver) error {
+	screenDriver, ok := driver.(ScreenDriver)
+	if !ok {
+		return fmt.Errorf("<waitScreen> can't be used with this builder, which can't capture the screen")
+	}
+
+	ref, err := loadScreenImage(w.path)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Waiting %s for the screen to match %s", w.timeout, w.path)
+	timeout := time.After(w.timeout)
+	for {
+		screen, err := screenDriver.Screen(ctx)
+		if err != nil {
+			return fmt.Errorf("Error capturing the screen: %s", err)
+		}
+
+		diff := screenDiff(screen, ref, w.region)
+		if diff <= w.tolerance {
+			log.Printf("[INFO] Screen matches %s, %.2f%% of pixels differ", w.path, diff*100)
+			return nil
+		}
+		log.Printf("[DEBUG] Screen doesn't match %s yet, %.2f%% of pixels differ", w.path, diff*100)
+
+		select {
+		case <-time.After(waitScreenInterval):
+		case <-timeout:
+			path := strings.TrimSuffix(w.path, filepath.Ext(w.path)) + ".timeout.png"
+			if err := saveScreenImage(path, screen); err != nil {
+				log.Printf("[ERROR] Error saving the screen: %s", err)
+				return fmt.Errorf("Timeout waiting for the screen to match %s", w.path)
+			}
+			return fmt.Errorf("Timeout waiting for the screen to match %s. The screen was saved to %s", w.path, path)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Validate returns an error if the options are out of range or, unless its
+// path is a template, the reference image doesn't exist.
+func (w *waitScreenExpression) Validate() error {
+	if w.path == "" {
+		return fmt.Errorf("Expecting the path of a reference image to wait for")
+	}
+	if w.timeout <= 0 {
+		return fmt.Errorf("Expecting a positive wait value. Got %s", w.timeout)
+	}
+	if w.tolerance < 0 || w.tolerance >= 1 {
+		return fmt.Errorf("Expecting a tolerance from 0%% to 100%%. Got %g%%", w.tolerance*100)
+	}
+	if w.region != (image.Rectangle{}) && w.region.Empty() {
+		return fmt.Errorf("Expecting a region of positive size. Got %s", w.region)
+	}
+	if !strings.Contains(w.path, "{{") {
+		if _, err := os.Stat(w.path); err != nil {
+			return fmt.Errorf("Bad reference image: %s", err)
+		}
+	}
+	return nil
+}
+
+func (w *waitScreenExpression) String() string {
+	return fmt.Sprintf("WaitScreen<%s %s %s %g>", w.path, w.timeout, w.region, w.tolerance)
+}
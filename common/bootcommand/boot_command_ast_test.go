@@ -1,9 +1,11 @@
 package bootcommand
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -132,3 +134,101 @@ func Test_empty(t *testing.T) {
 	assert.NoError(t, err, "should have parsed an empty input okay.")
 	assert.Len(t, exp, 0)
 }
+
+func Test_waitScreen(t *testing.T) {
+	var expressions = []struct {
+		in    string
+		out   string
+		valid bool
+	}{
+		{
+			`<waitScreen "test-fixtures/menu.png">`,
+			"WaitScreen<test-fixtures/menu.png 5m0s (0,0)-(0,0) 0.01>",
+			true,
+		},
+		{
+			`<WAITSCREEN "test-fixtures/menu.png" 30s>`,
+			"WaitScreen<test-fixtures/menu.png 30s (0,0)-(0,0) 0.01>",
+			true,
+		},
+		{
+			`<waitScreen "test-fixtures/menu.png" 1m 10,20,300,40 2.5% >`,
+			"WaitScreen<test-fixtures/menu.png 1m0s (10,20)-(310,60) 0.025>",
+			true,
+		},
+		{
+			`<waitScreen "test-fixtures/menu.png" 0%>`,
+			"WaitScreen<test-fixtures/menu.png 5m0s (0,0)-(0,0) 0>",
+			true,
+		},
+		{
+			`<waitScreen "test-fixtures/menu.png" 100%>`,
+			"WaitScreen<test-fixtures/menu.png 5m0s (0,0)-(0,0) 1>",
+			false,
+		},
+		{
+			`<waitScreen "test-fixtures/menu.png" 0,0,0,40>`,
+			"WaitScreen<test-fixtures/menu.png 5m0s (0,0)-(0,40) 0.01>",
+			false,
+		},
+		{
+			`<waitScreen "i/dont/exist.png">`,
+			"WaitScreen<i/dont/exist.png 5m0s (0,0)-(0,0) 0.01>",
+			false,
+		},
+		{
+			`<waitScreen "{{ .Name }}.png">`,
+			"WaitScreen<{{ .Name }}.png 5m0s (0,0)-(0,0) 0.01>",
+			true,
+		},
+	}
+	for _, tt := range expressions {
+		exp, err := GenerateExpressionSequence(tt.in)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.in, err)
+		}
+
+		assert.Len(t, exp, 1)
+		assert.Equal(t, tt.out, fmt.Sprintf("%s", exp[0]))
+		err = exp[0].Validate()
+		if tt.valid {
+			assert.NoError(t, err, tt.in)
+		} else {
+			assert.Error(t, err, tt.in)
+		}
+	}
+}
+
+func Test_repeat(t *testing.T) {
+	var expressions = []struct {
+		in    string
+		out   string
+		valid bool
+	}{
+		{"<tab*5>", "Repeat<5 Spec-Press(tab)>", true},
+		{"<PageDown*12>", "Repeat<12 Spec-Press(pagedown)>", true},
+		{"<x*3>", "Repeat<3 LIT-Press(x)>", true},
+		{"<enter*0>", "Repeat<0 Spec-Press(enter)>", false},
+	}
+	for _, tt := range expressions {
+		exp, err := GenerateExpressionSequence(tt.in)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.in, err)
+		}
+
+		assert.Len(t, exp, 1)
+		assert.Equal(t, tt.out, fmt.Sprintf("%s", exp[0]))
+		err = exp[0].Validate()
+		if tt.valid {
+			assert.NoError(t, err, tt.in)
+		} else {
+			assert.Error(t, err, tt.in)
+		}
+	}
+
+	s := &sender{}
+	seq, err := GenerateExpressionSequence("<tab*3>")
+	assert.NoError(t, err)
+	assert.NoError(t, seq.Do(context.Background(), NewVNCDriver(s, time.Millisecond)))
+	assert.Len(t, s.e, 6)
+}
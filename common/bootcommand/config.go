@@ -2,6 +2,7 @@ package bootcommand
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -9,11 +10,15 @@ import (
 )
 
 type BootConfig struct {
-	RawBootGroupInterval string        `mapstructure:"boot_keygroup_interval"`
-	RawBootWait          string        `mapstructure:"boot_wait"`
-	BootCommand          []string      `mapstructure:"boot_command"`
-	BootGroupInterval    time.Duration ``
-	BootWait             time.Duration ``
+	RawBootGroupInterval string              `mapstructure:"boot_keygroup_interval"`
+	RawBootWait          string              `mapstructure:"boot_wait"`
+	BootCommand          []string            `mapstructure:"boot_command"`
+	BootCommandMacros    map[string][]string `mapstructure:"boot_command_macros"`
+	BootGroupInterval    time.Duration       ``
+	BootWait             time.Duration       ``
+
+	// the boot command with its macros and includes expanded, once prepared
+	expandedBootCommand *string
 }
 
 type VNCConfig struct {
@@ -25,6 +30,12 @@ type VNCConfig struct {
 }
 
 func (c *BootConfig) Prepare(ctx *interpolate.Context) (errs []error) {
+	return c.prepare(ctx, false)
+}
+
+// prepare prepares the config, for a builder that can capture the screen, as
+// <waitScreen> needs, or not.
+func (c *BootConfig) prepare(ctx *interpolate.Context, screen bool) (errs []error) {
 	if c.RawBootWait == "" {
 		c.RawBootWait = "10s"
 	}
@@ -54,18 +65,48 @@ func (c *BootConfig) Prepare(ctx *interpolate.Context) (errs []error) {
 	}
 
 	if c.BootCommand != nil {
-		expSeq, err := GenerateExpressionSequence(c.FlatBootCommand())
+		macros := make(map[string]string, len(c.BootCommandMacros))
+		for name, macro := range c.BootCommandMacros {
+			macros[name] = strings.Join(macro, "")
+		}
+
+		// Includes are relative to the template
+		var dir string
+		if ctx != nil && ctx.TemplatePath != "" {
+			dir = filepath.Dir(ctx.TemplatePath)
+		}
+
+		flat := strings.Join(c.BootCommand, "")
+		command, err := ExpandBootCommand(flat, macros, dir)
 		if err != nil {
 			errs = append(errs, err)
-		} else if vErrs := expSeq.Validate(); vErrs != nil {
-			errs = append(errs, vErrs...)
+		} else {
+			c.expandedBootCommand = &command
+
+			expSeq, err := GenerateExpressionSequence(command)
+			if err != nil {
+				if command != flat {
+					err = expandedError(command, err)
+				}
+				errs = append(errs, err)
+			} else if vErrs := expSeq.Validate(); vErrs != nil {
+				errs = append(errs, vErrs...)
+			} else if !screen && expSeq.WaitsForScreen() {
+				errs = append(errs,
+					fmt.Errorf("<waitScreen> can't be used with this builder, which can't capture the screen"))
+			}
 		}
 	}
 
 	return
 }
 
+// FlatBootCommand returns the boot command as a single string, with its
+// macros and includes expanded once the config is prepared.
 func (c *BootConfig) FlatBootCommand() string {
+	if c.expandedBootCommand != nil {
+		return *c.expandedBootCommand
+	}
 	return strings.Join(c.BootCommand, "")
 }
 
@@ -89,6 +130,6 @@ func (c *VNCConfig) Prepare(ctx *interpolate.Context) (errs []error) {
 		}
 	}
 
-	errs = append(errs, c.BootConfig.Prepare(ctx)...)
+	errs = append(errs, c.BootConfig.prepare(ctx, true)...)
 	return
 }
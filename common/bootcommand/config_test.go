@@ -1,6 +1,7 @@
 package bootcommand
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer/template/interpolate"
@@ -63,3 +64,77 @@ func TestVNCConfigPrepare(t *testing.T) {
 		t.Fatalf("bad: %#v", errs)
 	}
 }
+
+func TestConfigPrepare_waitScreen(t *testing.T) {
+	command := []string{`<waitScreen "test-fixtures/menu.png">`}
+
+	// The scancode builders can't capture the screen
+	c := &BootConfig{BootCommand: command}
+	errs := c.Prepare(&interpolate.Context{})
+	if len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	v := &VNCConfig{BootConfig: BootConfig{BootCommand: command}}
+	errs = v.Prepare(&interpolate.Context{})
+	if len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestConfigPrepare_macros(t *testing.T) {
+	c := &BootConfig{
+		BootCommand: []string{`<esc><@login user="root">`, "<enter>"},
+		BootCommandMacros: map[string][]string{
+			"login": {"<$user><tab>", "packer<enter>"},
+		},
+	}
+	errs := c.Prepare(&interpolate.Context{})
+	if len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if cmd := c.FlatBootCommand(); cmd != "<esc>root<tab>packer<enter><enter>" {
+		t.Fatalf("bad: %s", cmd)
+	}
+
+	// Parse errors point to the expanded command
+	c = &BootConfig{
+		BootCommand: []string{`<@wait d="1000000000000h">`},
+		BootCommandMacros: map[string][]string{
+			"wait": {"<enter><wait<$d>>"},
+		},
+	}
+	errs = c.Prepare(&interpolate.Context{})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `1:13 (12)`) ||
+		!strings.Contains(errs[0].Error(), `at "<enter><wait1000000000000h>" of the expanded boot command`) {
+		t.Fatalf("bad: %v", errs)
+	}
+
+	// Unknown macros
+	c = &BootConfig{
+		BootCommand: []string{`<@login>`},
+	}
+	errs = c.Prepare(&interpolate.Context{})
+	if len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestConfigPrepare_include(t *testing.T) {
+	// Includes are relative to the template rather than to the working
+	// directory
+	c := &BootConfig{
+		BootCommand: []string{`<@setup user="root" password="packer">`},
+		BootCommandMacros: map[string][]string{
+			"login": {"<$user><tab><$password><enter>"},
+			"setup": {`<include "include.txt">`},
+		},
+	}
+	errs := c.Prepare(&interpolate.Context{TemplatePath: "test-fixtures/template.json"})
+	if len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if cmd := c.FlatBootCommand(); cmd != "root<enter><wait>root<tab>packer<enter>" {
+		t.Fatalf("bad: %s", cmd)
+	}
+}
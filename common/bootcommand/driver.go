@@ -1,5 +1,11 @@
 package bootcommand
 
+import (
+	"context"
+	"image"
+	"time"
+)
+
 const shiftedChars = "~!@#$%^&*()_+{}|:\"<>?"
 
 // BCDriver is our access to the VM we want to type boot commands to
@@ -9,3 +15,18 @@ type BCDriver interface {
 	// Flush will be called when we want to send scancodes to the VM.
 	Flush() error
 }
+
+// ScreenDriver is a BCDriver that can capture the screen of the VM too, for
+// the boot commands that wait for the screen to show something.
+type ScreenDriver interface {
+	BCDriver
+	// Screen returns the current screen of the VM.
+	Screen(context.Context) (image.Image, error)
+}
+
+// waiter is a BCDriver that does the waits of the boot command itself, such
+// as to record them.
+type waiter interface {
+	wait(ctx context.Context, d time.Duration) error
+	waitScreen(ctx context.Context, w *waitScreenExpression) error
+}
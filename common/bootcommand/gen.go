@@ -1,3 +1,3 @@
 package bootcommand
 
-//go:generate pigeon -o boot_command.go boot_command.pigeon
+//go:generate pigeon -alternate-entrypoints Expansion -o boot_command.go boot_command.pigeon
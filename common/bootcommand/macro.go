@@ -0,0 +1,185 @@
+package bootcommand
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExpansionDepth is how deep macros and includes may be nested, to catch
+// macros that call themselves.
+const maxExpansionDepth = 16
+
+// macroCall calls a macro of boot_command_macros, like
+// <@preseed hostname="web">.
+type macroCall struct {
+	name string
+	args map[string]string
+}
+
+// macroArg is replaced by an argument of the macro call, like <$hostname>.
+type macroArg string
+
+// include is replaced by the content of a file, like <include "keys.txt">.
+type include string
+
+// conditional is replaced by one of two parts of a macro, depending on an
+// argument of the macro call, like <if $proxy>...<else>...</if>. Without a
+// value, the condition holds when the argument is given and not empty.
+type conditional struct {
+	arg   string
+	value *string
+	then  []interface{}
+	els   []interface{}
+}
+
+// holds reports whether the condition holds for the arguments of a macro
+// call. Arguments that aren't given are empty.
+func (c *conditional) holds(args map[string]string) bool {
+	if c.value != nil {
+		return args[c.arg] == *c.value
+	}
+	return args[c.arg] != ""
+}
+
+// expansion is the state of the expansion of a boot command.
+type expansion struct {
+	macros map[string]string
+	// dir is the directory the paths of includes are relative to
+	dir string
+}
+
+// ExpandBootCommand expands the macro calls, the conditionals and the
+// includes of a boot command, with the given macros. The paths of includes
+// are relative to dir. The line breaks of included files are dropped, the
+// same as between the strings of boot_command.
+func ExpandBootCommand(command string, macros map[string]string, dir string) (string, error) {
+	e := &expansion{macros: macros, dir: dir}
+	return e.expand("boot_command", command, nil, 0)
+}
+
+func (e *expansion) expand(name, command string, args map[string]string, depth int) (string, error) {
+	if depth > maxExpansionDepth {
+		return "", fmt.Errorf("%s: macros and includes nested more than %d deep, does a macro call itself?", name, maxExpansionDepth)
+	}
+	if command == "" {
+		return "", nil
+	}
+
+	got, err := ParseReader(name, strings.NewReader(command), Entrypoint("Expansion"))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := e.expandParts(&b, name, got.([]interface{}), args, depth); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (e *expansion) expandParts(b *strings.Builder, name string, parts []interface{}, args map[string]string, depth int) error {
+	for _, part := range parts {
+		switch p := part.(type) {
+		case string:
+			b.WriteString(p)
+		case macroArg:
+			if args == nil {
+				return fmt.Errorf("%s: <$%s> can only be used in boot_command_macros", name, p)
+			}
+			v, ok := args[string(p)]
+			if !ok {
+				return fmt.Errorf("%s: argument %s not given", name, p)
+			}
+			b.WriteString(v)
+		case *conditional:
+			if args == nil {
+				return fmt.Errorf("%s: <if $%s> can only be used in boot_command_macros", name, p.arg)
+			}
+			branch := p.els
+			if p.holds(args) {
+				branch = p.then
+			}
+			if err := e.expandParts(b, name, branch, args, depth); err != nil {
+				return err
+			}
+		case *macroCall:
+			body, ok := e.macros[p.name]
+			if !ok {
+				return fmt.Errorf("%s: unknown macro %s", name, p.name)
+			}
+			// Arguments can pass on the arguments of the calling macro
+			for k, v := range p.args {
+				s, err := e.expand(name, v, args, depth+1)
+				if err != nil {
+					return err
+				}
+				p.args[k] = s
+			}
+			s, err := e.expand("boot_command_macros."+p.name, body, p.args, depth+1)
+			if err != nil {
+				return err
+			}
+			b.WriteString(s)
+		case include:
+			path := string(p)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(e.dir, path)
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: error including file: %s", name, err)
+			}
+			content := strings.NewReplacer("\r\n", "", "\n", "").Replace(string(data))
+			s, err := e.expand(string(p), content, args, depth+1)
+			if err != nil {
+				return err
+			}
+			b.WriteString(s)
+		}
+	}
+	return nil
+}
+
+// expandedError adds the text around the parse errors of an expanded boot
+// command to them, since their positions are in the expanded command rather
+// than in the boot_command as written.
+func expandedError(command string, err error) error {
+	list, ok := err.(errList)
+	if !ok {
+		return err
+	}
+
+	msgs := make([]string, 0, len(list))
+	for _, e := range list {
+		msg := e.Error()
+		if pe, ok := e.(*parserError); ok {
+			msg = fmt.Sprintf("%s, at %q of the expanded boot command", msg, excerpt(command, pe.pos.offset))
+		}
+		msgs = append(msgs, msg)
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// excerpt returns the text of s around the offset.
+func excerpt(s string, offset int) string {
+	const around = 20
+
+	start, end := offset-around, offset+around
+	if start < 0 {
+		start = 0
+	}
+	if end > len(s) {
+		end = len(s)
+	}
+	for start > 0 && !utf8.RuneStart(s[start]) {
+		start--
+	}
+	for end < len(s) && !utf8.RuneStart(s[end]) {
+		end++
+	}
+	return s[start:end]
+}
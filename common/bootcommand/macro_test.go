@@ -0,0 +1,98 @@
+package bootcommand
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandBootCommand(t *testing.T) {
+	macros := map[string]string{
+		"login":   `<$user><tab><$password><enter>`,
+		"install": `<@login user="<$user>" password="secret"><wait5>install<enter>`,
+		"include": `<include "test-fixtures/include.txt">`,
+		"loop":    `<@loop>`,
+		"proxy":   `<if $proxy>proxy=<$proxy><else>noproxy</if><enter>`,
+		"lang":    `<if $lang="de">de<else><if $lang="fr">fr<else>en</if></if>`,
+	}
+
+	cases := []struct {
+		in  string
+		out string
+		err string
+	}{
+		{"", "", ""},
+		{"<esc><wait>a<b>", "<esc><wait>a<b>", ""},
+		{
+			`<@login user="root" password="vagrant">`,
+			"root<tab>vagrant<enter>",
+			"",
+		},
+		{
+			`a<@install   user="root">b`,
+			"aroot<tab>secret<enter><wait5>install<enter>b",
+			"",
+		},
+		{
+			`<@include user="admin" password="x">`,
+			"root<enter><wait>admin<tab>x<enter>",
+			"",
+		},
+		{`<@foo>`, "", "unknown macro foo"},
+		{`<@login user="root">`, "", "argument password not given"},
+		{`<$user>`, "", "can only be used in boot_command_macros"},
+		{`<@loop>`, "", "nested more than"},
+		{`<@login user=root>`, "", "boot_command:1:14 (13)"},
+		{`<@login user="a" user="b">`, "", "given twice"},
+		{`<include "test-fixtures/missing.txt">`, "", "error including file"},
+		{`<@proxy proxy="http://proxy">`, "proxy=http://proxy<enter>", ""},
+		{`<@proxy>`, "noproxy<enter>", ""},
+		{`<@proxy proxy="">`, "noproxy<enter>", ""},
+		{`<@lang lang="fr">`, "fr", ""},
+		{`<@lang>`, "en", ""},
+		{`<if $proxy>a</if>`, "", "can only be used in boot_command_macros"},
+		{`<@lang lang="de"><else>`, "", "boot_command"},
+		{`<@lang lang="de"><end><else>`, "", "boot_command"},
+	}
+
+	for _, tc := range cases {
+		out, err := ExpandBootCommand(tc.in, macros, "")
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("%s: bad error: %v", tc.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.in, err)
+		}
+		if out != tc.out {
+			t.Fatalf("%s: bad: %q", tc.in, out)
+		}
+	}
+}
+
+func TestExpandBootCommand_includeDir(t *testing.T) {
+	macros := map[string]string{
+		"login": `<$user><tab><$password><enter>`,
+		"setup": `<include "include.txt">`,
+	}
+
+	out, err := ExpandBootCommand(`<@setup user="admin" password="x">`, macros, "test-fixtures")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out != "root<enter><wait>admin<tab>x<enter>" {
+		t.Fatalf("bad: %q", out)
+	}
+
+	// Absolute paths are kept
+	abs, err := filepath.Abs("test-fixtures/include.txt")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, err = ExpandBootCommand(`<@setup user="a" password="b"><include "`+abs+`">`, macros, "missing")
+	if err == nil || !strings.Contains(err.Error(), "missing/include.txt") {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -24,6 +24,8 @@ type pcXTDriver struct {
 	buffer      [][]string
 	// TODO: set from env
 	scancodeChunkSize int
+	// sleep waits between the chunks of codes
+	sleep func(time.Duration)
 }
 
 type scancode struct {
@@ -122,6 +124,7 @@ func NewPCXTDriver(send SendCodeFunc, chunkSize int, interval time.Duration) *pc
 		specialMap:        sMap,
 		scancodeMap:       scancodeMap,
 		scancodeChunkSize: chunkSize,
+		sleep:             time.Sleep,
 	}
 }
 
@@ -138,7 +141,7 @@ func (d *pcXTDriver) Flush() error {
 		if err := d.sendImpl(b); err != nil {
 			return err
 		}
-		time.Sleep(d.interval)
+		d.sleep(d.interval)
 	}
 	return nil
 }
@@ -0,0 +1,200 @@
+package bootcommand
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// BootCommandLogEnv is the environment variable with the path of a file to
+// append the timelines of the boot commands of builds to, so that failed
+// installs can be replayed.
+const BootCommandLogEnv = "PACKER_BOOT_COMMAND_LOG"
+
+// Recorder records the timeline of a boot command: the keys and the waits the
+// driver gets, and the codes the driver sends to the VM for them. Each line
+// starts with the time since the boot command started.
+type Recorder struct {
+	name   string
+	w      io.Writer
+	closer io.Closer
+	start  time.Time
+
+	// A dry run only records the boot command, without typing to a VM or
+	// waiting, and keeps the time of the timeline itself.
+	dryRun  bool
+	elapsed time.Duration
+}
+
+// NewRecorder returns a Recorder for the boot command of a build, which
+// records to the log and, when PACKER_BOOT_COMMAND_LOG is set, appends to that
+// file too. The name, such as the name of the VM, tells the boot commands in
+// the file apart.
+func NewRecorder(name string) (*Recorder, error) {
+	r := &Recorder{name: name, start: time.Now()}
+	if path := os.Getenv(BootCommandLogEnv); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		r.w, r.closer = f, f
+	}
+	return r, nil
+}
+
+// NewDryRunRecorder returns a Recorder that writes the timeline to w, for a
+// dry run of the boot command.
+func NewDryRunRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w, dryRun: true}
+}
+
+// Close closes the file the timeline is appended to, if any.
+func (r *Recorder) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// Driver returns a driver that records the boot command and then types it
+// with d. The codes d sends to the VM are recorded too when d was returned by
+// NewPCXTDriver, NewVNCDriver or NewVNCScreenDriver. In a dry run, d neither
+// sends them nor waits.
+func (r *Recorder) Driver(d BCDriver) BCDriver {
+	switch d := d.(type) {
+	case *pcXTDriver:
+		send := d.sendImpl
+		d.sendImpl = func(codes []string) error {
+			r.record("codes", "%s", strings.Join(codes, " "))
+			if r.dryRun {
+				return nil
+			}
+			return send(codes)
+		}
+		if r.dryRun {
+			d.sleep = r.sleep
+		}
+	case *vncDriver:
+		r.recordVNC(d)
+	case *vncScreenDriver:
+		r.recordVNC(d.vncDriver)
+	}
+	return &recordingDriver{r: r, d: d}
+}
+
+func (r *Recorder) recordVNC(d *vncDriver) {
+	d.c = &recordingKeyEvents{r: r, c: d.c}
+	if r.dryRun {
+		d.sleep = r.sleep
+	}
+}
+
+// sleep only moves the time of a dry run on.
+func (r *Recorder) sleep(d time.Duration) {
+	r.elapsed += d
+}
+
+func (r *Recorder) now() time.Duration {
+	if r.dryRun {
+		return r.elapsed
+	}
+	return time.Since(r.start)
+}
+
+func (r *Recorder) record(event, format string, args ...interface{}) {
+	line := fmt.Sprintf("%9.3fs  %-6s %s", r.now().Seconds(), event, fmt.Sprintf(format, args...))
+	if !r.dryRun {
+		log.Printf("[INFO] Boot command: %s", line)
+	}
+	if r.w == nil {
+		return
+	}
+	if r.name != "" {
+		line = r.name + " " + line
+	}
+	fmt.Fprintln(r.w, line)
+}
+
+// recordingDriver records the keys and the waits of the boot command before
+// passing them on to the driver.
+type recordingDriver struct {
+	r *Recorder
+	d BCDriver
+}
+
+func (d *recordingDriver) SendKey(key rune, action KeyAction) error {
+	if action == KeyPress {
+		d.r.record("key", "%q", key)
+	} else {
+		d.r.record("key", "<%c%s>", key, action)
+	}
+	return d.d.SendKey(key, action)
+}
+
+func (d *recordingDriver) SendSpecial(special string, action KeyAction) error {
+	if action == KeyPress {
+		d.r.record("key", "<%s>", special)
+	} else {
+		d.r.record("key", "<%s%s>", special, action)
+	}
+	return d.d.SendSpecial(special, action)
+}
+
+func (d *recordingDriver) Flush() error {
+	return d.d.Flush()
+}
+
+func (d *recordingDriver) wait(ctx context.Context, t time.Duration) error {
+	d.r.record("wait", "%s", t)
+	if d.r.dryRun {
+		d.r.sleep(t)
+		return ctx.Err()
+	}
+	return wait(ctx, t)
+}
+
+func (d *recordingDriver) waitScreen(ctx context.Context, w *waitScreenExpression) error {
+	d.r.record("screen", "waiting up to %s for %s", w.timeout, w.path)
+	if d.r.dryRun {
+		return ctx.Err()
+	}
+	if err := w.wait(ctx, d.d); err != nil {
+		d.r.record("error", "%s", err)
+		return err
+	}
+	d.r.record("screen", "matches %s", w.path)
+	return nil
+}
+
+// recordingKeyEvents records the key events sent over VNC.
+type recordingKeyEvents struct {
+	r *Recorder
+	c VNCKeyEvent
+}
+
+func (k *recordingKeyEvents) KeyEvent(key uint32, down bool) error {
+	if down {
+		k.r.record("keysym", "0x%X down", key)
+	} else {
+		k.r.record("keysym", "0x%X up", key)
+	}
+	if k.r.dryRun {
+		return nil
+	}
+	return k.c.KeyEvent(key, down)
+}
+
+// DryRun does the boot command with d in a dry run, writing the timeline of
+// what the VM would get to w. d is a driver of the kind the builder types the
+// boot command with, created without a VM.
+func DryRun(ctx context.Context, command string, d BCDriver, w io.Writer) error {
+	seq, err := GenerateExpressionSequence(command)
+	if err != nil {
+		return err
+	}
+	return seq.Do(ctx, NewDryRunRecorder(w).Driver(d))
+}
@@ -0,0 +1,94 @@
+package bootcommand
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRun_pcXT(t *testing.T) {
+	var buf bytes.Buffer
+	d := NewPCXTDriver(nil, -1, 100*time.Millisecond)
+	err := DryRun(context.Background(), `a<wait5><enter><waitScreen "test-fixtures/menu.png">`, d, &buf)
+	assert.NoError(t, err)
+
+	expected := []string{
+		`    0.000s  key    'a'`,
+		`    0.000s  codes  1e 9e`,
+		`    0.100s  wait   5s`,
+		`    5.100s  key    <enter>`,
+		`    5.100s  codes  1c 9c`,
+		`    5.200s  screen waiting up to 5m0s for test-fixtures/menu.png`,
+		``,
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), buf.String())
+}
+
+func TestDryRun_vnc(t *testing.T) {
+	var buf bytes.Buffer
+	d := NewVNCDriver(nil, 100*time.Millisecond)
+	err := DryRun(context.Background(), "A<leftCtrlOn>", d, &buf)
+	assert.NoError(t, err)
+
+	expected := []string{
+		`    0.000s  key    'A'`,
+		`    0.000s  keysym 0xFFE1 down`,
+		`    0.100s  keysym 0x41 down`,
+		`    0.200s  keysym 0x41 up`,
+		`    0.300s  keysym 0xFFE1 up`,
+		`    0.400s  key    <leftctrlOn>`,
+		`    0.400s  keysym 0xFFE3 down`,
+		``,
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), buf.String())
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "boot.log")
+	os.Setenv(BootCommandLogEnv, path)
+	defer os.Unsetenv(BootCommandLogEnv)
+
+	r, err := NewRecorder("packer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []string
+	d := NewPCXTDriver(func(codes []string) error {
+		sent = append(sent, codes...)
+		return nil
+	}, -1, time.Millisecond)
+	seq, err := GenerateExpressionSequence("<bs><waitScreen \"test-fixtures/menu.png\">")
+	assert.NoError(t, err)
+	err = seq.Do(context.Background(), r.Driver(d))
+	assert.Error(t, err)
+	assert.NoError(t, r.Close())
+
+	assert.Equal(t, []string{"0e", "8e"}, sent)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.True(t, strings.HasPrefix(lines[0], "packer-test "))
+		assert.Contains(t, lines[0], "key    <bs>")
+		assert.Contains(t, lines[1], "codes  0e 8e")
+		assert.Contains(t, lines[2], "screen waiting up to 5m0s for test-fixtures/menu.png")
+		assert.Contains(t, lines[3], "error  <waitScreen> can't be used with this builder")
+	}
+}
@@ -0,0 +1,104 @@
+package bootcommand
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"time"
+)
+
+const (
+	// defaultWaitScreenTimeout is how long <waitScreen> waits by default.
+	defaultWaitScreenTimeout = 5 * time.Minute
+
+	// defaultWaitScreenTolerance is the fraction of the pixels that may
+	// differ from the reference image by default, for blinking cursors.
+	defaultWaitScreenTolerance = 0.01
+
+	// waitScreenInterval is how often the screen is captured while waiting.
+	waitScreenInterval = time.Second
+
+	// pixelTolerance is how much the channels of a pixel may differ from the
+	// reference image, out of 0xffff, for pixels to still be the same.
+	pixelTolerance = 0x2000
+)
+
+// screenDiff returns the fraction of the pixels of the region of the
+// screen, or of the whole screen when the region is empty, that differ from
+// the reference image. The reference image is either the size of the region
+// or the size of the screen. A screen that is too small, such as while the
+// VM switches to another resolution, doesn't match at all.
+func screenDiff(screen, ref image.Image, region image.Rectangle) float64 {
+	bounds := screen.Bounds()
+	if region.Empty() {
+		region = bounds
+	}
+	if !region.In(bounds) {
+		return 1
+	}
+
+	var offset image.Point
+	switch refBounds := ref.Bounds(); refBounds.Size() {
+	case region.Size():
+		offset = refBounds.Min.Sub(region.Min)
+	case bounds.Size():
+		offset = refBounds.Min.Sub(bounds.Min)
+	default:
+		return 1
+	}
+
+	differ := 0
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			if pixelsDiffer(screen.At(x, y), ref.At(x+offset.X, y+offset.Y)) {
+				differ++
+			}
+		}
+	}
+	return float64(differ) / float64(region.Dx()*region.Dy())
+}
+
+func pixelsDiffer(a, b color.Color) bool {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	return channelDiff(ar, br) > pixelTolerance ||
+		channelDiff(ag, bg) > pixelTolerance ||
+		channelDiff(ab, bb) > pixelTolerance
+}
+
+func channelDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// loadScreenImage reads a PNG reference image.
+func loadScreenImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Bad reference image: %s", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Bad reference image %s: %s", path, err)
+	}
+	return img, nil
+}
+
+// saveScreenImage saves a captured screen as a PNG image.
+func saveScreenImage(path string, screen image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, screen); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
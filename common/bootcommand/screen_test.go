@@ -0,0 +1,136 @@
+package bootcommand
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testScreen returns a screen of the given size with the pixels of the
+// region set to white.
+func testScreen(width, height int, white image.Rectangle) *image.RGBA {
+	screen := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{0, 0, 0, 0xff}
+			if (image.Point{x, y}).In(white) {
+				c = color.RGBA{0xff, 0xff, 0xff, 0xff}
+			}
+			screen.Set(x, y, c)
+		}
+	}
+	return screen
+}
+
+func Test_screenDiff(t *testing.T) {
+	screen := testScreen(4, 2, image.Rect(0, 0, 2, 1))
+
+	ref, err := loadScreenImage("test-fixtures/menu.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0.0, screenDiff(screen, ref, image.Rectangle{}))
+	assert.Equal(t, 0.0, screenDiff(screen, ref, image.Rect(1, 0, 3, 2)))
+
+	// A reference of the size of the region
+	region := testScreen(2, 1, image.Rect(0, 0, 1, 1))
+	assert.Equal(t, 0.0, screenDiff(screen, region, image.Rect(1, 0, 3, 1)))
+	assert.Equal(t, 0.5, screenDiff(screen, region, image.Rect(2, 0, 4, 1)))
+
+	// Slightly different colors still match
+	dim := testScreen(4, 2, image.Rectangle{})
+	dim.Set(0, 0, color.RGBA{0xf0, 0xf0, 0xf0, 0xff})
+	dim.Set(1, 0, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	assert.Equal(t, 0.125, screenDiff(dim, ref, image.Rectangle{}))
+
+	// Other sizes don't
+	assert.Equal(t, 1.0, screenDiff(testScreen(8, 2, image.Rectangle{}), ref, image.Rectangle{}))
+	assert.Equal(t, 1.0, screenDiff(screen, ref, image.Rect(2, 0, 6, 2)))
+}
+
+// screenDriver is a ScreenDriver that shows the given screens in turn, and
+// then the last one.
+type screenDriver struct {
+	sender
+	screens []image.Image
+}
+
+func (d *screenDriver) Flush() error {
+	return nil
+}
+
+func (d *screenDriver) SendKey(key rune, action KeyAction) error {
+	return nil
+}
+
+func (d *screenDriver) SendSpecial(special string, action KeyAction) error {
+	return nil
+}
+
+func (d *screenDriver) Screen(context.Context) (image.Image, error) {
+	screen := d.screens[0]
+	if len(d.screens) > 1 {
+		d.screens = d.screens[1:]
+	}
+	return screen, nil
+}
+
+func Test_waitScreenExpression(t *testing.T) {
+	d := &screenDriver{screens: []image.Image{
+		testScreen(4, 2, image.Rectangle{}),
+		testScreen(4, 2, image.Rect(0, 0, 2, 1)),
+	}}
+
+	w := &waitScreenExpression{
+		path:    "test-fixtures/menu.png",
+		timeout: time.Minute,
+	}
+	assert.NoError(t, w.Do(context.Background(), d))
+	assert.Len(t, d.screens, 1)
+}
+
+func Test_waitScreenExpression_timeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ref := filepath.Join(dir, "menu.png")
+	if err := saveScreenImage(ref, testScreen(4, 2, image.Rect(0, 0, 4, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &screenDriver{screens: []image.Image{testScreen(4, 2, image.Rectangle{})}}
+	w := &waitScreenExpression{
+		path:      ref,
+		timeout:   time.Millisecond,
+		tolerance: 0.5,
+	}
+	err = w.Do(context.Background(), d)
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Fatalf("bad: %s", err)
+	}
+
+	screen, err := loadScreenImage(filepath.Join(dir, "menu.timeout.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1.0, screenDiff(screen, testScreen(4, 2, image.Rect(0, 0, 4, 2)), image.Rectangle{}))
+}
+
+func Test_waitScreenExpression_noScreen(t *testing.T) {
+	w := &waitScreenExpression{
+		path:    "test-fixtures/menu.png",
+		timeout: time.Minute,
+	}
+	assert.Error(t, w.Do(context.Background(), NewVNCDriver(new(sender), time.Millisecond)))
+}
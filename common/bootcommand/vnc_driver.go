@@ -1,7 +1,11 @@
 package bootcommand
 
 import (
+	"context"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -9,6 +13,7 @@ import (
 	"unicode"
 
 	"github.com/hashicorp/packer/common"
+	"github.com/mitchellh/go-vnc"
 )
 
 const KeyLeftShift uint32 = 0xFFE1
@@ -23,6 +28,8 @@ type vncDriver struct {
 	specialMap map[string]uint32
 	// keyEvent can set this error which will prevent it from continuing
 	err error
+	// sleep waits between the key events
+	sleep func(time.Duration)
 }
 
 func NewVNCDriver(c VNCKeyEvent, interval time.Duration) *vncDriver {
@@ -81,6 +88,7 @@ func NewVNCDriver(c VNCKeyEvent, interval time.Duration) *vncDriver {
 		c:          c,
 		interval:   keyInterval,
 		specialMap: sMap,
+		sleep:      time.Sleep,
 	}
 }
 
@@ -92,7 +100,7 @@ func (d *vncDriver) keyEvent(k uint32, down bool) error {
 		d.err = err
 		return err
 	}
-	time.Sleep(d.interval)
+	d.sleep(d.interval)
 	return nil
 }
 
@@ -149,3 +157,142 @@ func (d *vncDriver) SendSpecial(special string, action KeyAction) error {
 
 	return d.err
 }
+
+const (
+	// VNCServerMessageBuffer is the size to buffer the channel of server
+	// messages given to NewVNCScreenDriver with, so that the connection
+	// doesn't block on the messages the server sends while the driver types.
+	VNCServerMessageBuffer = 64
+
+	// vncScreenTimeout is how long to wait for the screen after requesting
+	// it.
+	vncScreenTimeout = 10 * time.Second
+)
+
+// vncScreenDriver is a vncDriver that captures the screen of the VM too, from
+// the framebuffer updates of the VNC connection.
+type vncScreenDriver struct {
+	*vncDriver
+
+	conn    *vnc.ClientConn
+	updates <-chan vnc.ServerMessage
+	screen  *image.RGBA
+}
+
+// NewVNCScreenDriver returns a ScreenDriver that types over the VNC
+// connection and captures the screen from it. The connection must send its
+// server messages to updates, which only the driver may read. Use it only
+// for boot commands that wait for the screen, as the server keeps sending
+// framebuffer updates once asked.
+func NewVNCScreenDriver(c *vnc.ClientConn, updates <-chan vnc.ServerMessage, interval time.Duration) (*vncScreenDriver, error) {
+	// Ask to be told when the screen is resized too, as installers do when
+	// they switch to a graphical mode
+	encodings := []vnc.Encoding{new(vnc.RawEncoding), new(desktopSizeEncoding)}
+	if err := c.SetEncodings(encodings); err != nil {
+		return nil, err
+	}
+
+	return &vncScreenDriver{
+		vncDriver: NewVNCDriver(c, interval),
+		conn:      c,
+		updates:   updates,
+		screen:    image.NewRGBA(image.Rect(0, 0, int(c.FrameBufferWidth), int(c.FrameBufferHeight))),
+	}, nil
+}
+
+// Screen requests the whole screen from the server, and returns it once the
+// server sent it.
+func (d *vncScreenDriver) Screen(ctx context.Context) (image.Image, error) {
+	d.drain()
+
+	timeout := time.After(vncScreenTimeout)
+	for {
+		size := d.screen.Bounds().Size()
+		if err := d.conn.FramebufferUpdateRequest(false, 0, 0, uint16(size.X), uint16(size.Y)); err != nil {
+			return nil, err
+		}
+
+		var update *vnc.FramebufferUpdateMessage
+		for update == nil {
+			select {
+			case msg := <-d.updates:
+				update, _ = msg.(*vnc.FramebufferUpdateMessage)
+			case <-timeout:
+				return nil, fmt.Errorf("no framebuffer update in %s", vncScreenTimeout)
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		// When the screen was resized, request all of it again
+		if !d.apply(update) {
+			screen := image.NewRGBA(d.screen.Bounds())
+			copy(screen.Pix, d.screen.Pix)
+			return screen, nil
+		}
+	}
+}
+
+// drain applies the framebuffer updates the server sent since the screen was
+// last requested, so that they aren't taken for the answer to the next
+// request. They may still resize the screen.
+func (d *vncScreenDriver) drain() {
+	for {
+		select {
+		case msg := <-d.updates:
+			if update, ok := msg.(*vnc.FramebufferUpdateMessage); ok {
+				d.apply(update)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// apply applies a framebuffer update to the screen, and reports whether the
+// screen was resized.
+func (d *vncScreenDriver) apply(update *vnc.FramebufferUpdateMessage) (resized bool) {
+	for _, rect := range update.Rectangles {
+		switch enc := rect.Enc.(type) {
+		case *desktopSizeEncoding:
+			log.Printf("Screen resized to %dx%d", rect.Width, rect.Height)
+			d.screen = image.NewRGBA(image.Rect(0, 0, int(rect.Width), int(rect.Height)))
+			resized = true
+		case *vnc.RawEncoding:
+			width := int(rect.Width)
+			for i, c := range enc.Colors {
+				d.screen.Set(int(rect.X)+i%width, int(rect.Y)+i/width, d.color(c))
+			}
+		}
+	}
+	return resized
+}
+
+// color converts a color of the pixel format of the connection.
+func (d *vncScreenDriver) color(c vnc.Color) color.RGBA {
+	pf := d.conn.PixelFormat
+	if !pf.TrueColor {
+		// Colors of the color map are 16 bits
+		return color.RGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), 0xff}
+	}
+	return color.RGBA{scaleColor(c.R, pf.RedMax), scaleColor(c.G, pf.GreenMax), scaleColor(c.B, pf.BlueMax), 0xff}
+}
+
+func scaleColor(v, max uint16) uint8 {
+	if max == 0 {
+		return 0
+	}
+	return uint8(uint32(v) * 0xff / uint32(max))
+}
+
+// desktopSizeEncoding is the DesktopSize pseudo-encoding, with which the
+// server tells the size of the screen changed. See RFC 6143 Section 7.8.2.
+type desktopSizeEncoding struct{}
+
+func (*desktopSizeEncoding) Type() int32 {
+	return -223
+}
+
+func (e *desktopSizeEncoding) Read(*vnc.ClientConn, *vnc.Rectangle, io.Reader) (vnc.Encoding, error) {
+	return e, nil
+}
@@ -2,9 +2,15 @@ package bootcommand
 
 import (
 	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/mitchellh/go-vnc"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,3 +56,81 @@ func Test_vncIntervalGiven(t *testing.T) {
 	d := NewVNCDriver(s, time.Duration(5000)*time.Millisecond)
 	assert.Equal(t, d.interval, time.Duration(5000)*time.Millisecond)
 }
+
+// testVNCServer is a fake VNC server with a 2x1 screen, that resizes the
+// screen to 3x1 on the first framebuffer update request and then sends the
+// given pixels, as 0xRRGGBB.
+func testVNCServer(t *testing.T, conn net.Conn, pixels []uint32) {
+	defer conn.Close()
+
+	read := func(n int) {
+		if _, err := io.ReadFull(conn, make([]byte, n)); err != nil {
+			t.Errorf("err: %s", err)
+		}
+	}
+	write := func(data ...interface{}) {
+		for _, v := range data {
+			if err := binary.Write(conn, binary.BigEndian, v); err != nil {
+				t.Errorf("err: %s", err)
+			}
+		}
+	}
+
+	// Handshake with no authentication
+	write([]byte("RFB 003.008\n"))
+	read(12)
+	write(uint8(1), uint8(1))
+	read(1)
+	write(uint32(0))
+	read(1)
+
+	// ServerInit, with a little endian 32 bits true color pixel format
+	write(uint16(2), uint16(1))
+	write(uint8(32), uint8(24), uint8(0), uint8(1))
+	write(uint16(0xff), uint16(0xff), uint16(0xff), uint8(16), uint8(8), uint8(0))
+	write([3]byte{}, uint32(0))
+
+	// SetEncodings
+	read(4 + 4*2)
+
+	// DesktopSize
+	read(10)
+	write(uint8(0), uint8(0), uint16(1))
+	write(uint16(0), uint16(0), uint16(3), uint16(1), int32(-223))
+
+	// Raw
+	read(10)
+	write(uint8(0), uint8(0), uint16(1))
+	write(uint16(0), uint16(0), uint16(3), uint16(1), int32(0))
+	for _, p := range pixels {
+		if err := binary.Write(conn, binary.LittleEndian, p); err != nil {
+			t.Errorf("err: %s", err)
+		}
+	}
+}
+
+func Test_vncScreenDriver(t *testing.T) {
+	client, server := net.Pipe()
+	go testVNCServer(t, server, []uint32{0xff0000, 0x00ff00, 0xffffff})
+
+	updates := make(chan vnc.ServerMessage, VNCServerMessageBuffer)
+	c, err := vnc.Client(client, &vnc.ClientConfig{ServerMessageCh: updates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	d, err := NewVNCScreenDriver(c, updates, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	screen, err := d.Screen(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, image.Rect(0, 0, 3, 1), screen.Bounds())
+	assert.Equal(t, color.RGBA{0xff, 0, 0, 0xff}, screen.At(0, 0))
+	assert.Equal(t, color.RGBA{0, 0xff, 0, 0xff}, screen.At(1, 0))
+	assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, screen.At(2, 0))
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// CDConfig is the configuration of a CD that builders attach to the machine
+// with the given files, such as a kickstart file or a cloud-init seed.
+type CDConfig struct {
+	CDFiles []string `mapstructure:"cd_files"`
+	CDLabel string   `mapstructure:"cd_label"`
+}
+
+func (c *CDConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+	var err error
+
+	if c.CDFiles == nil {
+		c.CDFiles = make([]string, 0)
+	}
+
+	for _, path := range c.CDFiles {
+		if strings.ContainsAny(path, "*?[") {
+			_, err = filepath.Glob(path)
+		} else {
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Bad CD file '%s': %s", path, err))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestCDConfigPrepare(t *testing.T) {
+	c := CDConfig{}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("no CD files should not fail: %v", errs)
+	}
+	if c.CDFiles == nil {
+		t.Fatal("CD files should default to an empty array")
+	}
+
+	c = CDConfig{
+		CDFiles: []string{"cd_config.go", "cd_config.foo", "*.go"},
+	}
+	errs := c.Prepare(nil)
+	if len(errs) != 1 {
+		t.Fatalf("only the missing file should fail: %v", errs)
+	}
+}
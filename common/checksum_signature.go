@@ -0,0 +1,180 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	getter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// The kinds of signatures of checksum files.
+const (
+	SignatureTypeGPG      = "gpg"
+	SignatureTypeMinisign = "minisign"
+)
+
+// ChecksumSignature is a detached signature of a checksum file, along with
+// the keys that are trusted to sign it.
+type ChecksumSignature struct {
+	// URL is where the signature is downloaded from.
+	URL string
+
+	// Type is SignatureTypeGPG or SignatureTypeMinisign. When it is empty,
+	// it is guessed from the extension of URL.
+	Type string
+
+	// Keyring is the path to a file with the trusted keys: a GPG keyring or
+	// exported keys, or a minisign public key file. Key is a trusted key
+	// itself: an ASCII armored GPG public key, or a minisign public key.
+	Keyring string
+	Key     string
+}
+
+// SignatureType returns the type of the signature, guessing it from the URL
+// when it isn't set.
+func (s *ChecksumSignature) SignatureType() string {
+	if s.Type != "" {
+		return s.Type
+	}
+	if strings.HasSuffix(strings.ToLower(s.URL), ".minisig") {
+		return SignatureTypeMinisign
+	}
+	return SignatureTypeGPG
+}
+
+// Fetch downloads the checksum file and its signature into dir, verifies
+// the signature, and returns the path to the checksum file. The checksum
+// file must not be used unless this succeeds.
+func (s *ChecksumSignature) Fetch(ctx context.Context, checksumURL, dir string) (string, error) {
+	wd, _ := os.Getwd()
+
+	checksumPath := filepath.Join(dir, "checksums")
+	signaturePath := filepath.Join(dir, "checksums.sig")
+	for src, dst := range map[string]string{checksumURL: checksumPath, s.URL: signaturePath} {
+		gc := getter.Client{
+			Ctx:  ctx,
+			Src:  src,
+			Dst:  dst,
+			Pwd:  wd,
+			Mode: getter.ClientModeFile,
+			// Copy local files so that they can't change once verified
+			Getters: signatureGetters(),
+		}
+		if err := gc.Get(); err != nil {
+			return "", fmt.Errorf("Error downloading %s: %s", src, err)
+		}
+	}
+
+	if err := s.Verify(ctx, checksumPath, signaturePath); err != nil {
+		return "", err
+	}
+	return checksumPath, nil
+}
+
+func signatureGetters() map[string]getter.Getter {
+	getters := make(map[string]getter.Getter, len(getter.Getters))
+	for scheme, g := range getter.Getters {
+		getters[scheme] = g
+	}
+	getters["file"] = &getter.FileGetter{Copy: true}
+	return getters
+}
+
+// Verify verifies the signature of the file at path with the trusted keys.
+func (s *ChecksumSignature) Verify(ctx context.Context, path, signaturePath string) error {
+	switch t := s.SignatureType(); t {
+	case SignatureTypeGPG:
+		return s.verifyGPG(ctx, path, signaturePath)
+	case SignatureTypeMinisign:
+		return s.verifyMinisign(ctx, path, signaturePath)
+	default:
+		return fmt.Errorf("Unknown signature type: %s", t)
+	}
+}
+
+// verifyGPG imports the trusted keys into an empty, temporary keyring and
+// requires gpg to report a valid signature made by one of them.
+func (s *ChecksumSignature) verifyGPG(ctx context.Context, path, signaturePath string) error {
+	home, err := tmp.Dir("packer-gpg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+
+	keys := s.Keyring
+	if s.Key != "" {
+		keys = filepath.Join(home, "key.asc")
+		if err := ioutil.WriteFile(keys, []byte(s.Key), 0600); err != nil {
+			return err
+		}
+	}
+
+	if _, err := runSignatureTool(ctx, "gpg", "--batch", "--homedir", home, "--import", keys); err != nil {
+		return fmt.Errorf("Error importing the trusted keys: %s", err)
+	}
+
+	status, err := runSignatureTool(ctx, "gpg", "--batch", "--homedir", home,
+		"--status-fd", "1", "--verify", signaturePath, path)
+
+	valid := false
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "[GNUPG:]" {
+			continue
+		}
+		switch fields[1] {
+		case "VALIDSIG":
+			valid = true
+		case "BADSIG", "ERRSIG", "NO_PUBKEY", "EXPSIG", "EXPKEYSIG", "REVKEYSIG":
+			return fmt.Errorf("Bad signature of the checksum file: %s",
+				strings.Join(fields[1:], " "))
+		}
+	}
+	if err != nil || !valid {
+		return fmt.Errorf("Signature of the checksum file couldn't be verified: %v", err)
+	}
+
+	return nil
+}
+
+func (s *ChecksumSignature) verifyMinisign(ctx context.Context, path, signaturePath string) error {
+	args := []string{"-V", "-q", "-m", path, "-x", signaturePath}
+	if s.Key != "" {
+		args = append(args, "-P", strings.TrimSpace(s.Key))
+	} else {
+		args = append(args, "-p", s.Keyring)
+	}
+
+	if _, err := runSignatureTool(ctx, "minisign", args...); err != nil {
+		return fmt.Errorf("Bad signature of the checksum file: %s", err)
+	}
+	return nil
+}
+
+// runSignatureTool runs gpg or minisign, returning its output, and its error
+// output within the error when it fails.
+func runSignatureTool(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is required to verify the signature, but it isn't installed", name)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	log.Printf("Executing %s: %#v", name, args)
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// testGPGKey generates a signing key in a temporary home directory, and
+// returns the directory and the armored public key.
+func testGPGKey(t *testing.T) (string, string) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is required")
+	}
+
+	home := createTempDir(t)
+	gpg(t, home, "--passphrase", "", "--quick-gen-key",
+		"Packer Test <test@example.com>", "ed25519", "sign", "never")
+	return home, gpg(t, home, "--armor", "--export")
+}
+
+func gpg(t *testing.T, home string, args ...string) string {
+	args = append([]string{"--batch", "--homedir", home}, args...)
+	out, err := exec.Command("gpg", args...).Output()
+	if err != nil {
+		t.Fatalf("gpg %v: %s", args, err)
+	}
+	return string(out)
+}
+
+// testSignedChecksums writes a checksum file for the file and signs it.
+func testSignedChecksums(t *testing.T, home, dir, file string) (string, string) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sum := sha256.Sum256(contents)
+
+	checksums := filepath.Join(dir, "SHA256SUMS")
+	data := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(file))
+	if err := ioutil.WriteFile(checksums, []byte(data), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	signature := checksums + ".gpg"
+	gpg(t, home, "--detach-sign", "--output", signature, checksums)
+	return checksums, signature
+}
+
+func TestChecksumSignature_gpg(t *testing.T) {
+	home, key := testGPGKey(t)
+	defer os.RemoveAll(home)
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	checksums, signature := testSignedChecksums(t, home, dir, "./test-fixtures/root/basic.txt")
+
+	s := &ChecksumSignature{URL: signature, Key: key}
+	if s.SignatureType() != SignatureTypeGPG {
+		t.Fatalf("bad: %s", s.SignatureType())
+	}
+	if err := s.Verify(context.Background(), checksums, signature); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Keyring files work too
+	keyring := filepath.Join(dir, "keyring.asc")
+	if err := ioutil.WriteFile(keyring, []byte(key), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s = &ChecksumSignature{URL: signature, Keyring: keyring}
+	if err := s.Verify(context.Background(), checksums, signature); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A changed checksum file fails
+	if err := ioutil.WriteFile(checksums, []byte("0000  basic.txt\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.Verify(context.Background(), checksums, signature); err == nil {
+		t.Fatal("should fail")
+	}
+}
+
+func TestChecksumSignature_gpgUntrustedKey(t *testing.T) {
+	home, _ := testGPGKey(t)
+	defer os.RemoveAll(home)
+	otherHome, otherKey := testGPGKey(t)
+	defer os.RemoveAll(otherHome)
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	checksums, signature := testSignedChecksums(t, home, dir, "./test-fixtures/root/basic.txt")
+
+	s := &ChecksumSignature{URL: signature, Key: otherKey}
+	if err := s.Verify(context.Background(), checksums, signature); err == nil {
+		t.Fatal("should fail")
+	}
+}
+
+func TestChecksumSignature_type(t *testing.T) {
+	cases := map[string]string{
+		"http://example.com/SHA256SUMS.gpg":     SignatureTypeGPG,
+		"http://example.com/SHA256SUMS.sig":     SignatureTypeGPG,
+		"http://example.com/SHA256SUMS.minisig": SignatureTypeMinisign,
+	}
+	for url, expected := range cases {
+		s := &ChecksumSignature{URL: url}
+		if actual := s.SignatureType(); actual != expected {
+			t.Fatalf("%s: bad: %s", url, actual)
+		}
+	}
+
+	s := &ChecksumSignature{URL: "http://example.com/SHA256SUMS.sig", Type: SignatureTypeMinisign}
+	if s.SignatureType() != SignatureTypeMinisign {
+		t.Fatalf("bad: %s", s.SignatureType())
+	}
+}
+
+func TestStepDownload_checksumSignature(t *testing.T) {
+	home, key := testGPGKey(t)
+	defer os.RemoveAll(home)
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	cacheDir := createTempDir(t)
+	defer os.RemoveAll(cacheDir)
+	defer os.Setenv("PACKER_CACHE_DIR", os.Getenv("PACKER_CACHE_DIR"))
+	os.Setenv("PACKER_CACHE_DIR", cacheDir)
+
+	checksums, signature := testSignedChecksums(t, home, dir, "./test-fixtures/root/basic.txt")
+
+	s := &StepDownload{
+		Checksum:          checksums,
+		ChecksumType:      "file",
+		ChecksumSignature: &ChecksumSignature{URL: signature, Key: key},
+		Description:       "ISO",
+		ResultKey:         "iso_path",
+		Url:               []string{abs(t, "./test-fixtures/root/basic.txt")},
+	}
+	state := testState(t)
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %s", action, state.Get("error"))
+	}
+
+	// With a changed checksum file, nothing is downloaded
+	os.RemoveAll(cacheDir)
+	os.MkdirAll(cacheDir, 0755)
+	data := "f572d396fae9206628714fb2ce00f72e94f2258f  basic.txt\n"
+	if err := ioutil.WriteFile(checksums, []byte(data), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state = testState(t)
+	if action := s.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if files := listFiles(t, cacheDir); len(files) != 0 {
+		t.Fatalf("bad: %#v", files)
+	}
+}
@@ -1,3 +1,6 @@
+// Package chroot contains the steps and the communicator that the chroot
+// builders share: mounting additional filesystems, copying files from the
+// host and provisioning within a chroot of a mounted root filesystem.
 package chroot
 
 import (
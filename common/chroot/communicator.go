@@ -25,9 +25,8 @@ type Communicator struct {
 
 func (c *Communicator) Start(cmd *packer.RemoteCmd) error {
 	// need extra escapes for the command since we're wrapping it in quotes
-	cmd.Command = strconv.Quote(cmd.Command)
 	command, err := c.CmdWrapper(
-		fmt.Sprintf("chroot %s /bin/sh -c %s", c.Chroot, cmd.Command))
+		fmt.Sprintf("chroot %s /bin/sh -c %s", c.Chroot, strconv.Quote(cmd.Command)))
 	if err != nil {
 		return err
 	}
@@ -67,17 +66,19 @@ func (c *Communicator) Start(cmd *packer.RemoteCmd) error {
 func (c *Communicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
 	dst = filepath.Join(c.Chroot, dst)
 	log.Printf("Uploading to chroot dir: %s", dst)
-	tf, err := tmp.File("packer-amazon-chroot")
+	tf, err := tmp.File("packer-chroot")
 	if err != nil {
 		return fmt.Errorf("Error preparing shell script: %s", err)
 	}
 	defer os.Remove(tf.Name())
 
 	if _, err := io.Copy(tf, r); err != nil {
+		tf.Close()
 		return err
 	}
+	tf.Close()
 
-	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp %s %s", tf.Name(), dst))
+	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp '%s' '%s'", tf.Name(), dst))
 	if err != nil {
 		return err
 	}
@@ -86,6 +87,12 @@ func (c *Communicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
 }
 
 func (c *Communicator) UploadDir(dst string, src string, exclude []string) error {
+	src, cleanup, err := packer.StageDir(src, exclude)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	// If src ends with a trailing "/", copy from "src/." so that
 	// directory contents (including hidden files) are copied, but the
 	// directory "src" is omitted.  BSD does this automatically when
@@ -94,35 +101,30 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 		src = src + "."
 	}
 
-	// TODO: remove any file copied if it appears in `exclude`
 	chrootDest := filepath.Join(c.Chroot, dst)
 
 	log.Printf("Uploading directory '%s' to '%s'", src, chrootDest)
-	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp -R '%s' %s", src, chrootDest))
-	if err != nil {
-		return err
+	return c.copyDir(src, chrootDest)
+}
+
+func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
+	chrootSrc := filepath.Join(c.Chroot, src)
+	if src[len(src)-1] == '/' {
+		chrootSrc = chrootSrc + "/"
 	}
 
-	var stderr bytes.Buffer
-	cmd := ShellCommand(cpCmd)
-	cmd.Env = append(cmd.Env, "LANG=C")
-	cmd.Env = append(cmd.Env, os.Environ()...)
-	cmd.Stderr = &stderr
-	err = cmd.Run()
-	if err == nil {
+	chrootSrc, cleanup, err := packer.StageDir(chrootSrc, exclude)
+	if err != nil {
 		return err
 	}
+	defer cleanup()
 
-	if strings.Contains(stderr.String(), "No such file") {
-		// This just means that the directory was empty. Just ignore it.
-		return nil
+	if chrootSrc[len(chrootSrc)-1] == '/' {
+		chrootSrc = chrootSrc + "."
 	}
 
-	return err
-}
-
-func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
-	return fmt.Errorf("DownloadDir is not implemented for amazon-chroot")
+	log.Printf("Downloading directory '%s' to '%s'", chrootSrc, dst)
+	return c.copyDir(chrootSrc, dst)
 }
 
 func (c *Communicator) Download(src string, w io.Writer) error {
@@ -140,3 +142,27 @@ func (c *Communicator) Download(src string, w io.Writer) error {
 
 	return nil
 }
+
+func (c *Communicator) copyDir(src, dst string) error {
+	cpCmd, err := c.CmdWrapper(fmt.Sprintf("cp -R '%s' '%s'", src, dst))
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := ShellCommand(cpCmd)
+	cmd.Env = append(cmd.Env, "LANG=C")
+	cmd.Env = append(cmd.Env, os.Environ()...)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err == nil {
+		return err
+	}
+
+	if strings.Contains(stderr.String(), "No such file") {
+		// This just means that the directory was empty. Just ignore it.
+		return nil
+	}
+
+	return fmt.Errorf("Error copying %s: %s\nStderr: %s", src, err, stderr.String())
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
+// RunLocalCommands interpolates each of the commands with ctx and runs it
+// on the host through the command wrapper.
 func RunLocalCommands(commands []string, wrappedCommand CommandWrapper, ctx interpolate.Context, ui packer.Ui) error {
 	for _, rawCmd := range commands {
 		intCmd, err := interpolate.Render(rawCmd, &ctx)
@@ -0,0 +1,161 @@
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// StepCopyFiles copies some files from the host into the chroot environment,
+// such as /etc/resolv.conf so that provisioners can resolve names. The files
+// they replace are put back when cleaning up, along with the time of their
+// directory unless the provisioners changed it, so that the copies leave no
+// trace in the image.
+//
+// Produces:
+//   copy_files_cleanup CleanupFunc - A function to clean up the copied files
+//   early.
+type StepCopyFiles struct {
+	Files []string
+
+	files     []copiedFile
+	backupDir string
+}
+
+type copiedFile struct {
+	path string
+
+	// backup is where the file the copy replaced is kept, if there was one
+	backup string
+
+	// dirTime is the modification time of the directory of the file before
+	// the copy, and copiedTime the one after it
+	dirTime    time.Time
+	copiedTime time.Time
+}
+
+func (s *StepCopyFiles) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	mountPath := state.Get("mount_path").(string)
+	ui := state.Get("ui").(packer.Ui)
+	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
+
+	halt := func(err error) multistep.StepAction {
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	s.files = make([]copiedFile, 0, len(s.Files))
+	if len(s.Files) > 0 {
+		ui.Say("Copying files from host to chroot...")
+		var err error
+		s.backupDir, err = tmp.Dir("packer-chroot-copy-files")
+		if err != nil {
+			return halt(fmt.Errorf("Error creating backup directory: %s", err))
+		}
+
+		for i, path := range s.Files {
+			ui.Message(path)
+			chrootPath := filepath.Join(mountPath, path)
+			log.Printf("Copying '%s' to '%s'", path, chrootPath)
+
+			copied := copiedFile{path: chrootPath}
+			if info, err := os.Stat(filepath.Dir(chrootPath)); err == nil {
+				copied.dirTime = info.ModTime()
+			}
+			if _, err := os.Lstat(chrootPath); err == nil {
+				copied.backup = filepath.Join(s.backupDir, strconv.Itoa(i))
+				if err := runWrapped(wrappedCommand, "cp -a '%s' '%s'", chrootPath, copied.backup); err != nil {
+					return halt(fmt.Errorf("Error keeping %s: %s", path, err))
+				}
+			}
+			s.files = append(s.files, copied)
+
+			if err := runWrapped(wrappedCommand, "cp --remove-destination '%s' '%s'", path, chrootPath); err != nil {
+				return halt(fmt.Errorf("Error copying file: %s", err))
+			}
+			if info, err := os.Stat(filepath.Dir(chrootPath)); err == nil {
+				s.files[i].copiedTime = info.ModTime()
+			}
+		}
+	}
+
+	state.Put("copy_files_cleanup", s)
+	return multistep.ActionContinue
+}
+
+func (s *StepCopyFiles) Cleanup(state multistep.StateBag) {
+	ui := state.Get("ui").(packer.Ui)
+	if err := s.CleanupFunc(state); err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+func (s *StepCopyFiles) CleanupFunc(state multistep.StateBag) error {
+	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
+	for len(s.files) > 0 {
+		last := len(s.files) - 1
+		file := s.files[last]
+
+		// Changes to the directory since the copy are the provisioners'
+		restoreTime := false
+		if info, err := os.Stat(filepath.Dir(file.path)); err == nil && !file.dirTime.IsZero() {
+			restoreTime = info.ModTime().Equal(file.copiedTime)
+		}
+
+		log.Printf("Removing: %s", file.path)
+		if err := runWrapped(wrappedCommand, "rm -f '%s'", file.path); err != nil {
+			return err
+		}
+
+		if file.backup != "" {
+			log.Printf("Restoring: %s", file.path)
+			if err := runWrapped(wrappedCommand, "cp -a '%s' '%s'", file.backup, file.path); err != nil {
+				return err
+			}
+		}
+
+		if restoreTime {
+			if err := runWrapped(wrappedCommand, "touch -c -m -d @%d.%09d '%s'",
+				file.dirTime.Unix(), file.dirTime.Nanosecond(), filepath.Dir(file.path)); err != nil {
+				return err
+			}
+		}
+
+		s.files = s.files[:last]
+	}
+	s.files = nil
+
+	if s.backupDir != "" {
+		if err := runWrapped(wrappedCommand, "rm -rf '%s'", s.backupDir); err != nil {
+			return err
+		}
+		s.backupDir = ""
+	}
+	return nil
+}
+
+// runWrapped runs a command on the host through the command wrapper.
+func runWrapped(wrappedCommand CommandWrapper, format string, args ...interface{}) error {
+	cmdText, err := wrappedCommand(fmt.Sprintf(format, args...))
+	if err != nil {
+		return fmt.Errorf("Error building command: %s", err)
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := ShellCommand(cmdText)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestCopyFilesCleanupFunc_ImplementsCleanupFunc(t *testing.T) {
+	var raw interface{}
+	raw = new(StepCopyFiles)
+	if _, ok := raw.(Cleanup); !ok {
+		t.Fatalf("cleanup func should be a CleanupFunc")
+	}
+}
+
+func testState(t *testing.T) multistep.StateBag {
+	state := new(multistep.BasicStateBag)
+	state.Put("hook", &packer.MockHook{})
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+	state.Put("wrappedCommand", CommandWrapper(func(command string) (string, error) {
+		return command, nil
+	}))
+	return state
+}
+
+func TestStepCopyFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// One host file replaces a file of the chroot, the other one is new
+	hostDir := filepath.Join(dir, "host")
+	os.MkdirAll(hostDir, 0755)
+	replaced := filepath.Join(hostDir, "resolv.conf")
+	added := filepath.Join(hostDir, "hosts")
+	ioutil.WriteFile(replaced, []byte("host"), 0644)
+	ioutil.WriteFile(added, []byte("host"), 0644)
+
+	mountPath := filepath.Join(dir, "chroot")
+	chrootDir := filepath.Join(mountPath, hostDir)
+	os.MkdirAll(chrootDir, 0755)
+	ioutil.WriteFile(filepath.Join(mountPath, replaced), []byte("chroot"), 0600)
+	dirTime := time.Unix(1500000000, 123456789)
+	os.Chtimes(chrootDir, dirTime, dirTime)
+
+	state := testState(t)
+	state.Put("mount_path", mountPath)
+
+	step := &StepCopyFiles{Files: []string{replaced, added}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v %s", action, state.Get("error"))
+	}
+	for _, path := range step.Files {
+		data, err := ioutil.ReadFile(filepath.Join(mountPath, path))
+		if err != nil || string(data) != "host" {
+			t.Fatalf("%s should be copied: %q %s", path, data, err)
+		}
+	}
+
+	if err := step.CleanupFunc(state); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	info, err := os.Stat(filepath.Join(mountPath, replaced))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("the replaced file should be restored: %#v %s", info, err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(mountPath, replaced)); string(data) != "chroot" {
+		t.Fatalf("bad: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(mountPath, added)); !os.IsNotExist(err) {
+		t.Fatal("the added file should be removed")
+	}
+	if info, err := os.Stat(chrootDir); err != nil || !info.ModTime().Equal(dirTime) {
+		t.Fatalf("the time of the directory should be restored: %#v %s", info, err)
+	}
+	if step.backupDir != "" {
+		t.Fatal("the backup directory should be removed")
+	}
+}
+
+func TestStepCopyFiles_provisionedDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-chroot")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	host := filepath.Join(dir, "resolv.conf")
+	ioutil.WriteFile(host, []byte("host"), 0644)
+	mountPath := filepath.Join(dir, "chroot")
+	chrootDir := filepath.Join(mountPath, dir)
+	os.MkdirAll(chrootDir, 0755)
+	dirTime := time.Unix(1500000000, 0)
+	os.Chtimes(chrootDir, dirTime, dirTime)
+
+	state := testState(t)
+	state.Put("mount_path", mountPath)
+
+	step := &StepCopyFiles{Files: []string{host}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v %s", action, state.Get("error"))
+	}
+
+	// A provisioner adds a file next to the copy
+	provisioned := time.Unix(1600000000, 0)
+	ioutil.WriteFile(filepath.Join(chrootDir, "hosts"), []byte("hosts"), 0644)
+	os.Chtimes(chrootDir, provisioned, provisioned)
+
+	if err := step.CleanupFunc(state); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if info, err := os.Stat(chrootDir); err != nil || info.ModTime().Equal(dirTime) {
+		t.Fatalf("the time of a provisioned directory should be kept: %#v %s", info, err)
+	}
+}
@@ -10,7 +10,8 @@ import (
 )
 
 // StepEarlyCleanup performs some of the cleanup steps early in order to
-// prepare for snapshotting and creating an AMI.
+// prepare for snapshotting the device or committing the changes. The cleanup
+// funcs of the steps the builder doesn't run are skipped.
 type StepEarlyCleanup struct{}
 
 func (s *StepEarlyCleanup) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -23,7 +24,11 @@ func (s *StepEarlyCleanup) Run(_ context.Context, state multistep.StateBag) mult
 	}
 
 	for _, key := range cleanupKeys {
-		c := state.Get(key).(Cleanup)
+		raw, ok := state.GetOk(key)
+		if !ok {
+			continue
+		}
+		c := raw.(Cleanup)
 		log.Printf("Running cleanup func: %s", key)
 		if err := c.CleanupFunc(state); err != nil {
 			err := fmt.Errorf("Error cleaning up: %s", err)
@@ -12,24 +12,27 @@ import (
 	"github.com/hashicorp/packer/packer"
 )
 
-// StepMountExtra mounts the attached device.
+// StepMountExtra mounts additional paths within the chroot, such as /proc
+// and /dev. Each of the ChrootMounts is a filesystem type, a source and a
+// path within the chroot.
 //
 // Produces:
 //   mount_extra_cleanup CleanupFunc - To perform early cleanup
 type StepMountExtra struct {
+	ChrootMounts [][]string
+
 	mounts []string
 }
 
 func (s *StepMountExtra) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	config := state.Get("config").(*Config)
 	mountPath := state.Get("mount_path").(string)
 	ui := state.Get("ui").(packer.Ui)
 	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
 
-	s.mounts = make([]string, 0, len(config.ChrootMounts))
+	s.mounts = make([]string, 0, len(s.ChrootMounts))
 
 	ui.Say("Mounting additional paths within the chroot...")
-	for _, mountInfo := range config.ChrootMounts {
+	for _, mountInfo := range s.ChrootMounts {
 		innerPath := mountPath + mountInfo[2]
 
 		if err := os.MkdirAll(innerPath, 0755); err != nil {
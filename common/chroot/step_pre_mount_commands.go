@@ -5,19 +5,21 @@ import (
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
 )
 
 type preMountCommandsData struct {
 	Device string
 }
 
-// StepPreMountCommands sets up the a new block device when building from scratch
+// StepPreMountCommands runs commands on the host before the device is
+// mounted, such as to partition and format a new block device.
 type StepPreMountCommands struct {
 	Commands []string
+	Ctx      interpolate.Context
 }
 
 func (s *StepPreMountCommands) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	config := state.Get("config").(*Config)
 	device := state.Get("device").(string)
 	ui := state.Get("ui").(packer.Ui)
 	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
@@ -26,7 +28,7 @@ func (s *StepPreMountCommands) Run(_ context.Context, state multistep.StateBag)
 		return multistep.ActionContinue
 	}
 
-	ctx := config.ctx
+	ctx := s.Ctx
 	ctx.Data = &preMountCommandsData{Device: device}
 
 	ui.Say("Running device setup commands...")
@@ -0,0 +1,492 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	getter "github.com/hashicorp/go-getter"
+	"github.com/hashicorp/packer/packer"
+)
+
+// DefaultSpeedWindow is how long the throughput of a download is measured
+// before it is compared to the minimum speed.
+const DefaultSpeedWindow = 30 * time.Second
+
+// SlowDownloadError is returned when a download is abandoned because its
+// throughput went below the minimum speed.
+type SlowDownloadError struct {
+	// Speed is the throughput in bytes per second of the last window, and
+	// MinSpeed is the minimum it had to reach.
+	Speed    int64
+	MinSpeed int64
+}
+
+func (e *SlowDownloadError) Error() string {
+	return fmt.Sprintf("download too slow: %s/s, below the minimum of %s/s",
+		packer.FormatSize(e.Speed), packer.FormatSize(e.MinSpeed))
+}
+
+// httpRangeGetter is a getter for HTTP URLs that downloads into a ".part"
+// file next to the destination, so that an interrupted download is resumed
+// with Range requests the next time, even from another mirror. When the
+// server supports ranges, the file can be fetched as several ranges at the
+// same time; which ranges are done is kept in a ".part.ranges" file. The
+// destination is only written once the download is complete, and go-getter
+// checks its checksum afterwards.
+type httpRangeGetter struct {
+	getter.HttpGetter
+
+	// Ranges is how many ranges of the file are downloaded at the same
+	// time. Servers that don't support ranges get a single request.
+	Ranges int
+
+	// MinSpeed is the throughput in bytes per second under which the
+	// download is abandoned with a SlowDownloadError, measured every
+	// SpeedWindow. Zero disables it.
+	MinSpeed    int64
+	SpeedWindow time.Duration
+
+	// Speed is the average throughput of the last download in bytes per
+	// second.
+	Speed int64
+
+	client *getter.Client
+}
+
+func (g *httpRangeGetter) SetClient(c *getter.Client) {
+	g.client = c
+	g.HttpGetter.SetClient(c)
+}
+
+// downloadRange is a part of the file that is downloaded by one request.
+// End is exclusive, and -1 when the size of the file isn't known. Done is
+// the number of bytes from Start that are written.
+type downloadRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"`
+}
+
+// rangeState is the content of the ".part.ranges" file.
+type rangeState struct {
+	Size   int64            `json:"size"`
+	Ranges []*downloadRange `json:"ranges"`
+}
+
+func (g *httpRangeGetter) GetFile(dst string, src *url.URL) error {
+	ctx := context.Background()
+	if g.client != nil && g.client.Ctx != nil {
+		ctx = g.client.Ctx
+	}
+	client := g.Client
+	if client == nil {
+		client = cleanhttp.DefaultClient()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	part := dst + ".part"
+	stateFile := part + ".ranges"
+
+	size, acceptRanges := g.probe(ctx, client, src)
+	state := g.plan(part, stateFile, size, acceptRanges)
+
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// A download that can't be resumed starts over
+	var resumed int64
+	for _, r := range state.Ranges {
+		resumed += r.Done
+	}
+	if resumed == 0 {
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+	} else {
+		log.Printf("Resuming download of %s at %d of %d bytes", src, resumed, size)
+	}
+
+	// The ranges file has to exist before ranges are written out of order,
+	// so that a part file without it is always a contiguous prefix.
+	if len(state.Ranges) > 1 {
+		if err := writeRangeState(stateFile, state); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var written int64
+	progress := newProgressStream()
+	progressDone := make(chan struct{})
+	var body io.ReadCloser = progress
+	if g.client != nil && g.client.ProgressListener != nil {
+		body = g.client.ProgressListener.TrackProgress(
+			filepath.Base(src.EscapedPath()), resumed, size, progress)
+	}
+	go func() {
+		defer close(progressDone)
+		io.Copy(ioutil.Discard, body)
+	}()
+	defer func() {
+		progress.Close()
+		<-progressDone
+		body.Close()
+	}()
+	add := func(n int64) {
+		atomic.AddInt64(&written, n)
+		progress.add(n)
+	}
+
+	var slowErr error
+	var wg sync.WaitGroup
+	monitorDone := make(chan struct{})
+	if g.MinSpeed > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slowErr = g.monitor(ctx, &written, monitorDone)
+			if slowErr != nil {
+				cancel()
+			}
+		}()
+	}
+
+	// Keep the ranges file up to date while ranges are downloaded
+	if len(state.Ranges) > 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(2 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					writeRangeState(stateFile, state)
+				case <-monitorDone:
+					return
+				}
+			}
+		}()
+	}
+
+	// The first range that fails stops the others
+	start := time.Now()
+	var rangeErr error
+	var errOnce sync.Once
+	var rangeWg sync.WaitGroup
+	for _, r := range state.Ranges {
+		rangeWg.Add(1)
+		go func(r *downloadRange) {
+			defer rangeWg.Done()
+			err := g.fetchRange(ctx, client, src, f, r, len(state.Ranges) == 1, add)
+			if err != nil {
+				errOnce.Do(func() {
+					rangeErr = err
+					cancel()
+				})
+			}
+		}(r)
+	}
+	rangeWg.Wait()
+	close(monitorDone)
+	wg.Wait()
+
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		g.Speed = int64(float64(atomic.LoadInt64(&written)) / elapsed)
+	}
+
+	if slowErr != nil {
+		rangeErr = slowErr
+	}
+	if rangeErr != nil {
+		if len(state.Ranges) > 1 {
+			writeRangeState(stateFile, state)
+		}
+		return rangeErr
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	os.Remove(stateFile)
+	os.Remove(dst)
+	return os.Rename(part, dst)
+}
+
+// probe asks the server for the size of the file and whether it supports
+// ranges. The size is -1 when it isn't known.
+func (g *httpRangeGetter) probe(ctx context.Context, client *http.Client, src *url.URL) (int64, bool) {
+	req, err := http.NewRequest("HEAD", src.String(), nil)
+	if err != nil {
+		return -1, false
+	}
+	req = req.WithContext(ctx)
+	g.setHeader(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return -1, false
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, false
+	}
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		return -1, false
+	}
+	return size, resp.Header.Get("Accept-Ranges") == "bytes"
+}
+
+// plan decides which ranges to download, resuming from the ranges file or
+// the part file when they match the file on the server.
+func (g *httpRangeGetter) plan(part, stateFile string, size int64, acceptRanges bool) *rangeState {
+	if !acceptRanges {
+		os.Remove(stateFile)
+		return &rangeState{Size: size, Ranges: []*downloadRange{{End: size}}}
+	}
+
+	if data, err := ioutil.ReadFile(stateFile); err == nil {
+		var state rangeState
+		if err := json.Unmarshal(data, &state); err == nil && state.Size == size && len(state.Ranges) > 0 {
+			return &state
+		}
+		log.Printf("Ranges of %s don't match the file anymore, starting over", part)
+		os.Remove(stateFile)
+		os.Remove(part)
+	}
+
+	// Without a ranges file, the part file is what a single request wrote
+	var done int64
+	if info, err := os.Stat(part); err == nil && info.Size() <= size {
+		done = info.Size()
+	}
+
+	ranges := g.Ranges
+	if ranges < 1 {
+		ranges = 1
+	}
+	state := &rangeState{Size: size}
+	if done > 0 {
+		state.Ranges = append(state.Ranges, &downloadRange{Start: 0, End: done, Done: done})
+	}
+	remaining := size - done
+	chunk := remaining / int64(ranges)
+	if chunk == 0 {
+		chunk = remaining
+	}
+	for start := done; start < size; start += chunk {
+		end := start + chunk
+		if end > size || size-end < chunk {
+			end = size
+		}
+		state.Ranges = append(state.Ranges, &downloadRange{Start: start, End: end})
+		if end == size {
+			break
+		}
+	}
+
+	// A single request can simply append to the part file
+	if ranges == 1 {
+		state.Ranges = []*downloadRange{{Start: 0, End: size, Done: done}}
+	}
+	return state
+}
+
+// fetchRange downloads what is left of a range into f. When it is the only
+// range and the server ignores the Range header, it starts over.
+func (g *httpRangeGetter) fetchRange(ctx context.Context, client *http.Client, src *url.URL, f *os.File, r *downloadRange, only bool, add func(int64)) error {
+	offset := r.Start + atomic.LoadInt64(&r.Done)
+	if r.End >= 0 && offset >= r.End {
+		return nil
+	}
+
+	req, err := http.NewRequest("GET", src.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	g.setHeader(req)
+	if offset > 0 || (r.End >= 0 && !only) {
+		if r.End >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, r.End-1))
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		if req.Header.Get("Range") != "" {
+			if !only {
+				return fmt.Errorf("server doesn't support ranges anymore")
+			}
+			log.Printf("Server ignored the range request for %s, starting over", src)
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+			atomic.StoreInt64(&r.Done, 0)
+			offset = 0
+		}
+	default:
+		return fmt.Errorf("bad response code: %d", resp.StatusCode)
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := f.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+			atomic.AddInt64(&r.Done, int64(n))
+			add(int64(n))
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+
+	if r.End >= 0 && offset < r.End {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// monitor returns a SlowDownloadError when fewer bytes than MinSpeed allows
+// are written within a window, or nil once done is closed.
+func (g *httpRangeGetter) monitor(ctx context.Context, written *int64, done <-chan struct{}) error {
+	window := g.SpeedWindow
+	if window <= 0 {
+		window = DefaultSpeedWindow
+	}
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	var last int64
+	for {
+		select {
+		case <-ticker.C:
+			current := atomic.LoadInt64(written)
+			speed := int64(float64(current-last) / window.Seconds())
+			last = current
+			if speed < g.MinSpeed {
+				return &SlowDownloadError{Speed: speed, MinSpeed: g.MinSpeed}
+			}
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (g *httpRangeGetter) setHeader(req *http.Request) {
+	for k, v := range g.Header {
+		req.Header[k] = v
+	}
+}
+
+// writeRangeState writes the ranges file atomically. The Done counts are
+// read as they are written, so they never count bytes that aren't in the
+// part file yet.
+func writeRangeState(path string, state *rangeState) error {
+	snapshot := rangeState{Size: state.Size}
+	for _, r := range state.Ranges {
+		snapshot.Ranges = append(snapshot.Ranges, &downloadRange{
+			Start: r.Start,
+			End:   r.End,
+			Done:  atomic.LoadInt64(&r.Done),
+		})
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// progressStream is a reader that lets a ProgressTracker count the bytes
+// that the ranges write: it returns as many bytes as were added, until it
+// is closed.
+type progressStream struct {
+	l       sync.Mutex
+	cond    *sync.Cond
+	pending int64
+	closed  bool
+}
+
+func newProgressStream() *progressStream {
+	s := &progressStream{}
+	s.cond = sync.NewCond(&s.l)
+	return s
+}
+
+func (s *progressStream) add(n int64) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.pending += n
+	s.cond.Signal()
+}
+
+func (s *progressStream) Read(p []byte) (int, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	for s.pending == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.pending == 0 {
+		return 0, io.EOF
+	}
+
+	n := int64(len(p))
+	if n > s.pending {
+		n = s.pending
+	}
+	s.pending -= n
+	return int(n), nil
+}
+
+func (s *progressStream) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+	return nil
+}
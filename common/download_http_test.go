@@ -0,0 +1,281 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+var testDownloadContent = []byte(strings.Repeat("0123456789abcdef", 4096))
+
+// testRangeServer serves testDownloadContent, with range support unless
+// noRanges is set, and records the Range headers of the requests.
+type testRangeServer struct {
+	noRanges bool
+
+	l      sync.Mutex
+	ranges []string
+}
+
+func (s *testRangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		s.l.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.l.Unlock()
+	}
+	if s.noRanges {
+		w.Header().Set("Content-Length", strconv.Itoa(len(testDownloadContent)))
+		if r.Method == "GET" {
+			w.Write(testDownloadContent)
+		}
+		return
+	}
+	http.ServeContent(w, r, "file.iso", time.Time{}, bytes.NewReader(testDownloadContent))
+}
+
+func testRangeGetter(t *testing.T, server http.Handler, g *httpRangeGetter) (string, error) {
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "packer-download")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	dst := filepath.Join(dir, "file.iso")
+
+	u, _ := url.Parse(srv.URL + "/file.iso")
+	return dst, g.GetFile(dst, u)
+}
+
+func TestHttpRangeGetter_resume(t *testing.T) {
+	server := &testRangeServer{}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "packer-download")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "file.iso")
+
+	// A previous download stopped half way
+	half := len(testDownloadContent) / 2
+	if err := ioutil.WriteFile(dst+".part", testDownloadContent[:half], 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	u, _ := url.Parse(srv.URL + "/file.iso")
+	g := &httpRangeGetter{}
+	if err := g.GetFile(dst, u); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(contents, testDownloadContent) {
+		t.Fatal("downloaded file doesn't match")
+	}
+	if len(server.ranges) != 1 || server.ranges[0] != "bytes=32768-65535" {
+		t.Fatalf("bad: %#v", server.ranges)
+	}
+	if _, err := os.Stat(dst + ".part"); !os.IsNotExist(err) {
+		t.Fatal("part file should be gone")
+	}
+}
+
+func TestHttpRangeGetter_parallel(t *testing.T) {
+	server := &testRangeServer{}
+	dst, err := testRangeGetter(t, server, &httpRangeGetter{Ranges: 4})
+	defer os.RemoveAll(filepath.Dir(dst))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(contents, testDownloadContent) {
+		t.Fatal("downloaded file doesn't match")
+	}
+	if len(server.ranges) != 4 {
+		t.Fatalf("bad: %#v", server.ranges)
+	}
+	if _, err := os.Stat(dst + ".part.ranges"); !os.IsNotExist(err) {
+		t.Fatal("ranges file should be gone")
+	}
+}
+
+func TestHttpRangeGetter_parallelResume(t *testing.T) {
+	server := &testRangeServer{}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "packer-download")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "file.iso")
+
+	// The first of two ranges is done, the second one is half done
+	size := int64(len(testDownloadContent))
+	part := make([]byte, size)
+	copy(part[:size/2], testDownloadContent[:size/2])
+	copy(part[size/2:size*3/4], testDownloadContent[size/2:size*3/4])
+	if err := ioutil.WriteFile(dst+".part", part, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state := &rangeState{
+		Size: size,
+		Ranges: []*downloadRange{
+			{Start: 0, End: size / 2, Done: size / 2},
+			{Start: size / 2, End: size, Done: size / 4},
+		},
+	}
+	if err := writeRangeState(dst+".part.ranges", state); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	u, _ := url.Parse(srv.URL + "/file.iso")
+	if err := (&httpRangeGetter{Ranges: 2}).GetFile(dst, u); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(contents, testDownloadContent) {
+		t.Fatal("downloaded file doesn't match")
+	}
+	if len(server.ranges) != 1 || server.ranges[0] != "bytes=49152-65535" {
+		t.Fatalf("bad: %#v", server.ranges)
+	}
+}
+
+func TestHttpRangeGetter_noRanges(t *testing.T) {
+	server := &testRangeServer{noRanges: true}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "packer-download")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "file.iso")
+
+	// The part file can't be resumed, so it is started over
+	if err := ioutil.WriteFile(dst+".part", []byte("garbage"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	u, _ := url.Parse(srv.URL + "/file.iso")
+	if err := (&httpRangeGetter{Ranges: 4}).GetFile(dst, u); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(contents, testDownloadContent) {
+		t.Fatal("downloaded file doesn't match")
+	}
+	if len(server.ranges) != 1 || server.ranges[0] != "" {
+		t.Fatalf("bad: %#v", server.ranges)
+	}
+}
+
+// slowHandler writes a byte at a time, forever.
+type slowHandler struct{}
+
+func (slowHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(testDownloadContent)))
+	if r.Method != "GET" {
+		return
+	}
+	for i := range testDownloadContent {
+		if _, err := w.Write(testDownloadContent[i : i+1]); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func TestHttpRangeGetter_slow(t *testing.T) {
+	g := &httpRangeGetter{
+		MinSpeed:    1024,
+		SpeedWindow: 200 * time.Millisecond,
+	}
+	dst, err := testRangeGetter(t, slowHandler{}, g)
+	defer os.RemoveAll(filepath.Dir(dst))
+	if _, ok := err.(*SlowDownloadError); !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
+func TestStepDownload_slowMirror(t *testing.T) {
+	slow := httptest.NewServer(slowHandler{})
+	defer slow.Close()
+	fast := &testRangeServer{}
+	fastSrv := httptest.NewServer(fast)
+	defer fastSrv.Close()
+
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("PACKER_CACHE_DIR", os.Getenv("PACKER_CACHE_DIR"))
+	os.Setenv("PACKER_CACHE_DIR", dir)
+
+	sum := sha1.Sum(testDownloadContent)
+	s := &StepDownload{
+		Checksum:     hex.EncodeToString(sum[:]),
+		ChecksumType: "sha1",
+		Description:  "ISO",
+		ResultKey:    "iso_path",
+		Url:          []string{slow.URL + "/file.iso", fastSrv.URL + "/file.iso"},
+		MinSpeed:     1024,
+		speedWindow:  200 * time.Millisecond,
+	}
+
+	state := testState(t)
+	if action := s.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v: %s", action, state.Get("error"))
+	}
+
+	// The fast mirror resumed what the slow one downloaded
+	if len(fast.ranges) != 1 || !strings.HasPrefix(fast.ranges[0], "bytes=") {
+		t.Fatalf("bad: %#v", fast.ranges)
+	}
+
+	contents, err := ioutil.ReadFile(state.Get("iso_path").(string))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(contents, testDownloadContent) {
+		t.Fatal("downloaded file doesn't match")
+	}
+}
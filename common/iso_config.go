@@ -3,8 +3,10 @@ package common
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
@@ -17,6 +19,22 @@ type ISOConfig struct {
 	TargetPath      string   `mapstructure:"iso_target_path"`
 	TargetExtension string   `mapstructure:"iso_target_extension"`
 	RawSingleISOUrl string   `mapstructure:"iso_url"`
+
+	// ISODownloadRanges is how many ranges of the ISO are downloaded at the
+	// same time from HTTP servers that support it.
+	ISODownloadRanges int `mapstructure:"iso_download_ranges"`
+
+	// RawISODownloadMinSpeed is a size per second, such as "1MB", under
+	// which a download is abandoned for the next URL.
+	RawISODownloadMinSpeed string `mapstructure:"iso_download_min_speed"`
+	ISODownloadMinSpeed    int64
+
+	// The detached signature of the checksum file, and the keys trusted to
+	// sign it: a keyring file or a key itself.
+	ISOChecksumSignatureURL  string `mapstructure:"iso_checksum_signature_url"`
+	ISOChecksumSignatureType string `mapstructure:"iso_checksum_signature_type"`
+	ISOChecksumKeyring       string `mapstructure:"iso_checksum_keyring"`
+	ISOChecksumKey           string `mapstructure:"iso_checksum_key"`
 }
 
 func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs []error) {
@@ -37,7 +55,19 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 		return
 	}
 
+	if c.ISODownloadRanges < 0 {
+		errs = append(errs, errors.New("iso_download_ranges can't be negative"))
+	}
+	if c.RawISODownloadMinSpeed != "" {
+		speed, err := packer.ParseSize(c.RawISODownloadMinSpeed)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed parsing iso_download_min_speed: %s", err))
+		}
+		c.ISODownloadMinSpeed = speed
+	}
+
 	c.ISOChecksumType = strings.ToLower(c.ISOChecksumType)
+	errs = append(errs, c.prepareSignature()...)
 
 	if c.TargetExtension == "" {
 		c.TargetExtension = "iso"
@@ -68,3 +98,59 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 
 	return warnings, errs
 }
+
+func (c *ISOConfig) prepareSignature() (errs []error) {
+	if c.ISOChecksumSignatureURL == "" {
+		if c.ISOChecksumSignatureType != "" || c.ISOChecksumKeyring != "" || c.ISOChecksumKey != "" {
+			errs = append(errs, errors.New("iso_checksum_signature_url must be "+
+				"specified to use iso_checksum_signature_type, iso_checksum_keyring "+
+				"or iso_checksum_key"))
+		}
+		return errs
+	}
+
+	// The signature is of a checksum file, so the checksums have to come from
+	// one as well.
+	if c.ISOChecksumURL == "" && c.ISOChecksumType != "file" {
+		errs = append(errs, errors.New("iso_checksum_signature_url requires "+
+			"the checksum to come from iso_checksum_url"))
+	}
+
+	c.ISOChecksumSignatureType = strings.ToLower(c.ISOChecksumSignatureType)
+	switch c.ISOChecksumSignatureType {
+	case "", SignatureTypeGPG, SignatureTypeMinisign:
+	default:
+		errs = append(errs, fmt.Errorf("iso_checksum_signature_type must be "+
+			"%q or %q", SignatureTypeGPG, SignatureTypeMinisign))
+	}
+
+	switch {
+	case c.ISOChecksumKeyring == "" && c.ISOChecksumKey == "":
+		errs = append(errs, errors.New("One of iso_checksum_keyring or "+
+			"iso_checksum_key must be specified to verify the checksum signature"))
+	case c.ISOChecksumKeyring != "" && c.ISOChecksumKey != "":
+		errs = append(errs, errors.New("Only one of iso_checksum_keyring or "+
+			"iso_checksum_key must be specified"))
+	case c.ISOChecksumKeyring != "":
+		if _, err := os.Stat(c.ISOChecksumKeyring); err != nil {
+			errs = append(errs, fmt.Errorf("iso_checksum_keyring is invalid: %s", err))
+		}
+	}
+
+	return errs
+}
+
+// ChecksumSignature returns the signature that the checksum file must have,
+// or nil if it isn't verified.
+func (c *ISOConfig) ChecksumSignature() *ChecksumSignature {
+	if c.ISOChecksumSignatureURL == "" {
+		return nil
+	}
+
+	return &ChecksumSignature{
+		URL:     c.ISOChecksumSignatureURL,
+		Type:    c.ISOChecksumSignatureType,
+		Keyring: c.ISOChecksumKeyring,
+		Key:     c.ISOChecksumKey,
+	}
+}
@@ -3,8 +3,10 @@
 package common
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"reflect"
 	"testing"
 )
@@ -245,3 +247,104 @@ func TestISOConfigPrepare_TargetExtension(t *testing.T) {
 		t.Fatalf("should've lowercased: %s", i.TargetExtension)
 	}
 }
+
+func TestISOConfigPrepare_Download(t *testing.T) {
+	i := testISOConfig()
+	i.ISODownloadRanges = 4
+	i.RawISODownloadMinSpeed = "2MB"
+	_, err := i.Prepare(nil)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if i.ISODownloadMinSpeed != 2<<20 {
+		t.Fatalf("bad: %d", i.ISODownloadMinSpeed)
+	}
+
+	i = testISOConfig()
+	i.RawISODownloadMinSpeed = "fast"
+	_, err = i.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	i = testISOConfig()
+	i.ISODownloadRanges = -1
+	_, err = i.Prepare(nil)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestISOConfigPrepare_ChecksumSignature(t *testing.T) {
+	keyring, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(keyring.Name())
+	keyring.Close()
+
+	cases := []struct {
+		Name   string
+		Modify func(*ISOConfig)
+		Err    bool
+	}{
+		{"no signature", func(i *ISOConfig) {}, false},
+		{"keyring", func(i *ISOConfig) {
+			i.ISOChecksumType = "file"
+			i.ISOChecksum = "http://www.packer.io/SHA256SUMS"
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.gpg"
+			i.ISOChecksumKeyring = keyring.Name()
+		}, false},
+		{"key", func(i *ISOConfig) {
+			i.ISOChecksumType = "file"
+			i.ISOChecksum = "http://www.packer.io/SHA256SUMS"
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.minisig"
+			i.ISOChecksumSignatureType = "MINISIGN"
+			i.ISOChecksumKey = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+		}, false},
+		{"checksum isn't from a file", func(i *ISOConfig) {
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.gpg"
+			i.ISOChecksumKeyring = keyring.Name()
+		}, true},
+		{"no key", func(i *ISOConfig) {
+			i.ISOChecksumType = "file"
+			i.ISOChecksum = "http://www.packer.io/SHA256SUMS"
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.gpg"
+		}, true},
+		{"keyring and key", func(i *ISOConfig) {
+			i.ISOChecksumType = "file"
+			i.ISOChecksum = "http://www.packer.io/SHA256SUMS"
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.gpg"
+			i.ISOChecksumKeyring = keyring.Name()
+			i.ISOChecksumKey = "key"
+		}, true},
+		{"missing keyring", func(i *ISOConfig) {
+			i.ISOChecksumType = "file"
+			i.ISOChecksum = "http://www.packer.io/SHA256SUMS"
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.gpg"
+			i.ISOChecksumKeyring = keyring.Name() + ".missing"
+		}, true},
+		{"bad type", func(i *ISOConfig) {
+			i.ISOChecksumType = "file"
+			i.ISOChecksum = "http://www.packer.io/SHA256SUMS"
+			i.ISOChecksumSignatureURL = "http://www.packer.io/SHA256SUMS.gpg"
+			i.ISOChecksumSignatureType = "pgp"
+			i.ISOChecksumKeyring = keyring.Name()
+		}, true},
+		{"key without signature", func(i *ISOConfig) {
+			i.ISOChecksumKeyring = keyring.Name()
+		}, true},
+	}
+
+	for _, tc := range cases {
+		i := testISOConfig()
+		tc.Modify(&i)
+		_, errs := i.Prepare(nil)
+		if (len(errs) > 0) != tc.Err {
+			t.Fatalf("%s: errs: %v", tc.Name, errs)
+		}
+		if !tc.Err && (i.ChecksumSignature() != nil) != (i.ISOChecksumSignatureURL != "") {
+			t.Fatalf("%s: bad signature: %#v", tc.Name, i.ChecksumSignature())
+		}
+	}
+}
@@ -18,11 +18,17 @@ func newRunner(steps []multistep.Step, config PackerConfig, ui packer.Ui) (multi
 	case "", "cleanup":
 	case "abort":
 		for i, step := range steps {
-			steps[i] = abortStep{step, ui}
+			steps[i] = resumableWrapper(abortStep{step, ui}, step)
 		}
 	case "ask":
 		for i, step := range steps {
-			steps[i] = askStep{step, ui}
+			steps[i] = resumableWrapper(askStep{step, ui}, step)
+		}
+	}
+
+	if config.PackerMachineReadable {
+		for i, step := range steps {
+			steps[i] = resumableWrapper(eventStep{step, ui}, step)
 		}
 	}
 
@@ -30,7 +36,16 @@ func newRunner(steps []multistep.Step, config PackerConfig, ui packer.Ui) (multi
 		pauseFn := MultistepDebugFn(ui)
 		return &multistep.DebugRunner{Steps: steps, PauseFn: pauseFn}, pauseFn
 	} else {
-		return &multistep.BasicRunner{Steps: steps}, nil
+		runner := &multistep.BasicRunner{Steps: steps}
+		if config.PackerResume {
+			path, err := packer.ResumePath(config.PackerBuildName, config.PackerResumeKey, "checkpoint.json")
+			if err != nil {
+				log.Printf("Resume disabled, unable to find resume path: %s", err)
+			} else {
+				runner.Checkpoints = &multistep.FileCheckpointStore{Path: path}
+			}
+		}
+		return runner, nil
 	}
 }
 
@@ -123,6 +138,65 @@ func (s askStep) Cleanup(state multistep.StateBag) {
 	s.step.Cleanup(state)
 }
 
+// eventStep reports the start and end of a step as machine-readable
+// output, which a JSON UI turns into step events.
+type eventStep struct {
+	step multistep.Step
+	ui   packer.Ui
+}
+
+func (s eventStep) InnerStepName() string {
+	return multistep.StepName(s.step)
+}
+
+func (s eventStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	name := s.InnerStepName()
+	s.ui.Machine(packer.EventStepStart, name)
+
+	action := s.step.Run(ctx, state)
+	switch action {
+	case multistep.ActionContinue:
+		s.ui.Machine(packer.EventStepEnd, name, "continue")
+	case multistep.ActionHalt:
+		s.ui.Machine(packer.EventStepEnd, name, "halt")
+	}
+
+	return action
+}
+
+func (s eventStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
+
+// resumableStep keeps a wrapped step resumable when the step it wraps is.
+type resumableStep struct {
+	multistep.Step
+	inner multistep.ResumableStep
+}
+
+func resumableWrapper(wrapped multistep.Step, inner multistep.Step) multistep.Step {
+	r, ok := inner.(multistep.ResumableStep)
+	if !ok {
+		return wrapped
+	}
+	return resumableStep{wrapped, r}
+}
+
+func (s resumableStep) InnerStepName() string {
+	return multistep.StepName(s.Step)
+}
+
+func (s resumableStep) ResumeStateKeys() []string {
+	return s.inner.ResumeStateKeys()
+}
+
+func (s resumableStep) Resume(state multistep.StateBag) bool {
+	if r, ok := s.inner.(multistep.StepResumer); ok {
+		return r.Resume(state)
+	}
+	return true
+}
+
 type askResponse int
 
 const (
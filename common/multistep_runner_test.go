@@ -0,0 +1,52 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type testActionStep struct {
+	action multistep.StepAction
+}
+
+func (s testActionStep) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	return s.action
+}
+
+func (testActionStep) Cleanup(multistep.StateBag) {}
+
+func TestNewRunner_stepEvents(t *testing.T) {
+	buf := new(bytes.Buffer)
+	ui := &packer.MachineReadableUi{Writer: buf}
+	steps := []multistep.Step{
+		testActionStep{multistep.ActionContinue},
+		testActionStep{multistep.ActionHalt},
+	}
+
+	state := new(multistep.BasicStateBag)
+	runner := NewRunner(steps, PackerConfig{PackerMachineReadable: true}, ui)
+	runner.Run(state)
+
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		parts := strings.SplitN(line, ",", 3)
+		if strings.HasPrefix(parts[2], "step-") {
+			events = append(events, parts[2])
+		}
+	}
+
+	expected := []string{
+		"step-start,testActionStep",
+		"step-end,testActionStep,continue",
+		"step-start,testActionStep",
+		"step-end,testActionStep,halt",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("bad: %#v", events)
+	}
+}
@@ -4,11 +4,15 @@ package common
 // are sent by packer, properly tagged already so mapstructure can load
 // them. Embed this structure into your configuration class to get it.
 type PackerConfig struct {
-	PackerBuildName     string            `mapstructure:"packer_build_name"`
-	PackerBuilderType   string            `mapstructure:"packer_builder_type"`
-	PackerDebug         bool              `mapstructure:"packer_debug"`
-	PackerForce         bool              `mapstructure:"packer_force"`
-	PackerOnError       string            `mapstructure:"packer_on_error"`
-	PackerUserVars      map[string]string `mapstructure:"packer_user_variables"`
-	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables"`
+	PackerBuildName       string            `mapstructure:"packer_build_name"`
+	PackerBuilderType     string            `mapstructure:"packer_builder_type"`
+	PackerDebug           bool              `mapstructure:"packer_debug"`
+	PackerForce           bool              `mapstructure:"packer_force"`
+	PackerMachineReadable bool              `mapstructure:"packer_machine_readable"`
+	PackerOnError         string            `mapstructure:"packer_on_error"`
+	PackerResume          bool              `mapstructure:"packer_resume"`
+	PackerResumeKey       string            `mapstructure:"packer_resume_key"`
+	PackerTemplatePath    string            `mapstructure:"packer_template_path"`
+	PackerUserVars        map[string]string `mapstructure:"packer_user_variables"`
+	PackerSensitiveVars   []string          `mapstructure:"packer_sensitive_variables"`
 }
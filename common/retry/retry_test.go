@@ -1,6 +1,7 @@
-package common
+package retry
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -62,3 +63,19 @@ func TestRetry(t *testing.T) {
 		t.Fatalf("Unsuccessful retry function should have returned a retry exhausted error. Actual error: %s", err)
 	}
 }
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	numTries := 0
+	err := RetryContext(ctx, 60, 0, 0, func(i uint) (bool, error) {
+		numTries++
+		cancel()
+		return false, nil
+	})
+	if numTries != 1 {
+		t.Fatalf("Cancelled function should have been tried once. Tried %d times.", numTries)
+	}
+	if err != context.Canceled {
+		t.Fatalf("Cancelled retry should have returned the context error. Error: %s", err)
+	}
+}
@@ -0,0 +1,200 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// cdTool is a command that can create an ISO image.
+type cdTool struct {
+	name string
+	args func(output, label, dir string) []string
+}
+
+// cdTools are the commands StepCreateCD uses to create the CD, in order of
+// preference.
+var cdTools = []cdTool{
+	{"xorriso", func(output, label, dir string) []string {
+		return []string{"-as", "mkisofs", "-o", output, "-V", label, "-J", "-joliet-long", "-R", dir}
+	}},
+	{"mkisofs", func(output, label, dir string) []string {
+		return []string{"-o", output, "-V", label, "-J", "-joliet-long", "-R", dir}
+	}},
+	{"genisoimage", func(output, label, dir string) []string {
+		return []string{"-o", output, "-V", label, "-J", "-joliet-long", "-R", dir}
+	}},
+	{"hdiutil", func(output, label, dir string) []string {
+		return []string{"makehybrid", "-o", output, "-iso", "-joliet", "-default-volume-name", label, dir}
+	}},
+	{"oscdimg", func(output, label, dir string) []string {
+		return []string{"-j1", "-o", "-m", "-l" + label, dir, output}
+	}},
+}
+
+// StepCreateCD creates an ISO image of a CD with the given files, using the
+// first of xorriso, mkisofs, genisoimage, hdiutil or oscdimg that is
+// installed. Directories are added with their contents.
+//
+// Uses:
+//   cd_content map[string]string - Optional files to write to the root of
+//   the CD, keyed by their names, such as a generated cloud-init seed.
+//
+// Produces:
+//   cd_path string - The path to the ISO image.
+type StepCreateCD struct {
+	Files []string
+	Label string
+
+	dir string
+}
+
+func (s *StepCreateCD) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	var content map[string]string
+	if raw, ok := state.GetOk("cd_content"); ok {
+		content = raw.(map[string]string)
+	}
+	if len(s.Files) == 0 && len(content) == 0 {
+		log.Println("No CD files specified. CD will not be made.")
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	ui.Say("Creating CD disk...")
+
+	halt := func(err error) multistep.StepAction {
+		err = fmt.Errorf("Error creating CD: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	tool, err := findCDTool()
+	if err != nil {
+		return halt(err)
+	}
+
+	s.dir, err = tmp.Dir("packer-cd")
+	if err != nil {
+		return halt(err)
+	}
+
+	// Stage the contents of the CD
+	root := filepath.Join(s.dir, "root")
+	if err := os.Mkdir(root, 0755); err != nil {
+		return halt(err)
+	}
+	for _, pattern := range s.Files {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return halt(err)
+			}
+		}
+		for _, path := range matches {
+			ui.Message(fmt.Sprintf("Adding: %s", path))
+			if err := copyCDFile(path, filepath.Join(root, filepath.Base(path))); err != nil {
+				return halt(err)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ui.Message(fmt.Sprintf("Adding: %s", name))
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return halt(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content[name]), 0644); err != nil {
+			return halt(err)
+		}
+	}
+
+	cdPath := filepath.Join(s.dir, "cd.iso")
+	args := tool.args(cdPath, s.Label, root)
+	log.Printf("Executing: %s %#v", tool.name, args)
+	output, err := exec.Command(tool.name, args...).CombinedOutput()
+	if err != nil {
+		return halt(fmt.Errorf("%s: %s\n%s", tool.name, err, output))
+	}
+	if err := os.RemoveAll(root); err != nil {
+		return halt(err)
+	}
+
+	log.Printf("CD path: %s", cdPath)
+	state.Put("cd_path", cdPath)
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateCD) Cleanup(state multistep.StateBag) {
+	if s.dir == "" {
+		return
+	}
+
+	log.Printf("Deleting CD: %s", s.dir)
+	if err := os.RemoveAll(s.dir); err != nil {
+		ui := state.Get("ui").(packer.Ui)
+		ui.Error(fmt.Sprintf("Error deleting CD: %s", err))
+	}
+	s.dir = ""
+}
+
+func findCDTool() (*cdTool, error) {
+	for i := range cdTools {
+		if _, err := exec.LookPath(cdTools[i].name); err == nil {
+			return &cdTools[i], nil
+		}
+	}
+	return nil, errors.New(
+		"creating a CD needs xorriso, mkisofs, genisoimage, hdiutil or oscdimg, but none was found")
+}
+
+// copyCDFile copies the file or directory at src to dst.
+func copyCDFile(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
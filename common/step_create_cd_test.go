@@ -0,0 +1,104 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// fakeMkisofs lists the label and the files it is given instead of
+// creating an image.
+const fakeMkisofs = `#!/bin/sh
+out=$2; label=$4; dir=$8
+{ echo "$label"; cd "$dir" && for f in * */*; do [ -f "$f" ] && echo "$f"; done; } > "$out"
+`
+
+func TestStepCreateCD_Impl(t *testing.T) {
+	var _ multistep.Step = new(StepCreateCD)
+}
+
+func TestStepCreateCD(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-cd")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bin := filepath.Join(dir, "bin")
+	os.Mkdir(bin, 0755)
+	ioutil.WriteFile(filepath.Join(bin, "mkisofs"), []byte(fakeMkisofs), 0755)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", bin)
+
+	files := filepath.Join(dir, "files")
+	os.MkdirAll(filepath.Join(files, "scripts"), 0755)
+	ioutil.WriteFile(filepath.Join(files, "ks.cfg"), []byte("ks"), 0644)
+	ioutil.WriteFile(filepath.Join(files, "scripts", "setup.sh"), []byte("setup"), 0644)
+
+	state := testState(t)
+	state.Put("cd_content", map[string]string{"meta-data": "{}"})
+
+	step := &StepCreateCD{
+		Files: []string{filepath.Join(files, "*.cfg"), filepath.Join(files, "scripts")},
+		Label: "cidata",
+	}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v %s", action, state.Get("error"))
+	}
+
+	cdPath := state.Get("cd_path").(string)
+	listing, err := ioutil.ReadFile(cdPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "cidata\nks.cfg\nmeta-data\nscripts/setup.sh\n"
+	if string(listing) != expected {
+		t.Fatalf("bad: %q", listing)
+	}
+
+	step.Cleanup(state)
+	if _, err := os.Stat(cdPath); !os.IsNotExist(err) {
+		t.Fatal("the CD should be removed")
+	}
+}
+
+func TestStepCreateCD_noFiles(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	step := new(StepCreateCD)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("cd_path"); ok {
+		t.Fatal("no CD should be made")
+	}
+	step.Cleanup(state)
+}
+
+func TestStepCreateCD_noTool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-cd")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+
+	state := testState(t)
+	step := &StepCreateCD{Files: []string{"cd_config.go"}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if err := state.Get("error").(error); !strings.Contains(err.Error(), "xorriso") {
+		t.Fatalf("bad: %s", err)
+	}
+}
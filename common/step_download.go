@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"time"
 
-	"github.com/gofrs/flock"
 	getter "github.com/hashicorp/go-getter"
 	urlhelper "github.com/hashicorp/go-getter/helper/url"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
 )
 
 // StepDownload downloads a remote file using the download client within
@@ -47,6 +49,30 @@ type StepDownload struct {
 	// extension on the URL is used. Otherwise, this will be forced
 	// on the downloaded file for every URL.
 	Extension string
+
+	// TemplatePath is the path to the template of the build, which is
+	// recorded in the cache index as using the download.
+	TemplatePath string
+
+	// Ranges is how many ranges of a file are downloaded at the same time
+	// over HTTP, when the server supports it.
+	Ranges int
+
+	// MinSpeed is the throughput in bytes per second under which an HTTP
+	// download is abandoned for the next URL. Zero disables it.
+	MinSpeed int64
+
+	// ChecksumSignature, when set, is the signature of the checksum file
+	// that Checksum points to when ChecksumType is "file". The download
+	// fails unless it is valid.
+	ChecksumSignature *ChecksumSignature
+
+	// speedWindow overrides DefaultSpeedWindow in tests.
+	speedWindow time.Duration
+
+	// verifiedChecksumFile is the local copy of the checksum file whose
+	// signature was verified.
+	verifiedChecksumFile string
 }
 
 func (s *StepDownload) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -55,35 +81,95 @@ func (s *StepDownload) Run(ctx context.Context, state multistep.StateBag) multis
 
 	ui.Say(fmt.Sprintf("Retrieving %s", s.Description))
 
+	// Only trust the checksums once their signature is verified
+	if s.ChecksumSignature != nil {
+		dir, err := tmp.Dir("packer-checksum")
+		if err != nil {
+			state.Put("error", err)
+			return multistep.ActionHalt
+		}
+		defer os.RemoveAll(dir)
+
+		ui.Say(fmt.Sprintf("Verifying the signature of %s", s.Checksum))
+		path, err := s.ChecksumSignature.Fetch(ctx, s.Checksum, dir)
+		if err != nil {
+			err := fmt.Errorf("Error verifying the checksum file of %s: %s", s.Description, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Say("Signature of the checksum file is valid")
+		s.verifiedChecksumFile = path
+		defer func() { s.verifiedChecksumFile = "" }()
+	}
+
+	// Downloads go to a part file in the cache that the next URL resumes,
+	// so URLs that are too slow are skipped first, and only used again when
+	// all the others failed, fastest first.
 	var errs []error
+	var slow []slowSource
 	for _, source := range s.Url {
 		if ctx.Err() != nil {
 			state.Put("error", fmt.Errorf("Download cancelled: %v", errs))
 			return multistep.ActionHalt
 		}
-		ui.Say(fmt.Sprintf("Trying %s", source))
-		dst, err := s.download(ctx, ui, source)
+		dst, err := s.download(ctx, ui, source, s.MinSpeed)
 		if err == nil {
 			state.Put(s.ResultKey, dst)
 			return multistep.ActionContinue
 		}
+		if serr, ok := err.(*SlowDownloadError); ok {
+			ui.Say(fmt.Sprintf("Download from %s is too slow (%s/s), trying the next URL",
+				source, packer.FormatSize(serr.Speed)))
+			slow = append(slow, slowSource{source, serr.Speed})
+			continue
+		}
 		// may be another url will work
 		errs = append(errs, err)
 	}
 
+	sort.SliceStable(slow, func(i, j int) bool { return slow[i].speed > slow[j].speed })
+	for _, m := range slow {
+		if ctx.Err() != nil {
+			state.Put("error", fmt.Errorf("Download cancelled: %v", errs))
+			return multistep.ActionHalt
+		}
+		ui.Say(fmt.Sprintf("Finishing the download from %s, the fastest of the slow URLs", m.source))
+		dst, err := s.download(ctx, ui, m.source, 0)
+		if err == nil {
+			state.Put(s.ResultKey, dst)
+			return multistep.ActionContinue
+		}
+		errs = append(errs, err)
+	}
+
 	state.Put("error", fmt.Errorf("Downloading file: %v", errs))
 	return multistep.ActionHalt
 }
 
-func (s *StepDownload) download(ctx context.Context, ui packer.Ui, source string) (string, error) {
+// slowSource is a URL whose download was abandoned for being too slow, with
+// the throughput it had.
+type slowSource struct {
+	source string
+	speed  int64
+}
+
+func (s *StepDownload) download(ctx context.Context, ui packer.Ui, source string, minSpeed int64) (string, error) {
 	u, err := urlhelper.Parse(source)
 	if err != nil {
 		return "", fmt.Errorf("url parse: %s", err)
 	}
 	if checksum := u.Query().Get("checksum"); checksum != "" {
+		if s.ChecksumSignature != nil {
+			return "", fmt.Errorf("the checksum of %s can't be set in the URL when its signature is verified", source)
+		}
 		s.Checksum = checksum
 	}
-	if s.ChecksumType != "" && s.ChecksumType != "none" {
+	if s.verifiedChecksumFile != "" {
+		q := u.Query()
+		q.Set("checksum", "file:"+s.verifiedChecksumFile)
+		u.RawQuery = q.Encode()
+	} else if s.ChecksumType != "" && s.ChecksumType != "none" {
 		// add checksum to url query params as go getter will checksum for us
 		q := u.Query()
 		q.Set("checksum", s.ChecksumType+":"+s.Checksum)
@@ -112,16 +198,17 @@ func (s *StepDownload) download(ctx context.Context, ui packer.Ui, source string
 			targetPath += "." + s.Extension
 		}
 	}
-	targetPath, err = packer.CachePath(targetPath)
+	cache, err := packer.OpenCache()
 	if err != nil {
-		return "", fmt.Errorf("CachePath: %s", err)
+		return "", fmt.Errorf("Error opening cache: %s", err)
 	}
-	lockFile := targetPath + ".lock"
-
-	log.Printf("Acquiring lock for: %s (%s)", u.String(), lockFile)
-	lock := flock.New(lockFile)
-	lock.Lock()
-	defer lock.Unlock()
+	unlock, err := cache.Lock(targetPath)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	name := targetPath
+	targetPath = cache.Path(name)
 
 	wd, err := os.Getwd()
 	if err != nil {
@@ -133,6 +220,21 @@ func (s *StepDownload) download(ctx context.Context, ui packer.Ui, source string
 		// necessary.
 	}
 
+	// HTTP downloads go through a getter that resumes them; go-getter still
+	// checks the checksum once they are done.
+	httpGetter := &httpRangeGetter{
+		HttpGetter:  getter.HttpGetter{Netrc: true},
+		Ranges:      s.Ranges,
+		MinSpeed:    minSpeed,
+		SpeedWindow: s.speedWindow,
+	}
+	getters := make(map[string]getter.Getter, len(getter.Getters))
+	for scheme, g := range getter.Getters {
+		getters[scheme] = g
+	}
+	getters["http"] = httpGetter
+	getters["https"] = httpGetter
+
 	ui.Say(fmt.Sprintf("Trying %s", u.String()))
 	gc := getter.Client{
 		Ctx:              ctx,
@@ -141,11 +243,25 @@ func (s *StepDownload) download(ctx context.Context, ui packer.Ui, source string
 		ProgressListener: ui,
 		Pwd:              wd,
 		Dir:              false,
+		Getters:          getters,
 	}
 
 	switch err := gc.Get(); err.(type) {
 	case nil: // success !
 		ui.Say(fmt.Sprintf("%s => %s", u.String(), targetPath))
+		entry := &packer.CacheEntry{
+			Name:         name,
+			Source:       source,
+			Checksum:     s.Checksum,
+			ChecksumType: s.ChecksumType,
+		}
+		if s.TemplatePath != "" {
+			entry.Templates = []string{s.TemplatePath}
+		}
+		if err := cache.Record(entry); err != nil {
+			// The download is fine, it just won't be listed.
+			log.Printf("Error recording %s in the cache index: %s", name, err)
+		}
 		return targetPath, nil
 	case *getter.ChecksumError:
 		ui.Say(fmt.Sprintf("Checksum did not match, removing %s", targetPath))
@@ -160,3 +276,9 @@ func (s *StepDownload) download(ctx context.Context, ui packer.Ui, source string
 }
 
 func (s *StepDownload) Cleanup(multistep.StateBag) {}
+
+// ResumeStateKeys lets a resumed build skip the download and reuse the
+// downloaded file.
+func (s *StepDownload) ResumeStateKeys() []string {
+	return []string{s.ResultKey}
+}
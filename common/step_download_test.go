@@ -16,6 +16,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	urlhelper "github.com/hashicorp/go-getter/helper/url"
 	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/packer/tmp"
 )
 
@@ -74,6 +75,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(abs(t, "./test-fixtures/root/another.txt")),
 				toSha1(abs(t, "./test-fixtures/root/another.txt")) + ".lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"bad checksum removes file - checksum from string - no Checksum Type",
@@ -103,6 +106,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(srvr.URL+"/root/another.txt.sha1sum") + ".txt",
 				toSha1(srvr.URL+"/root/another.txt.sha1sum") + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull http dl - checksum from http file - url",
@@ -111,6 +116,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1("file:"+srvr.URL+"/root/another.txt.sha1sum") + ".txt",
 				toSha1("file:"+srvr.URL+"/root/another.txt.sha1sum") + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull http dl - checksum from url",
@@ -119,6 +126,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull http dl - checksum from parameter - no checksum type",
@@ -127,6 +136,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull http dl - checksum from parameter - checksum type",
@@ -135,6 +146,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull relative symlink - checksum from url",
@@ -143,6 +156,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull relative symlink - checksum from parameter - no checksum type",
@@ -151,6 +166,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull relative symlink - checksum from parameter -  checksum type",
@@ -159,6 +176,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull absolute symlink - checksum from url",
@@ -167,6 +186,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull absolute symlink - checksum from parameter - no checksum type",
@@ -175,6 +196,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"successfull absolute symlink - checksum from parameter - checksum type",
@@ -183,6 +206,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/another.txt"]) + ".txt",
 				toSha1(cs["/root/another.txt"]) + ".txt.lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 		{"wrong first 2 urls - absolute urls - checksum from parameter - no checksum type",
@@ -198,6 +223,8 @@ func TestStepDownload_Run(t *testing.T) {
 			[]string{
 				toSha1(cs["/root/basic.txt"]),
 				toSha1(cs["/root/basic.txt"]) + ".lock",
+				"index.json",
+				"index.json.lock",
 			},
 		},
 	}
@@ -252,3 +279,47 @@ func listFiles(t *testing.T, dir string) []string {
 
 	return files
 }
+
+func TestStepDownload_cacheIndex(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("PACKER_CACHE_DIR", os.Getenv("PACKER_CACHE_DIR"))
+	os.Setenv("PACKER_CACHE_DIR", dir)
+
+	source := abs(t, "./test-fixtures/root/another.txt")
+	s := &StepDownload{
+		Checksum:     "7c6e5dd1bacb3b48fdffba2ed096097eb172497d",
+		ChecksumType: "sha1",
+		Description:  "test",
+		ResultKey:    "path",
+		Url:          []string{source},
+		Extension:    "txt",
+		TemplatePath: "template.json",
+	}
+	if action := s.Run(context.Background(), testState(t)); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	cache, err := packer.OpenCache()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("bad: %#v", entries)
+	}
+
+	e := entries[0]
+	if e.Name != toSha1(s.Checksum)+".txt" || e.Source != source || e.Untracked {
+		t.Fatalf("bad: %#v", e)
+	}
+	if !reflect.DeepEqual(e.Templates, []string{"template.json"}) {
+		t.Fatalf("bad: %#v", e.Templates)
+	}
+	if checksummed, err := cache.Verify(e); err != nil || !checksummed {
+		t.Fatalf("bad: %t %v", checksummed, err)
+	}
+}
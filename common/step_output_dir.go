@@ -61,6 +61,23 @@ func (s *StepOutputDir) Run(_ context.Context, state multistep.StateBag) multist
 	return multistep.ActionContinue
 }
 
+// ResumeStateKeys lets a resumed build keep the output directory of the
+// failed build.
+func (s *StepOutputDir) ResumeStateKeys() []string {
+	return nil
+}
+
+// Resume takes over the output directory of the failed build, so that it is
+// cleaned up like it would have been if this build had created it.
+func (s *StepOutputDir) Resume(multistep.StateBag) bool {
+	if _, err := os.Stat(s.Path); err != nil {
+		return false
+	}
+
+	s.cleanup = true
+	return true
+}
+
 func (s *StepOutputDir) Cleanup(state multistep.StateBag) {
 	if !s.cleanup {
 		return
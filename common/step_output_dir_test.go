@@ -133,3 +133,31 @@ func TestStepOutputDir_halted(t *testing.T) {
 		t.Fatal("should not exist")
 	}
 }
+
+func TestStepOutputDir_resume(t *testing.T) {
+	state := testState(t)
+	step := testStepOutputDir(t)
+
+	// The directory of the failed build is gone
+	if step.Resume(state) {
+		t.Fatal("should not be resumed")
+	}
+
+	if err := os.MkdirAll(step.Path, 0755); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	defer os.RemoveAll(step.Path)
+
+	if !step.Resume(state) {
+		t.Fatal("should be resumed")
+	}
+
+	// Mark
+	state.Put(multistep.StateCancelled, true)
+
+	// Test the cleanup
+	step.Cleanup(state)
+	if _, err := os.Stat(step.Path); err == nil {
+		t.Fatal("should not exist")
+	}
+}
@@ -3,7 +3,6 @@ package common
 import (
 	"context"
 	"log"
-	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -22,7 +21,7 @@ type StepProvision struct {
 	Comm packer.Communicator
 }
 
-func (s *StepProvision) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepProvision) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	comm := s.Comm
 	if comm == nil {
 		raw, ok := state.Get("communicator").(packer.Communicator)
@@ -33,31 +32,19 @@ func (s *StepProvision) Run(_ context.Context, state multistep.StateBag) multist
 	hook := state.Get("hook").(packer.Hook)
 	ui := state.Get("ui").(packer.Ui)
 
-	// Run the provisioner in a goroutine so we can continually check
-	// for cancellations...
+	// The hook is cancelled along with the context of the runner.
 	log.Println("Running the provision hook")
-	errCh := make(chan error, 1)
-	go func() {
-		errCh <- hook.Run(packer.HookProvision, ui, comm, nil)
-	}()
-
-	for {
-		select {
-		case err := <-errCh:
-			if err != nil {
-				state.Put("error", err)
-				return multistep.ActionHalt
-			}
-
-			return multistep.ActionContinue
-		case <-time.After(1 * time.Second):
-			if _, ok := state.GetOk(multistep.StateCancelled); ok {
-				log.Println("Cancelling provisioning due to interrupt...")
-				hook.Cancel()
-				return multistep.ActionHalt
-			}
-		}
+	if err := packer.RunHook(ctx, hook, packer.HookProvision, ui, comm, nil); err != nil {
+		state.Put("error", err)
+		return multistep.ActionHalt
+	}
+
+	if ctx.Err() != nil {
+		log.Println("Provisioning cancelled due to interrupt...")
+		return multistep.ActionHalt
 	}
+
+	return multistep.ActionContinue
 }
 
 func (*StepProvision) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,178 @@
+package qga
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// commandTimeout is how long to wait for the guest agent to respond to
+	// a command.
+	commandTimeout = 30 * time.Second
+
+	// syncTimeout is how long to wait for the guest agent to respond to
+	// guest-sync-delimited, which it doesn't while it isn't running yet.
+	syncTimeout = 5 * time.Second
+)
+
+// syncDelimiter is the byte the guest agent sends before the response to
+// guest-sync-delimited, and that resets its parser when it gets it.
+const syncDelimiter = 0xFF
+
+// client is a client of the QEMU guest agent protocol, which is like QMP but
+// has neither a greeting nor events. Commands are run one at a time.
+type client struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// lock serializes commands, as responses have no ID to match them by
+	lock sync.Mutex
+
+	// synced is false until the responses are known to match the commands,
+	// such as after a command timed out and its response may still come.
+	synced bool
+}
+
+type response struct {
+	Return *json.RawMessage `json:"return"`
+	Error  *agentError      `json:"error"`
+}
+
+type agentError struct {
+	Class string `json:"class"`
+	Desc  string `json:"desc"`
+}
+
+func (e *agentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Class, e.Desc)
+}
+
+func newClient(conn net.Conn) *client {
+	return &client{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+}
+
+// execute runs a guest agent command with the given arguments, if any, and
+// decodes its return value into result, if not nil.
+func (c *client) execute(command string, args interface{}, result interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.synced {
+		if err := c.sync(); err != nil {
+			return err
+		}
+		c.synced = true
+	}
+
+	raw, err := c.roundTrip(command, args, commandTimeout)
+	if err != nil {
+		c.synced = false
+		return err
+	}
+	if result == nil || raw == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// sync discards what is left of the responses to earlier commands, and of
+// earlier connections, with guest-sync-delimited.
+func (c *client) sync() error {
+	id := rand.Int63n(1 << 31)
+
+	c.conn.SetDeadline(time.Now().Add(syncTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	// Resets the parser of the agent, should it have half a command
+	if _, err := c.conn.Write([]byte{syncDelimiter}); err != nil {
+		return fmt.Errorf("Error syncing with the guest agent: %s", err)
+	}
+	if err := c.send("guest-sync-delimited", map[string]int64{"id": id}); err != nil {
+		return err
+	}
+	for {
+		if _, err := c.r.ReadBytes(syncDelimiter); err != nil {
+			return fmt.Errorf("Error syncing with the guest agent: %s", err)
+		}
+		raw, err := c.receive()
+		if err != nil {
+			return err
+		}
+		var got int64
+		if raw != nil && json.Unmarshal(raw, &got) == nil && got == id {
+			return nil
+		}
+		log.Printf("[DEBUG] Discarding stale guest agent response: %s", raw)
+	}
+}
+
+func (c *client) roundTrip(command string, args interface{}, timeout time.Duration) (json.RawMessage, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.send(command, args); err != nil {
+		return nil, err
+	}
+	raw, err := c.receive()
+	if err != nil {
+		return nil, fmt.Errorf("Error running guest agent command %s: %s", command, err)
+	}
+	return raw, nil
+}
+
+func (c *client) send(command string, args interface{}) error {
+	req := map[string]interface{}{"execute": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("Error sending guest agent command %s: %s", command, err)
+	}
+	return nil
+}
+
+// receive reads the response to a command, and returns its raw return value
+// or its error.
+func (c *client) receive() (json.RawMessage, error) {
+	for {
+		line, err := c.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimLeft(line, "\xff \t\r\n")
+		if len(line) == 0 {
+			continue
+		}
+
+		var resp response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return nil, fmt.Errorf("bad response %q: %s", line, err)
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		if resp.Return == nil {
+			return nil, nil
+		}
+		return *resp.Return, nil
+	}
+}
+
+// Close closes the connection to the guest agent.
+func (c *client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,454 @@
+// Package qga implements a packer.Communicator over the QEMU guest agent,
+// which runs in the guest and listens on a virtio-serial port, so that
+// guests can be provisioned without a network. The guest must be Unix-like,
+// as commands are run with /bin/sh.
+package qga
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// chunkSize is how much of a file is written or read by each guest agent
+// command.
+const chunkSize = 48 * 1024
+
+// execStatusInterval is how often to check whether a command exited.
+var execStatusInterval = time.Second
+
+// Config is the configuration of the communicator.
+type Config struct {
+	// SocketPath is the path of the unix socket of the virtio-serial port
+	// the guest agent listens on.
+	SocketPath string
+}
+
+// Communicator runs commands and transfers files over the QEMU guest agent.
+type Communicator struct {
+	client *client
+}
+
+// New connects to the guest agent and checks that it responds.
+func New(config *Config) (*Communicator, error) {
+	conn, err := net.DialTimeout("unix", config.SocketPath, syncTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Communicator{client: newClient(conn)}
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := c.client.execute("guest-info", nil, &info); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	log.Printf("[INFO] Connected to QEMU guest agent %s", info.Version)
+	return c, nil
+}
+
+// Close closes the connection to the guest agent.
+func (c *Communicator) Close() error {
+	return c.client.Close()
+}
+
+type execStatus struct {
+	Exited       bool   `json:"exited"`
+	ExitCode     int    `json:"exitcode"`
+	Signal       int    `json:"signal"`
+	OutData      string `json:"out-data"`
+	ErrData      string `json:"err-data"`
+	OutTruncated bool   `json:"out-truncated"`
+	ErrTruncated bool   `json:"err-truncated"`
+}
+
+// Start implementation of communicator.Communicator interface. The guest
+// agent only returns the output of commands once they exit, and takes their
+// whole input when they start, so the input must be read without waiting
+// on a writer: see readInput.
+func (c *Communicator) Start(cmd *packer.RemoteCmd) error {
+	args := map[string]interface{}{
+		"path":           "/bin/sh",
+		"arg":            []string{"-c", cmd.Command},
+		"capture-output": true,
+	}
+	if cmd.Stdin != nil {
+		input, err := readInput(cmd.Stdin)
+		if err != nil {
+			return err
+		}
+		if len(input) > 0 {
+			args["input-data"] = base64.StdEncoding.EncodeToString(input)
+		}
+	}
+
+	log.Printf("[INFO] starting remote command: %s", cmd.Command)
+	var result struct {
+		PID int `json:"pid"`
+	}
+	if err := c.client.execute("guest-exec", args, &result); err != nil {
+		return err
+	}
+
+	go c.wait(cmd, result.PID)
+	return nil
+}
+
+// readInput reads the whole input of a command. Only inputs in memory and
+// regular files are read, since reading a stream that stays open, such as
+// a pipe, would never return.
+func readInput(r io.Reader) ([]byte, error) {
+	switch r := r.(type) {
+	case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+		return ioutil.ReadAll(r)
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if fi.Mode().IsRegular() {
+			return ioutil.ReadAll(r)
+		}
+	}
+	return nil, fmt.Errorf("the QEMU guest agent can't stream the input of commands, "+
+		"which must be a file or in memory, not a %T", r)
+}
+
+// wait waits for the command with the given PID to exit, and then writes
+// its output and sets its exit status.
+func (c *Communicator) wait(cmd *packer.RemoteCmd, pid int) {
+	var status execStatus
+	for {
+		time.Sleep(execStatusInterval)
+		if err := c.client.execute("guest-exec-status", map[string]int{"pid": pid}, &status); err != nil {
+			log.Printf("[ERROR] Error waiting for remote command: %s", err)
+			cmd.SetExited(packer.CmdDisconnect)
+			return
+		}
+		if status.Exited {
+			break
+		}
+	}
+
+	writeOutput(cmd.Stdout, status.OutData, status.OutTruncated, "stdout")
+	writeOutput(cmd.Stderr, status.ErrData, status.ErrTruncated, "stderr")
+
+	code := status.ExitCode
+	if status.Signal != 0 {
+		// Like a shell reports the commands killed by a signal
+		code = 128 + status.Signal
+	}
+	log.Printf("[INFO] command '%s' exited with code: %d", cmd.Command, code)
+	cmd.SetExited(code)
+}
+
+func writeOutput(w io.Writer, data string, truncated bool, name string) {
+	if truncated {
+		log.Printf("[WARN] The guest agent truncated the %s of the remote command", name)
+	}
+	if w == nil || data == "" {
+		return
+	}
+	output, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		log.Printf("[ERROR] Error decoding %s of remote command: %s", name, err)
+		return
+	}
+	w.Write(output)
+}
+
+// run runs a command and returns an error if it fails.
+func (c *Communicator) run(command string) error {
+	var stderr strings.Builder
+	cmd := &packer.RemoteCmd{Command: command, Stderr: &stderr}
+	if err := c.Start(cmd); err != nil {
+		return err
+	}
+	cmd.Wait()
+	if cmd.ExitStatus != 0 {
+		return fmt.Errorf("%q exited with code %d: %s", command, cmd.ExitStatus, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Upload implementation of communicator.Communicator interface. The file is
+// given the mode of fi, if any.
+func (c *Communicator) Upload(dst string, input io.Reader, fi *os.FileInfo) error {
+	if strings.HasSuffix(dst, "/") && fi != nil {
+		dst += (*fi).Name()
+	}
+	if err := c.upload(dst, input); err != nil {
+		return err
+	}
+	if fi == nil {
+		return nil
+	}
+	return c.run(fmt.Sprintf("chmod %o %s", (*fi).Mode().Perm(), shellQuote(dst)))
+}
+
+// upload writes a file with the mode that the guest agent creates files
+// with.
+func (c *Communicator) upload(dst string, input io.Reader) error {
+	log.Printf("Uploading file to '%s'", dst)
+
+	handle, err := c.open(dst, "w")
+	if err != nil {
+		return err
+	}
+	defer c.close(handle)
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(input, buf)
+		if n > 0 {
+			args := map[string]interface{}{
+				"handle":  handle,
+				"buf-b64": base64.StdEncoding.EncodeToString(buf[:n]),
+			}
+			if err := c.client.execute("guest-file-write", args, nil); err != nil {
+				return fmt.Errorf("Error writing %s: %s", dst, err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.client.execute("guest-file-flush", map[string]int{"handle": handle}, nil)
+}
+
+// UploadDir implementation of communicator.Communicator interface
+func (c *Communicator) UploadDir(dst string, src string, exclude []string) error {
+	if !strings.HasSuffix(src, "/") {
+		dst = path.Join(dst, filepath.Base(src))
+	}
+	log.Printf("Uploading dir '%s' to '%s'", src, dst)
+
+	// Walk doesn't follow a symlink at the root
+	root := filepath.Clean(src)
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	// The files are given their modes afterwards, with a command per mode
+	// rather than per file.
+	var dirs, files []string
+	modes := make(map[os.FileMode][]string)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if packer.ExcludedPath(rel, exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := path.Join(dst, filepath.ToSlash(rel))
+		if info.IsDir() {
+			dirs = append(dirs, shellQuote(target))
+		} else {
+			files = append(files, rel)
+			modes[info.Mode().Perm()] = append(modes[info.Mode().Perm()], shellQuote(target))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.run("mkdir -p " + strings.Join(dirs, " ")); err != nil {
+		return err
+	}
+	for _, rel := range files {
+		if err := c.uploadFile(path.Join(dst, filepath.ToSlash(rel)), filepath.Join(root, rel)); err != nil {
+			return err
+		}
+	}
+
+	perms := make([]int, 0, len(modes))
+	for perm := range modes {
+		perms = append(perms, int(perm))
+	}
+	sort.Ints(perms)
+	for _, perm := range perms {
+		if err := c.run(fmt.Sprintf("chmod %o %s", perm, strings.Join(modes[os.FileMode(perm)], " "))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Communicator) uploadFile(dst, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.upload(dst, f)
+}
+
+// Download implementation of communicator.Communicator interface
+func (c *Communicator) Download(src string, output io.Writer) error {
+	log.Printf("Downloading file from '%s'", src)
+
+	handle, err := c.open(src, "r")
+	if err != nil {
+		return err
+	}
+	defer c.close(handle)
+
+	for {
+		var result struct {
+			Count int    `json:"count"`
+			Data  string `json:"buf-b64"`
+			EOF   bool   `json:"eof"`
+		}
+		args := map[string]int{"handle": handle, "count": chunkSize}
+		if err := c.client.execute("guest-file-read", args, &result); err != nil {
+			return fmt.Errorf("Error reading %s: %s", src, err)
+		}
+		data, err := base64.StdEncoding.DecodeString(result.Data)
+		if err != nil {
+			return fmt.Errorf("Error reading %s: %s", src, err)
+		}
+		if _, err := output.Write(data); err != nil {
+			return err
+		}
+		if result.EOF || result.Count == 0 {
+			return nil
+		}
+	}
+}
+
+// DownloadDir implementation of communicator.Communicator interface. The
+// directory is archived with tar in the guest, downloaded and extracted into
+// dst. Excluded files are left out when extracting.
+func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
+	src = path.Clean(src)
+	log.Printf("Downloading dir '%s' to '%s'", src, dst)
+
+	parent, base := path.Split(src)
+	if parent == "" {
+		parent = "."
+	}
+	archive := fmt.Sprintf("/tmp/packer-download-%d.tar", time.Now().UnixNano())
+	if err := c.run(fmt.Sprintf("tar -C %s -cf %s %s", shellQuote(parent), shellQuote(archive), shellQuote(base))); err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.run("rm -f " + shellQuote(archive)); err != nil {
+			log.Printf("[ERROR] Error removing %s: %s", archive, err)
+		}
+	}()
+
+	r, w := io.Pipe()
+	downloaded := make(chan error, 1)
+	go func() {
+		err := c.Download(archive, w)
+		w.CloseWithError(err)
+		downloaded <- err
+	}()
+
+	if err := extractDir(tar.NewReader(r), base, dst, exclude); err != nil {
+		// Stop the download, which is done before the archive is removed
+		r.CloseWithError(err)
+		<-downloaded
+		return err
+	}
+	// The padding of the archive is left
+	io.Copy(ioutil.Discard, r)
+	return <-downloaded
+}
+
+// extractDir extracts the tar archive of the directory base into dst,
+// leaving out the excluded files.
+func extractDir(tr *tar.Reader, base, dst string, exclude []string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, base), "/")
+		if name != base && !strings.HasPrefix(name, base+"/") {
+			return fmt.Errorf("unexpected path in the archive of %s: %s", base, hdr.Name)
+		}
+		if packer.ExcludedPath(rel, exclude) {
+			log.Printf("[DEBUG] qga: skipping excluded %s", name)
+			continue
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		default:
+			log.Printf("[WARN] qga: skipping %s, which isn't a file or a directory", name)
+		}
+	}
+}
+
+func (c *Communicator) open(path, mode string) (int, error) {
+	var handle int
+	args := map[string]string{"path": path, "mode": mode}
+	if err := c.client.execute("guest-file-open", args, &handle); err != nil {
+		return 0, fmt.Errorf("Error opening %s: %s", path, err)
+	}
+	return handle, nil
+}
+
+func (c *Communicator) close(handle int) {
+	if err := c.client.execute("guest-file-close", map[string]int{"handle": handle}, nil); err != nil {
+		log.Printf("[ERROR] Error closing guest file: %s", err)
+	}
+}
+
+// shellQuote quotes a path for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
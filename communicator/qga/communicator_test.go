@@ -0,0 +1,412 @@
+package qga
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func init() {
+	execStatusInterval = time.Millisecond
+}
+
+// testAgent is a fake guest agent with files in memory, that runs the
+// commands it is sent with the given exit status and output.
+type testAgent struct {
+	conn     net.Conn
+	files    map[string][]byte
+	commands []string
+
+	exitCode int
+	output   string
+	// exec is called with the commands the agent is sent
+	exec func(command string)
+}
+
+func newTestCommunicator(t *testing.T) (*Communicator, *testAgent) {
+	client, server := net.Pipe()
+	a := &testAgent{conn: server, files: map[string][]byte{}}
+	go a.serve()
+
+	c := &Communicator{client: newClient(client)}
+	if err := c.client.execute("guest-info", nil, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return c, a
+}
+
+func (a *testAgent) serve() {
+	defer a.conn.Close()
+
+	handles := map[int]string{}
+	r := bufio.NewReader(a.conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var cmd struct {
+			Execute   string
+			Arguments struct {
+				ID     int64  `json:"id"`
+				Path   string `json:"path"`
+				Arg    []string
+				Handle int
+				Count  int
+				Data   string `json:"buf-b64"`
+				Input  string `json:"input-data"`
+				Mode   string
+			}
+		}
+		if err := json.Unmarshal(bytes.TrimLeft(line, "\xff"), &cmd); err != nil {
+			return
+		}
+		args := cmd.Arguments
+
+		var result interface{} = map[string]interface{}{}
+		switch cmd.Execute {
+		case "guest-sync-delimited":
+			// A stale response of an earlier connection comes first
+			a.conn.Write([]byte(`{"return": {}}` + "\n"))
+			a.conn.Write([]byte{syncDelimiter})
+			result = args.ID
+		case "guest-exec":
+			command := strings.Join(append([]string{args.Path}, args.Arg...), " ")
+			if args.Input != "" {
+				input, _ := base64.StdEncoding.DecodeString(args.Input)
+				command += " < " + string(input)
+			}
+			a.commands = append(a.commands, command)
+			if a.exec != nil {
+				a.exec(command)
+			}
+			result = map[string]int{"pid": len(a.commands)}
+		case "guest-exec-status":
+			result = map[string]interface{}{
+				"exited":   true,
+				"exitcode": a.exitCode,
+				"out-data": base64.StdEncoding.EncodeToString([]byte(a.output)),
+			}
+		case "guest-file-open":
+			if _, ok := a.files[args.Path]; !ok && args.Mode == "r" {
+				a.conn.Write([]byte(`{"error": {"class": "GenericError", "desc": "No such file"}}` + "\n"))
+				continue
+			}
+			if args.Mode == "w" {
+				a.files[args.Path] = nil
+			}
+			handles[len(handles)+1] = args.Path
+			result = len(handles)
+		case "guest-file-write":
+			data, _ := base64.StdEncoding.DecodeString(args.Data)
+			path := handles[args.Handle]
+			a.files[path] = append(a.files[path], data...)
+			result = map[string]int{"count": len(data)}
+		case "guest-file-read":
+			path := handles[args.Handle]
+			data := a.files[path]
+			if len(data) > args.Count {
+				data = data[:args.Count]
+			}
+			a.files[path] = a.files[path][len(data):]
+			result = map[string]interface{}{
+				"count":   len(data),
+				"buf-b64": base64.StdEncoding.EncodeToString(data),
+				"eof":     len(a.files[path]) == 0,
+			}
+		}
+
+		resp, _ := json.Marshal(map[string]interface{}{"return": result})
+		a.conn.Write(append(resp, '\n'))
+	}
+}
+
+func TestCommunicator_impl(t *testing.T) {
+	var _ packer.Communicator = new(Communicator)
+}
+
+func TestCommunicator_Start(t *testing.T) {
+	c, a := newTestCommunicator(t)
+	defer c.Close()
+	a.exitCode = 3
+	a.output = "hello\n"
+
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: "echo hello",
+		Stdin:   strings.NewReader("input"),
+		Stdout:  &stdout,
+	}
+	if err := c.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+
+	if cmd.ExitStatus != 3 {
+		t.Fatalf("bad: %d", cmd.ExitStatus)
+	}
+	if stdout.String() != "hello\n" {
+		t.Fatalf("bad: %q", stdout.String())
+	}
+	if a.commands[0] != "/bin/sh -c echo hello < input" {
+		t.Fatalf("bad: %#v", a.commands)
+	}
+}
+
+func TestCommunicator_Start_streamInput(t *testing.T) {
+	c, a := newTestCommunicator(t)
+	defer c.Close()
+
+	r, w := io.Pipe()
+	defer w.Close()
+	cmd := &packer.RemoteCmd{Command: "cat", Stdin: r}
+	if err := c.Start(cmd); err == nil {
+		t.Fatal("should error")
+	}
+	if len(a.commands) != 0 {
+		t.Fatalf("bad: %#v", a.commands)
+	}
+}
+
+func TestCommunicator_UploadDownload(t *testing.T) {
+	c, a := newTestCommunicator(t)
+	defer c.Close()
+
+	data := bytes.Repeat([]byte("0123456789"), chunkSize/4)
+	if err := c.Upload("/tmp/data", bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(a.files["/tmp/data"], data) {
+		t.Fatalf("bad: %d bytes", len(a.files["/tmp/data"]))
+	}
+
+	var out bytes.Buffer
+	if err := c.Download("/tmp/data", &out); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("bad: %d bytes", out.Len())
+	}
+
+	err := c.Download("/tmp/missing", &out)
+	if err == nil || !strings.Contains(err.Error(), "No such file") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestCommunicator_UploadMode(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := f.Chmod(0755); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, a := newTestCommunicator(t)
+	defer c.Close()
+	if err := c.Upload("/tmp/", f, &fi); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dst := "/tmp/" + fi.Name()
+	if _, ok := a.files[dst]; !ok {
+		t.Fatalf("bad: %#v", a.files)
+	}
+	commands := []string{fmt.Sprintf("/bin/sh -c chmod 755 '%s'", dst)}
+	if !reflect.DeepEqual(a.commands, commands) {
+		t.Fatalf("bad: %#v", a.commands)
+	}
+}
+
+func TestCommunicator_UploadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	for _, p := range []string{"a.txt", "sub/b.sh", ".git/config"} {
+		p = filepath.Join(src, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(src, "a.txt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(src, "sub/b.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		src   string
+		files []string
+	}{
+		{src, []string{"/dst/src/a.txt", "/dst/src/sub/b.sh"}},
+		{src + "/", []string{"/dst/a.txt", "/dst/sub/b.sh"}},
+	}
+	for _, tc := range cases {
+		c, a := newTestCommunicator(t)
+		if err := c.UploadDir("/dst", tc.src, []string{".git"}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		c.Close()
+
+		var files []string
+		for p := range a.files {
+			files = append(files, p)
+		}
+		sort.Strings(files)
+		if !reflect.DeepEqual(files, tc.files) {
+			t.Fatalf("%s: bad: %#v", tc.src, files)
+		}
+
+		root := tc.files[0][:strings.LastIndex(tc.files[0], "/")]
+		commands := []string{
+			fmt.Sprintf("/bin/sh -c mkdir -p '%s' '%s/sub'", root, root),
+			fmt.Sprintf("/bin/sh -c chmod 644 '%s/a.txt'", root),
+			fmt.Sprintf("/bin/sh -c chmod 755 '%s/sub/b.sh'", root),
+		}
+		if !reflect.DeepEqual(a.commands, commands) {
+			t.Fatalf("%s: bad: %#v", tc.src, a.commands)
+		}
+	}
+}
+
+// testTar returns a tar archive of the given files, with the modes, and of
+// the directories, whose names end with a slash.
+func testTar(t *testing.T, files map[string]int64) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: files[name], Typeflag: tar.TypeReg, Size: int64(len(name))}
+		if strings.HasSuffix(name, "/") {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte(name))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCommunicator_DownloadDir(t *testing.T) {
+	dst, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	archive := testTar(t, map[string]int64{
+		"src/":            0755,
+		"src/a.txt":       0644,
+		"src/sub/b.sh":    0755,
+		"src/.git/":       0755,
+		"src/.git/config": 0644,
+	})
+
+	c, a := newTestCommunicator(t)
+	defer c.Close()
+	a.exec = func(command string) {
+		if i := strings.Index(command, "-cf '"); i >= 0 {
+			p := command[i+len("-cf '"):]
+			a.files[p[:strings.Index(p, "'")]] = archive
+		}
+	}
+
+	if err := c.DownloadDir("/remote/src/", dst, []string{".git"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, mode := range map[string]os.FileMode{"a.txt": 0644, "sub/b.sh": 0755} {
+		p := filepath.Join(dst, "src", name)
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if fi.Mode().Perm() != mode {
+			t.Fatalf("%s: bad mode: %s", name, fi.Mode())
+		}
+		data, _ := ioutil.ReadFile(p)
+		if string(data) != "src/"+name {
+			t.Fatalf("%s: bad: %q", name, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, "src", ".git")); !os.IsNotExist(err) {
+		t.Fatalf("should exclude .git: %v", err)
+	}
+
+	if len(a.commands) != 2 ||
+		!strings.HasPrefix(a.commands[0], "/bin/sh -c tar -C '/remote/' -cf '/tmp/packer-download-") ||
+		!strings.HasSuffix(a.commands[0], ".tar' 'src'") ||
+		!strings.HasPrefix(a.commands[1], "/bin/sh -c rm -f '/tmp/packer-download-") {
+		t.Fatalf("bad: %#v", a.commands)
+	}
+}
+
+func TestCommunicator_DownloadDir_outside(t *testing.T) {
+	dst, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dst)
+
+	archive := testTar(t, map[string]int64{"src/../evil": 0644})
+	c, a := newTestCommunicator(t)
+	defer c.Close()
+	a.exec = func(command string) {
+		if i := strings.Index(command, "-cf '"); i >= 0 {
+			p := command[i+len("-cf '"):]
+			a.files[p[:strings.Index(p, "'")]] = archive
+		}
+	}
+
+	err = c.DownloadDir("/remote/src", dst, nil)
+	if err == nil || !strings.Contains(err.Error(), "unexpected path") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Fatalf("bad: %s", got)
+	}
+}
@@ -3,6 +3,7 @@ package ssh
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -10,9 +11,11 @@ import (
 	"log"
 	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/packer/packer"
@@ -32,6 +35,8 @@ type comm struct {
 	config  *Config
 	conn    net.Conn
 	address string
+
+	checksumWarning sync.Once
 }
 
 // Config is the structure used to configure the SSH communicator.
@@ -57,12 +62,24 @@ type Config struct {
 	// UseSftp, if true, sftp will be used instead of scp for file transfers
 	UseSftp bool
 
+	// SftpRequests is how many sftp requests are in flight at once when
+	// transferring a file. It defaults to DefaultSftpRequests.
+	SftpRequests int
+
+	// DisableChecksum, if true, skips comparing the checksums of uploaded
+	// and downloaded files with the ones computed by the remote machine.
+	DisableChecksum bool
+
 	// KeepAliveInterval sets how often we send a channel request to the
 	// server. A value < 0 disables.
 	KeepAliveInterval time.Duration
 
 	// Timeout is how long to wait for a read or write to succeed.
 	Timeout time.Duration
+
+	// Ui, if set, is told how fast files were transferred, and when their
+	// checksums can't be verified.
+	Ui packer.Ui
 }
 
 // Creates a new packer.Communicator implementation over SSH. This takes
@@ -160,83 +177,40 @@ func (c *comm) Upload(path string, input io.Reader, fi *os.FileInfo) error {
 
 func (c *comm) UploadDir(dst string, src string, excl []string) error {
 	log.Printf("[DEBUG] Upload dir '%s' to '%s'", src, dst)
+	start := time.Now()
+	var err error
 	if c.config.UseSftp {
-		return c.sftpUploadDirSession(dst, src, excl)
+		err = c.sftpUploadDirSession(dst, src, excl)
 	} else {
-		return c.scpUploadDirSession(dst, src, excl)
+		err = c.scpUploadDirSession(dst, src, excl)
+	}
+	if err != nil {
+		return err
 	}
+
+	c.reportTransfer("Uploaded", src, dirSize(src, excl), time.Since(start))
+	return nil
 }
 
 func (c *comm) DownloadDir(src string, dst string, excl []string) error {
 	log.Printf("[DEBUG] Download dir '%s' to '%s'", src, dst)
-	scpFunc := func(w io.Writer, stdoutR *bufio.Reader) error {
-		dirStack := []string{dst}
-		for {
-			fmt.Fprint(w, "\x00")
-
-			// read file info
-			fi, err := stdoutR.ReadString('\n')
-			if err != nil {
-				return err
-			}
-
-			if len(fi) < 0 {
-				return fmt.Errorf("empty response from server")
-			}
-
-			switch fi[0] {
-			case '\x01', '\x02':
-				return fmt.Errorf("%s", fi[1:])
-			case 'C', 'D':
-				break
-			case 'E':
-				dirStack = dirStack[:len(dirStack)-1]
-				if len(dirStack) == 0 {
-					fmt.Fprint(w, "\x00")
-					return nil
-				}
-				continue
-			default:
-				return fmt.Errorf("unexpected server response (%x)", fi[0])
-			}
-
-			var mode int64
-			var size int64
-			var name string
-			log.Printf("[DEBUG] Download dir str:%s", fi)
-			n, err := fmt.Sscanf(fi[1:], "%o %d %s", &mode, &size, &name)
-			if err != nil || n != 3 {
-				return fmt.Errorf("can't parse server response (%s)", fi)
-			}
-			if size < 0 {
-				return fmt.Errorf("negative file size")
-			}
-
-			log.Printf("[DEBUG] Download dir mode:%0o size:%d name:%s", mode, size, name)
-
-			dst = filepath.Join(dirStack...)
-			switch fi[0] {
-			case 'D':
-				err = os.MkdirAll(filepath.Join(dst, name), os.FileMode(mode))
-				if err != nil {
-					return err
-				}
-				dirStack = append(dirStack, name)
-				continue
-			case 'C':
-				fmt.Fprint(w, "\x00")
-				err = scpDownloadFile(filepath.Join(dst, name), stdoutR, size, os.FileMode(mode))
-				if err != nil {
-					return err
-				}
-			}
+	start := time.Now()
+	var err error
+	if c.config.UseSftp {
+		err = c.sftpDownloadDirSession(src, dst, excl)
+	} else {
+		err = c.scpDownloadDirSession(src, dst, excl)
+	}
+	if err != nil {
+		return err
+	}
 
-			if err := checkSCPStatus(stdoutR); err != nil {
-				return err
-			}
-		}
+	d := time.Since(start)
+	log.Printf("[INFO] Downloaded %s in %s", src, d)
+	if c.config.Ui != nil {
+		c.config.Ui.Message(fmt.Sprintf("Downloaded %s in %s", src, roundDuration(d)))
 	}
-	return c.scpSession("scp -vrf "+src, scpFunc)
+	return nil
 }
 
 func (c *comm) Download(path string, output io.Writer) error {
@@ -401,11 +375,12 @@ func (c *comm) connectToAgent() {
 }
 
 func (c *comm) sftpUploadSession(path string, input io.Reader, fi *os.FileInfo) error {
-	sftpFunc := func(client *sftp.Client) error {
-		return c.sftpUploadFile(path, input, client, fi)
+	sum, err := c.sftpUploadStream(path, input, fi)
+	if err != nil {
+		return err
 	}
 
-	return c.sftpSession(sftpFunc)
+	return c.verifyChecksum(path, sum)
 }
 
 func (c *comm) sftpUploadFile(path string, input io.Reader, client *sftp.Client, fi *os.FileInfo) error {
@@ -448,6 +423,13 @@ func (c *comm) sftpUploadDirSession(dst string, src string, excl []string) error
 			if err != nil {
 				return err
 			}
+			if packer.ExcludedPath(relSrc, excl) {
+				log.Printf("[DEBUG] sftp: skipping excluded %s", path)
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
 			finalDst := filepath.Join(rootDst, relSrc)
 
 			// In Windows, Join uses backslashes which we don't want to get
@@ -502,15 +484,44 @@ func (c *comm) sftpVisitFile(dst string, src string, fi os.FileInfo, client *sft
 }
 
 func (c *comm) sftpDownloadSession(path string, output io.Writer) error {
+	sum, err := c.sftpDownloadStream(path, output)
+	if err != nil {
+		return err
+	}
+
+	return c.verifyChecksum(path, sum)
+}
+
+// sftpDownloadDirSession downloads the directory src, or the files and
+// directories matching src when its last element has wildcards, into dst.
+func (c *comm) sftpDownloadDirSession(src string, dst string, excl []string) error {
 	sftpFunc := func(client *sftp.Client) error {
-		f, err := client.Open(path)
+		roots, err := sftpGlob(client, src)
 		if err != nil {
 			return err
 		}
-		defer f.Close()
 
-		if _, err = io.Copy(output, f); err != nil {
-			return err
+		for _, root := range roots {
+			walker := client.Walk(root)
+			for walker.Step() {
+				if err := walker.Err(); err != nil {
+					return err
+				}
+
+				rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), root), "/")
+				if packer.ExcludedPath(rel, excl) {
+					log.Printf("[DEBUG] sftp: skipping excluded %s", walker.Path())
+					if walker.Stat().IsDir() {
+						walker.SkipDir()
+					}
+					continue
+				}
+
+				finalDst := filepath.Join(dst, path.Base(root), filepath.FromSlash(rel))
+				if err := c.sftpVisitRemoteFile(finalDst, walker.Path(), walker.Stat(), client); err != nil {
+					return err
+				}
+			}
 		}
 
 		return nil
@@ -519,6 +530,62 @@ func (c *comm) sftpDownloadSession(path string, output io.Writer) error {
 	return c.sftpSession(sftpFunc)
 }
 
+// sftpGlob returns the paths matching a remote path whose last element may
+// have wildcards. The sftp protocol has no globbing, so the parent directory
+// is listed.
+func sftpGlob(client *sftp.Client, pattern string) ([]string, error) {
+	pattern = path.Clean(pattern)
+	dir, base := path.Split(pattern)
+	if !strings.ContainsAny(base, "*?[") {
+		return []string{pattern}, nil
+	}
+
+	entries, err := client.ReadDir(path.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, fi := range entries {
+		if ok, _ := path.Match(base, fi.Name()); ok {
+			matches = append(matches, path.Join(dir, fi.Name()))
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	return matches, nil
+}
+
+func (c *comm) sftpVisitRemoteFile(dst string, src string, fi os.FileInfo, client *sftp.Client) error {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		// Download what symlinks point to, like scp does
+		target, err := client.Stat(src)
+		if err != nil {
+			return err
+		}
+		if target.IsDir() {
+			log.Printf("[DEBUG] sftp: not following symlink to directory %s", src)
+			return nil
+		}
+		fi = target
+	}
+
+	if fi.IsDir() {
+		log.Printf("[DEBUG] sftp: creating local dir %s", dst)
+		return os.MkdirAll(dst, fi.Mode().Perm())
+	}
+
+	log.Printf("[DEBUG] sftp: downloading %s", src)
+	f, err := client.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return scpDownloadFile(dst, f, fi.Size(), fi.Mode().Perm())
+}
+
 func (c *comm) sftpSession(f func(*sftp.Client) error) error {
 	client, err := c.newSftpClient()
 	if err != nil {
@@ -551,7 +618,9 @@ func (c *comm) newSftpClient() (*sftp.Client, error) {
 	// Capture stdout so we can return errors to the user
 	var stdout bytes.Buffer
 	tee := io.TeeReader(pr, &stdout)
-	client, err := sftp.NewClientPipe(tee, pw)
+	client, err := sftp.NewClientPipe(tee, pw,
+		sftp.MaxPacket(sftpPacketSize),
+		sftp.MaxConcurrentRequestsPerFile(c.sftpRequests()))
 	if err != nil && stdout.Len() > 0 {
 		log.Printf("[ERROR] Upload failed: %s", stdout.Bytes())
 	}
@@ -573,11 +642,18 @@ func (c *comm) scpUploadSession(path string, input io.Reader, fi *os.FileInfo) e
 	// Escape spaces in remote directory
 	target_dir = strings.Replace(target_dir, " ", "\\ ", -1)
 
+	hr := &hashingReader{r: input, h: sha256.New()}
 	scpFunc := func(w io.Writer, stdoutR *bufio.Reader) error {
-		return scpUploadFile(target_file, input, w, stdoutR, fi)
+		return scpUploadFile(target_file, hr, w, stdoutR, fi)
+	}
+
+	start := time.Now()
+	if err := c.scpSession("scp -vt "+target_dir, scpFunc); err != nil {
+		return err
 	}
+	c.reportTransfer("Uploaded", path, hr.n, time.Since(start))
 
-	return c.scpSession("scp -vt "+target_dir, scpFunc)
+	return c.verifyChecksum(path, hr.h.Sum(nil))
 }
 
 func (c *comm) scpUploadDirSession(dst string, src string, excl []string) error {
@@ -594,7 +670,7 @@ func (c *comm) scpUploadDirSession(dst string, src string, excl []string) error
 				return err
 			}
 
-			return scpUploadDir(src, entries, w, r)
+			return scpUploadDir(src, src, entries, excl, w, r)
 		}
 
 		if src[len(src)-1] != '/' {
@@ -613,7 +689,106 @@ func (c *comm) scpUploadDirSession(dst string, src string, excl []string) error
 	return c.scpSession("scp -rvt "+dst, scpFunc)
 }
 
+func (c *comm) scpDownloadDirSession(src string, dst string, excl []string) error {
+	scpFunc := func(w io.Writer, stdoutR *bufio.Reader) error {
+		dirStack := []string{dst}
+
+		// skipped is how deep we are within excluded directories, whose
+		// contents are read and thrown away
+		skipped := 0
+		for {
+			fmt.Fprint(w, "\x00")
+
+			// read file info
+			fi, err := stdoutR.ReadString('\n')
+			if err != nil {
+				return err
+			}
+
+			if len(fi) < 0 {
+				return fmt.Errorf("empty response from server")
+			}
+
+			switch fi[0] {
+			case '\x01', '\x02':
+				return fmt.Errorf("%s", fi[1:])
+			case 'C', 'D':
+				break
+			case 'E':
+				if skipped > 0 {
+					skipped--
+					continue
+				}
+				dirStack = dirStack[:len(dirStack)-1]
+				if len(dirStack) == 0 {
+					fmt.Fprint(w, "\x00")
+					return nil
+				}
+				continue
+			default:
+				return fmt.Errorf("unexpected server response (%x)", fi[0])
+			}
+
+			var mode int64
+			var size int64
+			var name string
+			log.Printf("[DEBUG] Download dir str:%s", fi)
+			n, err := fmt.Sscanf(fi[1:], "%o %d %s", &mode, &size, &name)
+			if err != nil || n != 3 {
+				return fmt.Errorf("can't parse server response (%s)", fi)
+			}
+			if size < 0 {
+				return fmt.Errorf("negative file size")
+			}
+
+			log.Printf("[DEBUG] Download dir mode:%0o size:%d name:%s", mode, size, name)
+
+			// The exclude patterns are relative to the directories named by
+			// src, which are right below dst on the stack
+			excluded := skipped > 0
+			if !excluded && len(dirStack) > 1 {
+				rel := append(append([]string{}, dirStack[2:]...), name)
+				excluded = packer.ExcludedPath(strings.Join(rel, "/"), excl)
+			}
+
+			dir := filepath.Join(dirStack...)
+			switch fi[0] {
+			case 'D':
+				if excluded {
+					log.Printf("[DEBUG] Skipping excluded directory: %s", name)
+					skipped++
+					continue
+				}
+				err = os.MkdirAll(filepath.Join(dir, name), os.FileMode(mode))
+				if err != nil {
+					return err
+				}
+				dirStack = append(dirStack, name)
+				continue
+			case 'C':
+				fmt.Fprint(w, "\x00")
+				if excluded {
+					log.Printf("[DEBUG] Skipping excluded file: %s", name)
+					_, err = io.CopyN(ioutil.Discard, stdoutR, size)
+				} else {
+					err = scpDownloadFile(filepath.Join(dir, name), stdoutR, size, os.FileMode(mode))
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := checkSCPStatus(stdoutR); err != nil {
+				return err
+			}
+		}
+	}
+	return c.scpSession("scp -vrf "+src, scpFunc)
+}
+
 func (c *comm) scpDownloadSession(path string, output io.Writer) error {
+	h := sha256.New()
+	var size int64
 	scpFunc := func(w io.Writer, stdoutR *bufio.Reader) error {
 		fmt.Fprint(w, "\x00")
 
@@ -638,7 +813,6 @@ func (c *comm) scpDownloadSession(path string, output io.Writer) error {
 		}
 
 		var mode string
-		var size int64
 
 		n, err := fmt.Sscanf(fi, "%6s %d ", &mode, &size)
 		if err != nil || n != 2 {
@@ -650,7 +824,7 @@ func (c *comm) scpDownloadSession(path string, output io.Writer) error {
 
 		fmt.Fprint(w, "\x00")
 
-		if _, err := io.CopyN(output, stdoutR, size); err != nil {
+		if _, err := io.CopyN(io.MultiWriter(output, h), stdoutR, size); err != nil {
 			return err
 		}
 
@@ -659,10 +833,18 @@ func (c *comm) scpDownloadSession(path string, output io.Writer) error {
 		return checkSCPStatus(stdoutR)
 	}
 
-	if !strings.Contains(path, " ") {
-		return c.scpSession("scp -vf "+path, scpFunc)
+	scpCommand := "scp -vf " + path
+	if strings.Contains(path, " ") {
+		scpCommand = "scp -vf " + strconv.Quote(path)
 	}
-	return c.scpSession("scp -vf "+strconv.Quote(path), scpFunc)
+
+	start := time.Now()
+	if err := c.scpSession(scpCommand, scpFunc); err != nil {
+		return err
+	}
+	c.reportTransfer("Downloaded", path, size, time.Since(start))
+
+	return c.verifyChecksum(path, h.Sum(nil))
 }
 
 func (c *comm) scpSession(scpCommand string, f func(io.Writer, *bufio.Reader) error) error {
@@ -869,10 +1051,17 @@ func scpUploadDirProtocol(name string, w io.Writer, r *bufio.Reader, f func() er
 	return err
 }
 
-func scpUploadDir(root string, fs []os.FileInfo, w io.Writer, r *bufio.Reader) error {
+// scpUploadDir uploads the entries of the directory root, which is src or a
+// directory within it, leaving out the ones that are excluded.
+func scpUploadDir(src string, root string, fs []os.FileInfo, excl []string, w io.Writer, r *bufio.Reader) error {
 	for _, fi := range fs {
 		realPath := filepath.Join(root, fi.Name())
 
+		if rel, err := filepath.Rel(src, realPath); err == nil && packer.ExcludedPath(rel, excl) {
+			log.Printf("[DEBUG] SCP: skipping excluded %s", realPath)
+			continue
+		}
+
 		// Track if this is actually a symlink to a directory. If it is
 		// a symlink to a file we don't do any special behavior because uploading
 		// a file just works. If it is a directory, we need to know so we
@@ -924,7 +1113,7 @@ func scpUploadDir(root string, fs []os.FileInfo, w io.Writer, r *bufio.Reader) e
 				return err
 			}
 
-			return scpUploadDir(realPath, entries, w, r)
+			return scpUploadDir(src, realPath, entries, excl, w, r)
 		}, fi)
 		if err != nil {
 			return err
@@ -4,12 +4,21 @@ package ssh
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/packer/packer"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -225,3 +234,362 @@ func TestHandshakeTimeout(t *testing.T) {
 		t.Fatalf("Expected handshake timeout, got: %s", err)
 	}
 }
+
+// droppingConn closes the connection once more than limit bytes were read,
+// like a network failure in the middle of a transfer.
+type droppingConn struct {
+	net.Conn
+	limit int64
+	read  int64
+}
+
+func (c *droppingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read += int64(n)
+	if c.read > c.limit {
+		c.Conn.Close()
+	}
+	return n, err
+}
+
+// newMockSftpServer serves the local filesystem over the sftp subsystem, and
+// runs exec requests with the local shell. When dropAfter is set, the first
+// connection is dropped after that many bytes were received.
+func newMockSftpServer(t *testing.T, dropAfter int64) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen for connection: %s", err)
+	}
+
+	go func() {
+		defer l.Close()
+		for first := true; ; first = false {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if first && dropAfter > 0 {
+				c = &droppingConn{Conn: c, limit: dropAfter}
+			}
+			go serveMockSftp(t, c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveMockSftp(t *testing.T, c net.Conn) {
+	defer c.Close()
+	conn, chans, reqs, err := ssh.NewServerConn(c, serverConfig)
+	if err != nil {
+		t.Logf("Handshaking error: %v", err)
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				switch req.Type {
+				case "subsystem":
+					ok := string(req.Payload[4:]) == "sftp"
+					req.Reply(ok, nil)
+					if !ok {
+						continue
+					}
+					server, err := sftp.NewServer(channel)
+					if err != nil {
+						t.Errorf("Unable to start sftp server: %s", err)
+						return
+					}
+					server.Serve()
+					return
+				case "exec":
+					var payload struct{ Command string }
+					ssh.Unmarshal(req.Payload, &payload)
+					req.Reply(true, nil)
+					cmd := exec.Command("sh", "-c", payload.Command)
+					cmd.Stdout = channel
+					cmd.Stderr = channel.Stderr()
+					status := struct{ Status uint32 }{0}
+					if err := cmd.Run(); err != nil {
+						status.Status = 1
+					}
+					channel.SendRequest("exit-status", false, ssh.Marshal(&status))
+					return
+				default:
+					req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func newSftpComm(t *testing.T, dropAfter int64) *comm {
+	address := newMockSftpServer(t, dropAfter)
+	config := &Config{
+		Connection: func() (net.Conn, error) {
+			return net.Dial("tcp", address)
+		},
+		SSHConfig: &ssh.ClientConfig{
+			User:            "user",
+			Auth:            []ssh.AuthMethod{ssh.Password("pass")},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+		UseSftp:      true,
+		SftpRequests: 4,
+	}
+
+	client, err := New(address, config)
+	if err != nil {
+		t.Fatalf("error connecting to SSH: %s", err)
+	}
+	return client
+}
+
+func testDirTree(t *testing.T, names ...string) string {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	return dir
+}
+
+func listDirTree(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestSftpDownloadDir(t *testing.T) {
+	src := testDirTree(t, "a.txt", "b.log", "sub/c.txt", "sub/d.log", ".git/config")
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dst)
+
+	c := newSftpComm(t, 0)
+	if err := c.DownloadDir(src+"/", dst, []string{"*.log", ".git"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	base := filepath.Base(src)
+	expected := []string{base + "/a.txt", base + "/sub/c.txt"}
+	if files := listDirTree(t, dst); !reflect.DeepEqual(files, expected) {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func TestSftpDownloadDir_glob(t *testing.T) {
+	src := testDirTree(t, "a.txt", "b.log", "sub/c.txt")
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dst)
+
+	c := newSftpComm(t, 0)
+	if err := c.DownloadDir(filepath.ToSlash(src)+"/*.txt", dst, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if files := listDirTree(t, dst); !reflect.DeepEqual(files, []string{"a.txt"}) {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func TestSftpUploadDir_exclude(t *testing.T) {
+	src := testDirTree(t, "a.txt", "b.log", "sub/c.txt", "sub/d.log")
+	defer os.RemoveAll(src)
+	dst, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dst)
+
+	c := newSftpComm(t, 0)
+	if err := c.UploadDir(dst, src+"/", []string{"*.log"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if files := listDirTree(t, dst); !reflect.DeepEqual(files, []string{"a.txt", "sub/c.txt"}) {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func testPayload() []byte {
+	payload := make([]byte, 1<<20)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+	return payload
+}
+
+func TestSftpUpload_resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "file")
+
+	// The connection drops a few hundred kilobytes into the upload
+	payload := testPayload()
+	c := newSftpComm(t, 300*1024)
+	if err := c.Upload(dst, bytes.NewReader(payload), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(contents, payload) {
+		t.Fatalf("uploaded file doesn't match: %d bytes", len(contents))
+	}
+}
+
+func TestSftpDownload_resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "file")
+	payload := testPayload()
+	if err := ioutil.WriteFile(src, payload, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Only requests are received while downloading, so drop the connection
+	// after a number of them
+	c := newSftpComm(t, 4096)
+	var out bytes.Buffer
+	if err := c.Download(src, &out); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(out.Bytes(), payload) {
+		t.Fatalf("downloaded file doesn't match: %d bytes", out.Len())
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum is required")
+	}
+
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "it's a file")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	c := newSftpComm(t, 0)
+	sum := sha256.Sum256([]byte("hello"))
+	if err := c.verifyChecksum(path, sum[:]); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sum = sha256.Sum256([]byte("hell"))
+	if err := c.verifyChecksum(path, sum[:]); err == nil {
+		t.Fatal("should fail")
+	}
+
+	// Verification is skipped when it is disabled
+	c.config.DisableChecksum = true
+	if err := c.verifyChecksum(path, sum[:]); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestVerifyChecksum_noTool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The mock server runs commands with a shell that finds nothing else
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is required")
+	}
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := os.Symlink(sh, filepath.Join(bin, "sh")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", bin)
+
+	c := newSftpComm(t, 0)
+	var out bytes.Buffer
+	c.config.Ui = &packer.BasicUi{Writer: &out, ErrorWriter: &out}
+	sum := sha256.Sum256([]byte("hello"))
+	for i := 0; i < 2; i++ {
+		if err := c.verifyChecksum(path, sum[:]); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The Ui is warned once
+	if n := strings.Count(out.String(), "checksums of transferred files aren't verified"); n != 1 {
+		t.Fatalf("should warn once: %q", out.String())
+	}
+}
+
+func TestSftpUpload_summary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newSftpComm(t, 0)
+	var out bytes.Buffer
+	c.config.Ui = &packer.BasicUi{Writer: &out, ErrorWriter: &out}
+	if err := c.Upload(filepath.Join(dir, "file"), bytes.NewReader(testPayload()), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !strings.Contains(out.String(), "Uploaded 1.0 MB in") {
+		t.Fatalf("should report the transfer: %q", out.String())
+	}
+}
@@ -0,0 +1,343 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/pkg/sftp"
+)
+
+// DefaultSftpRequests is how many requests are in flight at once when
+// transferring a file over sftp, unless Config.SftpRequests is set.
+const DefaultSftpRequests = 16
+
+// sftpPacketSize is the size of the data of an sftp read or write request.
+const sftpPacketSize = 1 << 15
+
+// sftpResumeAttempts is how many times an sftp transfer is resumed after
+// losing the connection.
+const sftpResumeAttempts = 5
+
+// transferError is an error of a file transfer that resuming can't fix, such
+// as a failure of the local side or a checksum mismatch.
+type transferError struct {
+	err error
+}
+
+func (e *transferError) Error() string {
+	return e.err.Error()
+}
+
+// sftpRequests returns how many requests the sftp client keeps in flight
+// for a file.
+func (c *comm) sftpRequests() int {
+	if c.config.SftpRequests <= 0 {
+		return DefaultSftpRequests
+	}
+	return c.config.SftpRequests
+}
+
+// sftpBufferSize returns the size of the chunks files are read and written
+// in. The sftp client splits each chunk into as many concurrent requests as
+// it keeps in flight.
+func (c *comm) sftpBufferSize() int {
+	return c.sftpRequests() * sftpPacketSize
+}
+
+// sftpResumableSession runs f in sftp sessions until it succeeds, starting a
+// new session after the connection is lost. f must continue the transfer
+// where the previous attempt stopped.
+func (c *comm) sftpResumableSession(f func(*sftp.Client) error) error {
+	for attempt := 1; ; attempt++ {
+		err := c.sftpSession(f)
+		if err == nil || attempt > sftpResumeAttempts {
+			return err
+		}
+		switch err.(type) {
+		case *transferError, *sftp.StatusError:
+			return err
+		}
+		if err == os.ErrNotExist || os.IsPermission(err) {
+			return err
+		}
+
+		log.Printf("[WARN] sftp: transfer interrupted, resuming (attempt %d): %s", attempt, err)
+	}
+}
+
+// sftpUploadStream writes input to the remote file at path. When the
+// connection is lost, the transfer is resumed from the last chunk the server
+// acknowledged, which is kept until then since input can't be read twice.
+// The checksum of what was sent is returned.
+func (c *comm) sftpUploadStream(path string, input io.Reader, fi *os.FileInfo) ([]byte, error) {
+	h := sha256.New()
+	buf := make([]byte, c.sftpBufferSize())
+	var pending []byte
+	var written int64
+	eof := false
+	start := time.Now()
+
+	sftpFunc := func(client *sftp.Client) error {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if written > 0 {
+			log.Printf("[DEBUG] sftp: resuming upload of %s at %d bytes", path, written)
+			flags = os.O_WRONLY
+		}
+		f, err := client.OpenFile(path, flags)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := f.Seek(written, io.SeekStart); err != nil {
+			return err
+		}
+
+		for {
+			if len(pending) == 0 && !eof {
+				n, err := io.ReadFull(input, buf)
+				switch err {
+				case nil:
+				case io.EOF, io.ErrUnexpectedEOF:
+					eof = true
+				default:
+					return &transferError{err}
+				}
+				pending = buf[:n]
+				h.Write(pending)
+			}
+			if len(pending) == 0 {
+				break
+			}
+
+			if _, err := f.Write(pending); err != nil {
+				return err
+			}
+			written += int64(len(pending))
+			pending = nil
+		}
+
+		// Drop whatever an interrupted write left past the end
+		if err := f.Truncate(written); err != nil {
+			return err
+		}
+		if err := sftpCheckSize(f, written); err != nil {
+			return err
+		}
+
+		if fi != nil && (*fi).Mode().IsRegular() {
+			if err := client.Chmod(path, (*fi).Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := c.sftpResumableSession(sftpFunc); err != nil {
+		return nil, err
+	}
+	c.reportTransfer("Uploaded", path, written, time.Since(start))
+	return h.Sum(nil), nil
+}
+
+// sftpDownloadStream writes the remote file at path to output, resuming the
+// transfer when the connection is lost. The checksum of what was received
+// is returned.
+func (c *comm) sftpDownloadStream(path string, output io.Writer) ([]byte, error) {
+	h := sha256.New()
+	buf := make([]byte, c.sftpBufferSize())
+	var read int64
+	start := time.Now()
+
+	sftpFunc := func(client *sftp.Client) error {
+		f, err := client.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if read > 0 {
+			log.Printf("[DEBUG] sftp: resuming download of %s at %d bytes", path, read)
+		}
+		if _, err := f.Seek(read, io.SeekStart); err != nil {
+			return err
+		}
+
+		for read < info.Size() {
+			n, err := f.Read(buf)
+			if n > 0 {
+				if _, err := output.Write(buf[:n]); err != nil {
+					return &transferError{err}
+				}
+				h.Write(buf[:n])
+				read += int64(n)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if read != info.Size() {
+			return fmt.Errorf("%s is %d bytes, but %d bytes were downloaded", path, info.Size(), read)
+		}
+		return nil
+	}
+
+	if err := c.sftpResumableSession(sftpFunc); err != nil {
+		return nil, err
+	}
+	c.reportTransfer("Downloaded", path, read, time.Since(start))
+	return h.Sum(nil), nil
+}
+
+func sftpCheckSize(f *sftp.File, size int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("%s is %d bytes, but %d bytes were uploaded", f.Name(), info.Size(), size)
+	}
+	return nil
+}
+
+// hashingReader hashes what is read through it.
+type hashingReader struct {
+	r io.Reader
+	h hash.Hash
+	n int64
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.h.Write(p[:n])
+	r.n += int64(n)
+	return n, err
+}
+
+// remoteChecksum returns the SHA-256 checksum of a remote file, computed by
+// the remote machine, or nil when it has no tool to compute it.
+func (c *comm) remoteChecksum(path string) ([]byte, error) {
+	session, err := c.newSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	quoted := shellQuote(path)
+	cmd := fmt.Sprintf("sha256sum %s 2>/dev/null || shasum -a 256 %s 2>/dev/null", quoted, quoted)
+	var stdout bytes.Buffer
+	session.Stdout = &stdout
+	if err := session.Run(cmd); err != nil {
+		log.Printf("[WARN] Can't compute the checksum of %s on the remote machine: %s", path, err)
+		return nil, nil
+	}
+
+	fields := strings.Fields(stdout.String())
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	sum, err := hex.DecodeString(fields[0])
+	if err != nil || len(sum) != sha256.Size {
+		log.Printf("[WARN] Unexpected checksum of %s: %q", path, stdout.String())
+		return nil, nil
+	}
+	return sum, nil
+}
+
+// verifyChecksum compares the checksum of what was transferred with the
+// checksum of the remote file, unless checksums are disabled or the remote
+// machine can't compute them.
+func (c *comm) verifyChecksum(path string, sum []byte) error {
+	if c.config.DisableChecksum {
+		return nil
+	}
+
+	remote, err := c.remoteChecksum(path)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		log.Printf("[WARN] Not verifying the checksum of %s", path)
+		c.checksumWarning.Do(func() {
+			if c.config.Ui != nil {
+				c.config.Ui.Error("Warning: The remote machine has neither sha256sum nor shasum, " +
+					"so the checksums of transferred files aren't verified.")
+			}
+		})
+		return nil
+	}
+	if !bytes.Equal(remote, sum) {
+		return &transferError{fmt.Errorf(
+			"Checksum mismatch for %s: the remote file has SHA-256 %x, but %x was transferred",
+			path, remote, sum)}
+	}
+
+	log.Printf("[DEBUG] Verified SHA-256 checksum of %s: %x", path, sum)
+	return nil
+}
+
+// reportTransfer logs how much was transferred to or from path, and how
+// fast, and tells the Ui.
+func (c *comm) reportTransfer(verb, path string, size int64, d time.Duration) {
+	rate := size
+	if seconds := d.Seconds(); seconds > 0 {
+		rate = int64(float64(size) / seconds)
+	}
+	log.Printf("[INFO] %s %s: %d bytes in %s (%d bytes/s)", verb, path, size, d, rate)
+
+	if c.config.Ui != nil {
+		c.config.Ui.Message(fmt.Sprintf("%s %s in %s (%s/s)",
+			verb, packer.FormatSize(size), roundDuration(d), packer.FormatSize(rate)))
+	}
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
+// dirSize returns the size of the files of a local directory that were
+// transferred.
+func dirSize(dir string, exclude []string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && packer.ExcludedPath(rel, exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// shellQuote quotes a path for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
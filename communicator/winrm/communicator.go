@@ -1,17 +1,21 @@
 package winrm
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/tmp"
 	"github.com/masterzen/winrm"
 	"github.com/packer-community/winrmcp/winrmcp"
 )
@@ -141,6 +145,15 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 		dst = fmt.Sprintf("%s\\%s", dst, filepath.Base(src))
 	}
 	log.Printf("Uploading dir '%s' to '%s'", src, dst)
+
+	// winrmcp copies whole directories, so the excluded files are left out
+	// of a local copy that is uploaded instead
+	src, cleanup, err := packer.StageDir(src, exclude)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	wcp, err := c.newCopyClient()
 	if err != nil {
 		return err
@@ -164,8 +177,138 @@ func (c *Communicator) Download(src string, dst io.Writer) error {
 	return err
 }
 
+// archiveScript zips the items matching a path, which may have wildcards,
+// into a temporary file on the guest, and prints the path to the file. The
+// items are named relative to the directory they are in, like scp does.
+const archiveScript = `$ErrorActionPreference = 'Stop'
+Add-Type -AssemblyName System.IO.Compression
+Add-Type -AssemblyName System.IO.Compression.FileSystem
+$zip = [System.IO.Path]::GetTempFileName()
+Remove-Item -LiteralPath $zip
+$archive = [System.IO.Compression.ZipFile]::Open($zip, 'Create')
+try {
+  foreach ($item in @(Get-Item -Path '%s' -Force)) {
+    $base = Split-Path -Parent $item.FullName
+    $files = @($item)
+    if ($item.PSIsContainer) {
+      $files += @(Get-ChildItem -LiteralPath $item.FullName -Recurse -Force)
+    }
+    foreach ($f in $files) {
+      $name = $f.FullName.Substring($base.Length).TrimStart('\') -replace '\\', '/'
+      if ($f.PSIsContainer) {
+        [void]$archive.CreateEntry($name + '/')
+      } else {
+        [void][System.IO.Compression.ZipFileExtensions]::CreateEntryFromFile($archive, $f.FullName, $name)
+      }
+    }
+  }
+} finally {
+  $archive.Dispose()
+}
+Write-Output $zip`
+
+// DownloadDir implementation of communicator.Communicator interface. The
+// directory is archived on the guest, and the archive is downloaded and
+// extracted into dst, leaving out the excluded files.
 func (c *Communicator) DownloadDir(src string, dst string, exclude []string) error {
-	return fmt.Errorf("WinRM doesn't support download dir.")
+	client, err := c.newWinRMClient()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Archiving dir '%s'", src)
+	src = strings.TrimRight(src, `/\`)
+	var stdout, stderr bytes.Buffer
+	script := fmt.Sprintf(archiveScript, strings.Replace(src, "'", "''", -1))
+	code, err := client.Run(winrm.Powershell(script), &stdout, &stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("Error archiving %s: %s", src, strings.TrimSpace(stderr.String()))
+	}
+	archive := strings.TrimSpace(stdout.String())
+	defer func() {
+		cmd := fmt.Sprintf("Remove-Item -LiteralPath '%s'", strings.Replace(archive, "'", "''", -1))
+		if _, err := client.Run(winrm.Powershell(cmd), ioutil.Discard, ioutil.Discard); err != nil {
+			log.Printf("Error removing %s: %s", archive, err)
+		}
+	}()
+
+	f, err := tmp.File("packer-download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	log.Printf("Downloading archive '%s'", archive)
+	if err := c.Download(archive, f); err != nil {
+		return err
+	}
+
+	return extractArchive(f, dst, exclude)
+}
+
+// extractArchive extracts the zip file downloaded by DownloadDir into dst.
+// The first element of the names in the archive is the downloaded item, and
+// the exclude patterns are relative to it.
+func extractArchive(f *os.File, dst string, exclude []string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("Error reading archive: %s", err)
+	}
+
+	for _, zf := range r.File {
+		name := path.Clean("/" + zf.Name)[1:]
+		if name == "" {
+			continue
+		}
+		if parts := strings.SplitN(name, "/", 2); len(parts) == 2 && packer.ExcludedPath(parts[1], exclude) {
+			log.Printf("Skipping excluded '%s'", name)
+			continue
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		if strings.HasSuffix(zf.Name, "/") {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := extractArchiveFile(zf, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractArchiveFile(zf *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	r, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
 }
 
 func (c *Communicator) getClientConfig() *winrmcp.Config {
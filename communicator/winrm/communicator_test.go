@@ -1,8 +1,15 @@
 package winrm
 
 import (
+	"archive/zip"
 	"bytes"
+	"encoding/base64"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -121,3 +128,97 @@ func TestUpload(t *testing.T) {
 	}
 
 }
+
+// matchPowershell matches encoded powershell commands containing text.
+func matchPowershell(text string) winrmtest.MatcherFunc {
+	return func(candidate string) bool {
+		prefix := "powershell.exe -EncodedCommand "
+		if !strings.HasPrefix(candidate, prefix) {
+			return false
+		}
+		wide, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(candidate, prefix))
+		if err != nil {
+			return false
+		}
+		return strings.Contains(strings.Replace(string(wide), "\x00", "", -1), text)
+	}
+}
+
+func TestDownloadDir(t *testing.T) {
+	// The archive the guest would make of C:/Temp/logs
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	for _, name := range []string{"logs/", "logs/a.txt", "logs/b.log", "logs/sub/", "logs/sub/c.txt", "logs/../../evil.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !strings.HasSuffix(name, "/") {
+			w.Write([]byte(name))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	wrm := winrmtest.NewRemote()
+	defer wrm.Close()
+	removed := false
+	wrm.CommandFunc(matchPowershell("Get-Item -Path 'C:/Temp/logs'"),
+		func(out, err io.Writer) int {
+			out.Write([]byte("C:\\Temp\\tmp1234.tmp\r\n"))
+			return 0
+		})
+	wrm.CommandFunc(matchPowershell(`ReadAllBytes("C:\Temp\tmp1234.tmp")`),
+		func(out, err io.Writer) int {
+			out.Write([]byte(base64.StdEncoding.EncodeToString(archive.Bytes())))
+			return 0
+		})
+	wrm.CommandFunc(matchPowershell("Remove-Item -LiteralPath 'C:\\Temp\\tmp1234.tmp'"),
+		func(out, err io.Writer) int {
+			removed = true
+			return 0
+		})
+	wrm.CommandFunc(winrmtest.MatchText("powershell"),
+		func(out, err io.Writer) int {
+			return 0
+		})
+
+	c, err := New(&Config{
+		Host:     wrm.Host,
+		Port:     wrm.Port,
+		Username: "user",
+		Password: "pass",
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("error creating communicator: %s", err)
+	}
+
+	dst, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dst)
+
+	if err := c.DownloadDir("C:/Temp/logs/", dst, []string{"*.log"}); err != nil {
+		t.Fatalf("error downloading dir: %s", err)
+	}
+
+	var files []string
+	filepath.Walk(dst, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dst, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	sort.Strings(files)
+	expected := []string{"evil.txt", "logs/a.txt", "logs/sub/c.txt"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("bad: %#v", files)
+	}
+	if !removed {
+		t.Fatal("archive should be removed from the guest")
+	}
+}
@@ -0,0 +1,605 @@
+package fix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/packer/template"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// HCL2Marker starts the comments that HCL2 leaves where part of a template
+// couldn't be converted, so that they are easy to search for.
+const HCL2Marker = "FIXME(packer fix):"
+
+var (
+	// hcl2UserRe matches the user function of the template engine when it
+	// is used on its own, which is what can be turned into a reference.
+	hcl2UserRe = regexp.MustCompile("\\{\\{\\s*user\\s+(`[^`]+`|\"[^\"]+\")\\s*\\}\\}")
+
+	// hcl2EnvRe is hcl2UserRe for the env function, which can be used in
+	// the defaults of variables.
+	hcl2EnvRe = regexp.MustCompile("\\{\\{\\s*env\\s+(`[^`]+`|\"[^\"]+\")\\s*\\}\\}")
+
+	// hcl2ArtifactRe matches the build name that the artifact function of
+	// the template engine is given.
+	hcl2ArtifactRe = regexp.MustCompile("(\\bartifact\\s+)(`[^`]+`|\"[^\"]+\")")
+
+	// hcl2VariableTypes are the HCL types of the types of user variables.
+	hcl2VariableTypes = map[string]hclwrite.Tokens{
+		"string": hcl2Ident("string"),
+		"number": hcl2Ident("number"),
+		"bool":   hcl2Ident("bool"),
+		"list":   hcl2Call("list", hcl2Ident("any")),
+		"map":    hcl2Call("map", hcl2Ident("any")),
+	}
+)
+
+// hcl2Expr is an expression that is written as it is, such as a reference.
+type hcl2Expr hclwrite.Tokens
+
+// HCL2 converts a template to the HCL format that template.ParseHCL reads.
+// Each builder becomes a source named after the builder, and a single build
+// block runs all of them, so a builder named NAME of type TYPE runs as a
+// build named TYPE.NAME. Builders whose names aren't identifiers are
+// renamed, with a marked comment giving their original name. Uses of
+// {{user `x`}} become ${var.x} references, and uses of {{env `X`}} in
+// variable defaults become calls to env("X"). Anything that can't be
+// written in HCL is left as a comment starting with HCL2Marker.
+func HCL2(tpl *template.Template) ([]byte, error) {
+	w := &hcl2Writer{
+		file:     hclwrite.NewEmptyFile(),
+		builders: make(map[string]string),
+	}
+	w.bodies = []*hclwrite.Body{w.file.Body()}
+
+	// Builders are named TYPE.NAME in HCL templates. Names that aren't
+	// identifiers are renamed, after the ones that are so that a new name
+	// never takes the name of another builder.
+	names := make([]string, 0, len(tpl.Builders))
+	taken := make(map[string]bool)
+	for name, b := range tpl.Builders {
+		names = append(names, name)
+		if hclsyntax.ValidIdentifier(name) {
+			w.builders[name] = b.Type + "." + name
+			taken[w.builders[name]] = true
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := w.builders[name]; ok {
+			continue
+		}
+		b := tpl.Builders[name]
+		base := hcl2Name(name)
+		hclName := b.Type + "." + base
+		for i := 2; taken[hclName]; i++ {
+			hclName = fmt.Sprintf("%s.%s_%d", b.Type, base, i)
+		}
+		w.builders[name] = hclName
+		taken[hclName] = true
+	}
+
+	w.comments(tpl)
+
+	if tpl.Description != "" {
+		w.attribute("description", tpl.Description)
+		w.line()
+	}
+
+	if tpl.MinVersion != "" {
+		w.open("packer")
+		w.attribute("required_version", ">= "+tpl.MinVersion)
+		w.close()
+	}
+
+	w.variables(tpl)
+
+	for _, name := range names {
+		b := tpl.Builders[name]
+		hclName := strings.TrimPrefix(w.builders[name], b.Type+".")
+		if hclName != name {
+			w.fixme("the builder %q is named %q, since the name of a source must be an identifier", name, hclName)
+		}
+		w.open("source", b.Type, hclName)
+		w.config(b.Config)
+		if b.Timeout > 0 {
+			w.attribute("timeout", b.Timeout.String())
+		}
+		if len(b.DependsOn) > 0 {
+			w.attribute("depends_on", w.sourceRefs(b.DependsOn))
+		}
+		w.close()
+	}
+
+	if len(names) > 0 {
+		w.open("build")
+		w.attribute("sources", w.sourceRefs(names))
+
+		for _, p := range tpl.Provisioners {
+			w.line()
+			w.provisioner(p)
+		}
+
+		for _, chain := range tpl.PostProcessors {
+			w.line()
+			if len(chain) == 1 {
+				w.postProcessor(chain[0])
+				continue
+			}
+
+			w.open("post-processors")
+			for i, pp := range chain {
+				if i > 0 {
+					w.line()
+				}
+				w.postProcessor(pp)
+			}
+			w.close()
+		}
+
+		w.close()
+	}
+
+	w.push(tpl.Push)
+
+	return bytes.TrimSpace(hclwrite.Format(w.file.Bytes())), nil
+}
+
+// hcl2Writer writes an HCL template one block at a time.
+type hcl2Writer struct {
+	file *hclwrite.File
+
+	// bodies are the bodies of the blocks being written, the innermost
+	// last.
+	bodies []*hclwrite.Body
+
+	// builders maps the builder names of the template to their HCL names.
+	builders map[string]string
+}
+
+func (w *hcl2Writer) body() *hclwrite.Body {
+	return w.bodies[len(w.bodies)-1]
+}
+
+// line writes an empty line.
+func (w *hcl2Writer) line() {
+	w.body().AppendNewline()
+}
+
+// comment writes a comment line.
+func (w *hcl2Writer) comment(format string, args ...interface{}) {
+	w.body().AppendUnstructuredTokens(hclwrite.Tokens{{
+		Type:  hclsyntax.TokenComment,
+		Bytes: []byte("# " + fmt.Sprintf(format, args...) + "\n"),
+	}})
+}
+
+// fixme leaves a marked comment about something that wasn't converted.
+func (w *hcl2Writer) fixme(format string, args ...interface{}) {
+	w.comment("%s %s", HCL2Marker, fmt.Sprintf(format, args...))
+}
+
+// open starts a block with the given labels.
+func (w *hcl2Writer) open(typ string, labels ...string) {
+	block := w.body().AppendNewBlock(typ, labels)
+	w.bodies = append(w.bodies, block.Body())
+}
+
+func (w *hcl2Writer) close() {
+	w.bodies = w.bodies[:len(w.bodies)-1]
+	if len(w.bodies) == 1 {
+		w.line()
+	}
+}
+
+func (w *hcl2Writer) comments(tpl *template.Template) {
+	keys := make([]string, 0, len(tpl.Comments))
+	for k := range tpl.Comments {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, l := range strings.Split(tpl.Comments[k], "\n") {
+			w.comment("%s", l)
+		}
+	}
+	if len(keys) > 0 {
+		w.line()
+	}
+}
+
+func (w *hcl2Writer) variables(tpl *template.Template) {
+	sensitive := make(map[string]bool)
+	for _, v := range tpl.SensitiveVariables {
+		sensitive[v.Key] = true
+	}
+
+	keys := make([]string, 0, len(tpl.Variables))
+	for k := range tpl.Variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := tpl.Variables[k]
+		if !hclsyntax.ValidIdentifier(k) {
+			w.fixme("variable %q: the name of a variable must be an identifier", k)
+			w.line()
+			continue
+		}
+
+		w.open("variable", k)
+		if v.Type != "" {
+			w.attribute("type", hcl2Expr(hcl2VariableTypes[v.Type]))
+		}
+		if v.Description != "" {
+			w.attribute("description", v.Description)
+		}
+		if !v.Required {
+			w.variableDefault(v)
+		}
+		if len(v.AllowedValues) > 0 {
+			w.attribute("allowed_values", v.AllowedValues)
+		}
+		if v.Pattern != "" {
+			w.attribute("pattern", v.Pattern)
+		}
+		if sensitive[k] {
+			w.attribute("sensitive", true)
+		}
+		w.close()
+	}
+}
+
+// variableDefault writes the default of a variable as a value of its type.
+// The default can only read the environment, not other variables.
+func (w *hcl2Writer) variableDefault(v *template.Variable) {
+	var value interface{} = v.Default
+	switch v.Type {
+	case "number":
+		if f, err := strconv.ParseFloat(v.Default, 64); err == nil {
+			value = f
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(v.Default); err == nil {
+			value = b
+		}
+	case "list", "map":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(v.Default), &decoded); err == nil {
+			value = decoded
+		}
+	}
+
+	toks, err := hcl2Value(value, hcl2Default)
+	if err != nil {
+		w.fixme("default = %q: %s", v.Default, err)
+		return
+	}
+	w.body().AppendUnstructuredTokens(hcl2Attribute("default", toks))
+}
+
+func (w *hcl2Writer) provisioner(p *template.Provisioner) {
+	w.open("provisioner", p.Type)
+	w.onlyExcept(p.OnlyExcept)
+	if p.PauseBefore > 0 {
+		w.attribute("pause_before", p.PauseBefore.String())
+	}
+	if p.Timeout > 0 {
+		w.attribute("timeout", p.Timeout.String())
+	}
+	if p.MaxRetries > 0 {
+		w.attribute("max_retries", p.MaxRetries)
+	}
+	if p.RetryBackoff > 0 {
+		w.attribute("retry_backoff", p.RetryBackoff.String())
+	}
+	w.config(p.Config)
+
+	if len(p.Override) > 0 {
+		override := make(map[string]interface{}, len(p.Override))
+		for name, v := range p.Override {
+			if hclName, ok := w.builders[name]; ok {
+				name = hclName
+			}
+			override[name] = v
+		}
+		w.attribute("override", override)
+	}
+	w.close()
+}
+
+func (w *hcl2Writer) postProcessor(pp *template.PostProcessor) {
+	w.open("post-processor", pp.Type)
+	if pp.Name != "" && pp.Name != pp.Type {
+		w.attribute("name", pp.Name)
+	}
+	w.onlyExcept(pp.OnlyExcept)
+	if pp.KeepInputArtifact {
+		w.attribute("keep_input_artifact", true)
+	}
+	w.config(pp.Config)
+	w.close()
+}
+
+func (w *hcl2Writer) onlyExcept(oe template.OnlyExcept) {
+	if len(oe.Only) > 0 {
+		w.attribute("only", w.sourceRefs(oe.Only))
+	}
+	if len(oe.Except) > 0 {
+		w.attribute("except", w.sourceRefs(oe.Except))
+	}
+}
+
+// sourceRefs returns the references to the sources of the given builders.
+func (w *hcl2Writer) sourceRefs(names []string) []interface{} {
+	refs := make([]interface{}, len(names))
+	for i, name := range names {
+		hclName, ok := w.builders[name]
+		if !ok {
+			// Leave the unknown name for the parser to point at.
+			refs[i] = name
+			continue
+		}
+
+		parts := strings.SplitN(hclName, ".", 2)
+		refs[i] = hcl2Expr(hcl2Ref("source", parts[0], parts[1]))
+	}
+	return refs
+}
+
+// push leaves the push configuration as a comment, since HCL templates
+// don't have it.
+func (w *hcl2Writer) push(push template.Push) {
+	if push.Name == "" {
+		return
+	}
+
+	out, _ := json.MarshalIndent(push, "", "  ")
+	w.fixme(`the "push" section can't be written in HCL:`)
+	for _, l := range strings.Split(string(out), "\n") {
+		w.comment("%s", l)
+	}
+}
+
+// config writes the configuration of a component in sorted order.
+func (w *hcl2Writer) config(config map[string]interface{}) {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		w.attribute(k, config[k])
+	}
+}
+
+// attribute writes `key = value`. Values that can't be written leave a
+// marked comment instead.
+func (w *hcl2Writer) attribute(key string, v interface{}) {
+	toks, err := hcl2Value(v, w.interpolate)
+	if err == nil && !hclsyntax.ValidIdentifier(key) {
+		err = fmt.Errorf("%q is not a valid argument name", key)
+	}
+	if err != nil {
+		out, _ := json.Marshal(v)
+		w.fixme("%s = %s: %s", key, out, err)
+		return
+	}
+	w.body().AppendUnstructuredTokens(hcl2Attribute(key, toks))
+}
+
+// interpolate writes a string of a configuration, turning the uses of the
+// user function into references to the variables. The builds that the uses
+// of the artifact function refer to are renamed, since builds are named
+// TYPE.NAME in HCL templates.
+func (w *hcl2Writer) interpolate(s string) (hclwrite.Tokens, error) {
+	s = hcl2ArtifactRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := hcl2ArtifactRe.FindStringSubmatch(m)
+		name, ok := w.builders[sub[2][1:len(sub[2])-1]]
+		if !ok {
+			return m
+		}
+		return sub[1] + "`" + name + "`"
+	})
+
+	return hcl2Template(s, hcl2UserRe, func(name string) (hclwrite.Tokens, error) {
+		if !hclsyntax.ValidIdentifier(name) {
+			return nil, fmt.Errorf("variable %q can't be referred to in HCL", name)
+		}
+		return hcl2Ref("var", name), nil
+	})
+}
+
+// hcl2Default writes a string of a variable default, turning the uses of
+// the env function into calls to env.
+func hcl2Default(s string) (hclwrite.Tokens, error) {
+	if hcl2UserRe.MatchString(s) {
+		return nil, fmt.Errorf("the default of a variable can't refer to other variables in HCL")
+	}
+
+	return hcl2Template(s, hcl2EnvRe, func(name string) (hclwrite.Tokens, error) {
+		return hcl2Call("env", hcl2String(name)), nil
+	})
+}
+
+// hcl2Template writes a string where the matches of re are replaced by the
+// expressions that expr returns for the name they are given. A string that
+// is a single match is written as the expression itself.
+func hcl2Template(s string, re *regexp.Regexp, expr func(string) (hclwrite.Tokens, error)) (hclwrite.Tokens, error) {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return expr(s[matches[0][2]+1 : matches[0][3]-1])
+	}
+
+	toks := hclwrite.Tokens{{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)}}
+	last := 0
+	for _, m := range matches {
+		toks = append(toks, hcl2Literal(s[last:m[0]])...)
+
+		e, err := expr(s[m[2]+1 : m[3]-1])
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenTemplateInterp, Bytes: []byte("${")})
+		toks = append(toks, e...)
+		toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenTemplateSeqEnd, Bytes: []byte("}")})
+		last = m[1]
+	}
+	toks = append(toks, hcl2Literal(s[last:])...)
+	toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)})
+
+	return toks, nil
+}
+
+// hcl2Value returns the tokens of a value of a configuration, writing its
+// strings with str.
+func hcl2Value(v interface{}, str func(string) (hclwrite.Tokens, error)) (hclwrite.Tokens, error) {
+	switch v := v.(type) {
+	case hcl2Expr:
+		return hclwrite.Tokens(v), nil
+	case nil:
+		return nil, fmt.Errorf("null values can't be written in HCL")
+	case string:
+		return str(v)
+	case bool:
+		return hclwrite.TokensForValue(cty.BoolVal(v)), nil
+	case int:
+		return hclwrite.TokensForValue(cty.NumberIntVal(int64(v))), nil
+	case float64:
+		return hclwrite.TokensForValue(cty.NumberFloatVal(v)), nil
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return hcl2Value(list, str)
+	case []interface{}:
+		toks := hclwrite.Tokens{{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")}}
+		for i, elem := range v {
+			if i > 0 {
+				toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+			}
+			elemToks, err := hcl2Value(elem, str)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, elemToks...)
+		}
+		return append(toks, &hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")}), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		toks := hclwrite.Tokens{
+			{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")},
+			{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+		}
+		for _, k := range keys {
+			valueToks, err := hcl2Value(v[k], str)
+			if err != nil {
+				return nil, err
+			}
+			if hclsyntax.ValidIdentifier(k) {
+				toks = append(toks, hcl2Ident(k)...)
+			} else {
+				toks = append(toks, hcl2String(k)...)
+			}
+			toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte("=")})
+			toks = append(toks, valueToks...)
+			toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+		}
+		return append(toks, &hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte("}")}), nil
+	}
+
+	return nil, fmt.Errorf("values of type %T can't be written in HCL", v)
+}
+
+// hcl2Attribute returns the tokens of `key = value`.
+func hcl2Attribute(key string, value hclwrite.Tokens) hclwrite.Tokens {
+	toks := hcl2Ident(key)
+	toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte("=")})
+	toks = append(toks, value...)
+	return append(toks, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+}
+
+// hcl2Name turns a name into an identifier, replacing each run of the
+// characters that can't be in one with an underscore.
+func hcl2Name(name string) string {
+	var b strings.Builder
+	invalid := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			invalid = true
+			continue
+		}
+		if invalid && b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		invalid = false
+		b.WriteRune(r)
+	}
+
+	// Identifiers can't start with a digit or a dash.
+	id := b.String()
+	if !hclsyntax.ValidIdentifier(id) {
+		id = "_" + id
+	}
+	return id
+}
+
+func hcl2Ident(name string) hclwrite.Tokens {
+	return hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(name)}}
+}
+
+// hcl2Ref returns the tokens of a reference like var.NAME.
+func hcl2Ref(names ...string) hclwrite.Tokens {
+	var toks hclwrite.Tokens
+	for i, name := range names {
+		if i > 0 {
+			toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenDot, Bytes: []byte(".")})
+		}
+		toks = append(toks, hcl2Ident(name)...)
+	}
+	return toks
+}
+
+// hcl2Call returns the tokens of a function call.
+func hcl2Call(name string, args ...hclwrite.Tokens) hclwrite.Tokens {
+	toks := hcl2Ident(name)
+	toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenOParen, Bytes: []byte("(")})
+	for i, arg := range args {
+		if i > 0 {
+			toks = append(toks, &hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")})
+		}
+		toks = append(toks, arg...)
+	}
+	return append(toks, &hclwrite.Token{Type: hclsyntax.TokenCParen, Bytes: []byte(")")})
+}
+
+// hcl2String returns the tokens of a string that is taken as it is, so ${
+// and %{ in it are escaped.
+func hcl2String(s string) hclwrite.Tokens {
+	return hclwrite.TokensForValue(cty.StringVal(s))
+}
+
+// hcl2Literal is hcl2String without the quotes, for the literal parts of a
+// string with interpolations.
+func hcl2Literal(s string) hclwrite.Tokens {
+	toks := hcl2String(s)
+	return toks[1 : len(toks)-1]
+}
@@ -0,0 +1,261 @@
+package fix
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+const hcl2TestTemplate = `{
+  "_comment": "Builds the base image",
+  "min_packer_version": "1.3.0",
+  "variables": {
+    "region": "us-east-1",
+    "home": "{{env ` + "`HOME`" + `}}",
+    "password": null
+  },
+  "sensitive-variables": ["password"],
+  "builders": [
+    {
+      "type": "qemu",
+      "vm_name": "base-{{user ` + "`region`" + `}}",
+      "disk_size": 4096,
+      "headless": true,
+      "boot_command": ["<tab> ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg<enter>"],
+      "timeout": "1h"
+    },
+    {
+      "type": "virtualbox-iso",
+      "name": "vbox",
+      "guest_additions_path": null
+    }
+  ],
+  "provisioners": [
+    {
+      "type": "shell",
+      "inline": ["echo ${HOME} \"{{user ` + "`password`" + `}}\""],
+      "only": ["qemu"],
+      "max_retries": 2
+    }
+  ],
+  "post-processors": [
+    [
+      "compress",
+      {"type": "manifest", "except": ["vbox"]}
+    ]
+  ],
+  "push": {"name": "test/base"}
+}`
+
+func TestHCL2(t *testing.T) {
+	tpl, err := template.Parse(strings.NewReader(hcl2TestTemplate))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	out, err := HCL2(tpl)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	hcl := string(out)
+
+	// Compare without the alignment of the formatting.
+	fields := strings.Join(strings.Fields(hcl), " ")
+	for _, expected := range []string{
+		"# Builds the base image",
+		`required_version = ">= 1.3.0"`,
+		`source "qemu" "qemu" {`,
+		`vm_name = "base-${var.region}"`,
+		`default = env("HOME")`,
+		`inline = ["echo $${HOME} \"${var.password}\""]`,
+		`only = [source.qemu.qemu]`,
+		`sources = [source.qemu.qemu, source.virtualbox-iso.vbox]`,
+		`# FIXME(packer fix): guest_additions_path = null: null values can't be written in HCL`,
+		`# FIXME(packer fix): the "push" section can't be written in HCL:`,
+	} {
+		if !strings.Contains(fields, expected) {
+			t.Fatalf("expected %q in:\n%s", expected, hcl)
+		}
+	}
+
+	// The converted template must read back as the same builds.
+	converted, err := template.ParseHCL(strings.NewReader(hcl), "test.pkr.hcl")
+	if err != nil {
+		t.Fatalf("err: %s\n%s", err, hcl)
+	}
+	if err := converted.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b := converted.Builders["qemu.qemu"]
+	if b == nil || b.Timeout != tpl.Builders["qemu"].Timeout {
+		t.Fatalf("bad: %#v", converted.Builders)
+	}
+	for k, v := range tpl.Builders["qemu"].Config {
+		switch k {
+		case "disk_size":
+			v = float64(4096)
+		case "vm_name":
+			// References are evaluated with the default of the variable
+			v = "base-us-east-1"
+		}
+		if got := b.Config[k]; !equalConfig(got, v) {
+			t.Fatalf("%s: %#v != %#v", k, got, v)
+		}
+	}
+
+	p := converted.Provisioners[0]
+	if p.MaxRetries != 2 || len(p.Only) != 1 || p.Only[0] != "qemu.qemu" {
+		t.Fatalf("bad: %#v", p)
+	}
+	if got := p.Config["inline"].([]interface{})[0]; got != "echo ${HOME} \"{{user `password`}}\"" {
+		t.Fatalf("bad: %#v", got)
+	}
+
+	chain := converted.PostProcessors[0]
+	if len(chain) != 2 || chain[1].Only[0] != "qemu.qemu" {
+		t.Fatalf("bad: %#v", chain)
+	}
+
+	if v := converted.Variables["password"]; !v.Required || len(converted.SensitiveVariables) != 1 {
+		t.Fatalf("bad: %#v", v)
+	}
+	if v := converted.Variables["home"]; v.Default != os.Getenv("HOME") {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestHCL2_escape(t *testing.T) {
+	tpl := &template.Template{
+		Variables: map[string]*template.Variable{
+			"sizes": {Key: "sizes", Type: "list", Default: `[1, 2]`},
+			"dir":   {Key: "dir", Default: "{{env `HOME`}}/${x}"},
+		},
+		Builders: map[string]*template.Builder{
+			"null": {
+				Name:   "null",
+				Type:   "null",
+				Config: map[string]interface{}{"command": "echo ${ %{ {{user `dir`}}"},
+			},
+		},
+	}
+
+	out, err := HCL2(tpl)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	converted, err := template.ParseHCL(bytes.NewReader(out), "test.pkr.hcl")
+	if err != nil {
+		t.Fatalf("err: %s\n%s", err, out)
+	}
+	dir := os.Getenv("HOME") + "/${x}"
+	if v := converted.Builders["null.null"].Config["command"]; v != "echo ${ %{ "+dir {
+		t.Fatalf("bad: %#v\n%s", v, out)
+	}
+	if v := converted.Variables["sizes"]; v.Type != "list" || v.Default != "[1,2]" {
+		t.Fatalf("bad: %#v\n%s", v, out)
+	}
+}
+
+func TestHCL2_dependsOn(t *testing.T) {
+	tpl := &template.Template{
+		Builders: map[string]*template.Builder{
+			"base": {
+				Name: "base",
+				Type: "qemu",
+			},
+			"app": {
+				Name:      "app",
+				Type:      "qemu",
+				DependsOn: []string{"base"},
+				Config:    map[string]interface{}{"iso_url": "{{ artifact `base` `files` }}"},
+			},
+		},
+	}
+
+	out, err := HCL2(tpl)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	converted, err := template.ParseHCL(bytes.NewReader(out), "test.pkr.hcl")
+	if err != nil {
+		t.Fatalf("err: %s\n%s", err, out)
+	}
+	if err := converted.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	b := converted.Builders["qemu.app"]
+	if len(b.DependsOn) != 1 || b.DependsOn[0] != "qemu.base" {
+		t.Fatalf("bad: %#v", b.DependsOn)
+	}
+	if v := b.Config["iso_url"]; v != "{{ artifact `qemu.base` `files` }}" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestHCL2_names(t *testing.T) {
+	tpl := &template.Template{
+		Builders: map[string]*template.Builder{
+			"n-{{user `size`}}": {Name: "n-{{user `size`}}", Type: "null"},
+			"2nd":               {Name: "2nd", Type: "null"},
+			"my builder":        {Name: "my builder", Type: "null"},
+			"my_builder":        {Name: "my_builder", Type: "null"},
+		},
+		Provisioners: []*template.Provisioner{
+			{
+				Type:       "shell",
+				OnlyExcept: template.OnlyExcept{Only: []string{"my builder"}},
+			},
+		},
+	}
+
+	out, err := HCL2(tpl)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	hcl := string(out)
+
+	for _, expected := range []string{
+		`# FIXME(packer fix): the builder "2nd" is named "_2nd"`,
+		`# FIXME(packer fix): the builder "my builder" is named "my_builder_2"`,
+		`# FIXME(packer fix): the builder "n-{{user ` + "`size`" + `}}" is named "n-_user_size"`,
+		`only = [source.null.my_builder_2]`,
+	} {
+		if !strings.Contains(hcl, expected) {
+			t.Fatalf("expected %q in:\n%s", expected, hcl)
+		}
+	}
+
+	converted, err := template.ParseHCL(bytes.NewReader(out), "test.pkr.hcl")
+	if err != nil {
+		t.Fatalf("err: %s\n%s", err, out)
+	}
+	for _, name := range []string{"null._2nd", "null.my_builder", "null.my_builder_2", "null.n-_user_size"} {
+		if _, ok := converted.Builders[name]; !ok {
+			t.Fatalf("no %s in %#v", name, converted.Builders)
+		}
+	}
+}
+
+func equalConfig(a, b interface{}) bool {
+	al, aok := a.([]interface{})
+	bl, bok := b.([]interface{})
+	if aok && bok {
+		if len(al) != len(bl) {
+			return false
+		}
+		for i := range al {
+			if !equalConfig(al[i], bl[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -46,6 +46,8 @@ type Config struct {
 	SSHBastionPassword        string        `mapstructure:"ssh_bastion_password"`
 	SSHBastionPrivateKeyFile  string        `mapstructure:"ssh_bastion_private_key_file"`
 	SSHFileTransferMethod     string        `mapstructure:"ssh_file_transfer_method"`
+	SSHFileTransferRequests   int           `mapstructure:"ssh_file_transfer_requests"`
+	SSHDisableChecksum        bool          `mapstructure:"ssh_disable_file_transfer_checksum"`
 	SSHProxyHost              string        `mapstructure:"ssh_proxy_host"`
 	SSHProxyPort              int           `mapstructure:"ssh_proxy_port"`
 	SSHProxyUsername          string        `mapstructure:"ssh_proxy_username"`
@@ -67,6 +69,9 @@ type Config struct {
 	WinRMUseNTLM            bool          `mapstructure:"winrm_use_ntlm"`
 	WinRMTransportDecorator func() winrm.Transporter
 
+	// QEMU guest agent
+	QGATimeout time.Duration `mapstructure:"qga_timeout"`
+
 	// Delay
 	PauseBeforeConnect time.Duration `mapstructure:"pause_before_connecting"`
 }
@@ -212,6 +217,11 @@ func (c *Config) Prepare(ctx *interpolate.Context) []error {
 		if es := c.prepareWinRM(ctx); len(es) > 0 {
 			errs = append(errs, es...)
 		}
+	case "qemu-guest-agent":
+		if ctx == nil || ctx.BuildType != "qemu" {
+			return []error{fmt.Errorf("Communicator type %s only works with the qemu builder", c.Type)}
+		}
+		c.prepareQGA()
 	case "docker", "none":
 		break
 	default:
@@ -303,6 +313,10 @@ func (c *Config) prepareSSH(ctx *interpolate.Context) []error {
 			c.SSHFileTransferMethod))
 	}
 
+	if c.SSHFileTransferRequests < 0 {
+		errs = append(errs, errors.New("ssh_file_transfer_requests can't be negative"))
+	}
+
 	if c.SSHBastionHost != "" && c.SSHProxyHost != "" {
 		errs = append(errs, errors.New("please specify either ssh_bastion_host or ssh_proxy_host, not both"))
 	}
@@ -332,3 +346,9 @@ func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 
 	return errs
 }
+
+func (c *Config) prepareQGA() {
+	if c.QGATimeout == 0 {
+		c.QGATimeout = 5 * time.Minute
+	}
+}
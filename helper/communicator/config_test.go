@@ -3,6 +3,7 @@ package communicator
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/template/interpolate"
 	"github.com/masterzen/winrm"
@@ -32,6 +33,22 @@ func TestConfig_none(t *testing.T) {
 	}
 }
 
+func TestConfig_qga(t *testing.T) {
+	c := &Config{Type: "qemu-guest-agent"}
+	if err := c.Prepare(&interpolate.Context{BuildType: "qemu"}); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+	if c.QGATimeout != 5*time.Minute {
+		t.Fatalf("bad: %s", c.QGATimeout)
+	}
+
+	// Only the qemu builder attaches the guest agent
+	c = &Config{Type: "qemu-guest-agent"}
+	if err := c.Prepare(&interpolate.Context{BuildType: "virtualbox-iso"}); len(err) != 1 {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
 func TestConfig_badtype(t *testing.T) {
 	c := &Config{Type: "foo"}
 	if err := c.Prepare(testContext(t)); len(err) != 1 {
@@ -39,6 +56,14 @@ func TestConfig_badtype(t *testing.T) {
 	}
 }
 
+func TestConfig_sshFileTransferRequests(t *testing.T) {
+	c := testConfig()
+	c.SSHFileTransferRequests = -1
+	if err := c.Prepare(testContext(t)); len(err) != 1 {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
 func TestConfig_winrm_noport(t *testing.T) {
 	c := &Config{
 		Type:      "winrm",
@@ -37,6 +37,11 @@ type StepConnect struct {
 	WinRMConfig func(multistep.StateBag) (*WinRMConfig, error)
 	WinRMPort   func(multistep.StateBag) (int, error)
 
+	// QGASocketPath should return the path of the unix socket of the QEMU
+	// guest agent, for connecting via the guest agent. Only builders that
+	// set it support the "qemu-guest-agent" communicator.
+	QGASocketPath func(multistep.StateBag) (string, error)
+
 	// CustomConnect can be set to have custom connectors for specific
 	// types. These take highest precedence so you can also override
 	// existing types.
@@ -75,6 +80,12 @@ func (s *StepConnect) Run(ctx context.Context, state multistep.StateBag) multist
 			WinRMPort:   s.WinRMPort,
 		},
 	}
+	if s.QGASocketPath != nil {
+		typeMap["qemu-guest-agent"] = &StepConnectQGA{
+			Config:        s.Config,
+			QGASocketPath: s.QGASocketPath,
+		}
+	}
 	for k, v := range s.CustomConnect {
 		typeMap[k] = v
 	}
@@ -0,0 +1,110 @@
+package communicator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/communicator/qga"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepConnectQGA is a multistep Step implementation that waits for the QEMU
+// guest agent of the VM to respond on its virtio-serial socket.
+//
+// Uses:
+//   ui packer.Ui
+//
+// Produces:
+//   communicator packer.Communicator
+type StepConnectQGA struct {
+	// All the fields below are documented on StepConnect
+	Config        *Config
+	QGASocketPath func(multistep.StateBag) (string, error)
+
+	comm *qga.Communicator
+}
+
+func (s *StepConnectQGA) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	ui := state.Get("ui").(packer.Ui)
+
+	var comm *qga.Communicator
+	var err error
+
+	cancel := make(chan struct{})
+	waitDone := make(chan bool, 1)
+	go func() {
+		ui.Say("Waiting for the QEMU guest agent to become available...")
+		comm, err = s.waitForQGA(state, cancel)
+		waitDone <- true
+	}()
+
+	log.Printf("Waiting for the QEMU guest agent, up to timeout: %s", s.Config.QGATimeout)
+	timeout := time.After(s.Config.QGATimeout)
+	for {
+		// Wait for either the guest agent to respond, a timeout to occur,
+		// or an interrupt to come through.
+		select {
+		case <-waitDone:
+			if err != nil {
+				ui.Error(fmt.Sprintf("Error waiting for the QEMU guest agent: %s", err))
+				return multistep.ActionHalt
+			}
+
+			ui.Say("Connected to the QEMU guest agent!")
+			s.comm = comm
+			state.Put("communicator", comm)
+			return multistep.ActionContinue
+		case <-timeout:
+			err := fmt.Errorf("Timeout waiting for the QEMU guest agent.")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			close(cancel)
+			return multistep.ActionHalt
+		case <-time.After(1 * time.Second):
+			if _, ok := state.GetOk(multistep.StateCancelled); ok {
+				// The step sequence was cancelled, so cancel waiting for the
+				// guest agent and just start the halting process.
+				close(cancel)
+				log.Println("Interrupt detected, quitting waiting for the QEMU guest agent.")
+				return multistep.ActionHalt
+			}
+		}
+	}
+}
+
+func (s *StepConnectQGA) Cleanup(multistep.StateBag) {
+	if s.comm != nil {
+		s.comm.Close()
+		s.comm = nil
+	}
+}
+
+func (s *StepConnectQGA) waitForQGA(state multistep.StateBag, cancel <-chan struct{}) (*qga.Communicator, error) {
+	for {
+		select {
+		case <-cancel:
+			log.Println("[INFO] QEMU guest agent wait cancelled. Exiting loop.")
+			return nil, errors.New("QEMU guest agent wait cancelled")
+		case <-time.After(2 * time.Second):
+		}
+
+		socketPath, err := s.QGASocketPath(state)
+		if err != nil {
+			log.Printf("[DEBUG] Error getting QEMU guest agent socket: %s", err)
+			continue
+		}
+
+		log.Println("[INFO] Attempting QEMU guest agent connection...")
+		comm, err := qga.New(&qga.Config{SocketPath: socketPath})
+		if err != nil {
+			// The guest agent doesn't respond until the guest started it
+			log.Printf("[DEBUG] QEMU guest agent connection err: %s", err)
+			continue
+		}
+		return comm, nil
+	}
+}
@@ -182,8 +182,11 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 			Pty:                    s.Config.SSHPty,
 			DisableAgentForwarding: s.Config.SSHDisableAgentForwarding,
 			UseSftp:                s.Config.SSHFileTransferMethod == "sftp",
+			SftpRequests:           s.Config.SSHFileTransferRequests,
+			DisableChecksum:        s.Config.SSHDisableChecksum,
 			KeepAliveInterval:      s.Config.SSHKeepAliveInterval,
 			Timeout:                s.Config.SSHReadWriteTimeout,
+			Ui:                     state.Get("ui").(packer.Ui),
 		}
 
 		log.Printf("[INFO] Attempting SSH connection to %s...", address)
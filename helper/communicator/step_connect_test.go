@@ -29,6 +29,22 @@ func TestStepConnect_none(t *testing.T) {
 	}
 }
 
+func TestStepConnect_qgaUnsupported(t *testing.T) {
+	state := testState(t)
+
+	// Only builders that have a guest agent socket support it
+	step := &StepConnect{
+		Config: &Config{
+			Type: "qemu-guest-agent",
+		},
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
 func testState(t *testing.T) multistep.StateBag {
 	state := new(multistep.BasicStateBag)
 	state.Put("hook", &packer.MockHook{})
@@ -1,4 +1,4 @@
-package common
+package communicator
 
 import (
 	"context"
@@ -6,21 +6,22 @@ import (
 	"os"
 
 	"github.com/hashicorp/packer/common/uuid"
-	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/helper/ssh"
 	"github.com/hashicorp/packer/packer"
 )
 
-// StepSshKeyPair executes the business logic for setting the SSH key pair in
-// the specified communicator.Config.
-type StepSshKeyPair struct {
+// StepSSHKeyGen sets the SSH key pair of the communicator Config: the one of
+// ssh_private_key_file, or a new ephemeral one for builders that can put the
+// public key in the machine themselves, such as in a boot command or a
+// cloud-init seed. Nothing is done when a password or the SSH agent is used.
+type StepSSHKeyGen struct {
 	Debug        bool
 	DebugKeyPath string
-	Comm         *communicator.Config
+	Comm         *Config
 }
 
-func (s *StepSshKeyPair) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepSSHKeyGen) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
 	if s.Comm.SSHPassword != "" {
 		return multistep.ActionContinue
 	}
@@ -96,7 +97,7 @@ func (s *StepSshKeyPair) Run(_ context.Context, state multistep.StateBag) multis
 	return multistep.ActionContinue
 }
 
-func (s *StepSshKeyPair) Cleanup(state multistep.StateBag) {
+func (s *StepSSHKeyGen) Cleanup(state multistep.StateBag) {
 	if s.Debug {
 		if err := os.Remove(s.DebugKeyPath); err != nil {
 			ui := state.Get("ui").(packer.Ui)
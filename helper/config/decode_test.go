@@ -51,6 +51,23 @@ func TestDecode(t *testing.T) {
 			nil,
 		},
 
+		"artifacts": {
+			[]interface{}{
+				map[string]interface{}{
+					"name": "{{artifact `base` `id`}}",
+				},
+				map[string]interface{}{
+					"packer_build_artifacts": map[string]map[string]string{
+						"base": {"id": "bar"},
+					},
+				},
+			},
+			&Target{
+				Name: "bar",
+			},
+			nil,
+		},
+
 		"filter": {
 			[]interface{}{
 				map[string]interface{}{
@@ -2,6 +2,7 @@ package multistep
 
 import (
 	"context"
+	"log"
 	"sync"
 	"sync/atomic"
 )
@@ -20,6 +21,14 @@ type BasicRunner struct {
 	// modified.
 	Steps []Step
 
+	// Checkpoints, if set, is where the runner records which steps completed
+	// so that a failed run can be resumed. When a checkpoint from an earlier
+	// run of the same steps exists, completed steps that implement
+	// ResumableStep are skipped and their saved state is restored. If the run
+	// halts, the cleanup of completed resumable steps is skipped so that the
+	// resources they created are still there for the next run.
+	Checkpoints CheckpointStore
+
 	cancel context.CancelFunc
 	doneCh chan struct{}
 	state  runState
@@ -27,7 +36,11 @@ type BasicRunner struct {
 }
 
 func (b *BasicRunner) Run(state StateBag) {
-	ctx, cancel := context.WithCancel(context.Background())
+	b.RunContext(context.Background(), state)
+}
+
+func (b *BasicRunner) RunContext(ctx context.Context, state StateBag) {
+	ctx, cancel := context.WithCancel(ctx)
 
 	b.l.Lock()
 	if b.state != stateIdle {
@@ -61,16 +74,54 @@ func (b *BasicRunner) Run(state StateBag) {
 		}
 	}()
 
-	for _, step := range b.Steps {
+	var prior, cp *Checkpoint
+	if b.Checkpoints != nil {
+		var err error
+		prior, err = b.Checkpoints.Load()
+		if err != nil {
+			log.Printf("Ignoring checkpoint: %s", err)
+			prior = nil
+		}
+		cp = newCheckpoint(b.Steps)
+	}
+
+	// keep is set when the run halted and the resources of completed
+	// resumable steps should survive for the next run.
+	keep := false
+
+	for i, step := range b.Steps {
 		// We also check for cancellation here since we can't be sure
 		// the goroutine that is running to set it actually ran.
-		if runState(atomic.LoadInt32((*int32)(&b.state))) == stateCancelling {
+		if runState(atomic.LoadInt32((*int32)(&b.state))) == stateCancelling || ctx.Err() != nil {
 			state.Put(StateCancelled, true)
 			break
 		}
 
+		if prior.resumable(b.Steps, i) {
+			if prior.restore(step, state) {
+				log.Printf("Resuming: skipping completed step %s", StepName(step))
+				cp.record(step, i, state)
+				state.Put(StateResumed, true)
+				defer b.cleanup(step, state, &keep)
+				continue
+			}
+
+			// What the step left behind is gone, so the steps that
+			// came after it can't be skipped either.
+			log.Printf("Resuming: step %s can't be resumed, running it and the steps after it", StepName(step))
+			prior = nil
+		}
+
 		action := step.Run(ctx, state)
-		defer step.Cleanup(state)
+		if cp != nil && action == ActionContinue {
+			cp.record(step, i, state)
+			if err := b.Checkpoints.Save(cp); err != nil {
+				log.Printf("Error saving checkpoint: %s", err)
+			}
+			defer b.cleanup(step, state, &keep)
+		} else {
+			defer step.Cleanup(state)
+		}
 
 		if _, ok := state.GetOk(StateCancelled); ok {
 			break
@@ -81,6 +132,32 @@ func (b *BasicRunner) Run(state StateBag) {
 			break
 		}
 	}
+
+	if cp == nil {
+		return
+	}
+
+	_, cancelled := state.GetOk(StateCancelled)
+	_, halted := state.GetOk(StateHalted)
+	if halted && !cancelled {
+		keep = true
+		return
+	}
+
+	if err := b.Checkpoints.Remove(); err != nil {
+		log.Printf("Error removing checkpoint: %s", err)
+	}
+}
+
+// cleanup cleans up a step that completed while checkpointing, unless the
+// step is resumable and its resources should be kept for the next run.
+func (b *BasicRunner) cleanup(step Step, state StateBag, keep *bool) {
+	if _, ok := step.(ResumableStep); ok && *keep {
+		log.Printf("Keeping resources of step %s for resume", StepName(step))
+		return
+	}
+
+	step.Cleanup(state)
 }
 
 func (b *BasicRunner) Cancel() {
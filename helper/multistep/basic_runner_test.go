@@ -1,6 +1,7 @@
 package multistep
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
@@ -154,6 +155,50 @@ func TestBasicRunner_Cancel(t *testing.T) {
 	}
 }
 
+func TestBasicRunner_RunContext(t *testing.T) {
+	ch := make(chan chan bool)
+	data := new(BasicStateBag)
+	stepA := &TestStepAcc{Data: "a"}
+	stepInt := &TestStepSync{ch}
+	stepB := &TestStepAcc{Data: "b"}
+
+	r := &BasicRunner{Steps: []Step{stepA, stepInt, stepB}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		RunContext(ctx, r, data)
+		close(doneCh)
+	}()
+
+	// Cancel the context at the sync point
+	responseCh := <-ch
+	cancel()
+	for {
+		if _, ok := data.GetOk(StateCancelled); ok {
+			responseCh <- true
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	<-doneCh
+
+	// Test run data
+	expected := []string{"a"}
+	results := data.Get("data").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected result: %#v", results)
+	}
+
+	// Test cleanup data
+	results = data.Get("cleanup").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected result: %#v", results)
+	}
+}
+
 func TestBasicRunner_Cancel_Special(t *testing.T) {
 	stepOne := &TestStepInjectCancel{}
 	stepTwo := &TestStepInjectCancel{}
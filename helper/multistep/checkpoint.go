@@ -0,0 +1,192 @@
+package multistep
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// This is the key set in the state bag when the runner skipped at least one
+// step because it was restored from a checkpoint.
+const StateResumed = "resumed"
+
+// ResumableStep is a Step that can be skipped when a previously failed run is
+// resumed from a checkpoint. Steps that don't implement this interface are
+// always run again, which is what you want for things like connecting to a
+// machine or starting an HTTP server.
+type ResumableStep interface {
+	Step
+
+	// ResumeStateKeys returns the keys in the state bag that this step
+	// produces. Their values are saved to the checkpoint after the step
+	// completes and are put back into the state bag instead of running the
+	// step when resuming. The values must survive a round trip through
+	// encoding/json, so keep them to strings, numbers and booleans. Whole
+	// numbers are restored as int.
+	ResumeStateKeys() []string
+}
+
+// StepResumer is a ResumableStep that needs more than its saved state to be
+// skipped, like a step that has to reattach to a machine it started and that
+// was kept running, or that has to know it should clean up after itself.
+type StepResumer interface {
+	ResumableStep
+
+	// Resume is called instead of Run when the step is skipped, after its
+	// state was restored. It returns false when the resources of the step
+	// are gone, in which case the step and all the steps after it are run
+	// again.
+	Resume(StateBag) bool
+}
+
+// Checkpoint is the persisted progress of a run: the names of the steps in
+// the order they were configured, how many of them completed, and the saved
+// state of the resumable steps among them.
+type Checkpoint struct {
+	Steps     []string               `json:"steps"`
+	Completed int                    `json:"completed"`
+	State     map[string]interface{} `json:"state"`
+}
+
+// CheckpointStore persists a Checkpoint between runs.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or nil if there is none.
+	Load() (*Checkpoint, error)
+
+	// Save replaces the saved checkpoint.
+	Save(*Checkpoint) error
+
+	// Remove deletes the saved checkpoint, if any.
+	Remove() error
+}
+
+// FileCheckpointStore is a CheckpointStore that saves the checkpoint as a
+// JSON file at Path.
+type FileCheckpointStore struct {
+	Path string
+}
+
+func (s *FileCheckpointStore) Load() (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("Error reading checkpoint %s: %s", s.Path, err)
+	}
+
+	return &cp, nil
+}
+
+func (s *FileCheckpointStore) Save(cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a crash never leaves a
+	// half-written checkpoint behind. Only the user can read it, since the
+	// saved state can hold secrets like temporary private keys.
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.Path)
+}
+
+func (s *FileCheckpointStore) Remove() error {
+	err := os.Remove(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// StepName returns the human readable name of a step, looking through
+// wrapped steps that implement StepWrapper.
+func StepName(step Step) string {
+	if wrapped, ok := step.(StepWrapper); ok {
+		return wrapped.InnerStepName()
+	}
+
+	return reflect.Indirect(reflect.ValueOf(step)).Type().Name()
+}
+
+// resumable reports whether the checkpoint was taken from the same sequence
+// of steps and step i was completed and can be skipped.
+func (cp *Checkpoint) resumable(steps []Step, i int) bool {
+	if cp == nil || i >= cp.Completed || len(cp.Steps) != len(steps) {
+		return false
+	}
+
+	for j, step := range steps {
+		if cp.Steps[j] != StepName(step) {
+			return false
+		}
+	}
+
+	_, ok := steps[i].(ResumableStep)
+	return ok
+}
+
+func newCheckpoint(steps []Step) *Checkpoint {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = StepName(step)
+	}
+
+	return &Checkpoint{
+		Steps: names,
+		State: make(map[string]interface{}),
+	}
+}
+
+// record marks step i as completed, saving the state of the step if it is
+// resumable.
+func (cp *Checkpoint) record(step Step, i int, state StateBag) {
+	cp.Completed = i + 1
+
+	if r, ok := step.(ResumableStep); ok {
+		for _, k := range r.ResumeStateKeys() {
+			if v, ok := state.GetOk(k); ok {
+				cp.State[k] = v
+			}
+		}
+	}
+}
+
+// restore puts the saved state of a resumable step back into the state bag,
+// and resumes the step if it implements StepResumer. It returns false if the
+// step couldn't be resumed.
+func (cp *Checkpoint) restore(step Step, state StateBag) bool {
+	for _, k := range step.(ResumableStep).ResumeStateKeys() {
+		if v, ok := cp.State[k]; ok {
+			// encoding/json turns all numbers into float64
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				v = int(f)
+			}
+			state.Put(k, v)
+		}
+	}
+
+	if r, ok := step.(StepResumer); ok {
+		return r.Resume(state)
+	}
+
+	return true
+}
@@ -0,0 +1,218 @@
+package multistep
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// A resumable step for testing that accumulates data like TestStepAcc and
+// records its data under the "resumable" key.
+type TestStepResumable struct {
+	TestStepAcc
+}
+
+func (s TestStepResumable) Run(ctx context.Context, state StateBag) StepAction {
+	state.Put("resumable", s.Data)
+	return s.TestStepAcc.Run(ctx, state)
+}
+
+func (s TestStepResumable) ResumeStateKeys() []string {
+	return []string{"resumable"}
+}
+
+// A resumable step for testing that can only be resumed when Resumable is
+// set, and that records its resumes under the "resumes" key.
+type TestStepResumer struct {
+	TestStepResumable
+	Resumable bool
+}
+
+func (s TestStepResumer) Resume(state StateBag) bool {
+	s.insertData(state, "resumes")
+	return s.Resumable
+}
+
+func testCheckpointStore(t *testing.T) (*FileCheckpointStore, func()) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	store := &FileCheckpointStore{Path: filepath.Join(td, "build", "checkpoint.json")}
+	return store, func() { os.RemoveAll(td) }
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store, cleanup := testCheckpointStore(t)
+	defer cleanup()
+
+	cp, err := store.Load()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cp != nil {
+		t.Fatalf("should not have a checkpoint: %#v", cp)
+	}
+
+	expected := &Checkpoint{
+		Steps:     []string{"a", "b"},
+		Completed: 1,
+		State:     map[string]interface{}{"foo": "bar"},
+	}
+	if err := store.Save(expected); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cp, err = store.Load()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(cp, expected) {
+		t.Fatalf("bad: %#v", cp)
+	}
+
+	if err := store.Remove(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := store.Remove(); err != nil {
+		t.Fatalf("removing twice should not error: %s", err)
+	}
+}
+
+func TestBasicRunner_Run_Resume(t *testing.T) {
+	store, cleanup := testCheckpointStore(t)
+	defer cleanup()
+
+	// First run halts at the third step.
+	data := new(BasicStateBag)
+	r := &BasicRunner{
+		Steps: []Step{
+			TestStepResumable{TestStepAcc{Data: "a"}},
+			&TestStepAcc{Data: "b"},
+			&TestStepAcc{Data: "c", Halt: true},
+		},
+		Checkpoints: store,
+	}
+	r.Run(data)
+
+	// The resumable step isn't cleaned up so it can be resumed.
+	expected := []string{"c", "b"}
+	results := data.Get("cleanup").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("unexpected cleanup: %#v", results)
+	}
+
+	// Second run of the same steps skips the resumable step only.
+	data = new(BasicStateBag)
+	r = &BasicRunner{
+		Steps: []Step{
+			TestStepResumable{TestStepAcc{Data: "a"}},
+			&TestStepAcc{Data: "b"},
+			&TestStepAcc{Data: "c"},
+		},
+		Checkpoints: store,
+	}
+	r.Run(data)
+
+	expected = []string{"b", "c"}
+	results = data.Get("data").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("unexpected data: %#v", results)
+	}
+
+	if v := data.Get("resumable"); v != "a" {
+		t.Fatalf("state not restored: %#v", v)
+	}
+
+	if _, ok := data.GetOk(StateResumed); !ok {
+		t.Fatal("resumed should be in state bag")
+	}
+
+	// A successful run cleans up everything and removes the checkpoint.
+	expected = []string{"c", "b", "a"}
+	results = data.Get("cleanup").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("unexpected cleanup: %#v", results)
+	}
+
+	if cp, _ := store.Load(); cp != nil {
+		t.Fatalf("checkpoint should be removed: %#v", cp)
+	}
+}
+
+func TestBasicRunner_Run_ResumeChangedSteps(t *testing.T) {
+	store, cleanup := testCheckpointStore(t)
+	defer cleanup()
+
+	store.Save(&Checkpoint{
+		Steps:     []string{"TestStepResumable", "TestStepAcc"},
+		Completed: 1,
+		State:     map[string]interface{}{"resumable": "old"},
+	})
+
+	data := new(BasicStateBag)
+	r := &BasicRunner{
+		Steps: []Step{
+			TestStepResumable{TestStepAcc{Data: "a"}},
+		},
+		Checkpoints: store,
+	}
+	r.Run(data)
+
+	expected := []string{"a"}
+	results := data.Get("data").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("checkpoint of other steps should be ignored: %#v", results)
+	}
+}
+
+func TestBasicRunner_Run_ResumeGone(t *testing.T) {
+	store, cleanup := testCheckpointStore(t)
+	defer cleanup()
+
+	store.Save(&Checkpoint{
+		Steps:     []string{"TestStepResumable", "TestStepResumer", "TestStepResumable"},
+		Completed: 3,
+		State:     map[string]interface{}{"resumable": "old"},
+	})
+
+	data := new(BasicStateBag)
+	r := &BasicRunner{
+		Steps: []Step{
+			TestStepResumable{TestStepAcc{Data: "a"}},
+			TestStepResumer{TestStepResumable{TestStepAcc{Data: "b"}}, false},
+			TestStepResumable{TestStepAcc{Data: "c"}},
+		},
+		Checkpoints: store,
+	}
+	r.Run(data)
+
+	// The step that can't be resumed and the steps after it are run again.
+	expected := []string{"b", "c"}
+	results := data.Get("data").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("unexpected data: %#v", results)
+	}
+
+	expected = []string{"b"}
+	results = data.Get("resumes").([]string)
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("unexpected resumes: %#v", results)
+	}
+}
+
+func TestCheckpoint_restore(t *testing.T) {
+	cp := &Checkpoint{State: map[string]interface{}{"resumable": float64(5900)}}
+
+	data := new(BasicStateBag)
+	if !cp.restore(TestStepResumable{}, data) {
+		t.Fatal("should be resumed")
+	}
+	if v := data.Get("resumable"); v != 5900 {
+		t.Fatalf("whole numbers should be restored as int: %#v", v)
+	}
+}
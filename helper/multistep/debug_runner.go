@@ -46,6 +46,10 @@ type DebugRunner struct {
 }
 
 func (r *DebugRunner) Run(state StateBag) {
+	r.RunContext(context.Background(), state)
+}
+
+func (r *DebugRunner) RunContext(ctx context.Context, state StateBag) {
 	r.l.Lock()
 	if r.runner != nil {
 		panic("already running")
@@ -78,7 +82,7 @@ func (r *DebugRunner) Run(state StateBag) {
 
 	// Then just use a basic runner to run it
 	r.runner.Steps = steps
-	r.runner.Run(state)
+	r.runner.RunContext(ctx, state)
 }
 
 func (r *DebugRunner) Cancel() {
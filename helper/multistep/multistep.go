@@ -49,3 +49,33 @@ type Runner interface {
 	// Cancel cancels a potentially running stack of steps.
 	Cancel()
 }
+
+// A ContextRunner is a Runner that takes a context when it runs. The
+// context the steps run with is cancelled when ctx is done, as well as when
+// the runner is cancelled.
+type ContextRunner interface {
+	RunContext(context.Context, StateBag)
+}
+
+// RunContext runs the steps of the runner with the given initial state
+// until they finish or ctx is done, whichever happens first. Runners that
+// don't implement ContextRunner are cancelled through Cancel.
+func RunContext(ctx context.Context, r Runner, state StateBag) {
+	if cr, ok := r.(ContextRunner); ok {
+		cr.RunContext(ctx, state)
+		return
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		r.Run(state)
+	}()
+
+	select {
+	case <-doneCh:
+	case <-ctx.Done():
+		r.Cancel()
+		<-doneCh
+	}
+}
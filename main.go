@@ -12,6 +12,7 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -169,11 +170,27 @@ func wrappedMain() int {
 	// Determine if we're in machine-readable mode by mucking around with
 	// the arguments...
 	args, machineReadable := extractMachineReadable(os.Args[1:])
+	args, output := extractOutput(args)
+	if output != "" && output != "json" {
+		fmt.Fprintf(os.Stderr, "Unsupported output format %q, must be \"json\"\n", output)
+		return 1
+	}
 
 	defer plugin.CleanupClients()
 
 	var ui packer.Ui
-	if machineReadable {
+	if output == "json" {
+		// Setup the UI to output a JSON event per line
+		ui = &packer.JSONUi{
+			Writer: os.Stdout,
+		}
+
+		// Set this so that we don't get colored output in our events.
+		if err := os.Setenv("PACKER_NO_COLOR", "1"); err != nil {
+			fmt.Fprintf(os.Stderr, "Packer failed to initialize UI: %s\n", err)
+			return 1
+		}
+	} else if machineReadable {
 		// Setup the UI as we're being machine-readable
 		ui = &packer.MachineReadableUi{
 			Writer: os.Stdout,
@@ -280,6 +297,22 @@ func extractMachineReadable(args []string) ([]string, bool) {
 	return args, false
 }
 
+// extractOutput checks the args for the output flag and returns the
+// output format it sets, if any. It modifies the args to remove this flag.
+func extractOutput(args []string) ([]string, string) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-output=") {
+			// We found it. Slice it out.
+			result := make([]string, len(args)-1)
+			copy(result, args[:i])
+			copy(result[i:], args[i+1:])
+			return result, strings.TrimPrefix(arg, "-output=")
+		}
+	}
+
+	return args, ""
+}
+
 func loadConfig() (*config, error) {
 	var config config
 	config.PluginMinPort = 10000
@@ -62,6 +62,32 @@ func TestExtractMachineReadable(t *testing.T) {
 	}
 }
 
+func TestExtractOutput(t *testing.T) {
+	var args, expected, result []string
+	var output string
+
+	// Not
+	args = []string{"build", "template.json"}
+	result, output = extractOutput(args)
+	if !reflect.DeepEqual(result, args) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if output != "" {
+		t.Fatalf("bad: %s", output)
+	}
+
+	// Yes
+	args = []string{"build", "-output=json", "template.json"}
+	result, output = extractOutput(args)
+	expected = []string{"build", "template.json"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if output != "json" {
+		t.Fatalf("bad: %s", output)
+	}
+}
+
 func TestRandom(t *testing.T) {
 	if rand.Intn(9999999) == 8498210 {
 		t.Fatal("math.rand is not seeded properly")
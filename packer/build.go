@@ -1,9 +1,11 @@
 package packer
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 )
 
 const (
@@ -11,6 +13,11 @@ const (
 	// build.
 	BuildNameConfigKey = "packer_build_name"
 
+	// This key contains a map[string]map[string]string of the artifacts
+	// of the builds that this build depends on, for the "artifact"
+	// template function.
+	BuildArtifactsConfigKey = "packer_build_artifacts"
+
 	// This is the key in the configuration that is set to the type
 	// of the builder that is run. This is useful for provisioners and
 	// such who want to make use of this.
@@ -24,12 +31,25 @@ const (
 	// force build is enabled.
 	ForceConfigKey = "packer_force"
 
+	// This key is set to "true" when the output of Packer is machine-readable,
+	// so that builders report their steps as machine-readable output.
+	MachineReadableConfigKey = "packer_machine_readable"
+
 	// This key determines what to do when a normal multistep step fails
 	// - "cleanup" - run cleanup steps
 	// - "abort" - exit without cleanup
 	// - "ask" - ask the user
 	OnErrorConfigKey = "packer_on_error"
 
+	// This key is set to "true" when the build should checkpoint its
+	// progress and resume from the last checkpoint if one exists.
+	ResumeConfigKey = "packer_resume"
+
+	// This key is set to a hash of the configuration of a resumable
+	// build, so that its checkpoints are only used by the same build of
+	// the same template.
+	ResumeKeyConfigKey = "packer_resume_key"
+
 	// TemplatePathKey is the path to the template that configured this build
 	TemplatePathKey = "packer_template_path"
 
@@ -79,6 +99,57 @@ type Build interface {
 	// - "abort" - exit without cleanup
 	// - "ask" - ask the user
 	SetOnError(string)
+
+	// SetMachineReadable will enable/disable the machine-readable output
+	// of the steps of the builder.
+	SetMachineReadable(bool)
+
+	// SetResume will enable/disable resumable builds. When enabled, builders
+	// record the steps that completed so that a failed build can continue
+	// from where it stopped the next time it is run.
+	SetResume(bool)
+
+	// SetArtifacts sets the artifacts of the builds that this build
+	// depends on, by build name. Their ID, files and state are made
+	// available to the configuration through the "artifact" template
+	// function. This must be called prior to Prepare.
+	SetArtifacts(map[string]Artifact)
+}
+
+// A ContextBuild is a Build that takes a context when it runs. The context
+// is cancelled to cancel the build, which then cancels the builder,
+// provisioners and post-processors through their own contexts.
+type ContextBuild interface {
+	RunContext(context.Context, Ui) ([]Artifact, error)
+}
+
+// RunBuild runs the build until it finishes or ctx is done, whichever
+// happens first. Builds that don't implement ContextBuild are cancelled
+// through Cancel, and RunBuild waits for them to return.
+func RunBuild(ctx context.Context, b Build, ui Ui) ([]Artifact, error) {
+	if cb, ok := b.(ContextBuild); ok {
+		return cb.RunContext(ctx, ui)
+	}
+
+	type result struct {
+		artifacts []Artifact
+		err       error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		artifacts, err := b.Run(ui)
+		resultCh <- result{artifacts, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.artifacts, r.err
+	case <-ctx.Done():
+		b.Cancel()
+		r := <-resultCh
+		return r.artifacts, r.err
+	}
 }
 
 // A build struct represents a single build job, the result of which should
@@ -94,13 +165,18 @@ type coreBuild struct {
 	postProcessors [][]coreBuildPostProcessor
 	provisioners   []coreBuildProvisioner
 	templatePath   string
+	timeout        time.Duration
 	variables      map[string]string
 
+	artifacts     map[string]Artifact
 	debug         bool
 	force         bool
+	machine       bool
 	onError       string
+	resume        bool
 	l             sync.Mutex
 	prepareCalled bool
+	runs          cancelGroup
 }
 
 // Keeps track of the post-processor and the configuration of the
@@ -115,9 +191,11 @@ type coreBuildPostProcessor struct {
 // Keeps track of the provisioner and the configuration of the provisioner
 // within the build.
 type coreBuildProvisioner struct {
-	pType       string
-	provisioner Provisioner
-	config      []interface{}
+	pType        string
+	provisioner  Provisioner
+	config       []interface{}
+	maxRetries   int
+	retryBackoff time.Duration
 }
 
 // Returns the name of the build.
@@ -144,9 +222,19 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 		DebugConfigKey:         b.debug,
 		ForceConfigKey:         b.force,
 		OnErrorConfigKey:       b.onError,
+		ResumeConfigKey:        b.resume,
 		TemplatePathKey:        b.templatePath,
 		UserVariablesConfigKey: b.variables,
 	}
+	if b.machine {
+		packerConfig[MachineReadableConfigKey] = true
+	}
+	if len(b.artifacts) > 0 {
+		packerConfig[BuildArtifactsConfigKey] = b.artifactData()
+	}
+	if b.resume {
+		packerConfig[ResumeKeyConfigKey] = b.resumeKey()
+	}
 
 	// Prepare the builder
 	warn, err = b.builder.Prepare(b.builderConfig, packerConfig)
@@ -181,10 +269,25 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 
 // Runs the actual build. Prepare must be called prior to running this.
 func (b *coreBuild) Run(originalUi Ui) ([]Artifact, error) {
+	return b.RunContext(context.Background(), originalUi)
+}
+
+// RunContext runs the actual build until it finishes or ctx is done.
+// Prepare must be called prior to running this.
+func (b *coreBuild) RunContext(ctx context.Context, originalUi Ui) ([]Artifact, error) {
 	if !b.prepareCalled {
 		panic("Prepare must be called first")
 	}
 
+	ctx, done := b.runs.start(ctx)
+	defer done()
+
+	if b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+
 	// Copy the hooks
 	hooks := make(map[string][]Hook)
 	for hookName, hookList := range b.hooks {
@@ -193,6 +296,7 @@ func (b *coreBuild) Run(originalUi Ui) ([]Artifact, error) {
 	}
 
 	// Add a hook for the provisioners if we have provisioners
+	var provisionHook *ProvisionHook
 	if len(b.provisioners) > 0 {
 		hookedProvisioners := make([]*HookedProvisioner, len(b.provisioners))
 		for i, p := range b.provisioners {
@@ -202,15 +306,19 @@ func (b *coreBuild) Run(originalUi Ui) ([]Artifact, error) {
 			}
 			if b.debug {
 				hookedProvisioners[i] = &HookedProvisioner{
-					&DebuggedProvisioner{Provisioner: p.provisioner},
-					pConfig,
-					p.pType,
+					Provisioner:  &DebuggedProvisioner{Provisioner: p.provisioner},
+					Config:       pConfig,
+					TypeName:     p.pType,
+					MaxRetries:   p.maxRetries,
+					RetryBackoff: p.retryBackoff,
 				}
 			} else {
 				hookedProvisioners[i] = &HookedProvisioner{
-					p.provisioner,
-					pConfig,
-					p.pType,
+					Provisioner:  p.provisioner,
+					Config:       pConfig,
+					TypeName:     p.pType,
+					MaxRetries:   p.maxRetries,
+					RetryBackoff: p.retryBackoff,
 				}
 			}
 		}
@@ -219,23 +327,47 @@ func (b *coreBuild) Run(originalUi Ui) ([]Artifact, error) {
 			hooks[HookProvision] = make([]Hook, 0, 1)
 		}
 
-		hooks[HookProvision] = append(hooks[HookProvision], &ProvisionHook{
+		provisionHook = &ProvisionHook{
 			Provisioners: hookedProvisioners,
-		})
+		}
+		hooks[HookProvision] = append(hooks[HookProvision], provisionHook)
 	}
 
 	hook := &DispatchHook{Mapping: hooks}
 	artifacts := make([]Artifact, 0, 1)
 
-	// The builder just has a normal Ui, but targeted
-	builderUi := &TargetedUI{
-		Target: b.Name(),
-		Ui:     originalUi,
+	// The builder just has a normal Ui, but targeted. Without a Ui, the
+	// builder and the provisioners don't get one either.
+	var builderUi Ui
+	if originalUi != nil {
+		builderUi = &TargetedUI{
+			Target: b.Name(),
+			Ui:     originalUi,
+		}
+	}
+
+	// machine reports an event of the build, if there is a Ui to report
+	// it to.
+	machine := func(t string, args ...string) {
+		if builderUi != nil {
+			builderUi.Machine(t, args...)
+		}
 	}
 
 	log.Printf("Running builder: %s", b.builderType)
+	machine(EventBuildStart, b.builderType)
 	ts := CheckpointReporter.AddSpan(b.builderType, "builder", b.builderConfig)
-	builderArtifact, err := b.builder.Run(builderUi, hook)
+	builderArtifact, err := RunBuilder(ctx, b.builder, builderUi, hook)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = &TimeoutError{
+			Name:    fmt.Sprintf("Build '%s'", b.name),
+			Timeout: b.timeout,
+		}
+	} else if err != nil && provisionHook != nil && provisionHook.TimedOut() != nil {
+		// The error of the provisioner went through the builder, so
+		// report the timeout itself instead.
+		err = provisionHook.TimedOut()
+	}
 	ts.End(err)
 	if err != nil {
 		return nil, err
@@ -255,15 +387,26 @@ PostProcessorRunSeqLoop:
 	for _, ppSeq := range b.postProcessors {
 		priorArtifact := builderArtifact
 		for i, corePP := range ppSeq {
-			ppUi := &TargetedUI{
+			var ppUi Ui = &TargetedUI{
 				Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.processorType),
 				Ui:     originalUi,
 			}
+			if _, ok := originalUi.(EventUi); ok {
+				// Events say which post-processor they come from
+				// instead, and belong to the build.
+				ppUi = builderUi
+			}
 
 			builderUi.Say(fmt.Sprintf("Running post-processor: %s", corePP.processorType))
+			machine(EventPostProcessorStart, corePP.processorType)
 			ts := CheckpointReporter.AddSpan(corePP.processorType, "post-processor", corePP.config)
-			artifact, keep, err := corePP.processor.PostProcess(ppUi, priorArtifact)
+			artifact, keep, err := RunPostProcessor(ctx, corePP.processor, ppUi, priorArtifact)
 			ts.End(err)
+			if err != nil {
+				machine(EventPostProcessorEnd, corePP.processorType, err.Error())
+			} else {
+				machine(EventPostProcessorEnd, corePP.processorType)
+			}
 			if err != nil {
 				errors = append(errors, fmt.Errorf("Post-processor failed: %s", err))
 				continue PostProcessorRunSeqLoop
@@ -349,7 +492,35 @@ func (b *coreBuild) SetOnError(val string) {
 	b.onError = val
 }
 
+func (b *coreBuild) SetMachineReadable(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.machine = val
+}
+
+func (b *coreBuild) SetResume(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.resume = val
+}
+
+func (b *coreBuild) SetArtifacts(val map[string]Artifact) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.artifacts = val
+}
+
 // Cancels the build if it is running.
 func (b *coreBuild) Cancel() {
-	b.builder.Cancel()
+	if !b.runs.Cancel() {
+		// Nothing is running, so pass it on to the builder like Cancel
+		// did before builds took a context.
+		b.builder.Cancel()
+	}
 }
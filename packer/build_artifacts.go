@@ -0,0 +1,99 @@
+package packer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// artifactData flattens the artifacts of the builds this build depends on
+// into the fields that the "artifact" template function reads. Artifacts
+// can only be read through their State method, so only the state keys that
+// the "artifact" calls in the configuration of this build read are included.
+func (b *coreBuild) artifactData() map[string]map[string]string {
+	var configs []interface{}
+	configs = append(configs, b.builderConfig)
+	for _, p := range b.provisioners {
+		configs = append(configs, p.config...)
+	}
+	for _, ppSeq := range b.postProcessors {
+		for _, pp := range ppSeq {
+			configs = append(configs, pp.config)
+		}
+	}
+
+	stateKeys := make(map[string][]string)
+	for _, config := range configs {
+		walkStrings(config, func(s string) {
+			// Templates that don't parse are reported when the
+			// configuration is interpolated.
+			calls, err := interpolate.ArtifactCalls(s)
+			if err != nil {
+				return
+			}
+			for _, call := range calls {
+				if strings.HasPrefix(call.Field, "state.") {
+					stateKeys[call.Build] = append(
+						stateKeys[call.Build], strings.TrimPrefix(call.Field, "state."))
+				}
+			}
+		})
+	}
+
+	result := make(map[string]map[string]string, len(b.artifacts))
+	for name, artifact := range b.artifacts {
+		result[name] = artifactFields(artifact, stateKeys[name])
+	}
+
+	return result
+}
+
+// artifactFields returns the fields of an artifact for the "artifact"
+// template function. State values that aren't strings are encoded as JSON.
+func artifactFields(a Artifact, stateKeys []string) map[string]string {
+	fields := map[string]string{
+		"builder_id": a.BuilderId(),
+		"files":      strings.Join(a.Files(), ","),
+		"id":         a.Id(),
+		"string":     a.String(),
+	}
+
+	for _, k := range stateKeys {
+		switch v := a.State(k).(type) {
+		case nil:
+		case string:
+			fields["state."+k] = v
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				fields["state."+k] = fmt.Sprint(v)
+				continue
+			}
+			fields["state."+k] = string(data)
+		}
+	}
+
+	return fields
+}
+
+// walkStrings calls fn with every string within a configuration.
+func walkStrings(v interface{}, fn func(string)) {
+	switch v := v.(type) {
+	case string:
+		fn(v)
+	case []interface{}:
+		for _, elem := range v {
+			walkStrings(elem, fn)
+		}
+	case []string:
+		for _, elem := range v {
+			fn(elem)
+		}
+	case map[string]interface{}:
+		for _, elem := range v {
+			walkStrings(elem, fn)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package packer
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func testBuild() *coreBuild {
@@ -15,7 +17,11 @@ func testBuild() *coreBuild {
 			"foo": {&MockHook{}},
 		},
 		provisioners: []coreBuildProvisioner{
-			{"mock-provisioner", &MockProvisioner{}, []interface{}{42}},
+			{
+				pType:       "mock-provisioner",
+				provisioner: &MockProvisioner{},
+				config:      []interface{}{42},
+			},
 		},
 		postProcessors: [][]coreBuildPostProcessor{
 			{
@@ -34,6 +40,7 @@ func testDefaultPackerConfig() map[string]interface{} {
 		DebugConfigKey:         false,
 		ForceConfigKey:         false,
 		OnErrorConfigKey:       "cleanup",
+		ResumeConfigKey:        false,
 		TemplatePathKey:        "",
 		UserVariablesConfigKey: make(map[string]string),
 	}
@@ -102,6 +109,49 @@ func TestBuild_Prepare_Twice(t *testing.T) {
 	build.Prepare()
 }
 
+func TestBuildPrepare_artifacts(t *testing.T) {
+	packerConfig := testDefaultPackerConfig()
+	packerConfig[BuildArtifactsConfigKey] = map[string]map[string]string{
+		"base": {
+			"builder_id":   "bid",
+			"files":        "a,b",
+			"id":           "ami-123",
+			"string":       "string",
+			"state.region": "us-east-1",
+			"state.disks":  `["sda","sdb"]`,
+		},
+	}
+
+	build := testBuild()
+	build.builderConfig = map[string]interface{}{
+		"source_ami": "{{ artifact `base` `id` }}",
+		"region":     "{{ artifact `base` `state.region` }}",
+	}
+	build.provisioners[0].config = []interface{}{
+		map[string]interface{}{
+			"inline": []interface{}{"echo {{artifact \"base\" \"state.disks\"}}"},
+		},
+	}
+	build.SetArtifacts(map[string]Artifact{
+		"base": &MockArtifact{
+			IdValue: "ami-123",
+			StateValues: map[string]interface{}{
+				"region":  "us-east-1",
+				"disks":   []string{"sda", "sdb"},
+				"ignored": "foo",
+			},
+		},
+	})
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	builder := build.builder.(*MockBuilder)
+	if !reflect.DeepEqual(builder.PrepareConfig[1], packerConfig) {
+		t.Fatalf("bad: %#v", builder.PrepareConfig[1])
+	}
+}
+
 func TestBuildPrepare_BuilderWarnings(t *testing.T) {
 	expected := []string{"foo"}
 
@@ -375,3 +425,39 @@ func TestBuild_Cancel(t *testing.T) {
 		t.Fatal("cancel should be called")
 	}
 }
+
+// blockingBuilder is a Builder that runs until it is cancelled.
+type blockingBuilder struct {
+	MockBuilder
+	cancelCh chan struct{}
+}
+
+func (b *blockingBuilder) Run(ui Ui, h Hook) (Artifact, error) {
+	<-b.cancelCh
+	return nil, errors.New("Build was cancelled.")
+}
+
+func (b *blockingBuilder) Cancel() {
+	b.CancelCalled = true
+	close(b.cancelCh)
+}
+
+func TestBuild_RunTimeout(t *testing.T) {
+	builder := &blockingBuilder{cancelCh: make(chan struct{})}
+	build := testBuild()
+	build.builder = builder
+	build.timeout = 10 * time.Millisecond
+
+	build.Prepare()
+	_, err := build.Run(testUi())
+	terr, ok := err.(*TimeoutError)
+	if !ok {
+		t.Fatalf("should be a timeout error: %#v", err)
+	}
+	if terr.Name != "Build 'test'" {
+		t.Fatalf("bad: %#v", terr)
+	}
+	if !builder.CancelCalled {
+		t.Fatal("builder should be cancelled")
+	}
+}
@@ -1,5 +1,7 @@
 package packer
 
+import "context"
+
 // Implementers of Builder are responsible for actually building images
 // on some platform given some configuration.
 //
@@ -34,3 +36,41 @@ type Builder interface {
 	// the builder actually cancels and cleans up after itself.
 	Cancel()
 }
+
+// A ContextBuilder is a Builder that takes a context when it runs. The
+// context is cancelled when the build is cancelled or runs out of time, so a
+// ContextBuilder doesn't need to keep track of its own runs to be able to
+// cancel them: Cancel is only called on builders that don't implement this
+// interface.
+type ContextBuilder interface {
+	RunContext(ctx context.Context, ui Ui, hook Hook) (Artifact, error)
+}
+
+// RunBuilder runs the builder until it finishes or ctx is done, whichever
+// happens first. Builders that don't implement ContextBuilder are cancelled
+// through Cancel, and RunBuilder waits for them to return.
+func RunBuilder(ctx context.Context, b Builder, ui Ui, hook Hook) (Artifact, error) {
+	if cb, ok := b.(ContextBuilder); ok {
+		return cb.RunContext(ctx, ui, hook)
+	}
+
+	type result struct {
+		artifact Artifact
+		err      error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		artifact, err := b.Run(ui, hook)
+		resultCh <- result{artifact, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.artifact, r.err
+	case <-ctx.Done():
+		b.Cancel()
+		r := <-resultCh
+		return r.artifact, r.err
+	}
+}
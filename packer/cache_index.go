@@ -0,0 +1,393 @@
+package packer
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// CacheIndexFile is the name of the file within the cache directory that
+// keeps the metadata of the files in the cache.
+const CacheIndexFile = "index.json"
+
+// cacheIndexVersion is the version of the format of the index.
+const cacheIndexVersion = 1
+
+// CacheEntry is the metadata of a file in the cache.
+type CacheEntry struct {
+	// Name is the path of the file relative to the cache directory.
+	Name string `json:"name"`
+
+	// Source is the URL the file was downloaded from, and Checksum and
+	// ChecksumType the checksum it was verified with, if any.
+	Source       string `json:"source"`
+	Checksum     string `json:"checksum,omitempty"`
+	ChecksumType string `json:"checksum_type,omitempty"`
+
+	Size     int64     `json:"size"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+
+	// Templates are the paths of the templates that used the file.
+	Templates []string `json:"templates,omitempty"`
+
+	// Untracked is set for files that are in the cache directory but not in
+	// the index, such as files downloaded by older versions of Packer.
+	Untracked bool `json:"-"`
+}
+
+type cacheIndex struct {
+	Version int                    `json:"version"`
+	Entries map[string]*CacheEntry `json:"entries"`
+}
+
+// Cache is the cache directory, along with an index of the files that were
+// downloaded into it. Files are locked while they are downloaded or
+// removed so that concurrent Packer runs can share a cache.
+type Cache struct {
+	// Dir is the absolute path to the cache directory.
+	Dir string
+}
+
+// OpenCache returns the cache in the directory given by CachePath, creating
+// the directory if it doesn't exist.
+func OpenCache() (*Cache, error) {
+	dir, err := CachePath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Cache{Dir: dir}, nil
+}
+
+// Path returns the absolute path to a file in the cache.
+func (c *Cache) Path(name string) string {
+	return filepath.Join(c.Dir, name)
+}
+
+// Lock locks a file in the cache, waiting for other Packer runs that hold
+// the lock. The returned function releases the lock.
+func (c *Cache) Lock(name string) (func(), error) {
+	path := c.Path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	lockFile := path + ".lock"
+	log.Printf("Acquiring lock for: %s (%s)", name, lockFile)
+	lock := flock.New(lockFile)
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("Error locking %s: %s", lockFile, err)
+	}
+
+	return func() { lock.Unlock() }, nil
+}
+
+// Record adds the file to the index, or updates its entry when it is
+// already there, marking it as used now. The entry is filled in with the
+// size of the file and the time.
+func (c *Cache) Record(e *CacheEntry) error {
+	info, err := os.Stat(c.Path(e.Name))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	return c.updateIndex(func(index *cacheIndex) {
+		if old, ok := index.Entries[e.Name]; ok {
+			e.Created = old.Created
+			e.Templates = mergeTemplates(old.Templates, e.Templates)
+		}
+		if e.Created.IsZero() {
+			e.Created = now
+		}
+		e.Size = info.Size()
+		e.LastUsed = now
+		index.Entries[e.Name] = e
+	})
+}
+
+// Entries returns the files in the cache sorted by name. Entries of the index
+// whose files are gone are left out, and files at the top of the cache
+// directory that aren't in the index are included as untracked.
+func (c *Cache) Entries() ([]*CacheEntry, error) {
+	index, err := c.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*CacheEntry
+	for name, e := range index.Entries {
+		info, err := os.Stat(c.Path(name))
+		if err != nil {
+			continue
+		}
+		e.Name = name
+		e.Size = info.Size()
+		entries = append(entries, e)
+	}
+
+	infos, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if _, ok := index.Entries[name]; ok || !info.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasSuffix(name, ".lock") || strings.HasPrefix(name, CacheIndexFile) {
+			continue
+		}
+		entries = append(entries, &CacheEntry{
+			Name:      name,
+			Size:      info.Size(),
+			Created:   info.ModTime().UTC(),
+			LastUsed:  info.ModTime().UTC(),
+			Untracked: true,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// Remove removes a file from the cache and the index. Files that are locked
+// by a running download are left alone, in which case removed is false.
+// The lock file is kept: a Lock waiting on it would otherwise get the lock of
+// a deleted file while the next Lock creates a new one.
+func (c *Cache) Remove(name string) (removed bool, err error) {
+	path := c.Path(name)
+	lockName := cacheLockName(name)
+	lockFile := c.Path(lockName) + ".lock"
+	lock := flock.New(lockFile)
+	ok, err := lock.TryLock()
+	if err != nil {
+		return false, fmt.Errorf("Error locking %s: %s", lockFile, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	defer lock.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	return true, c.updateIndex(func(index *cacheIndex) {
+		delete(index.Entries, name)
+	})
+}
+
+// cacheLockName returns the name of the file whose lock guards a file in the
+// cache. Partial downloads share the lock of the file they download.
+func cacheLockName(name string) string {
+	for _, suffix := range []string{".part.ranges", ".part"} {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix)
+		}
+	}
+	return name
+}
+
+// Prune removes the files that weren't used since olderThan, and then the
+// least recently used files until the cache is no larger than maxSize bytes.
+// A zero olderThan or maxSize disables that limit. Files that are in use are
+// kept. The removed files are returned.
+func (c *Cache) Prune(olderThan time.Duration, maxSize int64) ([]*CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	deadline := time.Now().Add(-olderThan)
+	var removed []*CacheEntry
+	for _, e := range entries {
+		old := olderThan > 0 && e.LastUsed.Before(deadline)
+		large := maxSize > 0 && size > maxSize
+		if !old && !large {
+			continue
+		}
+
+		ok, err := c.Remove(e.Name)
+		if err != nil {
+			return removed, err
+		}
+		if !ok {
+			log.Printf("Not removing %s from the cache, it is in use", e.Name)
+			continue
+		}
+		size -= e.Size
+		removed = append(removed, e)
+	}
+
+	return removed, nil
+}
+
+// Verify checks that a file in the cache still has the size and checksum it
+// was downloaded with. It returns false when there was no checksum to verify
+// the file against, such as for checksums read from a file or URL.
+func (c *Cache) Verify(e *CacheEntry) (checksummed bool, err error) {
+	path := c.Path(e.Name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if !e.Untracked && info.Size() != e.Size {
+		return false, fmt.Errorf("size is %d bytes, expected %d", info.Size(), e.Size)
+	}
+
+	h := cacheChecksumHash(e.ChecksumType, e.Checksum)
+	if h == nil {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, e.Checksum) {
+		return true, fmt.Errorf("checksum is %s, expected %s", actual, e.Checksum)
+	}
+
+	return true, nil
+}
+
+// cacheChecksumHash returns the hash for a checksum, guessing the type from
+// the length of the checksum when it isn't given, or nil when the checksum
+// isn't a hash that can be checked.
+func cacheChecksumHash(checksumType, checksum string) hash.Hash {
+	if _, err := hex.DecodeString(checksum); err != nil || checksum == "" {
+		return nil
+	}
+
+	if checksumType == "" {
+		switch len(checksum) {
+		case md5.Size * 2:
+			checksumType = "md5"
+		case sha1.Size * 2:
+			checksumType = "sha1"
+		case sha256.Size * 2:
+			checksumType = "sha256"
+		case sha512.Size * 2:
+			checksumType = "sha512"
+		}
+	}
+
+	switch strings.ToLower(checksumType) {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	default:
+		return nil
+	}
+}
+
+func (c *Cache) readIndex() (*cacheIndex, error) {
+	index := &cacheIndex{
+		Version: cacheIndexVersion,
+		Entries: make(map[string]*CacheEntry),
+	}
+
+	data, err := ioutil.ReadFile(c.Path(CacheIndexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("Error reading cache index %s: %s",
+			c.Path(CacheIndexFile), err)
+	}
+	if index.Entries == nil {
+		index.Entries = make(map[string]*CacheEntry)
+	}
+
+	return index, nil
+}
+
+// updateIndex changes the index while holding its lock, and writes it
+// atomically so that readers never see a partial index.
+func (c *Cache) updateIndex(fn func(*cacheIndex)) error {
+	unlock, err := c.Lock(CacheIndexFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index, err := c.readIndex()
+	if err != nil {
+		return err
+	}
+	fn(index)
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := c.Path(CacheIndexFile)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func mergeTemplates(a, b []string) []string {
+	result := append([]string{}, a...)
+	for _, t := range b {
+		found := false
+		for _, existing := range result {
+			if existing == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, t)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,202 @@
+package packer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testCache(t *testing.T) *Cache {
+	dir, err := ioutil.TempDir("", "packer-cache")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return &Cache{Dir: dir}
+}
+
+func testCacheFile(t *testing.T, c *Cache, name, contents string) {
+	if err := ioutil.WriteFile(c.Path(name), []byte(contents), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCache_Record(t *testing.T) {
+	c := testCache(t)
+	defer os.RemoveAll(c.Dir)
+
+	testCacheFile(t, c, "a.iso", "hello")
+	testCacheFile(t, c, "untracked.iso", "hi")
+	testCacheFile(t, c, "untracked.iso.lock", "")
+
+	err := c.Record(&CacheEntry{
+		Name:      "a.iso",
+		Source:    "http://example.com/a.iso",
+		Templates: []string{"one.json"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = c.Record(&CacheEntry{
+		Name:      "a.iso",
+		Source:    "http://example.com/a.iso",
+		Templates: []string{"two.json", "one.json"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	entries, err := c.Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("bad: %#v", entries)
+	}
+
+	e := entries[0]
+	if e.Name != "a.iso" || e.Source != "http://example.com/a.iso" || e.Size != 5 || e.Untracked {
+		t.Fatalf("bad: %#v", e)
+	}
+	if !reflect.DeepEqual(e.Templates, []string{"one.json", "two.json"}) {
+		t.Fatalf("bad: %#v", e.Templates)
+	}
+	if e.Created.IsZero() || e.LastUsed.Before(e.Created) {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	if e := entries[1]; e.Name != "untracked.iso" || !e.Untracked || e.Size != 2 {
+		t.Fatalf("bad: %#v", e)
+	}
+
+	// Entries whose files are gone are left out
+	os.Remove(c.Path("a.iso"))
+	entries, err = c.Entries()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "untracked.iso" {
+		t.Fatalf("bad: %#v", entries)
+	}
+}
+
+func TestCache_Prune(t *testing.T) {
+	c := testCache(t)
+	defer os.RemoveAll(c.Dir)
+
+	for _, name := range []string{"old", "middle", "new"} {
+		testCacheFile(t, c, name, "0123456789")
+		if err := c.Record(&CacheEntry{Name: name}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Make the files look used a while ago
+	now := time.Now().UTC()
+	err := c.updateIndex(func(index *cacheIndex) {
+		index.Entries["old"].LastUsed = now.Add(-72 * time.Hour)
+		index.Entries["middle"].LastUsed = now.Add(-48 * time.Hour)
+		index.Entries["new"].LastUsed = now
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	removed, err := c.Prune(60*time.Hour, 0)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(removed) != 1 || removed[0].Name != "old" {
+		t.Fatalf("bad: %#v", removed)
+	}
+
+	removed, err = c.Prune(0, 15)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(removed) != 1 || removed[0].Name != "middle" {
+		t.Fatalf("bad: %#v", removed)
+	}
+
+	if _, err := os.Stat(c.Path("new")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range []string{"old", "middle"} {
+		if _, err := os.Stat(c.Path(name)); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed", name)
+		}
+	}
+
+	// Lock files are kept, so that everyone waiting on one shares the lock.
+	if _, err := os.Stat(c.Path("old.lock")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCache_PruneLocked(t *testing.T) {
+	c := testCache(t)
+	defer os.RemoveAll(c.Dir)
+
+	testCacheFile(t, c, "a.iso", "hello")
+	unlock, err := c.Lock("a.iso")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer unlock()
+
+	removed, err := c.Prune(0, 1)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("bad: %#v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(c.Dir, "a.iso")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestCache_Verify(t *testing.T) {
+	c := testCache(t)
+	defer os.RemoveAll(c.Dir)
+
+	testCacheFile(t, c, "a.iso", "hello")
+
+	cases := []struct {
+		Name         string
+		Checksum     string
+		ChecksumType string
+		Checksummed  bool
+		Err          bool
+	}{
+		{"sha1", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", "sha1", true, false},
+		{"guessed type", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", "", true, false},
+		{"bad checksum", "0000000000000000000000000000000000000000", "sha1", true, true},
+		{"checksum file", "http://example.com/SHA256SUMS", "file", false, false},
+		{"no checksum", "", "none", false, false},
+	}
+
+	for _, tc := range cases {
+		e := &CacheEntry{
+			Name:         "a.iso",
+			Checksum:     tc.Checksum,
+			ChecksumType: tc.ChecksumType,
+			Size:         5,
+		}
+		checksummed, err := c.Verify(e)
+		if checksummed != tc.Checksummed {
+			t.Errorf("%s: checksummed is %t", tc.Name, checksummed)
+		}
+		if (err != nil) != tc.Err {
+			t.Errorf("%s: err: %v", tc.Name, err)
+		}
+	}
+
+	// The size has changed
+	_, err := c.Verify(&CacheEntry{Name: "a.iso", Size: 10})
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,60 @@
+package packer
+
+import (
+	"context"
+	"sync"
+)
+
+// cancelGroup keeps track of the runs of a component that takes a context so
+// that it can also be cancelled through the Cancel method of the interfaces
+// that predate contexts.
+type cancelGroup struct {
+	l    sync.Mutex
+	runs map[*cancelRun]struct{}
+}
+
+type cancelRun struct {
+	cancel context.CancelFunc
+	doneCh chan struct{}
+}
+
+// start returns a context for a new run, along with the function that must
+// be called when the run is over.
+func (g *cancelGroup) start(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	run := &cancelRun{cancel: cancel, doneCh: make(chan struct{})}
+
+	g.l.Lock()
+	if g.runs == nil {
+		g.runs = make(map[*cancelRun]struct{})
+	}
+	g.runs[run] = struct{}{}
+	g.l.Unlock()
+
+	return ctx, func() {
+		g.l.Lock()
+		delete(g.runs, run)
+		g.l.Unlock()
+
+		cancel()
+		close(run.doneCh)
+	}
+}
+
+// Cancel cancels all the runs in progress and blocks until they are over. It
+// reports whether there were any runs to cancel.
+func (g *cancelGroup) Cancel() bool {
+	g.l.Lock()
+	runs := make([]*cancelRun, 0, len(g.runs))
+	for run := range g.runs {
+		runs = append(runs, run)
+	}
+	g.l.Unlock()
+
+	for _, run := range runs {
+		run.cancel()
+		<-run.doneCh
+	}
+
+	return len(runs) > 0
+}
@@ -1,6 +1,7 @@
 package packer
 
 import (
+	"context"
 	"io"
 	"os"
 	"strings"
@@ -68,7 +69,7 @@ type Communicator interface {
 
 	// UploadDir uploads the contents of a directory recursively to
 	// the remote path. It also takes an optional slice of paths to
-	// ignore when uploading.
+	// ignore when uploading, which are matched with ExcludedPath.
 	//
 	// The folder name of the source folder should be created unless there
 	// is a trailing slash on the source "/". For example: "/tmp/src" as
@@ -81,6 +82,10 @@ type Communicator interface {
 	// block until it completes.
 	Download(string, io.Writer) error
 
+	// DownloadDir downloads a remote directory recursively, or the files
+	// and directories matching src if it has wildcards, into the local
+	// directory dst, leaving out the paths matched by the exclude patterns.
+	// Each downloaded directory is created within dst, like scp(1) does.
 	DownloadDir(src string, dst string, exclude []string) error
 }
 
@@ -88,6 +93,13 @@ type Communicator interface {
 // configured Writers for stdout/stderr, while also writing each line
 // as it comes to a Ui.
 func (r *RemoteCmd) StartWithUi(c Communicator, ui Ui) error {
+	return r.StartWithUiContext(context.Background(), c, ui)
+}
+
+// StartWithUiContext is like StartWithUi, but stops waiting for the remote
+// command and returns the error of ctx once ctx is done. Communicators can't
+// kill remote commands, so the command may keep running on the machine.
+func (r *RemoteCmd) StartWithUiContext(ctx context.Context, c Communicator, ui Ui) error {
 	stdout_r, stdout_w := io.Pipe()
 	stderr_r, stderr_w := io.Pipe()
 	defer stdout_w.Close()
@@ -149,6 +161,8 @@ OutputLoop:
 			}
 		case <-exitCh:
 			break OutputLoop
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
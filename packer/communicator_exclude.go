@@ -0,0 +1,126 @@
+package packer
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/packer/tmp"
+)
+
+// ExcludedPath reports whether a file within a directory that is uploaded or
+// downloaded matches one of the exclude patterns of UploadDir or DownloadDir.
+// rel is the path of the file relative to the directory, with forward
+// slashes. Patterns use the syntax of path.Match, and match the relative
+// path of the file or of any directory it is in, or just their names, so
+// that ".git" leaves out every .git directory along with its contents.
+func ExcludedPath(rel string, exclude []string) bool {
+	if len(exclude) == 0 {
+		return false
+	}
+
+	rel = strings.Trim(path.Clean("/"+filepath.ToSlash(rel)), "/")
+	if rel == "" {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		for _, pattern := range exclude {
+			pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+			if ok, _ := path.Match(pattern, prefix); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, parts[i]); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// StageDir copies the directory src to a temporary directory, leaving out
+// the files matching the exclude patterns, for communicators that can only
+// transfer whole directories. The copy has the same name as src and keeps
+// its trailing slash, so that it is uploaded to the same place. Without
+// exclude patterns src itself is returned. The returned function removes
+// the copy.
+func StageDir(src string, exclude []string) (string, func(), error) {
+	if len(exclude) == 0 {
+		return src, func() {}, nil
+	}
+
+	dir, err := tmp.Dir("packer-upload")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	root := filepath.Clean(src)
+	staged := filepath.Join(dir, filepath.Base(root))
+
+	// Walk doesn't follow a symlink at the root
+	walkRoot := root
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		walkRoot = resolved
+	}
+	err = filepath.Walk(walkRoot, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(walkRoot, p)
+		if err != nil {
+			return err
+		}
+		if ExcludedPath(rel, exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return stageFile(filepath.Join(staged, rel), p, info)
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	if strings.HasSuffix(src, "/") || strings.HasSuffix(src, string(filepath.Separator)) {
+		staged += string(filepath.Separator)
+	}
+	return staged, cleanup, nil
+}
+
+func stageFile(dst, src string, info os.FileInfo) error {
+	switch {
+	case info.IsDir():
+		return os.MkdirAll(dst, info.Mode().Perm())
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,95 @@
+package packer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExcludedPath(t *testing.T) {
+	cases := []struct {
+		Path     string
+		Exclude  []string
+		Expected bool
+	}{
+		{"foo", nil, false},
+		{"foo", []string{"foo"}, true},
+		{"bar/foo", []string{"foo"}, true},
+		{"foo/bar", []string{"foo"}, true},
+		{"foo/bar", []string{"foo/bar"}, true},
+		{"baz/foo/bar", []string{"foo/bar"}, false},
+		{"foo.log", []string{"*.log"}, true},
+		{"logs/foo.log", []string{"*.log"}, true},
+		{"logs/foo.txt", []string{"*.log"}, false},
+		{".git/config", []string{".git/"}, true},
+		{".", []string{"*"}, false},
+	}
+
+	for _, tc := range cases {
+		if actual := ExcludedPath(tc.Path, tc.Exclude); actual != tc.Expected {
+			t.Fatalf("%s %v: expected %t", tc.Path, tc.Exclude, tc.Expected)
+		}
+	}
+}
+
+func TestStageDir(t *testing.T) {
+	src, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(src)
+
+	for _, name := range []string{"a.txt", "b.log", "sub/c.txt", "sub/d.log", ".git/config"} {
+		p := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Nothing is copied without exclude patterns
+	staged, cleanup, err := StageDir(src, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cleanup()
+	if staged != src {
+		t.Fatalf("bad: %s", staged)
+	}
+
+	staged, cleanup, err = StageDir(src+"/", []string{"*.log", ".git"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer cleanup()
+
+	if filepath.Base(filepath.Clean(staged)) != filepath.Base(src) {
+		t.Fatalf("bad: %s", staged)
+	}
+	if staged[len(staged)-1] != filepath.Separator {
+		t.Fatalf("trailing slash is lost: %s", staged)
+	}
+
+	var files []string
+	filepath.Walk(staged, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(staged, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	sort.Strings(files)
+	if expected := []string{"a.txt", "sub/c.txt"}; !reflect.DeepEqual(files, expected) {
+		t.Fatalf("bad: %#v", files)
+	}
+
+	cleanup()
+	if _, err := os.Stat(staged); !os.IsNotExist(err) {
+		t.Fatal("staged directory should be gone")
+	}
+}
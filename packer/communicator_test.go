@@ -2,6 +2,8 @@ package packer
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -42,6 +44,28 @@ func TestRemoteCmd_StartWithUi(t *testing.T) {
 	}
 }
 
+func TestRemoteCmd_StartWithUiContext(t *testing.T) {
+	testUi := &BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+
+	// The command reads from stdin, which never ends, so it never exits
+	stdin, stdinW := io.Pipe()
+	defer stdinW.Close()
+	rc := &RemoteCmd{
+		Command: "test",
+		Stdin:   stdin,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := rc.StartWithUiContext(ctx, new(MockCommunicator), testUi)
+	if err != context.Canceled {
+		t.Fatalf("should be cancelled: %v", err)
+	}
+}
+
 func TestRemoteCmd_Wait(t *testing.T) {
 	var cmd RemoteCmd
 
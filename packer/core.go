@@ -80,6 +80,9 @@ func NewCore(c *CoreConfig) (*Core, error) {
 	if err := result.init(); err != nil {
 		return nil, err
 	}
+	if err := result.validateVariables(); err != nil {
+		return nil, err
+	}
 	for _, secret := range result.secrets {
 		LogSecretFilter.Set(secret)
 	}
@@ -162,6 +165,17 @@ func (c *Core) Build(n string) (Build, error) {
 			}
 		}
 
+		// If there's a timeout, we wrap the provisioner so it is cancelled
+		// when it runs out of time. This happens before pausing so that
+		// the pause doesn't count toward the timeout.
+		if rawP.Timeout > 0 {
+			provisioner = &TimeoutProvisioner{
+				Timeout:     rawP.Timeout,
+				Provisioner: provisioner,
+				TypeName:    rawP.Type,
+			}
+		}
+
 		// If we're pausing, we wrap the provisioner in a special pauser.
 		if rawP.PauseBefore > 0 {
 			provisioner = &PausedProvisioner{
@@ -171,9 +185,11 @@ func (c *Core) Build(n string) (Build, error) {
 		}
 
 		provisioners = append(provisioners, coreBuildProvisioner{
-			pType:       rawP.Type,
-			provisioner: provisioner,
-			config:      config,
+			pType:        rawP.Type,
+			provisioner:  provisioner,
+			config:       config,
+			maxRetries:   rawP.MaxRetries,
+			retryBackoff: rawP.RetryBackoff,
 		})
 	}
 
@@ -234,6 +250,7 @@ func (c *Core) Build(n string) (Build, error) {
 		postProcessors: postProcessors,
 		provisioners:   provisioners,
 		templatePath:   c.Template.Path,
+		timeout:        configBuilder.Timeout,
 		variables:      c.variables,
 	}, nil
 }
@@ -297,6 +314,32 @@ func (c *Core) validate() error {
 	return err
 }
 
+// validateVariables checks the values of the variables, whether they were
+// set with -var, -var-file or by their default, against the rules the
+// template declares for them.
+func (c *Core) validateVariables() error {
+	keys := make([]string, 0, len(c.Template.Variables))
+	for k := range c.Template.Variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var err error
+	for _, k := range keys {
+		value, ok := c.variables[k]
+		if !ok {
+			continue
+		}
+
+		if verr := c.Template.Variables[k].Check(value); verr != nil {
+			err = multierror.Append(err, fmt.Errorf(
+				"variable %s: %s", k, verr))
+		}
+	}
+
+	return err
+}
+
 func (c *Core) init() error {
 	if c.variables == nil {
 		c.variables = make(map[string]string)
@@ -497,6 +497,31 @@ func TestCoreValidate(t *testing.T) {
 			map[string]string{"foo": "bar"},
 			true,
 		},
+
+		// Typed variables
+		{
+			"validate-typed-variable.json",
+			map[string]string{"name": "abc"},
+			false,
+		},
+
+		{
+			"validate-typed-variable.json",
+			map[string]string{"name": "abc", "size": "big"},
+			true,
+		},
+
+		{
+			"validate-typed-variable.json",
+			map[string]string{"name": "abc", "env": "test"},
+			true,
+		},
+
+		{
+			"validate-typed-variable.json",
+			map[string]string{"name": "ABC"},
+			true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -0,0 +1,124 @@
+package packer
+
+import "time"
+
+// EventVersion is the version of the schema of Event. It changes when
+// fields are removed or change meaning, but not when fields or event types
+// are added, so readers should ignore what they don't know.
+const EventVersion = 1
+
+// The types of events. Events of the ui types come from Say, Message and
+// Error. The others come from Machine calls of the same category, which is
+// how builders, provisioners and post-processors running as plugins report
+// them, except for the artifact and build-end events that Packer sends once a
+// build has finished. Machine calls of any other category are events of the
+// machine type.
+const (
+	EventSay     = "say"
+	EventMessage = "message"
+	EventError   = "error"
+	EventMachine = "machine"
+
+	// A build started running its builder. Machine args: the builder type.
+	EventBuildStart = "build-start"
+
+	// A step of a builder started and ended. Machine args: the name of the
+	// step, and for step-end the action it returned, "continue" or "halt".
+	EventStepStart = "step-start"
+	EventStepEnd   = "step-end"
+
+	// A provisioner started and ended. Machine args: the provisioner type,
+	// and for provisioner-end the error if it failed.
+	EventProvisionerStart = "provisioner-start"
+	EventProvisionerEnd   = "provisioner-end"
+
+	// A post-processor started and ended. Machine args: the post-processor
+	// type, and for post-processor-end the error if it failed.
+	EventPostProcessorStart = "post-processor-start"
+	EventPostProcessorEnd   = "post-processor-end"
+
+	// A build produced an artifact.
+	EventArtifact = "artifact"
+
+	// A build finished, was skipped or failed.
+	EventBuildEnd = "build-end"
+)
+
+// The severities of events.
+const (
+	SeverityInfo  = "info"
+	SeverityError = "error"
+)
+
+// The components that events come from.
+const (
+	ComponentBuilder       = "builder"
+	ComponentProvisioner   = "provisioner"
+	ComponentPostProcessor = "post-processor"
+)
+
+// Event is a single event of a JSONUi, which is written as one JSON object
+// per line.
+type Event struct {
+	// Version is the version of the schema, EventVersion.
+	Version int `json:"version"`
+
+	Timestamp time.Time `json:"timestamp"`
+
+	// Type is one of the event types, such as EventSay or EventStepStart.
+	Type string `json:"type"`
+
+	// Severity is SeverityInfo or SeverityError.
+	Severity string `json:"severity"`
+
+	// Build is the name of the build the event comes from, if any.
+	Build string `json:"build,omitempty"`
+
+	// Component is the kind of component of the build that the event comes
+	// from, such as ComponentProvisioner, and ComponentType is its type,
+	// such as "shell".
+	Component     string `json:"component,omitempty"`
+	ComponentType string `json:"component_type,omitempty"`
+
+	// Message is the message of ui events.
+	Message string `json:"message,omitempty"`
+
+	// Category and Args are the category and arguments of the Machine
+	// call of machine events.
+	Category string   `json:"category,omitempty"`
+	Args     []string `json:"args,omitempty"`
+
+	// Step is the name of the step of step events, and Action is the
+	// action that it returned for step-end.
+	Step   string `json:"step,omitempty"`
+	Action string `json:"action,omitempty"`
+
+	// Error is the error that a component or build failed with.
+	Error string `json:"error,omitempty"`
+
+	// Artifact is the artifact of artifact events.
+	Artifact *ArtifactInfo `json:"artifact,omitempty"`
+}
+
+// ArtifactInfo describes an artifact within an Event.
+type ArtifactInfo struct {
+	BuilderId string   `json:"builder_id"`
+	Id        string   `json:"id"`
+	String    string   `json:"string"`
+	Files     []string `json:"files"`
+}
+
+// NewArtifactInfo describes an artifact for an Event.
+func NewArtifactInfo(a Artifact) *ArtifactInfo {
+	files := a.Files()
+	if files == nil {
+		files = []string{}
+	}
+
+	return &ArtifactInfo{
+		BuilderId: a.BuilderId(),
+		Id:        a.Id(),
+		String:    a.String(),
+		Files:     files,
+	}
+}
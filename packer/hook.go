@@ -1,7 +1,7 @@
 package packer
 
 import (
-	"sync"
+	"context"
 )
 
 // This is the hook that should be fired for provisioners to run.
@@ -25,47 +25,66 @@ type Hook interface {
 	Cancel()
 }
 
+// A ContextHook is a Hook that takes a context when it runs. The context is
+// cancelled when the build is cancelled or runs out of time, so Cancel is
+// only called on hooks that don't implement this interface.
+type ContextHook interface {
+	RunContext(context.Context, string, Ui, Communicator, interface{}) error
+}
+
+// RunHook runs the hook until it finishes or ctx is done, whichever happens
+// first. Hooks that don't implement ContextHook are cancelled through Cancel,
+// and RunHook waits for them to return.
+func RunHook(ctx context.Context, h Hook, name string, ui Ui, comm Communicator, data interface{}) error {
+	if ch, ok := h.(ContextHook); ok {
+		return ch.RunContext(ctx, name, ui, comm, data)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.Run(name, ui, comm, data)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		h.Cancel()
+		return <-errCh
+	}
+}
+
 // A Hook implementation that dispatches based on an internal mapping.
 type DispatchHook struct {
 	Mapping map[string][]Hook
 
-	l           sync.Mutex
-	cancelled   bool
-	runningHook Hook
+	runs cancelGroup
 }
 
 // Runs the hook with the given name by dispatching it to the proper
 // hooks if a mapping exists. If a mapping doesn't exist, then nothing
 // happens.
 func (h *DispatchHook) Run(name string, ui Ui, comm Communicator, data interface{}) error {
-	h.l.Lock()
-	h.cancelled = false
-	h.l.Unlock()
-
-	// Make sure when we exit that we reset the running hook.
-	defer func() {
-		h.l.Lock()
-		defer h.l.Unlock()
-		h.runningHook = nil
-	}()
+	return h.RunContext(context.Background(), name, ui, comm, data)
+}
 
+// RunContext is like Run, but stops running hooks once ctx is done.
+func (h *DispatchHook) RunContext(ctx context.Context, name string, ui Ui, comm Communicator, data interface{}) error {
 	hooks, ok := h.Mapping[name]
 	if !ok {
 		// No hooks for that name. No problem.
 		return nil
 	}
 
+	ctx, done := h.runs.start(ctx)
+	defer done()
+
 	for _, hook := range hooks {
-		h.l.Lock()
-		if h.cancelled {
-			h.l.Unlock()
+		if ctx.Err() != nil {
 			return nil
 		}
 
-		h.runningHook = hook
-		h.l.Unlock()
-
-		if err := hook.Run(name, ui, comm, data); err != nil {
+		if err := RunHook(ctx, hook, name, ui, comm, data); err != nil {
 			return err
 		}
 	}
@@ -76,12 +95,5 @@ func (h *DispatchHook) Run(name string, ui Ui, comm Communicator, data interface
 // Cancels all the hooks that are currently in-flight, if any. This will
 // block until the hooks are all cancelled.
 func (h *DispatchHook) Cancel() {
-	h.l.Lock()
-	defer h.l.Unlock()
-
-	if h.runningHook != nil {
-		h.runningHook.Cancel()
-	}
-
-	h.cancelled = true
+	h.runs.Cancel()
 }
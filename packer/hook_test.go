@@ -1,6 +1,7 @@
 package packer
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -86,3 +87,19 @@ func TestDispatchHook_cancel(t *testing.T) {
 		t.Fatal("hook should've cancelled")
 	}
 }
+
+func TestRunHook_context(t *testing.T) {
+	hook := new(CancelHook)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	RunHook(ctx, hook, "foo", nil, nil, 42)
+
+	if !hook.Cancelled {
+		t.Fatal("hook should've cancelled")
+	}
+}
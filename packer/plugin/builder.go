@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"log"
 
 	"github.com/hashicorp/packer/packer"
@@ -29,6 +30,15 @@ func (b *cmdBuilder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error
 	return b.builder.Run(ui, hook)
 }
 
+func (b *cmdBuilder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	defer func() {
+		r := recover()
+		b.checkExit(r, nil)
+	}()
+
+	return packer.RunBuilder(ctx, b.builder, ui, hook)
+}
+
 func (b *cmdBuilder) Cancel() {
 	defer func() {
 		r := recover()
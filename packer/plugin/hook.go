@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"log"
 
 	"github.com/hashicorp/packer/packer"
@@ -20,6 +21,15 @@ func (c *cmdHook) Run(name string, ui packer.Ui, comm packer.Communicator, data
 	return c.hook.Run(name, ui, comm, data)
 }
 
+func (c *cmdHook) RunContext(ctx context.Context, name string, ui packer.Ui, comm packer.Communicator, data interface{}) error {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return packer.RunHook(ctx, c.hook, name, ui, comm, data)
+}
+
 func (c *cmdHook) Cancel() {
 	defer func() {
 		r := recover()
@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"log"
 
 	"github.com/hashicorp/packer/packer"
@@ -29,6 +30,15 @@ func (c *cmdPostProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.
 	return c.p.PostProcess(ui, a)
 }
 
+func (c *cmdPostProcessor) PostProcessContext(ctx context.Context, ui packer.Ui, a packer.Artifact) (packer.Artifact, bool, error) {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return packer.RunPostProcessor(ctx, c.p, ui, a)
+}
+
 func (c *cmdPostProcessor) checkExit(p interface{}, cb func()) {
 	if c.client.Exited() && cb != nil {
 		cb()
@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"log"
 
 	"github.com/hashicorp/packer/packer"
@@ -29,6 +30,15 @@ func (c *cmdProvisioner) Provision(ui packer.Ui, comm packer.Communicator) error
 	return c.p.Provision(ui, comm)
 }
 
+func (c *cmdProvisioner) ProvisionContext(ctx context.Context, ui packer.Ui, comm packer.Communicator) error {
+	defer func() {
+		r := recover()
+		c.checkExit(r, nil)
+	}()
+
+	return packer.RunProvisioner(ctx, c.p, ui, comm)
+}
+
 func (c *cmdProvisioner) Cancel() {
 	defer func() {
 		r := recover()
@@ -1,5 +1,7 @@
 package packer
 
+import "context"
+
 // A PostProcessor is responsible for taking an artifact of a build
 // and doing some sort of post-processing to turn this into another
 // artifact. An example of a post-processor would be something that takes
@@ -16,3 +18,21 @@ type PostProcessor interface {
 	// is to true, then the previous artifact is forcibly kept.
 	PostProcess(Ui, Artifact) (a Artifact, keep bool, err error)
 }
+
+// A ContextPostProcessor is a PostProcessor that takes a context when it
+// post-processes an artifact. The context is cancelled when the build is
+// cancelled or runs out of time.
+type ContextPostProcessor interface {
+	PostProcessContext(ctx context.Context, ui Ui, a Artifact) (Artifact, bool, error)
+}
+
+// RunPostProcessor runs the post-processor with the given context. Post-processors
+// that don't implement ContextPostProcessor can't be cancelled, so they are
+// left to run until they finish.
+func RunPostProcessor(ctx context.Context, p PostProcessor, ui Ui, a Artifact) (Artifact, bool, error) {
+	if cp, ok := p.(ContextPostProcessor); ok {
+		return cp.PostProcessContext(ctx, ui, a)
+	}
+
+	return p.PostProcess(ui, a)
+}
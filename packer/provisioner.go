@@ -1,12 +1,20 @@
 package packer
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/hashicorp/packer/common/retry"
 )
 
+// DefaultRetryBackoff is the time waited before retrying a failed
+// provisioner for the first time when no backoff is configured.
+const DefaultRetryBackoff = 5 * time.Second
+
 // A provisioner is responsible for installing and configuring software
 // on a machine prior to building the actual image.
 type Provisioner interface {
@@ -27,11 +35,49 @@ type Provisioner interface {
 	Cancel()
 }
 
+// A ContextProvisioner is a Provisioner that takes a context when it
+// provisions. The context is cancelled when the build is cancelled or runs
+// out of time, so a ContextProvisioner doesn't need to keep track of its own
+// runs to be able to cancel them: Cancel is only called on provisioners that
+// don't implement this interface.
+type ContextProvisioner interface {
+	ProvisionContext(ctx context.Context, ui Ui, comm Communicator) error
+}
+
+// RunProvisioner runs the provisioner until it finishes or ctx is done,
+// whichever happens first. Provisioners that don't implement
+// ContextProvisioner are cancelled through Cancel, and RunProvisioner waits
+// for them to return.
+func RunProvisioner(ctx context.Context, p Provisioner, ui Ui, comm Communicator) error {
+	if cp, ok := p.(ContextProvisioner); ok {
+		return cp.ProvisionContext(ctx, ui, comm)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Provision(ui, comm)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		p.Cancel()
+		return <-errCh
+	}
+}
+
 // A HookedProvisioner represents a provisioner and information describing it
 type HookedProvisioner struct {
 	Provisioner Provisioner
 	Config      interface{}
 	TypeName    string
+
+	// MaxRetries is how many times the provisioner is run again when it
+	// fails. The wait between tries starts at RetryBackoff and doubles
+	// after each one.
+	MaxRetries   int
+	RetryBackoff time.Duration
 }
 
 // A Hook implementation that runs the given provisioners.
@@ -40,12 +86,21 @@ type ProvisionHook struct {
 	// be prepared (by calling Prepare) at some earlier stage.
 	Provisioners []*HookedProvisioner
 
-	lock               sync.Mutex
-	runningProvisioner Provisioner
+	lock   sync.Mutex
+	cancel context.CancelFunc
+
+	// timeoutErr is set when a provisioner timed out, so that the build can
+	// report it even after the error went through the builder.
+	timeoutErr *TimeoutError
 }
 
 // Runs the provisioners in order.
 func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interface{}) error {
+	return h.RunContext(context.Background(), name, ui, comm, data)
+}
+
+// Runs the provisioners in order, stopping when ctx is done.
+func (h *ProvisionHook) RunContext(ctx context.Context, name string, ui Ui, comm Communicator, data interface{}) error {
 	// Shortcut
 	if len(h.Provisioners) == 0 {
 		return nil
@@ -58,24 +113,49 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 				"then a communicator is required. Please fix this to continue.")
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	h.lock.Lock()
+	h.cancel = cancel
+	h.lock.Unlock()
+
 	defer func() {
 		h.lock.Lock()
 		defer h.lock.Unlock()
 
-		h.runningProvisioner = nil
+		h.cancel = nil
+		cancel()
 	}()
 
 	for _, p := range h.Provisioners {
-		h.lock.Lock()
-		h.runningProvisioner = p.Provisioner
-		h.lock.Unlock()
+		// A provisioner that is cancelled can still return without an
+		// error, like a PausedProvisioner that is cancelled while it
+		// pauses. Don't go on to start the next one then, and let the
+		// build know that provisioning didn't complete.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		ts := CheckpointReporter.AddSpan(p.TypeName, "provisioner", p.Config)
+		if ui != nil {
+			ui.Machine(EventProvisionerStart, p.TypeName)
+		}
 
-		err := p.Provisioner.Provision(ui, comm)
+		err := h.provision(ctx, p, ui, comm)
 
 		ts.End(err)
+		if ui != nil {
+			if err != nil {
+				ui.Machine(EventProvisionerEnd, p.TypeName, err.Error())
+			} else {
+				ui.Machine(EventProvisionerEnd, p.TypeName)
+			}
+		}
 		if err != nil {
+			if terr, ok := err.(*TimeoutError); ok {
+				h.lock.Lock()
+				h.timeoutErr = terr
+				h.lock.Unlock()
+			}
 			return err
 		}
 	}
@@ -83,25 +163,115 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 	return nil
 }
 
+// provision runs a single provisioner, trying it again with exponential
+// backoff when it fails and has retries left.
+func (h *ProvisionHook) provision(ctx context.Context, p *HookedProvisioner, ui Ui, comm Communicator) error {
+	if p.MaxRetries <= 0 {
+		return RunProvisioner(ctx, p.Provisioner, ui, comm)
+	}
+
+	backoff := p.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	tries := uint(p.MaxRetries) + 1
+	wait := backoff
+	return retry.RetryContext(ctx, backoff.Seconds(), 0, tries, func(i uint) (bool, error) {
+		if i > 0 {
+			ui.Machine("provisioner-retry", p.TypeName,
+				strconv.Itoa(int(i)), strconv.Itoa(p.MaxRetries))
+			ui.Say(fmt.Sprintf("Retrying provisioner '%s' (retry %d of %d)...",
+				p.TypeName, i, p.MaxRetries))
+		}
+
+		err := RunProvisioner(ctx, p.Provisioner, ui, comm)
+		if err == nil {
+			return true, nil
+		}
+
+		// Don't retry when the build is being cancelled or on the last try.
+		if ctx.Err() != nil || i+1 >= tries {
+			return false, err
+		}
+
+		ui.Machine("provisioner-failed", p.TypeName,
+			strconv.Itoa(int(i)), err.Error())
+		ui.Error(fmt.Sprintf("Provisioner '%s' failed: %s. Retrying in %s.",
+			p.TypeName, err, wait))
+		wait *= 2
+		return false, nil
+	})
+}
+
 // Cancels the provisioners that are still running.
 func (h *ProvisionHook) Cancel() {
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
-	if h.runningProvisioner != nil {
-		h.runningProvisioner.Cancel()
+	if h.cancel != nil {
+		h.cancel()
 	}
 }
 
+// TimedOut returns the error of the provisioner that timed out during the
+// last run, if any.
+func (h *ProvisionHook) TimedOut() *TimeoutError {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.timeoutErr
+}
+
+// TimeoutProvisioner is a Provisioner implementation that cancels the
+// provisioner when it runs for longer than Timeout.
+type TimeoutProvisioner struct {
+	Timeout     time.Duration
+	Provisioner Provisioner
+	TypeName    string
+
+	runs cancelGroup
+}
+
+func (p *TimeoutProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *TimeoutProvisioner) Provision(ui Ui, comm Communicator) error {
+	ctx, done := p.runs.start(context.Background())
+	defer done()
+
+	return p.ProvisionContext(ctx, ui, comm)
+}
+
+func (p *TimeoutProvisioner) ProvisionContext(ctx context.Context, ui Ui, comm Communicator) error {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	err := RunProvisioner(ctx, p.Provisioner, ui, comm)
+	if ctx.Err() == context.DeadlineExceeded {
+		terr := &TimeoutError{
+			Name:    fmt.Sprintf("Provisioner '%s'", p.TypeName),
+			Timeout: p.Timeout,
+		}
+		ui.Error(terr.Error())
+		return terr
+	}
+
+	return err
+}
+
+func (p *TimeoutProvisioner) Cancel() {
+	p.runs.Cancel()
+}
+
 // PausedProvisioner is a Provisioner implementation that pauses before
 // the provisioner is actually run.
 type PausedProvisioner struct {
 	PauseBefore time.Duration
 	Provisioner Provisioner
 
-	cancelCh chan struct{}
-	doneCh   chan struct{}
-	lock     sync.Mutex
+	runs cancelGroup
 }
 
 func (p *PausedProvisioner) Prepare(raws ...interface{}) error {
@@ -109,65 +279,26 @@ func (p *PausedProvisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *PausedProvisioner) Provision(ui Ui, comm Communicator) error {
-	p.lock.Lock()
-	cancelCh := make(chan struct{})
-	p.cancelCh = cancelCh
-
-	// Setup the done channel, which is trigger when we're done
-	doneCh := make(chan struct{})
-	defer close(doneCh)
-	p.doneCh = doneCh
-	p.lock.Unlock()
+	ctx, done := p.runs.start(context.Background())
+	defer done()
 
-	defer func() {
-		p.lock.Lock()
-		defer p.lock.Unlock()
-		if p.cancelCh == cancelCh {
-			p.cancelCh = nil
-		}
-		if p.doneCh == doneCh {
-			p.doneCh = nil
-		}
-	}()
+	return p.ProvisionContext(ctx, ui, comm)
+}
 
+func (p *PausedProvisioner) ProvisionContext(ctx context.Context, ui Ui, comm Communicator) error {
 	// Use a select to determine if we get cancelled during the wait
 	ui.Say(fmt.Sprintf("Pausing %s before the next provisioner...", p.PauseBefore))
 	select {
 	case <-time.After(p.PauseBefore):
-	case <-cancelCh:
+	case <-ctx.Done():
 		return nil
 	}
 
-	provDoneCh := make(chan error, 1)
-	go p.provision(provDoneCh, ui, comm)
-
-	select {
-	case err := <-provDoneCh:
-		return err
-	case <-cancelCh:
-		p.Provisioner.Cancel()
-		return <-provDoneCh
-	}
+	return RunProvisioner(ctx, p.Provisioner, ui, comm)
 }
 
 func (p *PausedProvisioner) Cancel() {
-	var doneCh chan struct{}
-
-	p.lock.Lock()
-	if p.cancelCh != nil {
-		close(p.cancelCh)
-		p.cancelCh = nil
-	}
-	if p.doneCh != nil {
-		doneCh = p.doneCh
-	}
-	p.lock.Unlock()
-
-	<-doneCh
-}
-
-func (p *PausedProvisioner) provision(result chan<- error, ui Ui, comm Communicator) {
-	result <- p.Provisioner.Provision(ui, comm)
+	p.runs.Cancel()
 }
 
 // DebuggedProvisioner is a Provisioner implementation that waits until a key
@@ -175,9 +306,7 @@ func (p *PausedProvisioner) provision(result chan<- error, ui Ui, comm Communica
 type DebuggedProvisioner struct {
 	Provisioner Provisioner
 
-	cancelCh chan struct{}
-	doneCh   chan struct{}
-	lock     sync.Mutex
+	runs cancelGroup
 }
 
 func (p *DebuggedProvisioner) Prepare(raws ...interface{}) error {
@@ -185,27 +314,13 @@ func (p *DebuggedProvisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *DebuggedProvisioner) Provision(ui Ui, comm Communicator) error {
-	p.lock.Lock()
-	cancelCh := make(chan struct{})
-	p.cancelCh = cancelCh
-
-	// Setup the done channel, which is trigger when we're done
-	doneCh := make(chan struct{})
-	defer close(doneCh)
-	p.doneCh = doneCh
-	p.lock.Unlock()
+	ctx, done := p.runs.start(context.Background())
+	defer done()
 
-	defer func() {
-		p.lock.Lock()
-		defer p.lock.Unlock()
-		if p.cancelCh == cancelCh {
-			p.cancelCh = nil
-		}
-		if p.doneCh == doneCh {
-			p.doneCh = nil
-		}
-	}()
+	return p.ProvisionContext(ctx, ui, comm)
+}
 
+func (p *DebuggedProvisioner) ProvisionContext(ctx context.Context, ui Ui, comm Communicator) error {
 	// Use a select to determine if we get cancelled during the wait
 	message := "Pausing before the next provisioner . Press enter to continue."
 
@@ -221,38 +336,13 @@ func (p *DebuggedProvisioner) Provision(ui Ui, comm Communicator) error {
 
 	select {
 	case <-result:
-	case <-cancelCh:
+	case <-ctx.Done():
 		return nil
 	}
 
-	provDoneCh := make(chan error, 1)
-	go p.provision(provDoneCh, ui, comm)
-
-	select {
-	case err := <-provDoneCh:
-		return err
-	case <-cancelCh:
-		p.Provisioner.Cancel()
-		return <-provDoneCh
-	}
+	return RunProvisioner(ctx, p.Provisioner, ui, comm)
 }
 
 func (p *DebuggedProvisioner) Cancel() {
-	var doneCh chan struct{}
-
-	p.lock.Lock()
-	if p.cancelCh != nil {
-		close(p.cancelCh)
-		p.cancelCh = nil
-	}
-	if p.doneCh != nil {
-		doneCh = p.doneCh
-	}
-	p.lock.Unlock()
-
-	<-doneCh
-}
-
-func (p *DebuggedProvisioner) provision(result chan<- error, ui Ui, comm Communicator) {
-	result <- p.Provisioner.Provision(ui, comm)
+	p.runs.Cancel()
 }
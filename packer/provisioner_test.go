@@ -1,6 +1,9 @@
 package packer
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -24,8 +27,8 @@ func TestProvisionHook(t *testing.T) {
 
 	hook := &ProvisionHook{
 		Provisioners: []*HookedProvisioner{
-			{pA, nil, ""},
-			{pB, nil, ""},
+			{Provisioner: pA},
+			{Provisioner: pB},
 		},
 	}
 
@@ -50,8 +53,8 @@ func TestProvisionHook_nilComm(t *testing.T) {
 
 	hook := &ProvisionHook{
 		Provisioners: []*HookedProvisioner{
-			{pA, nil, ""},
-			{pB, nil, ""},
+			{Provisioner: pA},
+			{Provisioner: pB},
 		},
 	}
 
@@ -79,7 +82,7 @@ func TestProvisionHook_cancel(t *testing.T) {
 
 	hook := &ProvisionHook{
 		Provisioners: []*HookedProvisioner{
-			{p, nil, ""},
+			{Provisioner: p},
 		},
 	}
 
@@ -105,6 +108,97 @@ func TestProvisionHook_cancel(t *testing.T) {
 	}
 }
 
+// A provisioner that returns without an error when it is cancelled doesn't
+// let the hook go on to the next provisioner.
+func TestProvisionHook_context(t *testing.T) {
+	provCh := make(chan struct{})
+	p := &MockProvisioner{
+		ProvFunc: func() error {
+			close(provCh)
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	}
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{Provisioner: p},
+			{Provisioner: new(MockProvisioner)},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-provCh
+		cancel()
+	}()
+
+	err := hook.RunContext(ctx, "foo", nil, new(MockCommunicator), nil)
+	if err != context.Canceled {
+		t.Fatalf("should be cancelled: %v", err)
+	}
+	if !p.CancelCalled {
+		t.Fatal("cancel should be called")
+	}
+	if hook.Provisioners[1].Provisioner.(*MockProvisioner).ProvCalled {
+		t.Fatal("second provisioner should not run")
+	}
+}
+
+func TestProvisionHook_retry(t *testing.T) {
+	tries := 0
+	p := &MockProvisioner{
+		ProvFunc: func() error {
+			tries++
+			if tries < 3 {
+				return errors.New("flaky")
+			}
+			return nil
+		},
+	}
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{Provisioner: p, MaxRetries: 2, RetryBackoff: time.Millisecond},
+		},
+	}
+
+	if err := hook.Run("foo", testUi(), new(MockCommunicator), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if tries != 3 {
+		t.Fatalf("should be tried 3 times: %d", tries)
+	}
+}
+
+func TestProvisionHook_retryExhausted(t *testing.T) {
+	tries := 0
+	p := &MockProvisioner{
+		ProvFunc: func() error {
+			tries++
+			return fmt.Errorf("try %d", tries)
+		},
+	}
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{Provisioner: p, MaxRetries: 1, RetryBackoff: time.Millisecond},
+			{Provisioner: new(MockProvisioner)},
+		},
+	}
+
+	err := hook.Run("foo", testUi(), new(MockCommunicator), nil)
+	if err == nil || err.Error() != "try 2" {
+		t.Fatalf("should return the last error: %v", err)
+	}
+	if tries != 2 {
+		t.Fatalf("should be tried 2 times: %d", tries)
+	}
+	if hook.Provisioners[1].Provisioner.(*MockProvisioner).ProvCalled {
+		t.Fatal("second provisioner should not run")
+	}
+}
+
 // TODO(mitchellh): Test that they're run in the proper order
 
 func TestPausedProvisioner_impl(t *testing.T) {
@@ -261,3 +355,49 @@ func TestDebuggedProvisionerCancel(t *testing.T) {
 		t.Fatal("cancel should be called")
 	}
 }
+
+func TestTimeoutProvisioner_impl(t *testing.T) {
+	var _ Provisioner = new(TimeoutProvisioner)
+}
+
+func TestTimeoutProvisionerProvision(t *testing.T) {
+	mock := new(MockProvisioner)
+	prov := &TimeoutProvisioner{
+		Timeout:     time.Minute,
+		Provisioner: mock,
+		TypeName:    "mock",
+	}
+
+	if err := prov.Provision(testUi(), new(MockCommunicator)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !mock.ProvCalled {
+		t.Fatal("prov should be called")
+	}
+}
+
+func TestTimeoutProvisionerProvision_timeout(t *testing.T) {
+	mock := new(MockProvisioner)
+	prov := &TimeoutProvisioner{
+		Timeout:     10 * time.Millisecond,
+		Provisioner: mock,
+		TypeName:    "mock",
+	}
+
+	mock.ProvFunc = func() error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	err := prov.Provision(testUi(), new(MockCommunicator))
+	terr, ok := err.(*TimeoutError)
+	if !ok {
+		t.Fatalf("should be a timeout error: %#v", err)
+	}
+	if terr.Timeout != 10*time.Millisecond {
+		t.Fatalf("bad: %#v", terr)
+	}
+	if !mock.CancelCalled {
+		t.Fatal("cancel should be called")
+	}
+}
@@ -0,0 +1,67 @@
+package packer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+var DefaultResumeDir = "packer_resume"
+
+// ResumePath returns an absolute path to a file or directory in the
+// directory where resumable builds keep their checkpoints.
+//
+// When the directory is not absolute, ResumePath will try to get
+// current working directory to be able to return a full path.
+//
+// ex:
+//
+//	PACKER_RESUME_DIR=""            ResumePath("foo") => "./packer_resume/foo
+//	PACKER_RESUME_DIR="/home/there" ResumePath("foo", "bar") => "/home/there/foo/bar
+func ResumePath(paths ...string) (string, error) {
+	resumeDir := DefaultResumeDir
+	if rd := os.Getenv("PACKER_RESUME_DIR"); rd != "" {
+		resumeDir = rd
+	}
+
+	paths = append([]string{resumeDir}, paths...)
+	return filepath.Abs(filepath.Join(paths...))
+}
+
+// resumeKey returns a hash of the configuration of the build, its
+// provisioners and post-processors and the user variables, which is part of
+// the path of its checkpoints. This keeps builds of the same name in
+// different templates, or of a template that changed, from resuming from
+// each other's checkpoints.
+func (b *coreBuild) resumeKey() string {
+	var provisioners [][]interface{}
+	for _, p := range b.provisioners {
+		provisioners = append(provisioners, append([]interface{}{p.pType}, p.config...))
+	}
+	var postProcessors []map[string]interface{}
+	for _, ppSeq := range b.postProcessors {
+		for _, pp := range ppSeq {
+			postProcessors = append(postProcessors, pp.config)
+		}
+	}
+
+	data, err := json.Marshal([]interface{}{
+		b.builderType,
+		b.builderConfig,
+		provisioners,
+		postProcessors,
+		b.variables,
+	})
+	if err != nil {
+		// The configuration always comes from JSON or HCL, so this
+		// shouldn't happen. Fall back to the path of the template.
+		log.Printf("Error hashing the configuration of %s: %s", b.name, err)
+		data = []byte(b.templatePath)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,67 @@
+package packer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumePath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	tmp := os.TempDir()
+
+	rd := os.Getenv("PACKER_RESUME_DIR")
+	os.Setenv("PACKER_RESUME_DIR", "")
+	defer os.Setenv("PACKER_RESUME_DIR", rd)
+
+	got, err := ResumePath("a", "b")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if want := filepath.Join(wd, "packer_resume", "a", "b"); got != want {
+		t.Fatalf("ResumePath() = %v, want %v", got, want)
+	}
+
+	os.Setenv("PACKER_RESUME_DIR", tmp)
+	got, err = ResumePath("a")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if want := filepath.Join(tmp, "a"); got != want {
+		t.Fatalf("ResumePath() = %v, want %v", got, want)
+	}
+}
+
+func TestBuild_Prepare_resumeKey(t *testing.T) {
+	resumeKey := func(b *coreBuild) interface{} {
+		b.SetResume(true)
+		if _, err := b.Prepare(); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		config := b.builder.(*MockBuilder).PrepareConfig[1]
+		return config.(map[string]interface{})[ResumeKeyConfigKey]
+	}
+
+	key := resumeKey(testBuild())
+	if key == nil || key == "" {
+		t.Fatal("should have a resume key")
+	}
+	if other := resumeKey(testBuild()); other != key {
+		t.Fatalf("the same build should have the same key: %s != %s", other, key)
+	}
+
+	b := testBuild()
+	b.builderConfig = 43
+	if other := resumeKey(b); other == key {
+		t.Fatal("a build with another configuration should have another key")
+	}
+
+	b = testBuild()
+	b.variables["foo"] = "bar"
+	if other := resumeKey(b); other == key {
+		t.Fatal("a build with other variables should have another key")
+	}
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"net/rpc"
 
 	"github.com/hashicorp/packer/packer"
@@ -18,6 +19,7 @@ type build struct {
 type BuildServer struct {
 	build packer.Build
 	mux   *muxBroker
+	calls runningCalls
 }
 
 type BuildPrepareResponse struct {
@@ -44,11 +46,18 @@ func (b *build) Prepare() ([]string, error) {
 }
 
 func (b *build) Run(ui packer.Ui) ([]packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui)
+}
+
+func (b *build) RunContext(ctx context.Context, ui packer.Ui) ([]packer.Artifact, error) {
 	nextId := b.mux.NextId()
 	server := newServerWithMux(b.mux, nextId)
 	server.RegisterUi(ui)
 	go server.Serve()
 
+	stop := cancelOnDone(ctx, b.Cancel)
+	defer stop()
+
 	var result []uint32
 	if err := b.client.Call("Build.Run", nextId, &result); err != nil {
 		return nil, err
@@ -85,6 +94,34 @@ func (b *build) SetOnError(val string) {
 	}
 }
 
+func (b *build) SetMachineReadable(val bool) {
+	if err := b.client.Call("Build.SetMachineReadable", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetResume(val bool) {
+	if err := b.client.Call("Build.SetResume", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetArtifacts(artifacts map[string]packer.Artifact) {
+	streamIds := make(map[string]uint32, len(artifacts))
+	for name, artifact := range artifacts {
+		streamId := b.mux.NextId()
+		server := newServerWithMux(b.mux, streamId)
+		server.RegisterArtifact(artifact)
+		go server.Serve()
+
+		streamIds[name] = streamId
+	}
+
+	if err := b.client.Call("Build.SetArtifacts", streamIds, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
 func (b *build) Cancel() {
 	if err := b.client.Call("Build.Cancel", new(interface{}), new(interface{})); err != nil {
 		panic(err)
@@ -112,7 +149,10 @@ func (b *BuildServer) Run(streamId uint32, reply *[]uint32) error {
 	}
 	defer client.Close()
 
-	artifacts, err := b.build.Run(client.Ui())
+	ctx, done := b.calls.start()
+	defer done()
+
+	artifacts, err := packer.RunBuild(ctx, b.build, client.Ui())
 	if err != nil {
 		return NewBasicError(err)
 	}
@@ -145,7 +185,34 @@ func (b *BuildServer) SetOnError(val *string, reply *interface{}) error {
 	return nil
 }
 
+func (b *BuildServer) SetMachineReadable(val *bool, reply *interface{}) error {
+	b.build.SetMachineReadable(*val)
+	return nil
+}
+
+func (b *BuildServer) SetResume(val *bool, reply *interface{}) error {
+	b.build.SetResume(*val)
+	return nil
+}
+
+func (b *BuildServer) SetArtifacts(streamIds map[string]uint32, reply *interface{}) error {
+	artifacts := make(map[string]packer.Artifact, len(streamIds))
+	for name, streamId := range streamIds {
+		client, err := newClientWithMux(b.mux, streamId)
+		if err != nil {
+			return NewBasicError(err)
+		}
+
+		artifacts[name] = client.Artifact()
+	}
+
+	b.build.SetArtifacts(artifacts)
+	return nil
+}
+
 func (b *BuildServer) Cancel(args *interface{}, reply *interface{}) error {
-	b.build.Cancel()
+	if !b.calls.cancel() {
+		b.build.Cancel()
+	}
 	return nil
 }
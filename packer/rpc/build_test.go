@@ -19,6 +19,9 @@ type testBuild struct {
 	setDebugCalled   bool
 	setForceCalled   bool
 	setOnErrorCalled bool
+	setMachineCalled bool
+	setResumeCalled  bool
+	artifacts        map[string]packer.Artifact
 	cancelCalled     bool
 
 	errRunResult bool
@@ -57,6 +60,18 @@ func (b *testBuild) SetOnError(string) {
 	b.setOnErrorCalled = true
 }
 
+func (b *testBuild) SetMachineReadable(bool) {
+	b.setMachineCalled = true
+}
+
+func (b *testBuild) SetResume(bool) {
+	b.setResumeCalled = true
+}
+
+func (b *testBuild) SetArtifacts(artifacts map[string]packer.Artifact) {
+	b.artifacts = artifacts
+}
+
 func (b *testBuild) Cancel() {
 	b.cancelCalled = true
 }
@@ -125,6 +140,24 @@ func TestBuild(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Test SetMachineReadable
+	bClient.SetMachineReadable(true)
+	if !b.setMachineCalled {
+		t.Fatal("should be called")
+	}
+
+	// Test SetResume
+	bClient.SetResume(true)
+	if !b.setResumeCalled {
+		t.Fatal("should be called")
+	}
+
+	// Test SetArtifacts
+	bClient.SetArtifacts(map[string]packer.Artifact{"base": testBuildArtifact})
+	if a, ok := b.artifacts["base"]; !ok || a.Id() != testBuildArtifact.Id() {
+		t.Fatalf("bad: %#v", b.artifacts)
+	}
+
 	// Test Cancel
 	bClient.Cancel()
 	if !b.cancelCalled {
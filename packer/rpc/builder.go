@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"log"
 	"net/rpc"
 
@@ -19,6 +20,7 @@ type builder struct {
 type BuilderServer struct {
 	builder packer.Builder
 	mux     *muxBroker
+	calls   runningCalls
 }
 
 type BuilderPrepareArgs struct {
@@ -45,12 +47,19 @@ func (b *builder) Prepare(config ...interface{}) ([]string, error) {
 }
 
 func (b *builder) Run(ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
+	return b.RunContext(context.Background(), ui, hook)
+}
+
+func (b *builder) RunContext(ctx context.Context, ui packer.Ui, hook packer.Hook) (packer.Artifact, error) {
 	nextId := b.mux.NextId()
 	server := newServerWithMux(b.mux, nextId)
 	server.RegisterHook(hook)
 	server.RegisterUi(ui)
 	go server.Serve()
 
+	stop := cancelOnDone(ctx, b.Cancel)
+	defer stop()
+
 	var responseId uint32
 	if err := b.client.Call("Builder.Run", nextId, &responseId); err != nil {
 		return nil, err
@@ -90,7 +99,10 @@ func (b *BuilderServer) Run(streamId uint32, reply *uint32) error {
 	}
 	defer client.Close()
 
-	artifact, err := b.builder.Run(client.Ui(), client.Hook())
+	ctx, done := b.calls.start()
+	defer done()
+
+	artifact, err := packer.RunBuilder(ctx, b.builder, client.Ui(), client.Hook())
 	if err != nil {
 		return NewBasicError(err)
 	}
@@ -108,6 +120,8 @@ func (b *BuilderServer) Run(streamId uint32, reply *uint32) error {
 }
 
 func (b *BuilderServer) Cancel(args *interface{}, reply *interface{}) error {
-	b.builder.Cancel()
+	if !b.calls.cancel() {
+		b.builder.Cancel()
+	}
 	return nil
 }
@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+)
+
+// runningCalls keeps track of the contexts of the calls in flight on a
+// server, so that a Cancel call from the client can cancel them.
+type runningCalls struct {
+	l       sync.Mutex
+	cancels map[*context.CancelFunc]struct{}
+}
+
+// start returns the context for a new call, along with the function that
+// must be called when the call is over.
+func (c *runningCalls) start() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c.l.Lock()
+	if c.cancels == nil {
+		c.cancels = make(map[*context.CancelFunc]struct{})
+	}
+	c.cancels[&cancel] = struct{}{}
+	c.l.Unlock()
+
+	return ctx, func() {
+		c.l.Lock()
+		delete(c.cancels, &cancel)
+		c.l.Unlock()
+
+		cancel()
+	}
+}
+
+// cancel cancels the calls in flight and reports whether there were any.
+func (c *runningCalls) cancel() bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	for cancel := range c.cancels {
+		(*cancel)()
+	}
+
+	return len(c.cancels) > 0
+}
+
+// cancelOnDone calls cancel once ctx is done, unless the returned stop
+// function is called first. Clients use it to turn a cancelled context into
+// a Cancel call on the server.
+func cancelOnDone(ctx context.Context, cancel func()) (stop func()) {
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-stopCh:
+		}
+	}()
+
+	return func() {
+		close(stopCh)
+	}
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"log"
 	"net/rpc"
 
@@ -17,8 +18,9 @@ type hook struct {
 // HookServer wraps a packer.Hook implementation and makes it exportable
 // as part of a Golang RPC server.
 type HookServer struct {
-	hook packer.Hook
-	mux  *muxBroker
+	hook  packer.Hook
+	mux   *muxBroker
+	calls runningCalls
 }
 
 type HookRunArgs struct {
@@ -28,6 +30,10 @@ type HookRunArgs struct {
 }
 
 func (h *hook) Run(name string, ui packer.Ui, comm packer.Communicator, data interface{}) error {
+	return h.RunContext(context.Background(), name, ui, comm, data)
+}
+
+func (h *hook) RunContext(ctx context.Context, name string, ui packer.Ui, comm packer.Communicator, data interface{}) error {
 	nextId := h.mux.NextId()
 	server := newServerWithMux(h.mux, nextId)
 	server.RegisterCommunicator(comm)
@@ -40,6 +46,9 @@ func (h *hook) Run(name string, ui packer.Ui, comm packer.Communicator, data int
 		StreamId: nextId,
 	}
 
+	stop := cancelOnDone(ctx, h.Cancel)
+	defer stop()
+
 	return h.client.Call("Hook.Run", &args, new(interface{}))
 }
 
@@ -57,7 +66,10 @@ func (h *HookServer) Run(args *HookRunArgs, reply *interface{}) error {
 	}
 	defer client.Close()
 
-	if err := h.hook.Run(args.Name, client.Ui(), client.Communicator(), args.Data); err != nil {
+	ctx, done := h.calls.start()
+	defer done()
+
+	if err := packer.RunHook(ctx, h.hook, args.Name, client.Ui(), client.Communicator(), args.Data); err != nil {
 		return NewBasicError(err)
 	}
 
@@ -66,6 +78,8 @@ func (h *HookServer) Run(args *HookRunArgs, reply *interface{}) error {
 }
 
 func (h *HookServer) Cancel(args *interface{}, reply *interface{}) error {
-	h.hook.Cancel()
+	if !h.calls.cancel() {
+		h.hook.Cancel()
+	}
 	return nil
 }
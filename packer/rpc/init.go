@@ -3,7 +3,11 @@ package rpc
 import "encoding/gob"
 
 func init() {
-	gob.Register(new(map[string]interface{}))
+	// go-cty, which the HCL template parser links in, registers
+	// map[string]interface{} by value as well. gob keeps a single name per
+	// type, so registering the pointer here would panic whenever both are
+	// in the same binary.
+	gob.Register(map[string]interface{}(nil))
 	gob.Register(new(map[string]string))
 	gob.Register(make([]interface{}, 0))
 	gob.Register(new(BasicError))
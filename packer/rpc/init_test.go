@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+func TestInit_HCLTemplate(t *testing.T) {
+	tpl, err := template.ParseHCL(strings.NewReader(`
+source "null" "example" {
+  communicator = "none"
+  tags         = { name = "example" }
+}
+
+build {
+  sources = [source.null.example]
+}
+`), "example.pkr.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	type wrapper struct {
+		Config interface{}
+	}
+	config := tpl.Builders["null.example"].Config
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&wrapper{Config: config}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var actual wrapper
+	if err := gob.NewDecoder(&buf).Decode(&actual); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(actual.Config, config) {
+		t.Fatalf("bad: %#v != %#v", actual.Config, config)
+	}
+}
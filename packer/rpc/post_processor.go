@@ -1,6 +1,8 @@
 package rpc
 
 import (
+	"context"
+	"log"
 	"net/rpc"
 
 	"github.com/hashicorp/packer/packer"
@@ -16,8 +18,9 @@ type postProcessor struct {
 // PostProcessorServer wraps a packer.PostProcessor implementation and makes it
 // exportable as part of a Golang RPC server.
 type PostProcessorServer struct {
-	mux *muxBroker
-	p   packer.PostProcessor
+	mux   *muxBroker
+	p     packer.PostProcessor
+	calls runningCalls
 }
 
 type PostProcessorConfigureArgs struct {
@@ -40,12 +43,19 @@ func (p *postProcessor) Configure(raw ...interface{}) (err error) {
 }
 
 func (p *postProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.Artifact, bool, error) {
+	return p.PostProcessContext(context.Background(), ui, a)
+}
+
+func (p *postProcessor) PostProcessContext(ctx context.Context, ui packer.Ui, a packer.Artifact) (packer.Artifact, bool, error) {
 	nextId := p.mux.NextId()
 	server := newServerWithMux(p.mux, nextId)
 	server.RegisterArtifact(a)
 	server.RegisterUi(ui)
 	go server.Serve()
 
+	stop := cancelOnDone(ctx, p.cancel)
+	defer stop()
+
 	var response PostProcessorProcessResponse
 	if err := p.client.Call("PostProcessor.PostProcess", nextId, &response); err != nil {
 		return nil, false, err
@@ -67,6 +77,13 @@ func (p *postProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.Art
 	return client.Artifact(), response.Keep, nil
 }
 
+func (p *postProcessor) cancel() {
+	err := p.client.Call("PostProcessor.Cancel", new(interface{}), new(interface{}))
+	if err != nil {
+		log.Printf("PostProcessor.Cancel err: %s", err)
+	}
+}
+
 func (p *PostProcessorServer) Configure(args *PostProcessorConfigureArgs, reply *interface{}) error {
 	err := p.p.Configure(args.Configs...)
 	return err
@@ -80,7 +97,10 @@ func (p *PostProcessorServer) PostProcess(streamId uint32, reply *PostProcessorP
 	defer client.Close()
 
 	streamId = 0
-	artifactResult, keep, err := p.p.PostProcess(client.Ui(), client.Artifact())
+	ctx, done := p.calls.start()
+	defer done()
+
+	artifactResult, keep, err := packer.RunPostProcessor(ctx, p.p, client.Ui(), client.Artifact())
 	if err == nil && artifactResult != nil {
 		streamId = p.mux.NextId()
 		server := newServerWithMux(p.mux, streamId)
@@ -96,3 +116,8 @@ func (p *PostProcessorServer) PostProcess(streamId uint32, reply *PostProcessorP
 
 	return nil
 }
+
+func (p *PostProcessorServer) Cancel(args *interface{}, reply *interface{}) error {
+	p.calls.cancel()
+	return nil
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"context"
 	"log"
 	"net/rpc"
 
@@ -17,8 +18,9 @@ type provisioner struct {
 // ProvisionerServer wraps a packer.Provisioner implementation and makes it
 // exportable as part of a Golang RPC server.
 type ProvisionerServer struct {
-	p   packer.Provisioner
-	mux *muxBroker
+	p     packer.Provisioner
+	mux   *muxBroker
+	calls runningCalls
 }
 
 type ProvisionerPrepareArgs struct {
@@ -35,12 +37,19 @@ func (p *provisioner) Prepare(configs ...interface{}) (err error) {
 }
 
 func (p *provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	return p.ProvisionContext(context.Background(), ui, comm)
+}
+
+func (p *provisioner) ProvisionContext(ctx context.Context, ui packer.Ui, comm packer.Communicator) error {
 	nextId := p.mux.NextId()
 	server := newServerWithMux(p.mux, nextId)
 	server.RegisterCommunicator(comm)
 	server.RegisterUi(ui)
 	go server.Serve()
 
+	stop := cancelOnDone(ctx, p.Cancel)
+	defer stop()
+
 	return p.client.Call("Provisioner.Provision", nextId, new(interface{}))
 }
 
@@ -62,7 +71,10 @@ func (p *ProvisionerServer) Provision(streamId uint32, reply *interface{}) error
 	}
 	defer client.Close()
 
-	if err := p.p.Provision(client.Ui(), client.Communicator()); err != nil {
+	ctx, done := p.calls.start()
+	defer done()
+
+	if err := packer.RunProvisioner(ctx, p.p, client.Ui(), client.Communicator()); err != nil {
 		return NewBasicError(err)
 	}
 
@@ -70,6 +82,8 @@ func (p *ProvisionerServer) Provision(streamId uint32, reply *interface{}) error
 }
 
 func (p *ProvisionerServer) Cancel(args *interface{}, reply *interface{}) error {
-	p.p.Cancel()
+	if !p.calls.cancel() {
+		p.p.Cancel()
+	}
 	return nil
 }
@@ -0,0 +1,51 @@
+package packer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = []string{"B", "KB", "MB", "GB", "TB"}
+
+// ParseSize parses a size in bytes with an optional unit, such as "20GB".
+// Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		unit := sizeUnits[i]
+		if strings.HasSuffix(value, unit) {
+			value = strings.TrimSuffix(value, unit)
+			multiplier = int64(1) << (10 * uint(i))
+			break
+		}
+		// Allow the B to be left out, as in "20G"
+		if i > 0 && strings.HasSuffix(value, unit[:1]) {
+			value = strings.TrimSuffix(value, unit[:1])
+			multiplier = int64(1) << (10 * uint(i))
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size, such as 500MB or 20GB", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize formats a size in bytes in the largest unit that keeps it at
+// least 1.
+func FormatSize(size int64) string {
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(sizeUnits)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", value, sizeUnits[i])
+}
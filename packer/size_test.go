@@ -0,0 +1,46 @@
+package packer
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output int64
+		Err    bool
+	}{
+		{"100", 100, false},
+		{"100B", 100, false},
+		{"2KB", 2048, false},
+		{"500MB", 500 << 20, false},
+		{"20GB", 20 << 30, false},
+		{"20g", 20 << 30, false},
+		{"1.5TB", 3 << 39, false},
+		{"-1GB", 0, true},
+		{"big", 0, true},
+	}
+
+	for _, tc := range cases {
+		size, err := ParseSize(tc.Input)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %v", tc.Input, err)
+		}
+		if size != tc.Output {
+			t.Fatalf("%s: bad: %d", tc.Input, size)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int64]string{
+		10:         "10 B",
+		2048:       "2.0 KB",
+		5 << 30:    "5.0 GB",
+		1536 << 20: "1.5 GB",
+	}
+
+	for size, expected := range cases {
+		if actual := FormatSize(size); actual != expected {
+			t.Fatalf("%d: bad: %s", size, actual)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package packer
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeoutError is the error returned when a build or a provisioner ran for
+// longer than its configured timeout and was cancelled.
+type TimeoutError struct {
+	// Name describes what timed out, such as "Build 'foo'" or
+	// "Provisioner 'shell'".
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Name, e.Timeout)
+}
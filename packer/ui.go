@@ -2,6 +2,7 @@ package packer
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -139,14 +140,29 @@ func (u *TargetedUI) Ask(query string) (string, error) {
 }
 
 func (u *TargetedUI) Say(message string) {
+	if eu, ok := u.Ui.(EventUi); ok {
+		eu.Event(&Event{Type: EventSay, Build: u.Target, Message: message})
+		return
+	}
+
 	u.Ui.Say(u.prefixLines(true, message))
 }
 
 func (u *TargetedUI) Message(message string) {
+	if eu, ok := u.Ui.(EventUi); ok {
+		eu.Event(&Event{Type: EventMessage, Build: u.Target, Message: message})
+		return
+	}
+
 	u.Ui.Message(u.prefixLines(false, message))
 }
 
 func (u *TargetedUI) Error(message string) {
+	if eu, ok := u.Ui.(EventUi); ok {
+		eu.Event(&Event{Type: EventError, Severity: SeverityError, Build: u.Target, Message: message})
+		return
+	}
+
 	u.Ui.Error(u.prefixLines(true, message))
 }
 
@@ -329,6 +345,167 @@ func (u *MachineReadableUi) Machine(category string, args ...string) {
 	}
 }
 
+// An EventUi is a Ui that outputs every call as a structured Event. Uis
+// that add the build to the output, like the TargetedUI, pass it to Event
+// instead.
+type EventUi interface {
+	Ui
+
+	// Event outputs an event. The version, timestamp, severity and
+	// component are filled in if they aren't set.
+	Event(*Event)
+}
+
+// JSONUi is a UI that outputs one JSON encoded Event per line. Machine calls
+// with a category of an event type become events of that type, which also
+// tell the UI which component of a build later events come from.
+type JSONUi struct {
+	Writer io.Writer
+	NoopProgressTracker
+
+	l          sync.Mutex
+	components map[string]jsonUiComponent
+}
+
+var _ EventUi = new(JSONUi)
+
+// jsonUiComponent is the component of a build that is running.
+type jsonUiComponent struct {
+	builderType   string
+	component     string
+	componentType string
+}
+
+func (u *JSONUi) Ask(query string) (string, error) {
+	return "", errors.New("JSON UI can't ask")
+}
+
+func (u *JSONUi) Say(message string) {
+	u.Event(&Event{Type: EventSay, Message: message})
+}
+
+func (u *JSONUi) Message(message string) {
+	u.Event(&Event{Type: EventMessage, Message: message})
+}
+
+func (u *JSONUi) Error(message string) {
+	u.Event(&Event{Type: EventError, Severity: SeverityError, Message: message})
+}
+
+func (u *JSONUi) Machine(category string, args ...string) {
+	e := &Event{Type: category}
+
+	// Determine if we have a target, and set it
+	commaIdx := strings.Index(category, ",")
+	if commaIdx > -1 {
+		e.Build = category[0:commaIdx]
+		e.Type = category[commaIdx+1:]
+	}
+
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	switch e.Type {
+	case EventBuildStart:
+		e.Component = ComponentBuilder
+		e.ComponentType = arg(0)
+		u.setComponent(e.Build, jsonUiComponent{builderType: arg(0)})
+	case EventStepStart, EventStepEnd:
+		e.Step = arg(0)
+		e.Action = arg(1)
+		if e.Action == "halt" {
+			e.Severity = SeverityError
+		}
+	case EventProvisionerStart, EventProvisionerEnd:
+		e.Component = ComponentProvisioner
+		e.ComponentType = arg(0)
+	case EventPostProcessorStart, EventPostProcessorEnd:
+		e.Component = ComponentPostProcessor
+		e.ComponentType = arg(0)
+	default:
+		e.Category = e.Type
+		e.Type = EventMachine
+		e.Args = args
+	}
+
+	switch e.Type {
+	case EventProvisionerStart, EventPostProcessorStart:
+		u.setComponent(e.Build, jsonUiComponent{
+			component:     e.Component,
+			componentType: e.ComponentType,
+		})
+	case EventProvisionerEnd, EventPostProcessorEnd:
+		if e.Error = arg(1); e.Error != "" {
+			e.Severity = SeverityError
+		}
+		u.setComponent(e.Build, jsonUiComponent{})
+	}
+
+	u.Event(e)
+}
+
+func (u *JSONUi) Event(e *Event) {
+	e.Version = EventVersion
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.Severity == "" {
+		e.Severity = SeverityInfo
+	}
+
+	u.l.Lock()
+	defer u.l.Unlock()
+
+	// Events of a build without a component come from what it is running
+	if c, ok := u.components[e.Build]; ok && e.Component == "" {
+		e.Component = ComponentBuilder
+		e.ComponentType = c.builderType
+		if c.component != "" {
+			e.Component = c.component
+			e.ComponentType = c.componentType
+		}
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		panic(err)
+	}
+	data = append(data, '\n')
+
+	if _, err := u.Writer.Write(data); err != nil {
+		if err == syscall.EPIPE || strings.Contains(err.Error(), "broken pipe") {
+			// Ignore epipe errors because that just means that the file
+			// is probably closed or going to /dev/null or something.
+		} else {
+			panic(err)
+		}
+	}
+}
+
+// setComponent sets the component that a build is running. A build-start
+// event sets the type of the builder, and the others set the provisioner or
+// post-processor that runs until it ends.
+func (u *JSONUi) setComponent(build string, c jsonUiComponent) {
+	if build == "" {
+		return
+	}
+
+	u.l.Lock()
+	defer u.l.Unlock()
+
+	if u.components == nil {
+		u.components = make(map[string]jsonUiComponent)
+	}
+	if c.builderType == "" {
+		c.builderType = u.components[build].builderType
+	}
+	u.components[build] = c
+}
+
 // TimestampedUi is a UI that wraps another UI implementation and
 // prefixes each message with an RFC3339 timestamp
 type TimestampedUi struct {
@@ -2,9 +2,12 @@ package packer
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 // This reads the output from the bytes.Buffer in our test object
@@ -296,3 +299,71 @@ func TestMachineReadableUi(t *testing.T) {
 		t.Fatalf("bad: %#v", data)
 	}
 }
+
+func TestJSONUi_ImplEventUi(t *testing.T) {
+	var raw interface{}
+	raw = &JSONUi{}
+	if _, ok := raw.(EventUi); !ok {
+		t.Fatalf("JSONUi must implement EventUi")
+	}
+}
+
+func TestJSONUi(t *testing.T) {
+	buf := new(bytes.Buffer)
+	ui := &JSONUi{Writer: buf}
+	buildUi := &TargetedUI{Target: "app", Ui: ui}
+
+	ui.Say("starting")
+	buildUi.Machine(EventBuildStart, "qemu")
+	buildUi.Say("hello")
+	buildUi.Machine(EventStepStart, "StepCreateVM")
+	buildUi.Machine(EventStepEnd, "StepCreateVM", "halt")
+	buildUi.Machine(EventProvisionerStart, "shell")
+	buildUi.Error("oops")
+	buildUi.Machine(EventProvisionerEnd, "shell", "exit status 1")
+	buildUi.Machine("artifact", "0", "id", "foo")
+	buildUi.Message("done")
+
+	expected := []Event{
+		{Type: EventSay, Severity: SeverityInfo, Message: "starting"},
+		{Type: EventBuildStart, Severity: SeverityInfo, Build: "app",
+			Component: ComponentBuilder, ComponentType: "qemu"},
+		{Type: EventSay, Severity: SeverityInfo, Build: "app",
+			Component: ComponentBuilder, ComponentType: "qemu", Message: "hello"},
+		{Type: EventStepStart, Severity: SeverityInfo, Build: "app",
+			Component: ComponentBuilder, ComponentType: "qemu", Step: "StepCreateVM"},
+		{Type: EventStepEnd, Severity: SeverityError, Build: "app",
+			Component: ComponentBuilder, ComponentType: "qemu", Step: "StepCreateVM", Action: "halt"},
+		{Type: EventProvisionerStart, Severity: SeverityInfo, Build: "app",
+			Component: ComponentProvisioner, ComponentType: "shell"},
+		{Type: EventError, Severity: SeverityError, Build: "app",
+			Component: ComponentProvisioner, ComponentType: "shell", Message: "oops"},
+		{Type: EventProvisionerEnd, Severity: SeverityError, Build: "app",
+			Component: ComponentProvisioner, ComponentType: "shell", Error: "exit status 1"},
+		{Type: EventMachine, Severity: SeverityInfo, Build: "app",
+			Component: ComponentBuilder, ComponentType: "qemu",
+			Category: "artifact", Args: []string{"0", "id", "foo"}},
+		{Type: EventMessage, Severity: SeverityInfo, Build: "app",
+			Component: ComponentBuilder, ComponentType: "qemu", Message: "done"},
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("bad: %s", buf.String())
+	}
+	for i, line := range lines {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+		if e.Version != EventVersion || e.Timestamp.IsZero() {
+			t.Fatalf("%d: bad: %s", i, line)
+		}
+
+		e.Version = 0
+		e.Timestamp = time.Time{}
+		if !reflect.DeepEqual(e, expected[i]) {
+			t.Fatalf("%d: bad: %#v\n\nexpected: %#v", i, e, expected[i])
+		}
+	}
+}
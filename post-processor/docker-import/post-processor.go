@@ -59,7 +59,8 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		importRepo += ":" + p.config.Tag
 	}
 
-	driver := &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+	driverName := docker.ArtifactDriverName(artifact)
+	driver := docker.NewDriver(driverName, &p.config.ctx, ui)
 
 	ui.Message("Importing image: " + artifact.Id())
 	ui.Message("Repository: " + importRepo)
@@ -74,6 +75,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	artifact = &docker.ImportArtifact{
 		BuilderIdValue: BuilderId,
 		Driver:         driver,
+		StateData:      map[string]interface{}{"driver": driverName},
 		IdValue:        importRepo,
 	}
 
@@ -63,7 +63,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+		driver = docker.NewDriver(docker.ArtifactDriverName(artifact), &p.config.ctx, ui)
 	}
 
 	if p.config.EcrLogin {
@@ -108,6 +108,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	artifact = &docker.ImportArtifact{
 		BuilderIdValue: BuilderIdImport,
 		Driver:         driver,
+		StateData:      map[string]interface{}{"driver": docker.ArtifactDriverName(artifact)},
 		IdValue:        name,
 	}
 
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/hashicorp/packer/builder/docker"
+	"github.com/hashicorp/packer/builder/oci"
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
@@ -47,9 +48,10 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 
 func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
 	if artifact.BuilderId() != dockerimport.BuilderId &&
-		artifact.BuilderId() != dockertag.BuilderId {
+		artifact.BuilderId() != dockertag.BuilderId &&
+		artifact.BuilderId() != oci.BuilderId {
 		err := fmt.Errorf(
-			"Unknown artifact type: %s\nCan only save Docker builder artifacts.",
+			"Unknown artifact type: %s\nCan only save Docker builder and OCI builder artifacts.",
 			artifact.BuilderId())
 		return nil, false, err
 	}
@@ -63,15 +65,21 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		return nil, false, err
 	}
 
-	driver := p.Driver
-	if driver == nil {
-		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+	if artifact.BuilderId() == oci.BuilderId {
+		// The image is already on disk, no container engine is needed
+		ui.Message("Saving OCI image layout: " + artifact.Id())
+		err = oci.WriteArchive(artifact.State("layout").(string), f)
+	} else {
+		driver := p.Driver
+		if driver == nil {
+			// If no driver is set, then we use the real driver
+			driver = docker.NewDriver(docker.ArtifactDriverName(artifact), &p.config.ctx, ui)
+		}
+
+		ui.Message("Saving image: " + artifact.Id())
+		err = driver.SaveImage(artifact.Id(), f)
 	}
-
-	ui.Message("Saving image: " + artifact.Id())
-
-	if err := driver.SaveImage(artifact.Id(), f); err != nil {
+	if err != nil {
 		f.Close()
 		os.Remove(f.Name())
 
@@ -1,9 +1,16 @@
 package dockersave
 
 import (
+	"archive/tar"
 	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/packer/builder/oci"
 	"github.com/hashicorp/packer/packer"
 )
 
@@ -30,3 +37,55 @@ func testUi() *packer.BasicUi {
 func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
 	var _ packer.PostProcessor = new(PostProcessor)
 }
+
+func TestPostProcessor_PostProcess_oci(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	layoutDir := filepath.Join(dir, "layout")
+	os.MkdirAll(filepath.Join(layoutDir, "blobs", "sha256"), 0755)
+	ioutil.WriteFile(filepath.Join(layoutDir, "index.json"), []byte("{}"), 0644)
+	ioutil.WriteFile(filepath.Join(layoutDir, "oci-layout"), []byte("{}"), 0644)
+
+	p := &PostProcessor{}
+	path := filepath.Join(dir, "image.tar")
+	if err := p.Configure(map[string]interface{}{"path": path}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// No container engine is needed to save an OCI image layout
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: oci.BuilderId,
+		IdValue:        "sha256:1234",
+		StateValues:    map[string]interface{}{"layout": layoutDir},
+	}
+	if _, _, err := p.PostProcess(testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	expected := "[blobs/ blobs/sha256/ index.json oci-layout]"
+	if actual := fmt.Sprint(names); actual != expected {
+		t.Fatalf("bad: %s", actual)
+	}
+}
@@ -57,7 +57,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	driver := p.Driver
 	if driver == nil {
 		// If no driver is set, then we use the real driver
-		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
+		driver = docker.NewDriver(docker.ArtifactDriverName(artifact), &p.config.ctx, ui)
 	}
 
 	importRepo := p.config.Repository
@@ -76,6 +76,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	artifact = &docker.ImportArtifact{
 		BuilderIdValue: BuilderId,
 		Driver:         driver,
+		StateData:      map[string]interface{}{"driver": docker.ArtifactDriverName(artifact)},
 		IdValue:        importRepo,
 	}
 
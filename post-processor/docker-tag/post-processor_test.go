@@ -111,3 +111,27 @@ func TestPostProcessor_PostProcess_Force(t *testing.T) {
 		t.Fatal("bad force")
 	}
 }
+
+func TestPostProcessor_PostProcess_Driver(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "1234567890abcdef",
+		StateValues:    map[string]interface{}{"driver": docker.DriverPodman},
+	}
+
+	result, _, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The image is tagged with Podman, so later post-processors use it too
+	if result.State("driver") != docker.DriverPodman {
+		t.Fatalf("bad: %#v", result.State("driver"))
+	}
+}
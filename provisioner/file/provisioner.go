@@ -1,6 +1,7 @@
 package file
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -30,6 +31,9 @@ type Config struct {
 	// False if the sources have to exist.
 	Generated bool
 
+	// Patterns of files to leave out when transferring directories.
+	Exclude []string
+
 	ctx interpolate.Context
 }
 
@@ -90,15 +94,29 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	return p.ProvisionContext(context.Background(), ui, comm)
+}
+
+// ProvisionContext transfers the files, aborting the transfer of a file and
+// skipping the rest once ctx is done.
+func (p *Provisioner) ProvisionContext(ctx context.Context, ui packer.Ui, comm packer.Communicator) error {
 	if p.config.Direction == "download" {
-		return p.ProvisionDownload(ui, comm)
+		return p.provisionDownload(ctx, ui, comm)
 	} else {
-		return p.ProvisionUpload(ui, comm)
+		return p.provisionUpload(ctx, ui, comm)
 	}
 }
 
 func (p *Provisioner) ProvisionDownload(ui packer.Ui, comm packer.Communicator) error {
+	return p.provisionDownload(context.Background(), ui, comm)
+}
+
+func (p *Provisioner) provisionDownload(ctx context.Context, ui packer.Ui, comm packer.Communicator) error {
 	for _, src := range p.config.Sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		dst := p.config.Destination
 		ui.Say(fmt.Sprintf("Downloading %s => %s", src, dst))
 		// ensure destination dir exists.  p.config.Destination may either be a file or a dir.
@@ -117,7 +135,7 @@ func (p *Provisioner) ProvisionDownload(ui packer.Ui, comm packer.Communicator)
 		}
 		// if the src was a dir, download the dir
 		if strings.HasSuffix(src, "/") || strings.ContainsAny(src, "*?[") {
-			return comm.DownloadDir(src, dst, nil)
+			return comm.DownloadDir(src, dst, p.config.Exclude)
 		}
 
 		f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -127,7 +145,7 @@ func (p *Provisioner) ProvisionDownload(ui packer.Ui, comm packer.Communicator)
 		defer f.Close()
 
 		// Create MultiWriter for the current progress
-		pf := io.MultiWriter(f)
+		pf := &contextWriter{ctx, io.MultiWriter(f)}
 
 		// Download the file
 		if err = comm.Download(src, pf); err != nil {
@@ -139,7 +157,15 @@ func (p *Provisioner) ProvisionDownload(ui packer.Ui, comm packer.Communicator)
 }
 
 func (p *Provisioner) ProvisionUpload(ui packer.Ui, comm packer.Communicator) error {
+	return p.provisionUpload(context.Background(), ui, comm)
+}
+
+func (p *Provisioner) provisionUpload(ctx context.Context, ui packer.Ui, comm packer.Communicator) error {
 	for _, src := range p.config.Sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		dst := p.config.Destination
 
 		ui.Say(fmt.Sprintf("Uploading %s => %s", src, dst))
@@ -151,7 +177,7 @@ func (p *Provisioner) ProvisionUpload(ui packer.Ui, comm packer.Communicator) er
 
 		// If we're uploading a directory, short circuit and do that
 		if info.IsDir() {
-			return comm.UploadDir(p.config.Destination, src, nil)
+			return comm.UploadDir(p.config.Destination, src, p.config.Exclude)
 		}
 
 		// We're uploading a file...
@@ -174,7 +200,7 @@ func (p *Provisioner) ProvisionUpload(ui packer.Ui, comm packer.Communicator) er
 		defer pf.Close()
 
 		// Upload the file
-		if err = comm.Upload(dst, pf, &fi); err != nil {
+		if err = comm.Upload(dst, &contextReader{ctx, pf}, &fi); err != nil {
 			if strings.Contains(err.Error(), "Error restoring file") {
 				ui.Error(fmt.Sprintf("Upload failed: %s; this can occur when "+
 					"your file destination is a folder without a trailing "+
@@ -187,8 +213,37 @@ func (p *Provisioner) ProvisionUpload(ui packer.Ui, comm packer.Communicator) er
 	return nil
 }
 
+// contextReader fails reads once ctx is done, which aborts the upload that
+// reads from it.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// contextWriter fails writes once ctx is done, which aborts the download
+// that writes to it.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
 func (p *Provisioner) Cancel() {
-	// Just hard quit. It isn't a big deal if what we're doing keeps
+	// Only called when the provisioner runs without a context. Just hard
+	// quit. It isn't a big deal if what we're doing keeps
 	// running on the other side.
 	os.Exit(0)
 }
@@ -2,6 +2,7 @@ package file
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -23,6 +24,9 @@ func TestProvisioner_Impl(t *testing.T) {
 	if _, ok := raw.(packer.Provisioner); !ok {
 		t.Fatalf("must be a provisioner")
 	}
+	if _, ok := raw.(packer.ContextProvisioner); !ok {
+		t.Fatalf("must be a context provisioner")
+	}
 }
 
 func TestProvisionerPrepare_InvalidKey(t *testing.T) {
@@ -148,6 +152,38 @@ func TestProvisionerProvision_SendsFile(t *testing.T) {
 	}
 }
 
+func TestProvisionerProvision_cancelled(t *testing.T) {
+	var p Provisioner
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("error tempfile: %s", err)
+	}
+	defer os.Remove(tf.Name())
+
+	config := map[string]interface{}{
+		"source":      tf.Name(),
+		"destination": "something",
+	}
+
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ui := &packer.BasicUi{
+		Writer: new(bytes.Buffer),
+	}
+	comm := &packer.MockCommunicator{}
+	if err := p.ProvisionContext(ctx, ui, comm); err != context.Canceled {
+		t.Fatalf("should be cancelled: %v", err)
+	}
+	if comm.UploadCalled {
+		t.Fatal("should not upload")
+	}
+}
+
 func TestProvisionDownloadMkdirAll(t *testing.T) {
 	tests := []struct {
 		path string
@@ -207,3 +243,33 @@ func TestProvisionDownloadMkdirAll(t *testing.T) {
 		}
 	}
 }
+
+func TestProvisionerProvision_SendsDirExclude(t *testing.T) {
+	var p Provisioner
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("error tempdir: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	config := map[string]interface{}{
+		"source":      td,
+		"destination": "something",
+		"exclude":     []string{"*.log"},
+	}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packer.BasicUi{
+		Writer: bytes.NewBuffer(nil),
+	}
+	comm := &packer.MockCommunicator{}
+	if err := p.Provision(ui, comm); err != nil {
+		t.Fatalf("should successfully provision: %s", err)
+	}
+
+	if len(comm.UploadDirExclude) != 1 || comm.UploadDirExclude[0] != "*.log" {
+		t.Fatalf("bad: %#v", comm.UploadDirExclude)
+	}
+}
@@ -4,6 +4,7 @@ package shell
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -183,6 +184,12 @@ func (p *Provisioner) Prepare(raws ...interface{}) error {
 }
 
 func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	return p.ProvisionContext(context.Background(), ui, comm)
+}
+
+// ProvisionContext runs the scripts, stopping once ctx is done. The script
+// that is running when ctx is done may keep running on the machine.
+func (p *Provisioner) ProvisionContext(ctx context.Context, ui packer.Ui, comm packer.Communicator) error {
 	scripts := make([]string, len(p.config.Scripts))
 	copy(scripts, p.config.Scripts)
 
@@ -236,7 +243,7 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 
 		// upload the var file
 		var cmd *packer.RemoteCmd
-		err = p.retryable(func() error {
+		err = p.retryable(ctx, func() error {
 			if _, err := tf.Seek(0, 0); err != nil {
 				return err
 			}
@@ -296,7 +303,7 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 		// and then the command is executed but the file doesn't exist
 		// any longer.
 		var cmd *packer.RemoteCmd
-		err = p.retryable(func() error {
+		err = p.retryable(ctx, func() error {
 			if _, err := f.Seek(0, 0); err != nil {
 				return err
 			}
@@ -321,7 +328,7 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 			cmd.Wait()
 
 			cmd = &packer.RemoteCmd{Command: command}
-			return cmd.StartWithUi(comm, ui)
+			return cmd.StartWithUiContext(ctx, comm, ui)
 		})
 
 		if err != nil {
@@ -347,11 +354,11 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 			// Delete the temporary file we created. We retry this a few times
 			// since if the above rebooted we have to wait until the reboot
 			// completes.
-			err = p.cleanupRemoteFile(p.config.RemotePath, comm)
+			err = p.cleanupRemoteFile(ctx, p.config.RemotePath, comm)
 			if err != nil {
 				return err
 			}
-			err = p.cleanupRemoteFile(p.config.envVarFile, comm)
+			err = p.cleanupRemoteFile(ctx, p.config.envVarFile, comm)
 			if err != nil {
 				return err
 			}
@@ -363,14 +370,16 @@ func (p *Provisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
 		select {
 		case <-time.After(p.config.PauseAfter):
 			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
 	return nil
 }
 
-func (p *Provisioner) cleanupRemoteFile(path string, comm packer.Communicator) error {
-	err := p.retryable(func() error {
+func (p *Provisioner) cleanupRemoteFile(ctx context.Context, path string, comm packer.Communicator) error {
+	err := p.retryable(ctx, func() error {
 		cmd := &packer.RemoteCmd{
 			Command: fmt.Sprintf("rm -f %s", path),
 		}
@@ -400,16 +409,21 @@ func (p *Provisioner) cleanupRemoteFile(path string, comm packer.Communicator) e
 }
 
 func (p *Provisioner) Cancel() {
-	// Just hard quit. It isn't a big deal if what we're doing keeps
-	// running on the other side.
+	// Only called when the provisioner runs without a context. Just hard
+	// quit. It isn't a big deal if what we're doing keeps running on the
+	// other side.
 	os.Exit(0)
 }
 
 // retryable will retry the given function over and over until a
-// non-error is returned.
-func (p *Provisioner) retryable(f func() error) error {
+// non-error is returned, or ctx is done.
+func (p *Provisioner) retryable(ctx context.Context, f func() error) error {
 	startTimeout := time.After(p.config.startRetryTimeout)
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		var err error
 		if err = f(); err == nil {
 			return nil
@@ -425,8 +439,9 @@ func (p *Provisioner) retryable(f func() error) error {
 		select {
 		case <-startTimeout:
 			return err
-		default:
-			time.Sleep(2 * time.Second)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
 		}
 	}
 }
@@ -1,6 +1,8 @@
 package shell
 
 import (
+	"bytes"
+	"context"
 	"io/ioutil"
 	"os"
 	"regexp"
@@ -22,6 +24,9 @@ func TestProvisioner_Impl(t *testing.T) {
 	if _, ok := raw.(packer.Provisioner); !ok {
 		t.Fatalf("must be a Provisioner")
 	}
+	if _, ok := raw.(packer.ContextProvisioner); !ok {
+		t.Fatalf("must be a ContextProvisioner")
+	}
 }
 
 func TestProvisionerPrepare_Defaults(t *testing.T) {
@@ -468,3 +473,25 @@ func TestProvisionerRemotePathDefaultsSuccessfully(t *testing.T) {
 		t.Fatalf("remote path does not match the expected default regex")
 	}
 }
+
+func TestProvisionerProvision_cancelled(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ui := &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+	comm := new(packer.MockCommunicator)
+	if err := p.ProvisionContext(ctx, ui, comm); err != context.Canceled {
+		t.Fatalf("should be cancelled: %v", err)
+	}
+	if comm.UploadCalled || comm.StartCalled {
+		t.Fatal("should not run the script")
+	}
+}
@@ -28,6 +28,7 @@ func init() {
 
 // Funcs are the interpolation funcs that are available within interpolations.
 var FuncGens = map[string]FuncGenerator{
+	"artifact":       funcGenArtifact,
 	"build_name":     funcGenBuildName,
 	"build_type":     funcGenBuildType,
 	"env":            funcGenEnv,
@@ -78,6 +79,26 @@ func funcGenSplitter(ctx *Context) interface{} {
 	}
 }
 
+func funcGenArtifact(ctx *Context) interface{} {
+	return func(build, field string) (string, error) {
+		if ctx == nil || ctx.BuildArtifacts == nil {
+			return "", errors.New("artifact not available")
+		}
+
+		fields, ok := ctx.BuildArtifacts[build]
+		if !ok {
+			return "", fmt.Errorf("no artifact of build '%s', it must be in depends_on", build)
+		}
+
+		val, ok := fields[field]
+		if !ok {
+			return "", fmt.Errorf("artifact of build '%s' has no %s", build, field)
+		}
+
+		return val, nil
+	}
+}
+
 func funcGenBuildName(ctx *Context) interface{} {
 	return func() (string, error) {
 		if ctx == nil || ctx.BuildName == "" {
@@ -11,6 +11,58 @@ import (
 	"github.com/hashicorp/packer/version"
 )
 
+func TestFuncArtifact(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output string
+		Error  bool
+	}{
+		{
+			"{{artifact `base` `id`}}",
+			"ami-123",
+			false,
+		},
+
+		{
+			"{{artifact `base` `state.region`}}",
+			"us-east-1",
+			false,
+		},
+
+		{
+			"{{artifact `base` `files`}}",
+			"",
+			true,
+		},
+
+		{
+			"{{artifact `app` `id`}}",
+			"",
+			true,
+		},
+	}
+
+	ctx := &Context{
+		BuildArtifacts: map[string]map[string]string{
+			"base": {
+				"id":           "ami-123",
+				"state.region": "us-east-1",
+			},
+		},
+	}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err != nil) != tc.Error {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+}
+
 func TestFuncBuildName(t *testing.T) {
 	cases := []struct {
 		Input  string
@@ -21,6 +21,12 @@ type Context struct {
 	// SensitiveVariables is a list of variables to sanitize.
 	SensitiveVariables []string
 
+	// BuildArtifacts is the mapping of the artifacts of the builds this
+	// build depends on that the "artifact" function reads from. It maps
+	// the name of a build to the fields of its artifact, such as "id",
+	// "files" or "state.KEY".
+	BuildArtifacts map[string]map[string]string
+
 	// EnableEnv enables the env function
 	EnableEnv bool
 
@@ -40,3 +40,66 @@ func functionsCalledWalk(raw parse.Node, r map[string]struct{}) {
 		panic(fmt.Sprintf("unknown type: %T", node))
 	}
 }
+
+// ArtifactCall is a call to the "artifact" function with a build and a
+// field that are written as strings, such as {{artifact `base` `id`}}.
+type ArtifactCall struct {
+	Build string
+	Field string
+}
+
+// ArtifactCalls parses v and returns the calls to the "artifact" function
+// in it, in the order they are made.
+func ArtifactCalls(v string) ([]ArtifactCall, error) {
+	tpl, err := (&I{Value: v}).template(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ArtifactCall
+	artifactCallsWalk(tpl.Tree.Root, &result)
+	return result, nil
+}
+
+func artifactCallsWalk(raw parse.Node, r *[]ArtifactCall) {
+	switch node := raw.(type) {
+	case *parse.ActionNode:
+		artifactCallsWalk(node.Pipe, r)
+	case *parse.CommandNode:
+		if in, ok := node.Args[0].(*parse.IdentifierNode); ok && in.Ident == "artifact" && len(node.Args) == 3 {
+			build, bok := node.Args[1].(*parse.StringNode)
+			field, fok := node.Args[2].(*parse.StringNode)
+			if bok && fok {
+				*r = append(*r, ArtifactCall{Build: build.Text, Field: field.Text})
+			}
+		}
+
+		for _, n := range node.Args[1:] {
+			artifactCallsWalk(n, r)
+		}
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			artifactCallsWalk(n, r)
+		}
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Cmds {
+			artifactCallsWalk(n, r)
+		}
+	case *parse.IfNode:
+		artifactCallsWalk(&node.BranchNode, r)
+	case *parse.RangeNode:
+		artifactCallsWalk(&node.BranchNode, r)
+	case *parse.WithNode:
+		artifactCallsWalk(&node.BranchNode, r)
+	case *parse.BranchNode:
+		artifactCallsWalk(node.Pipe, r)
+		artifactCallsWalk(node.List, r)
+		artifactCallsWalk(node.ElseList, r)
+	}
+}
@@ -37,3 +37,46 @@ func TestFunctionsCalled(t *testing.T) {
 		}
 	}
 }
+
+func TestArtifactCalls(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Result []ArtifactCall
+	}{
+		{
+			"foo {{user `bar`}}",
+			nil,
+		},
+
+		{
+			"{{ artifact `base` `id` }}-{{artifact \"base\" \"state.disks\" | upper}}",
+			[]ArtifactCall{
+				{Build: "base", Field: "id"},
+				{Build: "base", Field: "state.disks"},
+			},
+		},
+
+		{
+			"{{if true}}{{ upper (artifact `db` `state.region`) }}{{end}}",
+			[]ArtifactCall{
+				{Build: "db", Field: "state.region"},
+			},
+		},
+
+		{
+			// The comment doesn't call the function
+			"{{/* artifact `base` `state.x` */}}",
+			nil,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := ArtifactCalls(tc.Input)
+		if err != nil {
+			t.Fatalf("err: %s: %s", tc.Input, err)
+		}
+		if !reflect.DeepEqual(actual, tc.Result) {
+			t.Fatalf("bad: %v\n\ngot: %#v", tc.Input, actual)
+		}
+	}
+}
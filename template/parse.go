@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -84,14 +85,8 @@ func (r *rawTemplate) Template() (*Template, error) {
 	}
 
 	for k, rawV := range r.Variables {
-		var v Variable
-		v.Key = k
-
-		// Variable is required if the value is exactly nil
-		v.Required = rawV == nil
-
-		// Weak decode the default if we have one
-		if err := r.decoder(&v.Default, nil).Decode(rawV); err != nil {
+		v, err := r.decodeVariable(k, rawV)
+		if err != nil {
 			errs = multierror.Append(errs, fmt.Errorf(
 				"variable %s: %s", k, err))
 			continue
@@ -99,11 +94,11 @@ func (r *rawTemplate) Template() (*Template, error) {
 
 		for _, sVar := range r.SensitiveVariables {
 			if sVar == k {
-				result.SensitiveVariables = append(result.SensitiveVariables, &v)
+				result.SensitiveVariables = append(result.SensitiveVariables, v)
 			}
 		}
 
-		result.Variables[k] = &v
+		result.Variables[k] = v
 	}
 
 	// Let's start by gathering all the builders
@@ -111,35 +106,13 @@ func (r *rawTemplate) Template() (*Template, error) {
 		result.Builders = make(map[string]*Builder, len(r.Builders))
 	}
 	for i, rawB := range r.Builders {
-		var b Builder
-		if err := mapstructure.WeakDecode(rawB, &b); err != nil {
+		b, err := r.decodeBuilder(rawB)
+		if err != nil {
 			errs = multierror.Append(errs, fmt.Errorf(
 				"builder %d: %s", i+1, err))
 			continue
 		}
 
-		// Set the raw configuration and delete any special keys
-		b.Config = rawB.(map[string]interface{})
-
-		delete(b.Config, "name")
-		delete(b.Config, "type")
-
-		if len(b.Config) == 0 {
-			b.Config = nil
-		}
-
-		// If there is no type set, it is an error
-		if b.Type == "" {
-			errs = multierror.Append(errs, fmt.Errorf(
-				"builder %d: missing 'type'", i+1))
-			continue
-		}
-
-		// The name defaults to the type if it isn't set
-		if b.Name == "" {
-			b.Name = b.Type
-		}
-
 		// If this builder already exists, it is an error
 		if _, ok := result.Builders[b.Name]; ok {
 			errs = multierror.Append(errs, fmt.Errorf(
@@ -149,7 +122,7 @@ func (r *rawTemplate) Template() (*Template, error) {
 		}
 
 		// Append the builders
-		result.Builders[b.Name] = &b
+		result.Builders[b.Name] = b
 	}
 
 	// Gather all the post-processors
@@ -168,39 +141,14 @@ func (r *rawTemplate) Template() (*Template, error) {
 		// Parse the PostProcessors out of the configs
 		pps := make([]*PostProcessor, 0, len(configs))
 		for j, c := range configs {
-			var pp PostProcessor
-			if err := r.decoder(&pp, nil).Decode(c); err != nil {
+			pp, err := r.decodePostProcessor(c)
+			if err != nil {
 				errs = multierror.Append(errs, fmt.Errorf(
 					"post-processor %d.%d: %s", i+1, j+1, err))
 				continue
 			}
 
-			// Type is required
-			if pp.Type == "" {
-				errs = multierror.Append(errs, fmt.Errorf(
-					"post-processor %d.%d: type is required", i+1, j+1))
-				continue
-			}
-
-			// Set the raw configuration and delete any special keys
-			pp.Config = c
-
-			// The name defaults to the type if it isn't set
-			if pp.Name == "" {
-				pp.Name = pp.Type
-			}
-
-			delete(pp.Config, "except")
-			delete(pp.Config, "only")
-			delete(pp.Config, "keep_input_artifact")
-			delete(pp.Config, "type")
-			delete(pp.Config, "name")
-
-			if len(pp.Config) == 0 {
-				pp.Config = nil
-			}
-
-			pps = append(pps, &pp)
+			pps = append(pps, pp)
 		}
 
 		result.PostProcessors = append(result.PostProcessors, pps)
@@ -211,34 +159,14 @@ func (r *rawTemplate) Template() (*Template, error) {
 		result.Provisioners = make([]*Provisioner, 0, len(r.Provisioners))
 	}
 	for i, v := range r.Provisioners {
-		var p Provisioner
-		if err := r.decoder(&p, nil).Decode(v); err != nil {
+		p, err := r.decodeProvisioner(v)
+		if err != nil {
 			errs = multierror.Append(errs, fmt.Errorf(
 				"provisioner %d: %s", i+1, err))
 			continue
 		}
 
-		// Type is required before any richer validation
-		if p.Type == "" {
-			errs = multierror.Append(errs, fmt.Errorf(
-				"provisioner %d: missing 'type'", i+1))
-			continue
-		}
-
-		// Set the raw configuration and delete any special keys
-		p.Config = v.(map[string]interface{})
-
-		delete(p.Config, "except")
-		delete(p.Config, "only")
-		delete(p.Config, "override")
-		delete(p.Config, "pause_before")
-		delete(p.Config, "type")
-
-		if len(p.Config) == 0 {
-			p.Config = nil
-		}
-
-		result.Provisioners = append(result.Provisioners, &p)
+		result.Provisioners = append(result.Provisioners, p)
 	}
 
 	// Push
@@ -260,6 +188,164 @@ func (r *rawTemplate) Template() (*Template, error) {
 	return &result, nil
 }
 
+// decodeVariable decodes a variable, which is either its default or an
+// object that also sets its type and rules.
+func (r *rawTemplate) decodeVariable(k string, raw interface{}) (*Variable, error) {
+	v := &Variable{Key: k}
+
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		// Variable is required if the value is exactly nil
+		v.Required = raw == nil
+
+		// Weak decode the default if we have one
+		if err := r.decoder(&v.Default, nil).Decode(raw); err != nil {
+			return nil, err
+		}
+
+		return v, nil
+	}
+
+	var rv struct {
+		Type          string
+		Description   string
+		Default       interface{}
+		AllowedValues []string `mapstructure:"allowed_values"`
+		Pattern       string
+	}
+	var md mapstructure.Metadata
+	if err := r.weakDecoder(&rv, &md).Decode(m); err != nil {
+		return nil, err
+	}
+	if len(md.Unused) > 0 {
+		sort.Strings(md.Unused)
+		return nil, fmt.Errorf("unknown keys: %s", strings.Join(md.Unused, ", "))
+	}
+
+	v.Type = rv.Type
+	v.Description = rv.Description
+	v.AllowedValues = rv.AllowedValues
+	v.Pattern = rv.Pattern
+
+	// Lists and maps are kept as JSON, anything else is weakly decoded into
+	// a string. A variable without a default is required.
+	switch def := rv.Default.(type) {
+	case nil:
+		v.Required = true
+	case []interface{}, map[string]interface{}:
+		out, err := json.Marshal(def)
+		if err != nil {
+			return nil, err
+		}
+		v.Default = string(out)
+	default:
+		if err := r.weakDecoder(&v.Default, nil).Decode(def); err != nil {
+			return nil, fmt.Errorf("default: %s", err)
+		}
+	}
+
+	return v, nil
+}
+
+// decodeBuilder decodes a single builder, moving the special keys out of its
+// configuration.
+func (r *rawTemplate) decodeBuilder(raw interface{}) (*Builder, error) {
+	var b Builder
+	if err := r.weakDecoder(&b, nil).Decode(raw); err != nil {
+		return nil, err
+	}
+
+	// Set the raw configuration and delete any special keys
+	b.Config = raw.(map[string]interface{})
+
+	delete(b.Config, "depends_on")
+	delete(b.Config, "name")
+	delete(b.Config, "timeout")
+	delete(b.Config, "type")
+
+	if len(b.Config) == 0 {
+		b.Config = nil
+	}
+
+	// If there is no type set, it is an error
+	if b.Type == "" {
+		return nil, errors.New("missing 'type'")
+	}
+
+	// The name defaults to the type if it isn't set
+	if b.Name == "" {
+		b.Name = b.Type
+	}
+
+	return &b, nil
+}
+
+// decodePostProcessor decodes a single post-processor of a chain, moving the
+// special keys out of its configuration.
+func (r *rawTemplate) decodePostProcessor(c map[string]interface{}) (*PostProcessor, error) {
+	var pp PostProcessor
+	if err := r.decoder(&pp, nil).Decode(c); err != nil {
+		return nil, err
+	}
+
+	// Type is required
+	if pp.Type == "" {
+		return nil, errors.New("type is required")
+	}
+
+	// Set the raw configuration and delete any special keys
+	pp.Config = c
+
+	// The name defaults to the type if it isn't set
+	if pp.Name == "" {
+		pp.Name = pp.Type
+	}
+
+	delete(pp.Config, "except")
+	delete(pp.Config, "only")
+	delete(pp.Config, "keep_input_artifact")
+	delete(pp.Config, "type")
+	delete(pp.Config, "name")
+
+	if len(pp.Config) == 0 {
+		pp.Config = nil
+	}
+
+	return &pp, nil
+}
+
+// decodeProvisioner decodes a single provisioner, moving the special keys
+// out of its configuration.
+func (r *rawTemplate) decodeProvisioner(raw interface{}) (*Provisioner, error) {
+	var p Provisioner
+	if err := r.decoder(&p, nil).Decode(raw); err != nil {
+		return nil, err
+	}
+
+	// Type is required before any richer validation
+	if p.Type == "" {
+		return nil, errors.New("missing 'type'")
+	}
+
+	// Set the raw configuration and delete any special keys
+	p.Config = raw.(map[string]interface{})
+
+	delete(p.Config, "except")
+	delete(p.Config, "max_retries")
+	delete(p.Config, "only")
+	delete(p.Config, "override")
+	delete(p.Config, "pause_before")
+	delete(p.Config, "retry_backoff")
+	delete(p.Config, "timeout")
+	delete(p.Config, "type")
+
+	if len(p.Config) == 0 {
+		p.Config = nil
+	}
+
+	return &p, nil
+}
+
 func (r *rawTemplate) decoder(
 	result interface{},
 	md *mapstructure.Metadata) *mapstructure.Decoder {
@@ -277,6 +363,24 @@ func (r *rawTemplate) decoder(
 	return d
 }
 
+// weakDecoder is like decoder, but also converts between basic types, such
+// as a number to a string.
+func (r *rawTemplate) weakDecoder(
+	result interface{},
+	md *mapstructure.Metadata) *mapstructure.Decoder {
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		Metadata:         md,
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		// Like decoder, this can only be a bug.
+		panic(err)
+	}
+	return d
+}
+
 func (r *rawTemplate) parsePostProcessor(
 	i int, raw interface{}) ([]map[string]interface{}, error) {
 	switch v := raw.(type) {
@@ -386,8 +490,20 @@ func Parse(r io.Reader) (*Template, error) {
 }
 
 // ParseFile is the same as Parse but is a helper to automatically open
-// a file for parsing.
+// a file for parsing. Files with the HCLFileExt extension and directories
+// are parsed as HCL templates.
 func ParseFile(path string) (*Template, error) {
+	return ParseFileWithVars(path, nil)
+}
+
+// ParseFileWithVars is the same as ParseFile, with the values of the user
+// variables set on the command line. HCL templates evaluate their
+// expressions with them, JSON templates leave them to the core.
+func ParseFileWithVars(path string, vars map[string]string) (*Template, error) {
+	if isHCLPath(path) {
+		return parseHCLPath(path, vars)
+	}
+
 	var f *os.File
 	var err error
 	if path == "-" {
@@ -0,0 +1,963 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// HCLFileExt is the extension of template files written in HCL. A directory
+// given as a template is read as all the files in it with this extension.
+const HCLFileExt = ".pkr.hcl"
+
+var (
+	hclFileSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "description"},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "packer"},
+			{Type: "variable", LabelNames: []string{"name"}},
+			{Type: "locals"},
+			{Type: "source", LabelNames: []string{"type", "name"}},
+			{Type: "build"},
+		},
+	}
+
+	hclPackerSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "required_version"},
+		},
+	}
+
+	hclVariableSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "type"},
+			{Name: "default"},
+			{Name: "description"},
+			{Name: "sensitive"},
+			{Name: "allowed_values"},
+			{Name: "pattern"},
+		},
+	}
+
+	hclBuildSchema = &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "sources", Required: true},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "provisioner", LabelNames: []string{"type"}},
+			{Type: "post-processor", LabelNames: []string{"type"}},
+			{Type: "post-processors"},
+		},
+	}
+
+	hclPostProcessorsSchema = &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "post-processor", LabelNames: []string{"type"}},
+		},
+	}
+)
+
+// posError is an error at a position in a template file.
+type posError struct {
+	Range hcl.Range
+	Err   error
+}
+
+func (e *posError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s",
+		e.Range.Filename, e.Range.Start.Line, e.Range.Start.Column, e.Err)
+}
+
+// hclParser turns the blocks of one or more HCL files into a Template.
+// Errors are collected in errs so that all of them can be reported at once.
+type hclParser struct {
+	vars map[string]string
+	raw  rawTemplate
+	errs error
+
+	result Template
+
+	// The types and default values of the variables, by name
+	varTypes    map[string]cty.Type
+	varDefaults map[string]cty.Value
+
+	// The values of the variables and locals that expressions are evaluated
+	// with
+	varValues   map[string]cty.Value
+	localValues map[string]cty.Value
+
+	locals    map[string]*hcl.Attribute
+	resolving map[string]bool
+	sources   map[string]*hcl.Block
+	builds    []*hcl.Block
+}
+
+// ParseHCL parses a single HCL template from r. The filename is only used
+// to point at the errors.
+func ParseHCL(r io.Reader, filename string) (*Template, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	p := newHCLParser(nil)
+	p.parse(buf.Bytes(), filename)
+	return p.template()
+}
+
+// ParseHCLFiles parses the given HCL files as one template, as if all their
+// blocks were written in a single file. The expressions of the template are
+// evaluated with the given values of its variables, or their defaults.
+func ParseHCLFiles(vars map[string]string, paths ...string) (*Template, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no HCL template files given")
+	}
+
+	p := newHCLParser(vars)
+	for _, path := range paths {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p.parse(src, path)
+	}
+
+	tpl, err := p.template()
+	if err != nil {
+		return nil, err
+	}
+
+	tpl.Path, err = filepath.Abs(paths[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return tpl, nil
+}
+
+// parseHCLPath parses an HCL file, or all the HCL files in a directory.
+func parseHCLPath(path string, vars map[string]string) (*Template, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return ParseHCLFiles(vars, path)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(path, "*"+HCLFileExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no %s files found in %s", HCLFileExt, path)
+	}
+	sort.Strings(paths)
+
+	return ParseHCLFiles(vars, paths...)
+}
+
+// isHCLPath reports whether path is a template to parse as HCL: a file with
+// the HCLFileExt extension or a directory.
+func isHCLPath(path string) bool {
+	if path == "-" {
+		return false
+	}
+	if strings.HasSuffix(path, HCLFileExt) {
+		return true
+	}
+
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+func newHCLParser(vars map[string]string) *hclParser {
+	return &hclParser{
+		vars:        vars,
+		varTypes:    make(map[string]cty.Type),
+		varDefaults: make(map[string]cty.Value),
+		varValues:   make(map[string]cty.Value),
+		localValues: make(map[string]cty.Value),
+		locals:      make(map[string]*hcl.Attribute),
+		resolving:   make(map[string]bool),
+		sources:     make(map[string]*hcl.Block),
+	}
+}
+
+func (p *hclParser) errCount() int {
+	if merr, ok := p.errs.(*multierror.Error); ok {
+		return len(merr.Errors)
+	}
+	return 0
+}
+
+func (p *hclParser) errorf(rng hcl.Range, format string, args ...interface{}) {
+	p.errs = multierror.Append(p.errs, &posError{
+		Range: rng,
+		Err:   fmt.Errorf(format, args...),
+	})
+}
+
+// diags adds the errors of diags and reports whether there were any.
+func (p *hclParser) diags(diags hcl.Diagnostics) bool {
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+
+		msg := diag.Summary
+		if diag.Detail != "" {
+			msg = fmt.Sprintf("%s; %s", msg, diag.Detail)
+		}
+		if diag.Subject == nil {
+			p.errs = multierror.Append(p.errs, errors.New(msg))
+			continue
+		}
+		p.errs = multierror.Append(p.errs, &posError{
+			Range: *diag.Subject,
+			Err:   errors.New(msg),
+		})
+	}
+	return diags.HasErrors()
+}
+
+// parse reads the top level blocks of a file. Variables are declared right
+// away; the rest is kept until all files are read, because blocks can refer
+// to things declared in other files.
+func (p *hclParser) parse(src []byte, filename string) {
+	p.result.RawContents = append(p.result.RawContents, src...)
+
+	f, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if p.diags(diags) {
+		return
+	}
+
+	content, diags := f.Body.Content(hclFileSchema)
+	p.diags(diags)
+
+	if attr, ok := content.Attributes["description"]; ok {
+		if v, ok := p.constant(attr, cty.String); ok {
+			p.result.Description = v.AsString()
+		}
+	}
+
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "packer":
+			p.packer(block)
+		case "variable":
+			p.variable(block)
+		case "locals":
+			attrs, diags := block.Body.JustAttributes()
+			p.diags(diags)
+			for name, attr := range attrs {
+				if prev, ok := p.locals[name]; ok {
+					p.errorf(attr.NameRange, "local %q is already declared at %s", name, prev.NameRange)
+					continue
+				}
+				p.locals[name] = attr
+			}
+		case "source":
+			name := block.Labels[0] + "." + block.Labels[1]
+			if prev, ok := p.sources[name]; ok {
+				p.errorf(block.DefRange, "source %q is already declared at %s", name, prev.DefRange)
+				continue
+			}
+			p.sources[name] = block
+		case "build":
+			p.builds = append(p.builds, block)
+		}
+	}
+}
+
+// template builds the Template out of the build blocks of all files.
+func (p *hclParser) template() (*Template, error) {
+	if p.errs != nil {
+		return nil, p.errs
+	}
+
+	// Expressions are evaluated with the values of all the variables and
+	// locals, wherever they are declared.
+	p.variableValues()
+	names := make([]string, 0, len(p.locals))
+	for name := range p.locals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.local(name)
+	}
+	if p.errs != nil {
+		return nil, p.errs
+	}
+
+	// Gather the builders first so that the provisioners and post-processors
+	// of each build know which builders exist in the whole template.
+	contents := make([]*hcl.BodyContent, len(p.builds))
+	buildSources := make([][]string, len(p.builds))
+	for i, build := range p.builds {
+		content, diags := build.Body.Content(hclBuildSchema)
+		if p.diags(diags) {
+			continue
+		}
+		contents[i] = content
+		buildSources[i] = p.buildSources(build, content.Attributes["sources"])
+	}
+
+	for i, content := range contents {
+		if content == nil {
+			continue
+		}
+
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "provisioner":
+				p.provisioner(block, buildSources[i])
+			case "post-processor":
+				if pp := p.postProcessor(block, buildSources[i]); pp != nil {
+					p.result.PostProcessors = append(p.result.PostProcessors, []*PostProcessor{pp})
+				}
+			case "post-processors":
+				p.postProcessorChain(block, buildSources[i])
+			}
+		}
+	}
+
+	if p.errs != nil {
+		return nil, p.errs
+	}
+
+	return &p.result, nil
+}
+
+// packer reads the settings of the packer block.
+func (p *hclParser) packer(block *hcl.Block) {
+	content, diags := block.Body.Content(hclPackerSchema)
+	if p.diags(diags) {
+		return
+	}
+
+	if attr, ok := content.Attributes["required_version"]; ok {
+		v, ok := p.constant(attr, cty.String)
+		if !ok {
+			return
+		}
+		s := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(v.AsString()), ">="))
+		if s == "" || strings.ContainsAny(s, "<>=~!, ") {
+			p.errorf(attr.Expr.Range(), "required_version must be a version or \">= VERSION\"")
+			return
+		}
+		p.result.MinVersion = s
+	}
+}
+
+// variable declares a variable from a variable block.
+func (p *hclParser) variable(block *hcl.Block) {
+	name := block.Labels[0]
+	if _, ok := p.result.Variables[name]; ok {
+		p.errorf(block.DefRange, "variable %q is already declared", name)
+		return
+	}
+	if !hclsyntax.ValidIdentifier(name) {
+		p.errorf(block.LabelRanges[0], "variable name %q is not a valid identifier", name)
+		return
+	}
+
+	content, diags := block.Body.Content(hclVariableSchema)
+	if p.diags(diags) {
+		return
+	}
+
+	v := &Variable{Key: name, Required: true}
+	ty := cty.DynamicPseudoType
+	if attr, ok := content.Attributes["type"]; ok {
+		var diags hcl.Diagnostics
+		ty, diags = typeexpr.TypeConstraint(attr.Expr)
+		if p.diags(diags) {
+			return
+		}
+		v.Type = variableType(ty)
+	}
+
+	if attr, ok := content.Attributes["description"]; ok {
+		if s, ok := p.constant(attr, cty.String); ok {
+			v.Description = s.AsString()
+		}
+	}
+
+	var sensitive bool
+	if attr, ok := content.Attributes["sensitive"]; ok {
+		if b, ok := p.constant(attr, cty.Bool); ok {
+			sensitive = b.True()
+		}
+	}
+
+	if attr, ok := content.Attributes["allowed_values"]; ok {
+		if list, ok := p.constant(attr, cty.List(cty.String)); ok && !list.IsNull() {
+			for it := list.ElementIterator(); it.Next(); {
+				_, value := it.Element()
+				v.AllowedValues = append(v.AllowedValues, value.AsString())
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["pattern"]; ok {
+		if s, ok := p.constant(attr, cty.String); ok {
+			v.Pattern = s.AsString()
+			if _, err := regexp.Compile(v.Pattern); err != nil {
+				p.errorf(attr.Expr.Range(), "variable %q: invalid pattern: %s", name, err)
+				return
+			}
+		}
+	}
+
+	if attr, ok := content.Attributes["default"]; ok {
+		// Like in JSON templates, the default of a variable can only use
+		// the environment, not other variables or locals.
+		def, diags := attr.Expr.Value(&hcl.EvalContext{
+			Functions: map[string]function.Function{"env": envFunc},
+		})
+		if p.diags(diags) {
+			return
+		}
+		def, err := convert.Convert(def, ty)
+		if err != nil {
+			p.errorf(attr.Expr.Range(), "variable %q: default must be a %s: %s",
+				name, typeexpr.TypeString(ty), err)
+			return
+		}
+		if !def.IsNull() {
+			s, err := variableString(def)
+			if err != nil {
+				p.errorf(attr.Expr.Range(), "variable %q: %s", name, err)
+				return
+			}
+			v.Default = s
+		}
+		v.Required = false
+		p.varDefaults[name] = def
+	}
+
+	if p.result.Variables == nil {
+		p.result.Variables = make(map[string]*Variable)
+	}
+	p.result.Variables[name] = v
+	p.varTypes[name] = ty
+	if sensitive {
+		p.result.SensitiveVariables = append(p.result.SensitiveVariables, v)
+	}
+}
+
+// variableValues sets the value of every variable to the value it was
+// given, or its default. A required variable without a value refers to the
+// user variable instead, so that the core reports it as missing like it
+// does for JSON templates.
+func (p *hclParser) variableValues() {
+	for name, v := range p.result.Variables {
+		ty := p.varTypes[name]
+		s, ok := p.vars[name]
+		if !ok {
+			if def, ok := p.varDefaults[name]; ok {
+				p.varValues[name] = def
+			} else {
+				p.varValues[name] = cty.StringVal(fmt.Sprintf("{{user `%s`}}", v.Key))
+			}
+			continue
+		}
+
+		value, err := variableValue(s, ty)
+		if err != nil {
+			p.errs = multierror.Append(p.errs, fmt.Errorf(
+				"variable %q: value must be a %s: %s", name, typeexpr.TypeString(ty), err))
+			continue
+		}
+		p.varValues[name] = value
+	}
+}
+
+// local returns the value of a local, evaluating the locals it refers to
+// first. It returns false if the local can't be evaluated.
+func (p *hclParser) local(name string) (cty.Value, bool) {
+	if v, ok := p.localValues[name]; ok {
+		return v, true
+	}
+
+	attr := p.locals[name]
+	if p.resolving[name] {
+		p.errorf(attr.NameRange, "local %q refers to itself", name)
+		return cty.DynamicVal, false
+	}
+
+	p.resolving[name] = true
+	defer delete(p.resolving, name)
+
+	for _, tr := range attr.Expr.Variables() {
+		if dep := referenceName(tr); tr.RootName() == "local" && p.locals[dep] != nil {
+			if _, ok := p.local(dep); !ok {
+				return cty.DynamicVal, false
+			}
+		}
+	}
+
+	v, ok := p.eval(attr.Expr)
+	if !ok {
+		return cty.DynamicVal, false
+	}
+	p.localValues[name] = v
+	return v, true
+}
+
+// buildSources adds the builders for the sources a build block refers to
+// and returns their names.
+func (p *hclParser) buildSources(build *hcl.Block, attr *hcl.Attribute) []string {
+	var names []string
+	errs := p.errCount()
+	refs, ranges := p.sourceRefs(attr)
+	for i, name := range refs {
+		source, ok := p.sources[name]
+		if !ok {
+			p.errorf(ranges[i], "source %q is not declared", name)
+			continue
+		}
+		if _, ok := p.result.Builders[name]; ok {
+			p.errorf(ranges[i], "source %q is already used by a build", name)
+			continue
+		}
+
+		if b := p.builder(name, source); b != nil {
+			if p.result.Builders == nil {
+				p.result.Builders = make(map[string]*Builder)
+			}
+			p.result.Builders[name] = b
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 && p.errCount() == errs {
+		p.errorf(build.DefRange, "build must have at least one source")
+	}
+
+	return names
+}
+
+// builder decodes a source block into a builder named TYPE.NAME.
+func (p *hclParser) builder(name string, block *hcl.Block) *Builder {
+	config, ok := p.body(block.Body, "type", "name", "depends_on")
+	if !ok {
+		return nil
+	}
+	config["type"] = block.Labels[0]
+	config["name"] = name
+
+	b, err := p.raw.decodeBuilder(config)
+	if err != nil {
+		p.errorf(block.DefRange, "source %q: %s", name, err)
+		return nil
+	}
+
+	// Sources depend on other sources by reference.
+	if attr := hclAttribute(block.Body, "depends_on"); attr != nil {
+		b.DependsOn, _ = p.sourceRefs(attr)
+	}
+
+	return b
+}
+
+// provisioner decodes a provisioner block of a build.
+func (p *hclParser) provisioner(block *hcl.Block, sources []string) {
+	typ := block.Labels[0]
+	config, ok := p.body(block.Body, "type", "only", "except")
+	if !ok {
+		return
+	}
+	config["type"] = typ
+
+	prov, err := p.raw.decodeProvisioner(config)
+	if err != nil {
+		p.errorf(block.DefRange, "provisioner %q: %s", typ, err)
+		return
+	}
+
+	// Overrides are keyed by source name, with or without the source.
+	// prefix since an object key can't be a reference.
+	for name, override := range prov.Override {
+		builder := strings.TrimPrefix(name, "source.")
+		if builder != name {
+			delete(prov.Override, name)
+			prov.Override[builder] = override
+		}
+	}
+
+	if p.scope(block, &prov.OnlyExcept, sources) {
+		p.result.Provisioners = append(p.result.Provisioners, prov)
+	}
+}
+
+// postProcessor decodes a post-processor block of a build.
+func (p *hclParser) postProcessor(block *hcl.Block, sources []string) *PostProcessor {
+	typ := block.Labels[0]
+	config, ok := p.body(block.Body, "type", "only", "except")
+	if !ok {
+		return nil
+	}
+	config["type"] = typ
+
+	pp, err := p.raw.decodePostProcessor(config)
+	if err != nil {
+		p.errorf(block.DefRange, "post-processor %q: %s", typ, err)
+		return nil
+	}
+
+	if !p.scope(block, &pp.OnlyExcept, sources) {
+		return nil
+	}
+
+	return pp
+}
+
+// postProcessorChain decodes a post-processors block, a sequence of
+// post-processor blocks that each get the artifact of the previous one.
+func (p *hclParser) postProcessorChain(block *hcl.Block, sources []string) {
+	content, diags := block.Body.Content(hclPostProcessorsSchema)
+	if p.diags(diags) {
+		return
+	}
+
+	var chain []*PostProcessor
+	for _, ppBlock := range content.Blocks {
+		if pp := p.postProcessor(ppBlock, sources); pp != nil {
+			chain = append(chain, pp)
+		}
+	}
+
+	if len(chain) > 0 {
+		p.result.PostProcessors = append(p.result.PostProcessors, chain)
+	}
+}
+
+// scope limits a provisioner or post-processor of a build to the sources of
+// that build, taking its own only and except into account. It returns false
+// when nothing is left for it to run on.
+func (p *hclParser) scope(block *hcl.Block, oe *OnlyExcept, sources []string) bool {
+	inBuild := func(arg string) ([]string, bool) {
+		attr := hclAttribute(block.Body, arg)
+		if attr == nil {
+			return nil, true
+		}
+		names, ranges := p.sourceRefs(attr)
+		for i, name := range names {
+			if !containsString(sources, name) {
+				p.errorf(ranges[i], "%s: %q is not a source of this build", arg, name)
+				return nil, false
+			}
+		}
+		return names, true
+	}
+
+	only, ok := inBuild("only")
+	if !ok {
+		return false
+	}
+	except, ok := inBuild("except")
+	if !ok {
+		return false
+	}
+
+	var names []string
+	for _, name := range sources {
+		if len(only) > 0 && !containsString(only, name) {
+			continue
+		}
+		if containsString(except, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	oe.Only, oe.Except = nil, nil
+	if len(names) < len(p.result.Builders) {
+		oe.Only = names
+	}
+
+	return len(names) > 0
+}
+
+// sourceRefs decodes a list of references to sources, such as
+// [source.qemu.base], into the names of the sources, such as "qemu.base".
+// The ranges of the references are returned along with them.
+func (p *hclParser) sourceRefs(attr *hcl.Attribute) ([]string, []hcl.Range) {
+	exprs, diags := hcl.ExprList(attr.Expr)
+	if p.diags(diags) {
+		return nil, nil
+	}
+
+	var names []string
+	var ranges []hcl.Range
+	for _, expr := range exprs {
+		tr, diags := hcl.AbsTraversalForExpr(expr)
+		if diags.HasErrors() || len(tr) != 3 || tr.RootName() != "source" {
+			p.errorf(expr.Range(), "%s must be a list of references like source.TYPE.NAME", attr.Name)
+			continue
+		}
+		typ, _ := tr[1].(hcl.TraverseAttr)
+		name, _ := tr[2].(hcl.TraverseAttr)
+		names = append(names, typ.Name+"."+name.Name)
+		ranges = append(ranges, expr.Range())
+	}
+
+	return names, ranges
+}
+
+// body evaluates the arguments of a block body into a configuration map
+// like the JSON format has. Nested blocks become lists of maps, the same as
+// a JSON list of objects. The reserved names can only be set where the
+// caller reads them.
+func (p *hclParser) body(body hcl.Body, reserved ...string) (map[string]interface{}, bool) {
+	errs := p.errCount()
+	result := make(map[string]interface{})
+
+	b := body.(*hclsyntax.Body)
+	for name, attr := range b.Attributes {
+		if containsString(reserved, name) {
+			if name == "type" || name == "name" {
+				p.errorf(attr.NameRange, "%q can't be set here", name)
+			}
+			continue
+		}
+
+		v, ok := p.eval(attr.AsHCLAttribute().Expr)
+		if !ok || v.IsNull() {
+			continue
+		}
+		value, err := ctyToGo(v)
+		if err != nil {
+			p.errorf(attr.Expr.Range(), "%s: %s", name, err)
+			continue
+		}
+		result[name] = value
+	}
+
+	for _, block := range b.Blocks {
+		if containsString(reserved, block.Type) {
+			p.errorf(block.TypeRange, "%q can't be set here", block.Type)
+			continue
+		}
+		if len(block.Labels) > 0 {
+			p.errorf(block.LabelRanges[0], "%s block can't have labels", block.Type)
+			continue
+		}
+		if _, ok := b.Attributes[block.Type]; ok {
+			p.errorf(block.TypeRange, "argument %q is already set", block.Type)
+			continue
+		}
+
+		value, _ := p.body(block.Body)
+		list, _ := result[block.Type].([]interface{})
+		result[block.Type] = append(list, value)
+	}
+
+	return result, p.errCount() == errs
+}
+
+// eval evaluates an expression with the variables, locals and functions of
+// the template.
+func (p *hclParser) eval(expr hcl.Expression) (cty.Value, bool) {
+	errs := p.errCount()
+	for _, tr := range expr.Variables() {
+		name := referenceName(tr)
+		switch tr.RootName() {
+		case "var":
+			if _, ok := p.result.Variables[name]; !ok {
+				p.errorf(tr.SourceRange(), "variable %q is not declared", name)
+			}
+		case "local":
+			if _, ok := p.locals[name]; !ok {
+				p.errorf(tr.SourceRange(), "local %q is not declared", name)
+			}
+		}
+	}
+	if p.errCount() != errs {
+		return cty.DynamicVal, false
+	}
+
+	v, diags := expr.Value(&hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var":   cty.ObjectVal(p.varValues),
+			"local": cty.ObjectVal(p.localValues),
+		},
+		Functions: hclFunctions,
+	})
+	if p.diags(diags) {
+		return cty.DynamicVal, false
+	}
+	if !v.IsWhollyKnown() {
+		p.errorf(expr.Range(), "value can't be determined")
+		return cty.DynamicVal, false
+	}
+
+	return v, true
+}
+
+// constant evaluates an expression that can't refer to anything and
+// converts it to the given type.
+func (p *hclParser) constant(attr *hcl.Attribute, ty cty.Type) (cty.Value, bool) {
+	v, diags := attr.Expr.Value(nil)
+	if p.diags(diags) {
+		return cty.NullVal(ty), false
+	}
+
+	v, err := convert.Convert(v, ty)
+	if err != nil {
+		p.errorf(attr.Expr.Range(), "%s must be a %s: %s", attr.Name, typeexpr.TypeString(ty), err)
+		return cty.NullVal(ty), false
+	}
+	if v.IsNull() {
+		p.errorf(attr.Expr.Range(), "%s can't be null", attr.Name)
+		return cty.NullVal(ty), false
+	}
+
+	return v, true
+}
+
+// hclAttribute returns the attribute of a body with the given name, or nil.
+func hclAttribute(body hcl.Body, name string) *hcl.Attribute {
+	attr, ok := body.(*hclsyntax.Body).Attributes[name]
+	if !ok {
+		return nil
+	}
+	return attr.AsHCLAttribute()
+}
+
+// referenceName returns NAME for a reference like var.NAME or local.NAME.
+func referenceName(tr hcl.Traversal) string {
+	if len(tr) < 2 {
+		return ""
+	}
+	if attr, ok := tr[1].(hcl.TraverseAttr); ok {
+		return attr.Name
+	}
+	return ""
+}
+
+// variableType returns the type of user variable that a variable of the
+// given HCL type is. Any type is untyped.
+func variableType(ty cty.Type) string {
+	switch {
+	case ty == cty.String:
+		return "string"
+	case ty == cty.Number:
+		return "number"
+	case ty == cty.Bool:
+		return "bool"
+	case ty.IsListType(), ty.IsSetType(), ty.IsTupleType():
+		return "list"
+	case ty.IsMapType(), ty.IsObjectType():
+		return "map"
+	}
+	return ""
+}
+
+// variableString turns a value of a variable into the string that the rest
+// of Packer works with. Lists and maps are kept as JSON, like the defaults
+// of typed variables in JSON templates.
+func variableString(v cty.Value) (string, error) {
+	switch v.Type() {
+	case cty.String:
+		return v.AsString(), nil
+	case cty.Number:
+		return v.AsBigFloat().Text('f', -1), nil
+	case cty.Bool:
+		if v.True() {
+			return "true", nil
+		}
+		return "false", nil
+	}
+
+	out, err := ctyjson.SimpleJSONValue{Value: v}.MarshalJSON()
+	return string(out), err
+}
+
+// variableValue is the opposite of variableString: it reads a value given
+// on the command line as a value of the given type.
+func variableValue(s string, ty cty.Type) (cty.Value, error) {
+	if ty == cty.DynamicPseudoType || ty.IsPrimitiveType() {
+		return convert.Convert(cty.StringVal(s), ty)
+	}
+
+	var v ctyjson.SimpleJSONValue
+	if err := v.UnmarshalJSON([]byte(s)); err != nil {
+		return cty.NilVal, err
+	}
+	return convert.Convert(v.Value, ty)
+}
+
+// ctyToGo converts a value to what the JSON decoder makes of the same value
+// in a JSON template.
+func ctyToGo(v cty.Value) (interface{}, error) {
+	if v.IsNull() {
+		return nil, nil
+	}
+
+	ty := v.Type()
+	switch {
+	case ty == cty.String:
+		return v.AsString(), nil
+	case ty == cty.Number:
+		f, _ := v.AsBigFloat().Float64()
+		return f, nil
+	case ty == cty.Bool:
+		return v.True(), nil
+	case ty.IsListType(), ty.IsSetType(), ty.IsTupleType():
+		result := make([]interface{}, 0, v.LengthInt())
+		for it := v.ElementIterator(); it.Next(); {
+			_, elem := it.Element()
+			value, err := ctyToGo(elem)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	case ty.IsMapType(), ty.IsObjectType():
+		result := make(map[string]interface{})
+		for it := v.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			value, err := ctyToGo(elem)
+			if err != nil {
+				return nil, err
+			}
+			result[key.AsString()] = value
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value of type %s", ty.FriendlyName())
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
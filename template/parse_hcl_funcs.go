@@ -0,0 +1,104 @@
+package template
+
+import (
+	"os"
+	"strings"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// hclFunctions are the functions that expressions in HCL templates can
+// call. Template engine functions such as {{timestamp}} are still written
+// inside strings, because they are only evaluated when the build runs.
+var hclFunctions = map[string]function.Function{
+	"abs":        stdlib.AbsoluteFunc,
+	"coalesce":   stdlib.CoalesceFunc,
+	"concat":     stdlib.ConcatFunc,
+	"csvdecode":  stdlib.CSVDecodeFunc,
+	"format":     stdlib.FormatFunc,
+	"formatdate": stdlib.FormatDateFunc,
+	"formatlist": stdlib.FormatListFunc,
+	"join":       joinFunc,
+	"jsondecode": stdlib.JSONDecodeFunc,
+	"jsonencode": stdlib.JSONEncodeFunc,
+	"length":     stdlib.LengthFunc,
+	"lower":      stdlib.LowerFunc,
+	"max":        stdlib.MaxFunc,
+	"min":        stdlib.MinFunc,
+	"regex":      stdlib.RegexFunc,
+	"regexall":   stdlib.RegexAllFunc,
+	"replace":    replaceFunc,
+	"split":      splitFunc,
+	"substr":     stdlib.SubstrFunc,
+	"trimspace":  trimSpaceFunc,
+	"upper":      stdlib.UpperFunc,
+}
+
+// envFunc returns the value of an environment variable. Like in JSON
+// templates, it can only be used in the defaults of variables.
+var envFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "key", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(os.Getenv(args[0].AsString())), nil
+	},
+})
+
+var joinFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "separator", Type: cty.String},
+		{Name: "list", Type: cty.List(cty.String)},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		var elems []string
+		for it := args[1].ElementIterator(); it.Next(); {
+			_, v := it.Element()
+			elems = append(elems, v.AsString())
+		}
+		return cty.StringVal(strings.Join(elems, args[0].AsString())), nil
+	},
+})
+
+var splitFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "separator", Type: cty.String},
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.List(cty.String)),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		parts := strings.Split(args[1].AsString(), args[0].AsString())
+		elems := make([]cty.Value, len(parts))
+		for i, part := range parts {
+			elems[i] = cty.StringVal(part)
+		}
+		return cty.ListVal(elems), nil
+	},
+})
+
+var replaceFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+		{Name: "substr", Type: cty.String},
+		{Name: "replace", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(strings.Replace(
+			args[0].AsString(), args[1].AsString(), args[2].AsString(), -1)), nil
+	},
+})
+
+var trimSpaceFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "str", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(strings.TrimSpace(args[0].AsString())), nil
+	},
+})
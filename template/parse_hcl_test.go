@@ -0,0 +1,287 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseHCLFile(t *testing.T) {
+	path := fixtureDir("parse-hcl.pkr.hcl")
+	tpl, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	abs, _ := filepath.Abs(path)
+	password := &Variable{
+		Key:           "password",
+		Required:      true,
+		AllowedValues: []string{"hunter2", "letmein"},
+	}
+	expected := &Template{
+		Path:        abs,
+		Description: "A template in HCL",
+		MinVersion:  "1.3.0",
+		Variables: map[string]*Variable{
+			"region": {
+				Key:         "region",
+				Default:     "us-east-1",
+				Type:        "string",
+				Description: "The region to build in",
+				Pattern:     "[a-z]+-[a-z]+-[0-9]",
+			},
+			"password": password,
+			"disk_size": {
+				Key:     "disk_size",
+				Default: "4096",
+				Type:    "number",
+			},
+			"packages": {
+				Key:     "packages",
+				Default: `["git","make"]`,
+				Type:    "list",
+			},
+		},
+		SensitiveVariables: []*Variable{password},
+		Builders: map[string]*Builder{
+			"qemu.base": {
+				Name:    "qemu.base",
+				Type:    "qemu",
+				Timeout: time.Hour,
+				Config: map[string]interface{}{
+					"disk_size": float64(8192),
+					"vm_name":   "web-us-east-1",
+					"boot_command": []interface{}{
+						"<tab> text ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg<enter>",
+					},
+				},
+			},
+			"virtualbox-iso.base": {
+				Name: "virtualbox-iso.base",
+				Type: "virtualbox-iso",
+				Config: map[string]interface{}{
+					"guest_os_type": "RedHat_64",
+					"vm_name":       "WEB-US-EAST-1",
+				},
+			},
+		},
+		Provisioners: []*Provisioner{
+			{
+				Type:       "shell",
+				MaxRetries: 2,
+				Config: map[string]interface{}{
+					"inline": []interface{}{
+						"echo ${HOME} {{user `password`}}",
+						"yum install -y git make",
+					},
+				},
+			},
+			{
+				OnlyExcept: OnlyExcept{Only: []string{"qemu.base"}},
+				Type:       "file",
+				Config: map[string]interface{}{
+					"source":      "app.tar.gz",
+					"destination": "/tmp/app.tar.gz",
+				},
+			},
+		},
+		PostProcessors: [][]*PostProcessor{
+			{
+				{
+					Name: "compress",
+					Type: "compress",
+				},
+				{
+					OnlyExcept: OnlyExcept{Only: []string{"virtualbox-iso.base"}},
+					Name:       "manifest",
+					Type:       "manifest",
+				},
+			},
+		},
+	}
+
+	tpl.RawContents = nil
+	if diff := cmp.Diff(tpl, expected); diff != "" {
+		t.Fatalf("bad: %s", diff)
+	}
+	if err := tpl.Validate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestParseHCLFile_dir(t *testing.T) {
+	tpl, err := ParseFile(fixtureDir("hcl-dir"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(tpl.Builders) != 2 {
+		t.Fatalf("bad: %#v", tpl.Builders)
+	}
+	if v := tpl.Builders["qemu.db"].Config["iso_url"]; v != "base.qcow2" {
+		t.Fatalf("bad: %#v", v)
+	}
+	if v := tpl.Builders["qemu.app"].DependsOn; len(v) != 1 || v[0] != "qemu.db" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// Each build only runs its own provisioners and post-processors.
+	if len(tpl.Provisioners) != 2 {
+		t.Fatalf("bad: %#v", tpl.Provisioners)
+	}
+	for i, only := range []string{"qemu.app", "qemu.db"} {
+		p := tpl.Provisioners[i]
+		if len(p.Only) != 1 || p.Only[0] != only {
+			t.Fatalf("%d: bad: %#v", i, p.OnlyExcept)
+		}
+	}
+	if pp := tpl.PostProcessors[0][0]; len(pp.Only) != 1 || pp.Only[0] != "qemu.db" {
+		t.Fatalf("bad: %#v", pp.OnlyExcept)
+	}
+
+	// The first file sets the template path, so template_dir is the directory.
+	if filepath.Base(tpl.Path) != "build.pkr.hcl" {
+		t.Fatalf("bad: %s", tpl.Path)
+	}
+}
+
+func TestParseHCLFiles_vars(t *testing.T) {
+	path := fixtureDir("parse-hcl.pkr.hcl")
+	tpl, err := ParseHCLFiles(map[string]string{
+		"region":    "eu-west-1",
+		"disk_size": "100",
+		"packages":  `["vim"]`,
+	}, path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := tpl.Builders["qemu.base"].Config
+	if v := config["disk_size"]; v != float64(200) {
+		t.Fatalf("bad: %#v", v)
+	}
+	if v := config["vm_name"]; v != "web-eu-west-1" {
+		t.Fatalf("bad: %#v", v)
+	}
+	inline := tpl.Provisioners[0].Config["inline"].([]interface{})
+	if v := inline[1]; v != "yum install -y vim" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	// The defaults stay the same, for the core to check the variables.
+	if v := tpl.Variables["region"].Default; v != "us-east-1" {
+		t.Fatalf("bad: %#v", v)
+	}
+
+	_, err = ParseHCLFiles(map[string]string{"disk_size": "big"}, path)
+	if err == nil || !strings.Contains(err.Error(), `variable "disk_size": value must be a number`) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestParseHCL_escape(t *testing.T) {
+	src := `
+variable "home" {
+  default = "$${HOME}"
+}
+
+variable "user" {
+  default = env("PACKER_TEST_HCL_USER")
+}
+
+source "qemu" "a" {
+  vm_name = "$${var.home} ${var.home} %%{x} ${var.user}"
+}
+
+build {
+  sources = [source.qemu.a]
+}
+`
+	os.Setenv("PACKER_TEST_HCL_USER", "packer")
+	defer os.Unsetenv("PACKER_TEST_HCL_USER")
+
+	tpl, err := ParseHCL(strings.NewReader(src), "test.pkr.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v := tpl.Variables["home"].Default; v != "${HOME}" {
+		t.Fatalf("bad: %#v", v)
+	}
+	if v := tpl.Variables["user"].Default; v != "packer" {
+		t.Fatalf("bad: %#v", v)
+	}
+	if v := tpl.Builders["qemu.a"].Config["vm_name"]; v != "${var.home} ${HOME} %{x} packer" {
+		t.Fatalf("bad: %#v", v)
+	}
+}
+
+func TestParseHCL_bad(t *testing.T) {
+	cases := []struct {
+		Src      string
+		Expected string
+	}{
+		{
+			"source \"qemu\" \"a\" {\n  vm_name = \"x\"\n",
+			"test.pkr.hcl:",
+		},
+		{
+			"foo {}\n",
+			"test.pkr.hcl:1:1: Unsupported block type",
+		},
+		{
+			"source \"qemu\" \"a\" {\n  vm_name = \"x-${var.nope}\"\n}\nbuild {\n  sources = [source.qemu.a]\n}\n",
+			"test.pkr.hcl:2:18: variable \"nope\" is not declared",
+		},
+		{
+			"source \"qemu\" \"a\" {\n  vm_name = nope(\"x\")\n}\nbuild {\n  sources = [source.qemu.a]\n}\n",
+			"test.pkr.hcl:2:13: Call to unknown function",
+		},
+		{
+			"source \"qemu\" \"a\" {\n  vm_name = env(\"HOME\")\n}\nbuild {\n  sources = [source.qemu.a]\n}\n",
+			"test.pkr.hcl:2:13: Call to unknown function",
+		},
+		{
+			"locals {\n  a = local.b\n  b = local.a\n}\n",
+			"refers to itself",
+		},
+		{
+			"variable \"size\" {\n  type    = number\n  default = \"big\"\n}\n",
+			"test.pkr.hcl:3:13: variable \"size\": default must be a number",
+		},
+		{
+			"variable \"size\" {\n  type = float\n}\n",
+			"test.pkr.hcl:2:10: Invalid type specification",
+		},
+		{
+			"variable \"a\" {\n  default = \"x\"\n}\nvariable \"b\" {\n  default = var.a\n}\n",
+			"test.pkr.hcl:5:13: Variables not allowed",
+		},
+		{
+			"build {\n  sources = [source.qemu.a]\n}\n",
+			"test.pkr.hcl:2:14: source \"qemu.a\" is not declared",
+		},
+		{
+			"source \"qemu\" \"a\" {}\nbuild {\n  sources = [\"source.qemu.a\"]\n}\n",
+			"test.pkr.hcl:3:14: sources must be a list of references like source.TYPE.NAME",
+		},
+		{
+			"source \"qemu\" \"a\" {}\nsource \"qemu\" \"b\" {}\nbuild {\n  sources = [source.qemu.a]\n  provisioner \"shell\" {\n    only = [source.qemu.b]\n  }\n}\nbuild {\n  sources = [source.qemu.b]\n}\n",
+			"test.pkr.hcl:6:13: only: \"qemu.b\" is not a source of this build",
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := ParseHCL(strings.NewReader(tc.Src), "test.pkr.hcl")
+		if err == nil {
+			t.Fatalf("expected error for:\n%s", tc.Src)
+		}
+		if !strings.Contains(err.Error(), tc.Expected) {
+			t.Fatalf("Expected: %s\n%s\nfor:\n%s", tc.Expected, err, tc.Src)
+		}
+	}
+}
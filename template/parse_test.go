@@ -50,6 +50,39 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"parse-builder-timeout.json",
+			&Template{
+				Builders: map[string]*Builder{
+					"something": {
+						Name:    "something",
+						Type:    "something",
+						Timeout: 1 * time.Hour,
+					},
+				},
+			},
+			false,
+		},
+		{
+			"parse-builder-depends-on.json",
+			&Template{
+				Builders: map[string]*Builder{
+					"base": {
+						Name: "base",
+						Type: "something",
+					},
+					"app": {
+						Name:      "app",
+						Type:      "something",
+						DependsOn: []string{"base"},
+						Config: map[string]interface{}{
+							"source_image": "{{ artifact `base` `id` }}",
+						},
+					},
+				},
+			},
+			false,
+		},
 		{
 			"parse-builder-no-type.json",
 			nil,
@@ -101,6 +134,31 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"parse-provisioner-timeout.json",
+			&Template{
+				Provisioners: []*Provisioner{
+					{
+						Type:    "something",
+						Timeout: 5 * time.Minute,
+					},
+				},
+			},
+			false,
+		},
+		{
+			"parse-provisioner-retry.json",
+			&Template{
+				Provisioners: []*Provisioner{
+					{
+						Type:         "something",
+						MaxRetries:   3,
+						RetryBackoff: 10 * time.Second,
+					},
+				},
+			},
+			false,
+		},
 
 		{
 			"parse-provisioner-only.json",
@@ -179,6 +237,32 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-variable-typed.json",
+			&Template{
+				Variables: map[string]*Variable{
+					"size": {
+						Key:         "size",
+						Type:        "number",
+						Description: "Disk size in MB",
+						Default:     "4096",
+					},
+					"env": {
+						Key:           "env",
+						Required:      true,
+						AllowedValues: []string{"dev", "prod"},
+						Pattern:       "[a-z]+",
+					},
+					"tags": {
+						Key:     "tags",
+						Type:    "list",
+						Default: `["a","b"]`,
+					},
+				},
+			},
+			false,
+		},
+
 		{
 			"parse-pp-basic.json",
 			&Template{
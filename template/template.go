@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
@@ -80,9 +84,11 @@ func (t *Template) Raw() (*rawTemplate, error) {
 
 // Builder represents a builder configured in the template
 type Builder struct {
-	Name   string                 `json:"name,omitempty"`
-	Type   string                 `json:"type"`
-	Config map[string]interface{} `json:"config,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Type      string                 `json:"type"`
+	Timeout   time.Duration          `json:"timeout,omitempty"`
+	DependsOn []string               `mapstructure:"depends_on" json:"depends_on,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
 }
 
 // MarshalJSON conducts the necessary flattening of the Builder struct
@@ -148,6 +154,12 @@ type Provisioner struct {
 	Config      map[string]interface{} `json:"config,omitempty"`
 	Override    map[string]interface{} `json:"override,omitempty"`
 	PauseBefore time.Duration          `mapstructure:"pause_before" json:"pause_before,omitempty"`
+	Timeout     time.Duration          `json:"timeout,omitempty"`
+
+	// MaxRetries is how many times a failed provisioner is run again, with
+	// RetryBackoff between the first two tries, doubling after each one.
+	MaxRetries   int           `mapstructure:"max_retries" json:"max_retries,omitempty"`
+	RetryBackoff time.Duration `mapstructure:"retry_backoff" json:"retry_backoff,omitempty"`
 }
 
 // MarshalJSON conducts the necessary flattening of the Provisioner struct
@@ -186,9 +198,110 @@ type Variable struct {
 	Key      string
 	Default  string
 	Required bool
+
+	// Type is one of VariableTypes, or empty for a plain string. List and
+	// map values are written as JSON.
+	Type        string
+	Description string
+
+	// AllowedValues and Pattern restrict the values the variable can be
+	// set to. Pattern is a regular expression the whole value must match.
+	AllowedValues []string
+	Pattern       string
+}
+
+// VariableTypes are the types a variable can declare.
+var VariableTypes = []string{"string", "number", "bool", "list", "map"}
+
+func validVariableType(typ string) bool {
+	for _, t := range VariableTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Typed reports whether the variable has more than a default, which means
+// it has to be written as an object in JSON templates.
+func (v *Variable) Typed() bool {
+	return v.Type != "" || v.Description != "" ||
+		len(v.AllowedValues) > 0 || v.Pattern != ""
+}
+
+// Check returns an error if value isn't a valid value for the variable. The
+// error doesn't contain the value, since it can be a secret.
+func (v *Variable) Check(value string) error {
+	switch v.Type {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return errors.New("must be a number")
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("must be a bool")
+		}
+	case "list":
+		var list []interface{}
+		if err := json.Unmarshal([]byte(value), &list); err != nil {
+			return errors.New("must be a JSON list")
+		}
+	case "map":
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &m); err != nil {
+			return errors.New("must be a JSON object")
+		}
+	}
+
+	if len(v.AllowedValues) > 0 {
+		allowed := false
+		for _, a := range v.AllowedValues {
+			if a == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("must be one of %s",
+				strings.Join(v.AllowedValues, ", "))
+		}
+	}
+
+	if v.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + v.Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %s", err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("must match the pattern %s", v.Pattern)
+		}
+	}
+
+	return nil
 }
 
 func (v *Variable) MarshalJSON() ([]byte, error) {
+	if v.Typed() {
+		// Avoid recursion
+		type Variable_ struct {
+			Type          string   `json:"type,omitempty"`
+			Description   string   `json:"description,omitempty"`
+			Default       *string  `json:"default,omitempty"`
+			AllowedValues []string `json:"allowed_values,omitempty"`
+			Pattern       string   `json:"pattern,omitempty"`
+		}
+		out := Variable_{
+			Type:          v.Type,
+			Description:   v.Description,
+			AllowedValues: v.AllowedValues,
+			Pattern:       v.Pattern,
+		}
+		if !v.Required {
+			out.Default = &v.Default
+		}
+		return json.Marshal(out)
+	}
+
 	if v.Required {
 		// We use a nil pointer to coax Go into marshalling it as a JSON null
 		var ret *string
@@ -223,6 +336,36 @@ func (t *Template) Validate() error {
 			"at least one builder must be defined"))
 	}
 
+	// Verify the rules of the variables
+	for k, v := range t.Variables {
+		if v.Type != "" && !validVariableType(v.Type) {
+			err = multierror.Append(err, fmt.Errorf(
+				"variable %s: type must be one of %s",
+				k, strings.Join(VariableTypes, ", ")))
+		}
+		if v.Pattern != "" {
+			if _, rerr := regexp.Compile(v.Pattern); rerr != nil {
+				err = multierror.Append(err, fmt.Errorf(
+					"variable %s: invalid pattern: %s", k, rerr))
+			}
+		}
+	}
+
+	// Verify that the builds depended on exist and don't form a cycle
+	for name, b := range t.Builders {
+		for _, dep := range b.DependsOn {
+			if _, ok := t.Builders[dep]; !ok {
+				err = multierror.Append(err, fmt.Errorf(
+					"builder %s: depends on '%s', which doesn't exist", name, dep))
+			}
+		}
+	}
+	if cycle := t.dependencyCycle(); len(cycle) > 0 {
+		err = multierror.Append(err, fmt.Errorf(
+			"builds depend on each other in a cycle: %s",
+			strings.Join(cycle, " -> ")))
+	}
+
 	// Verify that the provisioner overrides target builders that exist
 	for i, p := range t.Provisioners {
 		// Validate only/except
@@ -233,6 +376,11 @@ func (t *Template) Validate() error {
 			}
 		}
 
+		if p.MaxRetries < 0 {
+			err = multierror.Append(err, fmt.Errorf(
+				"provisioner %d: max_retries can't be negative", i+1))
+		}
+
 		// Validate overrides
 		for name := range p.Override {
 			if _, ok := t.Builders[name]; !ok {
@@ -259,6 +407,61 @@ func (t *Template) Validate() error {
 	return err
 }
 
+// dependencyCycle returns the names of builds that depend on each other in
+// a cycle, starting and ending with the same build, or nil if there is no
+// cycle.
+func (t *Template) dependencyCycle() []string {
+	names := make([]string, 0, len(t.Builders))
+	for name := range t.Builders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		b, ok := t.Builders[name]
+		if !ok {
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range b.DependsOn {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
 // Skip says whether or not to skip the build with the given name.
 func (o *OnlyExcept) Skip(n string) bool {
 	if len(o.Only) > 0 {
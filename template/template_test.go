@@ -3,6 +3,7 @@ package template
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -72,6 +73,31 @@ func TestTemplateValidate(t *testing.T) {
 			"validate-good-pp-except.json",
 			false,
 		},
+
+		{
+			"validate-bad-prov-retries.json",
+			true,
+		},
+
+		{
+			"validate-bad-variable-type.json",
+			true,
+		},
+
+		{
+			"validate-good-depends-on.json",
+			false,
+		},
+
+		{
+			"validate-bad-depends-on.json",
+			true,
+		},
+
+		{
+			"validate-bad-depends-on-cycle.json",
+			true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -93,6 +119,22 @@ func TestTemplateValidate(t *testing.T) {
 	}
 }
 
+func TestTemplateValidate_dependencyCycle(t *testing.T) {
+	tpl, err := ParseFile(fixtureDir("validate-bad-depends-on-cycle.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	err = tpl.Validate()
+	if err == nil {
+		t.Fatal("should have error")
+	}
+	expected := "builds depend on each other in a cycle: app -> base -> app"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
 func TestOnlyExceptSkip(t *testing.T) {
 	cases := []struct {
 		Only, Except []string
@@ -135,3 +177,32 @@ func TestOnlyExceptSkip(t *testing.T) {
 		}
 	}
 }
+
+func TestVariableCheck(t *testing.T) {
+	cases := []struct {
+		Variable Variable
+		Value    string
+		Err      bool
+	}{
+		{Variable{}, "anything", false},
+		{Variable{Type: "number"}, "1.5", false},
+		{Variable{Type: "number"}, "big", true},
+		{Variable{Type: "bool"}, "true", false},
+		{Variable{Type: "bool"}, "yes", true},
+		{Variable{Type: "list"}, `["a"]`, false},
+		{Variable{Type: "list"}, "a,b", true},
+		{Variable{Type: "map"}, `{"a": "b"}`, false},
+		{Variable{Type: "map"}, `["a"]`, true},
+		{Variable{AllowedValues: []string{"dev", "prod"}}, "dev", false},
+		{Variable{AllowedValues: []string{"dev", "prod"}}, "test", true},
+		{Variable{Pattern: "[a-z]+"}, "abc", false},
+		{Variable{Pattern: "[a-z]+"}, "abc1", true},
+	}
+
+	for _, tc := range cases {
+		err := tc.Variable.Check(tc.Value)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%#v: %q: %v", tc.Variable, tc.Value, err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+source "qemu" "app" {
+  depends_on = [source.qemu.db]
+  iso_url    = "{{ artifact `qemu.db` `files` }}"
+}
+
+source "qemu" "db" {
+  iso_url = var.image
+}
+
+build {
+  sources = [source.qemu.app]
+
+  provisioner "shell" {
+    script = "app.sh"
+  }
+}
+
+build {
+  sources = [source.qemu.db]
+
+  provisioner "shell" {
+    script = "db.sh"
+  }
+
+  post-processor "manifest" {}
+}
@@ -0,0 +1,3 @@
+variable "image" {
+  default = "base.qcow2"
+}
@@ -0,0 +1,69 @@
+# Comments work the same as in any HCL file.
+description = "A template in HCL"
+
+packer {
+  required_version = ">= 1.3.0"
+}
+
+variable "region" {
+  type        = string
+  default     = "us-east-1"
+  description = "The region to build in"
+  pattern     = "[a-z]+-[a-z]+-[0-9]"
+}
+
+variable "password" {
+  sensitive      = true
+  allowed_values = ["hunter2", "letmein"]
+}
+
+variable "disk_size" {
+  type    = number
+  default = 4096
+}
+
+variable "packages" {
+  type    = list(string)
+  default = ["git", "make"]
+}
+
+locals {
+  name  = "web-${var.region}"
+  image = upper(local.name)
+}
+
+source "qemu" "base" {
+  disk_size = var.disk_size * 2
+  vm_name   = local.name
+  timeout   = "1h"
+
+  boot_command = ["<tab> text ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg<enter>"]
+}
+
+source "virtualbox-iso" "base" {
+  guest_os_type = "RedHat_64"
+  vm_name       = local.image
+}
+
+build {
+  sources = [source.qemu.base, source.virtualbox-iso.base]
+
+  provisioner "shell" {
+    inline      = ["echo $${HOME} ${var.password}", "yum install -y ${join(" ", var.packages)}"]
+    max_retries = 2
+  }
+
+  provisioner "file" {
+    only        = [source.qemu.base]
+    source      = "app.tar.gz"
+    destination = "/tmp/app.tar.gz"
+  }
+
+  post-processors {
+    post-processor "compress" {}
+
+    post-processor "manifest" {
+      except = [source.qemu.base]
+    }
+  }
+}